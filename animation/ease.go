@@ -0,0 +1,109 @@
+package animation
+
+import (
+	"math"
+)
+
+// Easing type is a function which maps the fraction of the tween duration which has elapsed, from 0 to 1, to
+// the fraction of the change in value. The standard curves are defined below, see https://easings.net
+type Easing func(t float32) float32
+
+const (
+	backC1 = 1.70158
+	backC3 = backC1 + 1
+)
+
+// EaseLinear changes at a constant rate
+func EaseLinear(t float32) float32 {
+	return t
+}
+
+// EaseInQuad starts slowly and accelerates
+func EaseInQuad(t float32) float32 {
+	return t * t
+}
+
+// EaseOutQuad starts quickly and decelerates
+func EaseOutQuad(t float32) float32 {
+	return t * (2 - t)
+}
+
+// EaseInOutQuad accelerates for the first half and decelerates for the second, this is the default
+func EaseInOutQuad(t float32) float32 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+// EaseInCubic is like EaseInQuad with a steeper curve
+func EaseInCubic(t float32) float32 {
+	return t * t * t
+}
+
+// EaseOutCubic is like EaseOutQuad with a steeper curve
+func EaseOutCubic(t float32) float32 {
+	t--
+	return t*t*t + 1
+}
+
+// EaseInOutCubic is like EaseInOutQuad with a steeper curve
+func EaseInOutCubic(t float32) float32 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return t*t*t/2 + 1
+}
+
+// EaseInSine starts slowly following a quarter of a sine wave
+func EaseInSine(t float32) float32 {
+	return 1 - float32(math.Cos(float64(t)*math.Pi/2))
+}
+
+// EaseOutSine finishes slowly following a quarter of a sine wave
+func EaseOutSine(t float32) float32 {
+	return float32(math.Sin(float64(t) * math.Pi / 2))
+}
+
+// EaseInOutSine follows half of a sine wave
+func EaseInOutSine(t float32) float32 {
+	return float32(1-math.Cos(float64(t)*math.Pi)) / 2
+}
+
+// EaseInBack pulls back a little before moving towards the target
+func EaseInBack(t float32) float32 {
+	return backC3*t*t*t - backC1*t*t
+}
+
+// EaseOutBack overshoots the target and then settles back on it
+func EaseOutBack(t float32) float32 {
+	t--
+	return 1 + backC3*t*t*t + backC1*t*t
+}
+
+// EaseOutBounce bounces against the target like a dropped ball
+func EaseOutBounce(t float32) float32 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	}
+	t -= 2.625 / d
+	return n*t*t + 0.984375
+}
+
+// EaseOutElastic overshoots and oscillates around the target like a spring
+func EaseOutElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+	x := float64(t)
+	return float32(math.Pow(2, -10*x)*math.Sin((10*x-0.75)*2*math.Pi/3)) + 1
+}
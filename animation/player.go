@@ -0,0 +1,223 @@
+package animation
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"math"
+)
+
+// Mode type sets what happens when a player reaches the end of the clip
+type Mode int
+
+const (
+	Once     Mode = iota // stop at the end
+	Loop                 // start again from the beginning
+	PingPong             // play backwards to the start, then forwards again
+)
+
+// Player type plays a clip on a target object. Call Update from the render loop with the time since the last
+// frame.
+type Player struct {
+	Clip    *Clip
+	Target  scene.Object
+	Mode    Mode
+	Speed   float32 // playback rate, 1 is normal speed and negative values play backwards
+	clock   float32
+	playing bool
+	found   scene.Object // target when the objects were found
+	objects map[string]scene.Object
+	lights  map[*scene.Light]mgl32.Vec3
+	colored map[*scene.Item]bool // items which have their own copy of the material
+}
+
+// NewPlayer creates a new player which starts playing the clip from the beginning on the next Update
+func NewPlayer(clip *Clip, target scene.Object, mode Mode) *Player {
+	return &Player{Clip: clip, Target: target, Mode: mode, Speed: 1, playing: true}
+}
+
+// Play method starts or resumes playback. If a clip in Once mode has finished then it is played again.
+func (p *Player) Play() *Player {
+	if p.Done() && p.Speed < 0 {
+		p.Seek(p.Clip.Duration())
+	} else if p.Done() {
+		p.Seek(0)
+	}
+	p.playing = true
+	return p
+}
+
+// Pause method stops playback at the current time
+func (p *Player) Pause() *Player {
+	p.playing = false
+	return p
+}
+
+// Stop method stops playback and puts the target back to how it is at the start of the clip
+func (p *Player) Stop() *Player {
+	p.playing = false
+	return p.Seek(0)
+}
+
+// Seek method moves to the given time in seconds from the start of the clip and updates the target
+func (p *Player) Seek(time float32) *Player {
+	p.clock = time
+	p.wrap()
+	p.Apply()
+	return p
+}
+
+// Playing returns true if the player is running
+func (p *Player) Playing() bool {
+	return p.playing
+}
+
+// Done returns true if a clip in Once mode has reached the end, or the start if it is playing backwards
+func (p *Player) Done() bool {
+	if p.Mode != Once {
+		return false
+	}
+	if p.Speed < 0 {
+		return p.clock <= 0
+	}
+	return p.clock >= p.Clip.Duration()
+}
+
+// Time returns the current position in the clip in seconds
+func (p *Player) Time() float32 {
+	if dur := p.Clip.Duration(); p.Mode == PingPong && p.clock > dur {
+		return 2*dur - p.clock
+	}
+	return p.clock
+}
+
+// Update method advances the clip by dt seconds and updates the target
+func (p *Player) Update(dt float32) {
+	if !p.playing {
+		return
+	}
+	p.clock += dt * p.Speed
+	p.wrap()
+	if p.Done() {
+		p.playing = false
+	}
+	p.Apply()
+}
+
+// keep the clock in range for the mode, for ping pong mode a full cycle is twice the clip length
+func (p *Player) wrap() {
+	dur := p.Clip.Duration()
+	switch {
+	case p.Mode == Once || dur == 0:
+		p.clock = clamp(p.clock, 0, dur)
+	case p.Mode == Loop:
+		p.clock = mod(p.clock, dur)
+	case p.Mode == PingPong:
+		p.clock = mod(p.clock, 2*dur)
+	}
+}
+
+// Apply method sets the animated properties of the target for the current time
+func (p *Player) Apply() {
+	time := p.Time()
+	for _, t := range p.Clip.Tracks {
+		obj := p.object(t.Path)
+		if obj == nil || len(t.Keys) == 0 {
+			continue
+		}
+		v := t.Sample(time)
+		switch t.Property {
+		case Position:
+			obj.SetPosition(v.Vec3())
+		case Rotation:
+			obj.SetRotation(toQuat(v))
+		case Scale:
+			obj.SetScale(v.Vec3())
+		case Color:
+			if item, ok := obj.(*scene.Item); ok && item.Material() != nil {
+				p.material(item).SetColor(v)
+			}
+		case Intensity:
+			if item, ok := obj.(*scene.Item); ok && item.Light != nil {
+				item.Light.Col = p.lightColor(item.Light).Mul(v[0]).Vec4(item.Light.Col.W())
+			}
+		case Weight:
+			setMorphWeight(obj, t.Target, v[0])
+		}
+	}
+}
+
+// objects found by path are saved so they are only looked up once
+func (p *Player) object(path string) scene.Object {
+	if path == "" {
+		return p.Target
+	}
+	if p.objects == nil || p.found != p.Target {
+		p.objects = map[string]scene.Object{}
+		p.found = p.Target
+	}
+	obj, ok := p.objects[path]
+	if !ok {
+		if grp, isGroup := p.Target.(*scene.Group); isGroup {
+			obj = grp.Find(path)
+		}
+		p.objects[path] = obj
+	}
+	return obj
+}
+
+// materials may be shared with other items, so the item is given its own copy the first time it is animated
+func (p *Player) material(item *scene.Item) mesh.Material {
+	if p.colored == nil {
+		p.colored = map[*scene.Item]bool{}
+	}
+	if !p.colored[item] {
+		item.SetMaterial(item.Material().Clone())
+		p.colored[item] = true
+	}
+	return item.Material()
+}
+
+// intensity is relative to the color of the light when it was first animated
+func (p *Player) lightColor(l *scene.Light) mgl32.Vec3 {
+	if p.lights == nil {
+		p.lights = map[*scene.Light]mgl32.Vec3{}
+	}
+	col, ok := p.lights[l]
+	if !ok {
+		col = l.Col.Vec3()
+		p.lights[l] = col
+	}
+	return col
+}
+
+func clamp(x, min, max float32) float32 {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
+
+func mod(x, y float32) float32 {
+	m := float32(math.Mod(float64(x), float64(y)))
+	if m < 0 {
+		m += y
+	}
+	return m
+}
+
+// set the weight on the item's mesh, or on the meshes of the items in a group
+func setMorphWeight(obj scene.Object, target int, w float32) {
+	objects := []scene.Object{obj}
+	if grp, ok := obj.(*scene.Group); ok {
+		objects = grp.Objects()
+	}
+	for _, o := range objects {
+		if item, ok := o.(*scene.Item); ok && item.Mesh != nil {
+			item.Mesh.SetMorphWeight(target, w)
+		}
+	}
+}
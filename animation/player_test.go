@@ -0,0 +1,208 @@
+package animation
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"testing"
+)
+
+// two items with the same material, as for items loaded from an OBJ file
+func sharedItems() (a, b *scene.Item, mtl mesh.Material) {
+	mtl = mesh.Diffuse().SetColor(mgl32.Vec4{1, 1, 1, 1})
+	a = scene.NewItem(mesh.Cube().SetMaterial(mtl))
+	b = scene.NewItem(mesh.Cube().SetMaterial(mtl))
+	return a, b, mtl
+}
+
+func TestColorSharedMaterial(t *testing.T) {
+	a, b, mtl := sharedItems()
+	red := mgl32.Vec4{1, 0, 0, 1}
+	clip := NewClip("fade", NewTrack(Color, Linear).Add(0, mgl32.Vec4{1, 1, 1, 1}).Add(1, red))
+	p := NewPlayer(clip, a, Once)
+	p.Update(0.5)
+	copied := a.Material()
+	p.Update(1)
+	if got := a.Material().Color(); got != red {
+		t.Errorf("animated item color is %v, expecting %v", got, red)
+	}
+	if a.Material() != copied {
+		t.Errorf("material copied more than once")
+	}
+	if b.Material() != mtl || mtl.Color() != (mgl32.Vec4{1, 1, 1, 1}) {
+		t.Errorf("shared material changed to %v", mtl.Color())
+	}
+}
+
+func near(a, b float32) bool {
+	return a-b < 1e-5 && b-a < 1e-5
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		interp Interpolation
+		keys   []float32 // values at times 0, 1, 2
+		time   float32
+		want   float32
+	}{
+		{Step, []float32{0, 2, 6}, 0, 0},
+		{Step, []float32{0, 2, 6}, 0.5, 0},
+		{Step, []float32{0, 2, 6}, 1, 2},
+		{Step, []float32{0, 2, 6}, 1.99, 2},
+		{Linear, []float32{0, 2, 6}, 0.5, 1},
+		{Linear, []float32{0, 2, 6}, 1, 2},
+		{Linear, []float32{0, 2, 6}, 1.25, 3},
+		{Linear, []float32{0, 2, 6}, -1, 0},
+		{Linear, []float32{0, 2, 6}, 3, 6},
+		{Slerp, []float32{0, 2, 6}, 1.5, 4},
+		{Cubic, []float32{0, 1, 0}, 0, 0},
+		{Cubic, []float32{0, 1, 0}, 1, 1},
+		{Cubic, []float32{0, 1, 0}, 2, 0},
+		// tangents are 1 at the first key and 0 at the middle one
+		{Cubic, []float32{0, 1, 0}, 0.5, 0.625},
+		{Cubic, []float32{0, 1, 0}, 1.5, 0.625},
+		// keys on a straight line give a straight line
+		{Cubic, []float32{0, 2, 4}, 0.25, 0.5},
+	}
+	for _, test := range tests {
+		track := NewTrack(Position, test.interp)
+		for i, v := range test.keys {
+			track.AddVec3(float32(i), mgl32.Vec3{v, -v, 0})
+		}
+		got := track.Sample(test.time)
+		if !near(got[0], test.want) || !near(got[1], -test.want) {
+			t.Errorf("interp %d keys %v at %g: got %v, expecting %g", test.interp, test.keys, test.time, got, test.want)
+		}
+	}
+}
+
+func TestSampleRotation(t *testing.T) {
+	up := mgl32.Vec3{0, 1, 0}
+	tests := []struct {
+		interp Interpolation
+		time   float32
+		angle  float32
+	}{
+		{Slerp, 0.25, 22.5},
+		{Slerp, 0.5, 45},
+		{Linear, 0.5, 45},
+		{Step, 0.9, 0},
+		{Slerp, 1, 90},
+	}
+	for _, test := range tests {
+		track := NewTrack(Rotation, test.interp).AddAngle(0, 0, up).AddAngle(1, 90, up)
+		v := track.Sample(test.time)
+		q := toQuat(v)
+		want := mgl32.QuatRotate(mgl32.DegToRad(test.angle), up)
+		if !near(v.Len(), 1) || !near(q.Dot(want), 1) {
+			t.Errorf("interp %d at %g: got %v, expecting %v", test.interp, test.time, q, want)
+		}
+	}
+	// linear blending of the quaternions is not constant speed
+	q := toQuat(NewTrack(Rotation, Linear).AddAngle(0, 0, up).AddAngle(1, 90, up).Sample(0.25))
+	if near(q.Dot(mgl32.QuatRotate(mgl32.DegToRad(22.5), up)), 1) {
+		t.Errorf("linear rotation at 0.25 should not be the same as slerp")
+	}
+}
+
+func TestRotationHemisphere(t *testing.T) {
+	up := mgl32.Vec3{0, 1, 0}
+	q0 := mgl32.QuatRotate(mgl32.DegToRad(170), up)
+	q1 := mgl32.QuatRotate(mgl32.DegToRad(-170), up)
+	track := NewTrack(Rotation, Slerp).AddQuat(0, q0).AddQuat(1, q1)
+	if track.Keys[0].Value.Dot(track.Keys[1].Value) < 0 {
+		t.Fatalf("second key was not flipped: %v", track.Keys)
+	}
+	// the short way round is through 180 degrees rather than back through 0
+	got := toQuat(track.Sample(0.5))
+	want := mgl32.QuatRotate(mgl32.DegToRad(180), up)
+	if d := got.Dot(want); !near(d, 1) && !near(d, -1) {
+		t.Errorf("rotation at 0.5 is %v, expecting %v", got, want)
+	}
+	// positions are never flipped
+	pos := NewTrack(Position, Linear).Add(0, mgl32.Vec4{1, 0, 0, 0}).Add(1, mgl32.Vec4{-1, 0, 0, 0})
+	if pos.Keys[1].Value[0] != -1 {
+		t.Errorf("position key was flipped")
+	}
+}
+
+// item whose x position goes from 0 to 2 over 2 seconds
+func playerFor(mode Mode) (*Player, *scene.Item) {
+	item := scene.NewItem(mesh.Cube())
+	clip := NewClip("move", NewTrack(Position, Linear).AddVec3(0, mgl32.Vec3{}).AddVec3(2, mgl32.Vec3{2, 0, 0}))
+	return NewPlayer(clip, item, mode), item
+}
+
+func TestPlayerModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    Mode
+		speed   float32
+		start   float32
+		steps   []float32
+		time    float32
+		playing bool
+	}{
+		{"once", Once, 1, 0, []float32{0.5, 0.75}, 1.25, true},
+		{"once end", Once, 1, 0, []float32{1.5, 10}, 2, false},
+		{"once backwards", Once, -1, 2, []float32{0.5}, 1.5, true},
+		{"once backwards end", Once, -1, 2, []float32{0.5, 10}, 0, false},
+		{"loop", Loop, 1, 0, []float32{1.5, 1}, 0.5, true},
+		{"loop large step", Loop, 1, 0, []float32{9.5}, 1.5, true},
+		{"loop backwards", Loop, -1, 0, []float32{0.5}, 1.5, true},
+		{"pingpong forwards", PingPong, 1, 0, []float32{1.5}, 1.5, true},
+		{"pingpong back", PingPong, 1, 0, []float32{1.5, 1}, 1.5, true},
+		{"pingpong large step", PingPong, 1, 0, []float32{11}, 1, true},
+		{"pingpong half speed", PingPong, 0.5, 0, []float32{5}, 1.5, true},
+		{"pingpong backwards", PingPong, -1, 0, []float32{0.5}, 0.5, true},
+	}
+	for _, test := range tests {
+		p, item := playerFor(test.mode)
+		p.Speed = test.speed
+		p.Seek(test.start)
+		for _, dt := range test.steps {
+			p.Update(dt)
+		}
+		if !near(p.Time(), test.time) || p.Playing() != test.playing {
+			t.Errorf("%s: time %g playing %v, expecting %g %v", test.name, p.Time(), p.Playing(), test.time, test.playing)
+		}
+		if p.Done() != (test.mode == Once && !test.playing) {
+			t.Errorf("%s: done is %v", test.name, p.Done())
+		}
+		if x := item.Position()[0]; !near(x, test.time) {
+			t.Errorf("%s: item is at %g, expecting %g", test.name, x, test.time)
+		}
+	}
+}
+
+func TestPlayerSeekStop(t *testing.T) {
+	p, item := playerFor(Once)
+	p.Seek(1.5)
+	if x := item.Position()[0]; !near(x, 1.5) || !p.Playing() {
+		t.Errorf("after seek item is at %g playing %v", x, p.Playing())
+	}
+	p.Seek(5)
+	if !p.Done() || !near(item.Position()[0], 2) {
+		t.Errorf("seek past the end: done %v position %v", p.Done(), item.Position())
+	}
+	p.Stop()
+	if p.Playing() || p.Time() != 0 || item.Position() != (mgl32.Vec3{}) {
+		t.Errorf("after stop: playing %v time %g position %v", p.Playing(), p.Time(), item.Position())
+	}
+	p.Update(1)
+	if item.Position() != (mgl32.Vec3{}) {
+		t.Errorf("stopped player moved the item to %v", item.Position())
+	}
+	// playing again after the end starts from the beginning, or the end if going backwards
+	p.Seek(2)
+	p.Play()
+	if p.Time() != 0 || !p.Playing() {
+		t.Errorf("play after the end: time %g playing %v", p.Time(), p.Playing())
+	}
+	p.Speed = -1
+	p.Seek(0)
+	p.Play()
+	if p.Time() != 2 || !p.Playing() {
+		t.Errorf("play backwards after the start: time %g playing %v", p.Time(), p.Playing())
+	}
+}
@@ -0,0 +1,213 @@
+// Package animation plays keyframed clips on scene objects. A clip is a set of tracks which each animate one
+// property, such as the position or rotation of an object or the color of its material, and a player advances
+// the clip by the elapsed time in seconds so the motion does not depend on the frame rate.
+package animation
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"sort"
+)
+
+// Property type identifies what a track animates
+type Property int
+
+const (
+	Position  Property = iota // object position, keys are Vec3 values
+	Rotation                  // object rotation, keys are quaternions stored as x, y, z, w
+	Scale                     // object scale, keys are Vec3 values
+	Color                     // color of the item's material, keys are RGBA values
+	Intensity                 // brightness of the light attached to an item, the key value is in the first component
+	Weight                    // weight of one morph target of the item's mesh, the key value is in the first component
+)
+
+var propertyNames = []string{"position", "rotation", "scale", "color", "intensity", "weight"}
+
+func (p Property) String() string {
+	if int(p) < len(propertyNames) {
+		return propertyNames[p]
+	}
+	return "unknown"
+}
+
+// Interpolation type is the method used to calculate values between keys
+type Interpolation int
+
+const (
+	Linear Interpolation = iota // straight line between keys, rotations are normalized after blending
+	Step                        // hold the value of each key until the next one
+	Slerp                       // constant angular speed between rotation keys, same as Linear for other properties
+	Cubic                       // smooth curve through the keys with Catmull-Rom style tangents
+)
+
+// Key type is the value of a property at a time in seconds from the start of the clip. Components of the
+// value which are not used by the property are ignored.
+type Key struct {
+	Time  float32
+	Value mgl32.Vec4
+}
+
+// Track type has the keys for one property. If Path is set then the track animates the object with that path
+// under the player's target group, as for scene.Group.Find, otherwise it animates the target itself. Target
+// is the morph target number for Weight tracks.
+type Track struct {
+	Property Property
+	Interp   Interpolation
+	Path     string
+	Target   int
+	Keys     []Key
+}
+
+// NewTrack creates a new empty track
+func NewTrack(prop Property, interp Interpolation) *Track {
+	return &Track{Property: prop, Interp: interp}
+}
+
+// On method sets the path of the object which the track animates
+func (t *Track) On(path string) *Track {
+	t.Path = path
+	return t
+}
+
+// Morph method sets the morph target which a Weight track animates
+func (t *Track) Morph(target int) *Track {
+	t.Target = target
+	return t
+}
+
+// Add method appends a key, keys must be added in time order. Rotation keys are flipped if needed so each
+// one is in the same hemisphere as the one before, so blending takes the shortest path.
+func (t *Track) Add(time float32, value mgl32.Vec4) *Track {
+	if n := len(t.Keys); n > 0 {
+		prev := t.Keys[n-1]
+		if time < prev.Time {
+			panic("animation: keys must be added in time order")
+		}
+		if t.Property == Rotation && prev.Value.Dot(value) < 0 {
+			value = value.Mul(-1)
+		}
+	}
+	t.Keys = append(t.Keys, Key{Time: time, Value: value})
+	return t
+}
+
+// AddVec3 method adds a position or scale key
+func (t *Track) AddVec3(time float32, v mgl32.Vec3) *Track {
+	return t.Add(time, v.Vec4(0))
+}
+
+// AddQuat method adds a rotation key
+func (t *Track) AddQuat(time float32, q mgl32.Quat) *Track {
+	return t.Add(time, q.V.Vec4(q.W))
+}
+
+// AddAngle method adds a rotation key given as an angle in degrees about an axis
+func (t *Track) AddAngle(time, angle float32, axis mgl32.Vec3) *Track {
+	return t.AddQuat(time, mgl32.QuatRotate(mgl32.DegToRad(angle), axis.Normalize()))
+}
+
+// AddValue method adds a key with a single value, such as the intensity of a light
+func (t *Track) AddValue(time, value float32) *Track {
+	return t.Add(time, mgl32.Vec4{value, 0, 0, 0})
+}
+
+// Duration returns the time of the last key
+func (t *Track) Duration() float32 {
+	if len(t.Keys) == 0 {
+		return 0
+	}
+	return t.Keys[len(t.Keys)-1].Time
+}
+
+// Sample returns the interpolated value at the given time. Before the first key or after the last one the
+// value of the nearest key is returned.
+func (t *Track) Sample(time float32) mgl32.Vec4 {
+	n := len(t.Keys)
+	switch {
+	case n == 0:
+		return mgl32.Vec4{}
+	case time <= t.Keys[0].Time:
+		return t.Keys[0].Value
+	case time >= t.Keys[n-1].Time:
+		return t.Keys[n-1].Value
+	}
+	i := sort.Search(n, func(i int) bool { return t.Keys[i].Time > time }) - 1
+	k0, k1 := t.Keys[i], t.Keys[i+1]
+	u := (time - k0.Time) / (k1.Time - k0.Time)
+	var v mgl32.Vec4
+	switch t.Interp {
+	case Step:
+		return k0.Value
+	case Slerp:
+		if t.Property == Rotation {
+			q := mgl32.QuatSlerp(toQuat(k0.Value), toQuat(k1.Value), u)
+			return q.V.Vec4(q.W)
+		}
+		v = k0.Value.Add(k1.Value.Sub(k0.Value).Mul(u))
+	case Cubic:
+		v = t.cubic(i, u)
+	default:
+		v = k0.Value.Add(k1.Value.Sub(k0.Value).Mul(u))
+	}
+	if t.Property == Rotation {
+		v = v.Normalize()
+	}
+	return v
+}
+
+// cubic Hermite curve between key i and i+1 with the tangents from the neighbouring keys
+func (t *Track) cubic(i int, u float32) mgl32.Vec4 {
+	k0, k1 := t.Keys[i], t.Keys[i+1]
+	h := k1.Time - k0.Time
+	u2, u3 := u*u, u*u*u
+	v := k0.Value.Mul(2*u3 - 3*u2 + 1)
+	v = v.Add(t.tangent(i).Mul(h * (u3 - 2*u2 + u)))
+	v = v.Add(k1.Value.Mul(3*u2 - 2*u3))
+	return v.Add(t.tangent(i + 1).Mul(h * (u3 - u2)))
+}
+
+func (t *Track) tangent(i int) mgl32.Vec4 {
+	lo, hi := i-1, i+1
+	if lo < 0 {
+		lo = i
+	}
+	if hi >= len(t.Keys) {
+		hi = i
+	}
+	k0, k1 := t.Keys[lo], t.Keys[hi]
+	if k1.Time <= k0.Time {
+		return mgl32.Vec4{}
+	}
+	return k1.Value.Sub(k0.Value).Mul(1 / (k1.Time - k0.Time))
+}
+
+func toQuat(v mgl32.Vec4) mgl32.Quat {
+	return mgl32.Quat{W: v[3], V: v.Vec3()}
+}
+
+// Clip type is a named set of tracks which are played together
+type Clip struct {
+	Name   string
+	Tracks []*Track
+}
+
+// NewClip creates a new clip with the given tracks
+func NewClip(name string, tracks ...*Track) *Clip {
+	return &Clip{Name: name, Tracks: tracks}
+}
+
+// Add method appends one or more tracks to the clip
+func (c *Clip) Add(tracks ...*Track) *Clip {
+	c.Tracks = append(c.Tracks, tracks...)
+	return c
+}
+
+// Duration returns the time of the last key in any of the tracks
+func (c *Clip) Duration() float32 {
+	dur := float32(0)
+	for _, t := range c.Tracks {
+		if d := t.Duration(); d > dur {
+			dur = d
+		}
+	}
+	return dur
+}
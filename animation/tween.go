@@ -0,0 +1,255 @@
+package animation
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/scene"
+)
+
+type tweenState int
+
+const (
+	waiting tweenState = iota
+	running
+	finished
+	cancelled
+)
+
+// Tween type changes a value from where it is when the tween starts to a target over a fixed time, without
+// needing a clip. Add it to a Tweens list which is updated from the render loop. e.g.
+//
+//	tweens.Add(MoveTo(obj, mgl32.Vec3{0, 1, 0}, 0.5)).Then(ScaleTo(obj, mgl32.Vec3{2, 2, 2}, 1)).OnDone(fn)
+type Tween struct {
+	duration float32
+	elapsed  float32
+	ease     Easing
+	start    func() func(f float32)
+	set      func(f float32)
+	state    tweenState
+	done     []func()
+	next     []*Tween
+}
+
+// NewTween creates a custom tween. When it starts start is called to read the current value, and returns the
+// function which sets the value given the eased fraction of the change. This goes from 0 to 1 and may go
+// outside that range for curves such as EaseOutBack.
+func NewTween(duration float32, start func() func(f float32)) *Tween {
+	return &Tween{duration: duration, ease: EaseInOutQuad, start: start}
+}
+
+// MoveTo creates a tween which moves the object to a new position
+func MoveTo(obj scene.Object, pos mgl32.Vec3, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		from := obj.Position()
+		return func(f float32) {
+			obj.SetPosition(lerp3(from, pos, f))
+		}
+	})
+}
+
+// RotateTo creates a tween which turns the object by the shortest path to a new rotation
+func RotateTo(obj scene.Object, rot mgl32.Quat, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		from := obj.Rotation()
+		return func(f float32) {
+			obj.SetRotation(mgl32.QuatSlerp(from, rot, f))
+		}
+	})
+}
+
+// ScaleTo creates a tween which changes the scale of the object
+func ScaleTo(obj scene.Object, scale mgl32.Vec3, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		from := obj.Scaling()
+		return func(f float32) {
+			obj.SetScale(lerp3(from, scale, f))
+		}
+	})
+}
+
+// ColorTo creates a tween which changes the color of the item's material. The item is given its own copy of
+// the material when the tween starts, as it may be shared with other items.
+func ColorTo(item *scene.Item, color mgl32.Vec4, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		if item.Material() == nil {
+			return func(float32) {}
+		}
+		mtl := item.Material().Clone()
+		item.SetMaterial(mtl)
+		from := mtl.Color()
+		return func(f float32) {
+			mtl.SetColor(from.Add(color.Sub(from).Mul(f)))
+		}
+	})
+}
+
+// LightColorTo creates a tween which changes the color of a light, the ambient factor is not changed
+func LightColorTo(l *scene.Light, color mgl32.Vec3, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		from := l.Col.Vec3()
+		return func(f float32) {
+			l.Col = lerp3(from, color, f).Vec4(l.Col.W())
+		}
+	})
+}
+
+// LightMoveTo creates a tween which moves a point light, or changes the direction of a directional light
+func LightMoveTo(l *scene.Light, pos mgl32.Vec3, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		from := l.Pos.Vec3()
+		return func(f float32) {
+			p := lerp3(from, pos, f)
+			if l.Directional() {
+				p = p.Normalize()
+			}
+			l.Pos = p.Vec4(l.Pos.W())
+		}
+	})
+}
+
+// CameraTo creates a tween which moves the camera to eye looking at center. The offset from the center to the
+// eye is interpolated in polar coordinates so the camera swings around the scene rather than cutting through.
+func CameraTo(camera scene.Camera, eye, center mgl32.Vec3, duration float32) *Tween {
+	return NewTween(duration, func() func(float32) {
+		c0 := camera.Center()
+		var p0, p1 glu.Polar
+		p0.Set(camera.Eye().Sub(c0))
+		p1.Set(eye.Sub(center))
+		// go the shortest way around the vertical axis
+		if p1.Phi-p0.Phi > 180 {
+			p1.Phi -= 360
+		} else if p1.Phi-p0.Phi < -180 {
+			p1.Phi += 360
+		}
+		return func(f float32) {
+			c := lerp3(c0, center, f)
+			p := glu.Polar{R: lerp(p0.R, p1.R, f), Theta: lerp(p0.Theta, p1.Theta, f), Phi: lerp(p0.Phi, p1.Phi, f)}
+			scene.PlaceCamera(camera, c.Add(p.Vec3()), c)
+		}
+	})
+}
+
+// SetEasing method sets the easing curve, the default is EaseInOutQuad
+func (t *Tween) SetEasing(ease Easing) *Tween {
+	t.ease = ease
+	return t
+}
+
+// SetDelay method sets a time in seconds to wait before the tween starts
+func (t *Tween) SetDelay(delay float32) *Tween {
+	t.elapsed = -delay
+	return t
+}
+
+// OnDone method adds a function to call when the tween finishes, it is not called if the tween is cancelled
+func (t *Tween) OnDone(fn func()) *Tween {
+	t.done = append(t.done, fn)
+	return t
+}
+
+// Then method adds a tween which starts when this one finishes. It returns the new tween so calls can be
+// chained to make a sequence.
+func (t *Tween) Then(next *Tween) *Tween {
+	t.next = append(t.next, next)
+	return next
+}
+
+// Cancel method stops the tween where it is, along with any which were due to follow it
+func (t *Tween) Cancel() {
+	if t.state != finished {
+		t.state = cancelled
+	}
+	for _, next := range t.next {
+		next.Cancel()
+	}
+}
+
+// Done returns true once the tween has finished or been cancelled
+func (t *Tween) Done() bool {
+	return t.state >= finished
+}
+
+// advance moves the tween on by dt seconds, returning the time left over if it has finished
+func (t *Tween) advance(dt float32) (left float32, done bool) {
+	t.elapsed += dt
+	if t.elapsed < 0 {
+		return 0, false
+	}
+	if t.state == waiting {
+		t.set = t.start()
+		t.state = running
+	}
+	if t.elapsed < t.duration {
+		t.set(t.ease(t.elapsed / t.duration))
+		return 0, false
+	}
+	t.set(1)
+	t.state = finished
+	return t.elapsed - t.duration, true
+}
+
+// Tweens type is a list of running tweens
+type Tweens struct {
+	list []*Tween
+}
+
+// Add method starts a tween on the next Update and returns it
+func (ts *Tweens) Add(t *Tween) *Tween {
+	ts.list = append(ts.list, t)
+	return t
+}
+
+// Update method advances the tweens by dt seconds. When a tween finishes its callbacks are called and the
+// tweens which follow it are started with the time which is left over.
+func (ts *Tweens) Update(dt float32) {
+	type step struct {
+		tween *Tween
+		dt    float32
+	}
+	queue := []step{}
+	for _, t := range ts.list {
+		queue = append(queue, step{t, dt})
+	}
+	// tweens added by callbacks are started on the next update
+	ts.list = nil
+	active := []*Tween{}
+	for i := 0; i < len(queue); i++ {
+		t := queue[i].tween
+		if t.Done() {
+			continue
+		}
+		left, done := t.advance(queue[i].dt)
+		if !done {
+			active = append(active, t)
+			continue
+		}
+		for _, fn := range t.done {
+			fn()
+		}
+		for _, next := range t.next {
+			queue = append(queue, step{next, left})
+		}
+	}
+	ts.list = append(active, ts.list...)
+}
+
+// Cancel method cancels all of the tweens
+func (ts *Tweens) Cancel() {
+	for _, t := range ts.list {
+		t.Cancel()
+	}
+	ts.list = nil
+}
+
+// Len returns the number of tweens which are waiting or running
+func (ts *Tweens) Len() int {
+	return len(ts.list)
+}
+
+func lerp(a, b, f float32) float32 {
+	return a + (b-a)*f
+}
+
+func lerp3(a, b mgl32.Vec3, f float32) mgl32.Vec3 {
+	return a.Add(b.Sub(a).Mul(f))
+}
@@ -0,0 +1,136 @@
+package animation
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"reflect"
+	"testing"
+)
+
+func TestColorToSharedMaterial(t *testing.T) {
+	a, b, mtl := sharedItems()
+	red := mgl32.Vec4{1, 0, 0, 1}
+	var ts Tweens
+	ts.Add(ColorTo(a, red, 1))
+	ts.Update(0.5)
+	ts.Update(0.5)
+	if got := a.Material().Color(); got != red {
+		t.Errorf("tweened item color is %v, expecting %v", got, red)
+	}
+	if b.Material() != mtl || mtl.Color() != (mgl32.Vec4{1, 1, 1, 1}) {
+		t.Errorf("shared material changed to %v", mtl.Color())
+	}
+}
+
+// tween which moves the x position linearly and logs when it finishes
+func moveX(obj scene.Object, x, duration float32, name string, log *[]string) *Tween {
+	return MoveTo(obj, mgl32.Vec3{x, 0, 0}, duration).SetEasing(EaseLinear).OnDone(func() {
+		*log = append(*log, name)
+	})
+}
+
+func TestTweens(t *testing.T) {
+	type step struct {
+		dt  float32
+		x   float32
+		n   int
+		log []string
+	}
+	tests := []struct {
+		name  string
+		build func(ts *Tweens, obj scene.Object, log *[]string)
+		steps []step
+	}{
+		{"single", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 2, 1, "a", log))
+		}, []step{{0.25, 0.5, 1, nil}, {0.5, 1.5, 1, nil}, {0.5, 2, 0, []string{"a"}}, {1, 2, 0, []string{"a"}}}},
+
+		{"chain with carried over time", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 1, 1, "a", log)).Then(moveX(obj, 3, 1, "b", log)).Then(moveX(obj, 0, 2, "c", log))
+		}, []step{{1.5, 2, 1, []string{"a"}}, {1, 2.25, 1, []string{"a", "b"}}, {1.5, 0, 0, []string{"a", "b", "c"}}}},
+
+		{"step past a whole chain", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 1, 1, "a", log)).Then(moveX(obj, 3, 1, "b", log))
+		}, []step{{5, 3, 0, []string{"a", "b"}}}},
+
+		{"delay", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 4, 1, "a", log).SetDelay(0.5))
+		}, []step{{0.25, 0, 1, nil}, {0.5, 1, 1, nil}, {0.75, 4, 0, []string{"a"}}}},
+
+		{"delay after chaining", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 1, 1, "a", log)).Then(moveX(obj, 3, 1, "b", log).SetDelay(1))
+		}, []step{{1.5, 1, 1, []string{"a"}}, {1, 2, 1, []string{"a"}}}},
+
+		{"parallel followers", func(ts *Tweens, obj scene.Object, log *[]string) {
+			first := ts.Add(moveX(obj, 1, 1, "a", log).OnDone(func() { *log = append(*log, "a2") }))
+			first.Then(moveX(obj, 5, 1, "b", log))
+			first.Then(NewTween(0.5, func() func(float32) { return func(float32) {} }).OnDone(func() { *log = append(*log, "c") }))
+		}, []step{{1, 1, 2, []string{"a", "a2"}}, {0.5, 3, 1, []string{"a", "a2", "c"}}, {0.5, 5, 0, []string{"a", "a2", "c", "b"}}}},
+
+		{"zero duration", func(ts *Tweens, obj scene.Object, log *[]string) {
+			ts.Add(moveX(obj, 1, 0, "a", log)).Then(moveX(obj, 2, 0, "b", log)).Then(moveX(obj, 4, 1, "c", log))
+		}, []step{{0, 2, 1, []string{"a", "b"}}, {0.5, 3, 1, []string{"a", "b"}}}},
+
+		{"cancel", func(ts *Tweens, obj scene.Object, log *[]string) {
+			a := ts.Add(moveX(obj, 2, 1, "a", log))
+			a.Then(moveX(obj, 3, 1, "b", log))
+			ts.Add(NewTween(0.5, func() func(float32) { return func(float32) {} }).OnDone(a.Cancel))
+		}, []step{{0.5, 1, 1, nil}, {1, 1, 0, nil}}},
+	}
+	for _, test := range tests {
+		obj := scene.NewItem(mesh.Cube())
+		var ts Tweens
+		var log []string
+		test.build(&ts, obj, &log)
+		for i, s := range test.steps {
+			ts.Update(s.dt)
+			if x := obj.Position()[0]; !near(x, s.x) || ts.Len() != s.n || !reflect.DeepEqual(log, s.log) {
+				t.Errorf("%s step %d: x %g len %d done %v, expecting %g %d %v", test.name, i, x, ts.Len(), log, s.x, s.n, s.log)
+			}
+		}
+	}
+}
+
+func TestTweenCancel(t *testing.T) {
+	obj := scene.NewItem(mesh.Cube())
+	var ts Tweens
+	var log []string
+	a := ts.Add(moveX(obj, 2, 1, "a", &log))
+	b := a.Then(moveX(obj, 3, 1, "b", &log))
+	c := b.Then(moveX(obj, 4, 1, "c", &log))
+	ts.Update(1.5)
+	b.Cancel()
+	if !a.Done() || !b.Done() || !c.Done() {
+		t.Errorf("done is %v %v %v after cancel", a.Done(), b.Done(), c.Done())
+	}
+	ts.Update(5)
+	if x := obj.Position()[0]; !near(x, 2.5) || ts.Len() != 0 || !reflect.DeepEqual(log, []string{"a"}) {
+		t.Errorf("after cancel: x %g len %d done %v", x, ts.Len(), log)
+	}
+	// cancelling the list stops everything
+	ts.Add(moveX(obj, 0, 1, "d", &log))
+	ts.Update(0.5)
+	ts.Cancel()
+	ts.Update(1)
+	if x := obj.Position()[0]; !near(x, 1.25) || ts.Len() != 0 || len(log) != 1 {
+		t.Errorf("after cancelling the list: x %g len %d done %v", x, ts.Len(), log)
+	}
+}
+
+func TestTweenAddedByCallback(t *testing.T) {
+	obj := scene.NewItem(mesh.Cube())
+	var ts Tweens
+	var log []string
+	ts.Add(moveX(obj, 1, 1, "a", &log).OnDone(func() {
+		ts.Add(moveX(obj, 2, 1, "b", &log))
+	}))
+	ts.Update(1.5)
+	if x := obj.Position()[0]; x != 1 || ts.Len() != 1 {
+		t.Errorf("tween added by a callback started early: x %g len %d", x, ts.Len())
+	}
+	ts.Update(0.5)
+	if x := obj.Position()[0]; !near(x, 1.5) {
+		t.Errorf("tween added by a callback: x is %g, expecting 1.5", x)
+	}
+}
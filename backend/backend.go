@@ -0,0 +1,48 @@
+// Package backend defines the interface between an application and the window system which provides the GL
+// context. Backends are provided for go-qml (qmlgl), GLFW (glfwgl) and headless EGL rendering (offscreen).
+package backend
+
+import (
+	"github.com/jnb666/go3d/glu"
+	"time"
+)
+
+// Interval between calls to the Tick method for backends which do not have their own timers
+var TickInterval = 20 * time.Millisecond
+
+// App interface is implemented by the application to draw each frame. Paint is called with the GL context
+// current, so the application should create its GL objects on the first call.
+type App interface {
+	Paint(gl glu.Context, width, height int)
+}
+
+// Window interface is implemented by each backend. FlipY returns true if the y axis of the framebuffer is
+// upside down, the app should copy this to scene.View.FlipY.
+type Window interface {
+	Update()
+	Close()
+	FlipY() bool
+}
+
+// MouseHandler is an optional interface for an App to handle mouse input. Event is one of start, move
+// or end. Button is 1 for the left mouse button or 2 for the right.
+type MouseHandler interface {
+	Mouse(event string, x, y, button int)
+}
+
+// KeyHandler is an optional interface for an App to handle keyboard input. Key is the lowercase character
+// for printable keys, or one of left, right, up, down, space, enter or escape.
+type KeyHandler interface {
+	Key(key string)
+}
+
+// ZoomHandler is an optional interface for an App to handle mouse wheel events. Delta is in eighths of a degree,
+// so a single step of a typical mouse wheel gives a value of +/-120.
+type ZoomHandler interface {
+	Zoom(delta int)
+}
+
+// Ticker is an optional interface for an App which is called every TickInterval for animation.
+type Ticker interface {
+	Tick()
+}
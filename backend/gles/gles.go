@@ -0,0 +1,325 @@
+// Package gles implements the glu.Context interface using the go-gl OpenGL ES2 bindings.
+// It is used by the glfwgl and offscreen backends.
+package gles
+
+import (
+	"github.com/go-gl/gl/v3.1/gles2"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"strings"
+	"unsafe"
+)
+
+// Context type implements glu.Context, and glu.DebugContext if the KHR_debug extension is supported.
+type Context struct {
+	extensions map[string]bool
+	debug      func(glu.DebugMessage)
+}
+
+// New initialises the GL function pointers, this must be called with a current context. If getProcAddr
+// is nil then the default loader for the platform is used.
+func New(getProcAddr func(name string) unsafe.Pointer) (*Context, error) {
+	var err error
+	if getProcAddr != nil {
+		err = gles2.InitWithProcAddrFunc(getProcAddr)
+	} else {
+		err = gles2.Init()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Context{extensions: map[string]bool{}}
+	if ext := gles2.GetString(gles2.EXTENSIONS); ext != nil {
+		for _, name := range strings.Fields(gles2.GoStr(ext)) {
+			c.extensions[name] = true
+		}
+	}
+	return c, nil
+}
+
+// HasExtension checks if the named extension is supported
+func (c *Context) HasExtension(name string) bool {
+	return c.extensions[name]
+}
+
+// Version returns the GL version and renderer strings
+func (c *Context) Version() string {
+	return gles2.GoStr(gles2.GetString(gles2.VERSION)) + " " + gles2.GoStr(gles2.GetString(gles2.RENDERER))
+}
+
+func (c *Context) DebugMessageCallback(fn func(glu.DebugMessage)) {
+	if !c.extensions["GL_KHR_debug"] {
+		return
+	}
+	c.debug = fn
+	gles2.Enable(gles2.DEBUG_OUTPUT_SYNCHRONOUS)
+	gles2.DebugMessageCallbackKHR(func(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+		if c.debug != nil {
+			c.debug(glu.DebugMessage{
+				Source:   glbase.Enum(source),
+				Type:     glbase.Enum(gltype),
+				ID:       id,
+				Severity: glbase.Enum(severity),
+				Message:  message,
+			})
+		}
+	}, nil)
+}
+
+func (c *Context) ObjectLabel(identifier glbase.Enum, name uint32, label string) {
+	if c.extensions["GL_KHR_debug"] && label != "" {
+		gles2.ObjectLabelKHR(uint32(identifier), name, int32(len(label)), gles2.Str(label+"\x00"))
+	}
+}
+
+func ptr(data interface{}) unsafe.Pointer {
+	if v, ok := data.([]uint8); ok && len(v) == 0 {
+		return nil
+	}
+	return gles2.Ptr(data)
+}
+
+func (c *Context) ActiveTexture(texture glbase.Enum) {
+	gles2.ActiveTexture(uint32(texture))
+}
+
+func (c *Context) AttachShader(program glbase.Program, shader glbase.Shader) {
+	gles2.AttachShader(uint32(program), uint32(shader))
+}
+
+func (c *Context) BindBuffer(target glbase.Enum, buffer glbase.Buffer) {
+	gles2.BindBuffer(uint32(target), uint32(buffer))
+}
+
+func (c *Context) BindTexture(target glbase.Enum, texture glbase.Texture) {
+	gles2.BindTexture(uint32(target), uint32(texture))
+}
+
+func (c *Context) BlendFunc(sfactor, dfactor glbase.Enum) {
+	gles2.BlendFunc(uint32(sfactor), uint32(dfactor))
+}
+
+func (c *Context) BufferData(target glbase.Enum, size int, data interface{}, usage glbase.Enum) {
+	gles2.BufferData(uint32(target), size, ptr(data), uint32(usage))
+}
+
+func (c *Context) BufferSubData(target glbase.Enum, offset, size int, data interface{}) {
+	gles2.BufferSubData(uint32(target), offset, size, ptr(data))
+}
+
+func (c *Context) Clear(mask glbase.Bitfield) {
+	gles2.Clear(uint32(mask))
+}
+
+func (c *Context) ClearColor(red, green, blue, alpha glbase.Clampf) {
+	gles2.ClearColor(float32(red), float32(green), float32(blue), float32(alpha))
+}
+
+func (c *Context) CompileShader(shader glbase.Shader) {
+	gles2.CompileShader(uint32(shader))
+}
+
+func (c *Context) CreateProgram() glbase.Program {
+	return glbase.Program(gles2.CreateProgram())
+}
+
+func (c *Context) CreateShader(gltype glbase.Enum) glbase.Shader {
+	return glbase.Shader(gles2.CreateShader(uint32(gltype)))
+}
+
+func (c *Context) DeleteBuffers(buffers []glbase.Buffer) {
+	for _, buf := range buffers {
+		id := uint32(buf)
+		gles2.DeleteBuffers(1, &id)
+	}
+}
+
+func (c *Context) DeleteShader(shader glbase.Shader) {
+	gles2.DeleteShader(uint32(shader))
+}
+
+func (c *Context) DepthFunc(glfunc glbase.Enum) {
+	gles2.DepthFunc(uint32(glfunc))
+}
+
+func (c *Context) DepthMask(flag bool) {
+	gles2.DepthMask(flag)
+}
+
+func (c *Context) Disable(cap glbase.Enum) {
+	gles2.Disable(uint32(cap))
+}
+
+func (c *Context) DrawArrays(mode glbase.Enum, first, count int) {
+	gles2.DrawArrays(uint32(mode), int32(first), int32(count))
+}
+
+func (c *Context) DrawElements(mode glbase.Enum, count int, gltype glbase.Enum, indices interface{}) {
+	gles2.DrawElements(uint32(mode), int32(count), uint32(gltype), ptr(indices))
+}
+
+func (c *Context) Enable(cap glbase.Enum) {
+	gles2.Enable(uint32(cap))
+}
+
+func (c *Context) DisableVertexAttribArray(index glbase.Attrib) {
+	gles2.DisableVertexAttribArray(uint32(index))
+}
+
+func (c *Context) EnableVertexAttribArray(index glbase.Attrib) {
+	gles2.EnableVertexAttribArray(uint32(index))
+}
+
+func (c *Context) FrontFace(mode glbase.Enum) {
+	gles2.FrontFace(uint32(mode))
+}
+
+func (c *Context) GenBuffers(n int) []glbase.Buffer {
+	ids := make([]uint32, n)
+	gles2.GenBuffers(int32(n), &ids[0])
+	bufs := make([]glbase.Buffer, n)
+	for i, id := range ids {
+		bufs[i] = glbase.Buffer(id)
+	}
+	return bufs
+}
+
+func (c *Context) GenTextures(n int) []glbase.Texture {
+	ids := make([]uint32, n)
+	gles2.GenTextures(int32(n), &ids[0])
+	tex := make([]glbase.Texture, n)
+	for i, id := range ids {
+		tex[i] = glbase.Texture(id)
+	}
+	return tex
+}
+
+func (c *Context) GenerateMipmap(target glbase.Enum) {
+	gles2.GenerateMipmap(uint32(target))
+}
+
+func (c *Context) GetAttribLocation(program glbase.Program, name string) glbase.Attrib {
+	return glbase.Attrib(gles2.GetAttribLocation(uint32(program), gles2.Str(name+"\x00")))
+}
+
+func (c *Context) GetError() glbase.Enum {
+	return glbase.Enum(gles2.GetError())
+}
+
+func (c *Context) GetProgramInfoLog(program glbase.Program) []byte {
+	var length int32
+	gles2.GetProgramiv(uint32(program), gles2.INFO_LOG_LENGTH, &length)
+	if length == 0 {
+		return nil
+	}
+	log := make([]byte, length)
+	gles2.GetProgramInfoLog(uint32(program), length, nil, &log[0])
+	return log[:length-1]
+}
+
+func (c *Context) GetProgramiv(program glbase.Program, pname glbase.Enum, params []int32) {
+	gles2.GetProgramiv(uint32(program), uint32(pname), &params[0])
+}
+
+func (c *Context) GetShaderInfoLog(shader glbase.Shader) []byte {
+	var length int32
+	gles2.GetShaderiv(uint32(shader), gles2.INFO_LOG_LENGTH, &length)
+	if length == 0 {
+		return nil
+	}
+	log := make([]byte, length)
+	gles2.GetShaderInfoLog(uint32(shader), length, nil, &log[0])
+	return log[:length-1]
+}
+
+func (c *Context) GetShaderiv(shader glbase.Shader, pname glbase.Enum, params []int32) {
+	gles2.GetShaderiv(uint32(shader), uint32(pname), &params[0])
+}
+
+func (c *Context) GetUniformLocation(program glbase.Program, name string) glbase.Uniform {
+	return glbase.Uniform(gles2.GetUniformLocation(uint32(program), gles2.Str(name+"\x00")))
+}
+
+func (c *Context) LinkProgram(program glbase.Program) {
+	gles2.LinkProgram(uint32(program))
+}
+
+func (c *Context) ReadPixels(x, y, width, height int, format, gltype glbase.Enum, pixels interface{}) {
+	gles2.ReadPixels(int32(x), int32(y), int32(width), int32(height), uint32(format), uint32(gltype), ptr(pixels))
+}
+
+func (c *Context) ShaderSource(shader glbase.Shader, source ...string) {
+	src, free := gles2.Strs(source...)
+	defer free()
+	length := make([]int32, len(source))
+	for i, s := range source {
+		length[i] = int32(len(s))
+	}
+	gles2.ShaderSource(uint32(shader), int32(len(source)), src, &length[0])
+}
+
+func (c *Context) StencilFunc(glfunc glbase.Enum, ref int32, mask uint32) {
+	gles2.StencilFunc(uint32(glfunc), ref, mask)
+}
+
+func (c *Context) StencilMask(mask uint32) {
+	gles2.StencilMask(mask)
+}
+
+func (c *Context) StencilOp(fail, zfail, zpass glbase.Enum) {
+	gles2.StencilOp(uint32(fail), uint32(zfail), uint32(zpass))
+}
+
+func (c *Context) TexImage2D(target glbase.Enum, level int, internalFormat int32, width, height, border int, format, gltype glbase.Enum, pixels interface{}) {
+	gles2.TexImage2D(uint32(target), int32(level), internalFormat, int32(width), int32(height), int32(border), uint32(format), uint32(gltype), ptr(pixels))
+}
+
+func (c *Context) TexParameteri(target, pname glbase.Enum, param int32) {
+	gles2.TexParameteri(uint32(target), uint32(pname), param)
+}
+
+func (c *Context) Uniform1f(location glbase.Uniform, v0 float32) {
+	gles2.Uniform1f(int32(location), v0)
+}
+
+func (c *Context) Uniform1i(location glbase.Uniform, v0 int32) {
+	gles2.Uniform1i(int32(location), v0)
+}
+
+func (c *Context) Uniform2f(location glbase.Uniform, v0, v1 float32) {
+	gles2.Uniform2f(int32(location), v0, v1)
+}
+
+func (c *Context) Uniform2i(location glbase.Uniform, v0, v1 int32) {
+	gles2.Uniform2i(int32(location), v0, v1)
+}
+
+func (c *Context) Uniform3fv(location glbase.Uniform, value []float32) {
+	gles2.Uniform3fv(int32(location), int32(len(value)/3), &value[0])
+}
+
+func (c *Context) Uniform4fv(location glbase.Uniform, value []float32) {
+	gles2.Uniform4fv(int32(location), int32(len(value)/4), &value[0])
+}
+
+func (c *Context) UniformMatrix3fv(location glbase.Uniform, transpose bool, value []float32) {
+	gles2.UniformMatrix3fv(int32(location), int32(len(value)/9), transpose, &value[0])
+}
+
+func (c *Context) UniformMatrix4fv(location glbase.Uniform, transpose bool, value []float32) {
+	gles2.UniformMatrix4fv(int32(location), int32(len(value)/16), transpose, &value[0])
+}
+
+func (c *Context) UseProgram(program glbase.Program) {
+	gles2.UseProgram(uint32(program))
+}
+
+func (c *Context) VertexAttribPointer(index glbase.Attrib, size int, gltype glbase.Enum, normalized bool, stride int, offset uintptr) {
+	gles2.VertexAttribPointerWithOffset(uint32(index), int32(size), uint32(gltype), normalized, int32(stride), offset)
+}
+
+func (c *Context) Viewport(x, y, width, height int) {
+	gles2.Viewport(int32(x), int32(y), int32(width), int32(height))
+}
+
+var _ glu.DebugContext = (*Context)(nil)
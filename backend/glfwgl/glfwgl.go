@@ -0,0 +1,165 @@
+// Package glfwgl runs an application in a GLFW window with an OpenGL ES 2.0 context.
+// Mouse, keyboard and scroll events are passed to the application if it implements the corresponding handler.
+// Mouse positions are in framebuffer pixels to match the width and height passed to Paint.
+package glfwgl
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/jnb666/go3d/backend"
+	"github.com/jnb666/go3d/backend/gles"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	// GLFW event handling must run on the main thread
+	runtime.LockOSThread()
+}
+
+// Window type is a GLFW window with the GL context
+type Window struct {
+	*glfw.Window
+	gl     *gles.Context
+	redraw int32 // set to 1 with sync/atomic as Update may be called from another goroutine
+	button int
+}
+
+// New creates a new window and makes the context current
+func New(title string, width, height int) (*Window, error) {
+	if err := glfw.Init(); err != nil {
+		return nil, err
+	}
+	glfw.WindowHint(glfw.ClientAPI, glfw.OpenGLESAPI)
+	glfw.WindowHint(glfw.ContextVersionMajor, 2)
+	glfw.WindowHint(glfw.ContextVersionMinor, 0)
+	win, err := glfw.CreateWindow(width, height, title, nil, nil)
+	if err != nil {
+		glfw.Terminate()
+		return nil, err
+	}
+	win.MakeContextCurrent()
+	w := &Window{Window: win, redraw: 1}
+	if w.gl, err = gles.New(glfw.GetProcAddress); err != nil {
+		win.Destroy()
+		glfw.Terminate()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Update schedules a repaint, it is safe to call this from any goroutine
+func (w *Window) Update() {
+	atomic.StoreInt32(&w.redraw, 1)
+	glfw.PostEmptyEvent()
+}
+
+// FlipY is false as the window is drawn directly
+func (w *Window) FlipY() bool {
+	return false
+}
+
+// Close destroys the window
+func (w *Window) Close() {
+	w.SetShouldClose(true)
+}
+
+// Run processes events until the window is closed.
+func (w *Window) Run(app backend.App) {
+	w.setCallbacks(app)
+	ticker, _ := app.(backend.Ticker)
+	lastTick := time.Now()
+	for !w.ShouldClose() {
+		if atomic.SwapInt32(&w.redraw, 0) != 0 {
+			width, height := w.GetFramebufferSize()
+			app.Paint(w.gl, width, height)
+			w.SwapBuffers()
+		}
+		if ticker == nil {
+			glfw.WaitEvents()
+			continue
+		}
+		glfw.WaitEventsTimeout(backend.TickInterval.Seconds())
+		if time.Since(lastTick) >= backend.TickInterval {
+			lastTick = time.Now()
+			ticker.Tick()
+		}
+	}
+	w.Destroy()
+	glfw.Terminate()
+}
+
+var keyNames = map[glfw.Key]string{
+	glfw.KeyLeft:   "left",
+	glfw.KeyRight:  "right",
+	glfw.KeyUp:     "up",
+	glfw.KeyDown:   "down",
+	glfw.KeySpace:  "space",
+	glfw.KeyEnter:  "enter",
+	glfw.KeyEscape: "escape",
+}
+
+// convert from screen coordinates to pixels, these differ on HiDPI displays
+func (w *Window) scale(x, y float64) (int, int) {
+	width, height := w.GetSize()
+	fbWidth, fbHeight := w.GetFramebufferSize()
+	if width > 0 && height > 0 {
+		x *= float64(fbWidth) / float64(width)
+		y *= float64(fbHeight) / float64(height)
+	}
+	return int(x), int(y)
+}
+
+func (w *Window) setCallbacks(app backend.App) {
+	w.SetFramebufferSizeCallback(func(win *glfw.Window, width, height int) {
+		atomic.StoreInt32(&w.redraw, 1)
+	})
+	keys, _ := app.(backend.KeyHandler)
+	w.SetKeyCallback(func(win *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			return
+		}
+		if key == glfw.KeyEscape && keys == nil {
+			w.Close()
+		} else if name, ok := keyNames[key]; ok && keys != nil {
+			keys.Key(name)
+		}
+	})
+	if keys != nil {
+		w.SetCharCallback(func(win *glfw.Window, char rune) {
+			if char != ' ' && char < 0x80 {
+				keys.Key(strings.ToLower(string(char)))
+			}
+		})
+	}
+	if mouse, ok := app.(backend.MouseHandler); ok {
+		w.SetMouseButtonCallback(func(win *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+			x, y := w.scale(win.GetCursorPos())
+			switch {
+			case action == glfw.Press && button == glfw.MouseButtonLeft:
+				w.button = 1
+			case action == glfw.Press && button == glfw.MouseButtonRight:
+				w.button = 2
+			case action == glfw.Release && w.button != 0:
+				mouse.Mouse("end", x, y, w.button)
+				w.button = 0
+				return
+			default:
+				return
+			}
+			mouse.Mouse("start", x, y, w.button)
+		})
+		w.SetCursorPosCallback(func(win *glfw.Window, x, y float64) {
+			if w.button != 0 {
+				px, py := w.scale(x, y)
+				mouse.Mouse("move", px, py, w.button)
+			}
+		})
+	}
+	if zoom, ok := app.(backend.ZoomHandler); ok {
+		w.SetScrollCallback(func(win *glfw.Window, xoff, yoff float64) {
+			zoom.Zoom(int(120 * yoff))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package offscreen
+
+/*
+#cgo pkg-config: egl
+#include <stdlib.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+// try the default display first, then fall back to the Mesa surfaceless platform if there is no window system
+static EGLDisplay getDisplay() {
+	EGLDisplay dpy = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+	if (dpy != EGL_NO_DISPLAY && eglInitialize(dpy, NULL, NULL)) {
+		return dpy;
+	}
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay == NULL) {
+		return EGL_NO_DISPLAY;
+	}
+	dpy = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+	if (dpy != EGL_NO_DISPLAY && eglInitialize(dpy, NULL, NULL)) {
+		return dpy;
+	}
+	return EGL_NO_DISPLAY;
+}
+
+static EGLint createContext(EGLDisplay dpy, EGLContext *ctx, EGLSurface *surf) {
+	EGLint configAttr[] = {
+		EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT,
+		EGL_RED_SIZE, 8, EGL_GREEN_SIZE, 8, EGL_BLUE_SIZE, 8, EGL_ALPHA_SIZE, 8,
+		EGL_NONE,
+	};
+	EGLint contextAttr[] = { EGL_CONTEXT_CLIENT_VERSION, 2, EGL_NONE };
+	EGLint pbufferAttr[] = { EGL_WIDTH, 1, EGL_HEIGHT, 1, EGL_NONE };
+	EGLConfig config;
+	EGLint n = 0;
+	if (!eglBindAPI(EGL_OPENGL_ES_API)) {
+		return eglGetError();
+	}
+	if (!eglChooseConfig(dpy, configAttr, &config, 1, &n) || n == 0) {
+		// surfaceless platform has no pbuffer configs
+		configAttr[1] = 0;
+		if (!eglChooseConfig(dpy, configAttr, &config, 1, &n) || n == 0) {
+			return eglGetError();
+		}
+	}
+	*ctx = eglCreateContext(dpy, config, EGL_NO_CONTEXT, contextAttr);
+	if (*ctx == EGL_NO_CONTEXT) {
+		return eglGetError();
+	}
+	*surf = eglCreatePbufferSurface(dpy, config, pbufferAttr);
+	if (!eglMakeCurrent(dpy, *surf, *surf, *ctx)) {
+		return eglGetError();
+	}
+	return EGL_SUCCESS;
+}
+
+static void *getProcAddress(const char *name) {
+	return eglGetProcAddress(name);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+type eglContext struct {
+	display C.EGLDisplay
+	context C.EGLContext
+	surface C.EGLSurface
+}
+
+func newEGLContext() (*eglContext, error) {
+	c := &eglContext{}
+	c.display = C.getDisplay()
+	if c.display == C.EGLDisplay(C.EGL_NO_DISPLAY) {
+		return nil, fmt.Errorf("offscreen: no EGL display: error %x", int(C.eglGetError()))
+	}
+	if code := C.createContext(c.display, &c.context, &c.surface); code != C.EGL_SUCCESS {
+		c.release()
+		return nil, fmt.Errorf("offscreen: failed to create EGL context: error %x", int(code))
+	}
+	return c, nil
+}
+
+func (c *eglContext) release() {
+	C.eglMakeCurrent(c.display, nil, nil, nil)
+	if c.surface != nil {
+		C.eglDestroySurface(c.display, c.surface)
+	}
+	if c.context != nil {
+		C.eglDestroyContext(c.display, c.context)
+	}
+	C.eglTerminate(c.display)
+}
+
+func getProcAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.getProcAddress(cname)
+}
@@ -0,0 +1,151 @@
+// Package offscreen renders without a window using an EGL context, e.g. with Mesa llvmpipe on a server
+// with no display or GPU. The app is drawn to a framebuffer object which can be saved as an image.
+package offscreen
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.1/gles2"
+	"github.com/jnb666/go3d/backend"
+	"github.com/jnb666/go3d/backend/gles"
+	"github.com/jnb666/go3d/glu"
+	"image"
+	"image/png"
+	"os"
+	"runtime"
+)
+
+func init() {
+	// the EGL context is current on the thread where it was created
+	runtime.LockOSThread()
+}
+
+// Window type is an offscreen render target
+type Window struct {
+	egl           *eglContext
+	gl            *gles.Context
+	fbo           uint32
+	rbo           [2]uint32
+	width, height int
+	allocated     bool
+	closed        bool
+}
+
+// New creates a new GL context with a framebuffer of the given size
+func New(width, height int) (*Window, error) {
+	egl, err := newEGLContext()
+	if err != nil {
+		return nil, err
+	}
+	w := &Window{egl: egl}
+	if w.gl, err = gles.New(getProcAddress); err != nil {
+		egl.release()
+		return nil, err
+	}
+	gles2.GenFramebuffers(1, &w.fbo)
+	gles2.BindFramebuffer(gles2.FRAMEBUFFER, w.fbo)
+	gles2.GenRenderbuffers(2, &w.rbo[0])
+	if err = w.Resize(width, height); err != nil {
+		w.release()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Resize reallocates the framebuffer storage if the size has changed
+func (w *Window) Resize(width, height int) error {
+	if width == w.width && height == w.height && w.allocated {
+		return nil
+	}
+	w.width, w.height = width, height
+	gles2.BindRenderbuffer(gles2.RENDERBUFFER, w.rbo[0])
+	gles2.RenderbufferStorage(gles2.RENDERBUFFER, gles2.RGBA8_OES, int32(width), int32(height))
+	gles2.FramebufferRenderbuffer(gles2.FRAMEBUFFER, gles2.COLOR_ATTACHMENT0, gles2.RENDERBUFFER, w.rbo[0])
+	gles2.BindRenderbuffer(gles2.RENDERBUFFER, w.rbo[1])
+	gles2.RenderbufferStorage(gles2.RENDERBUFFER, gles2.DEPTH24_STENCIL8_OES, int32(width), int32(height))
+	gles2.FramebufferRenderbuffer(gles2.FRAMEBUFFER, gles2.DEPTH_ATTACHMENT, gles2.RENDERBUFFER, w.rbo[1])
+	gles2.FramebufferRenderbuffer(gles2.FRAMEBUFFER, gles2.STENCIL_ATTACHMENT, gles2.RENDERBUFFER, w.rbo[1])
+	if status := gles2.CheckFramebufferStatus(gles2.FRAMEBUFFER); status != gles2.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("offscreen: framebuffer incomplete: status %x", status)
+	}
+	gles2.Viewport(0, 0, int32(width), int32(height))
+	w.allocated = true
+	return nil
+}
+
+// GL returns the context
+func (w *Window) GL() glu.Context {
+	return w.gl
+}
+
+// Version returns the GL version and renderer
+func (w *Window) Version() string {
+	return w.gl.Version()
+}
+
+// Update does nothing as each frame is drawn explicitly
+func (w *Window) Update() {}
+
+// FlipY is true as the image is read back from the bottom row up
+func (w *Window) FlipY() bool {
+	return true
+}
+
+// Close stops Run after the current frame
+func (w *Window) Close() {
+	w.closed = true
+}
+
+// Draw paints a single frame and waits for it to complete
+func (w *Window) Draw(app backend.App) {
+	app.Paint(w.gl, w.width, w.height)
+	gles2.Finish()
+}
+
+// Run draws the given number of frames, calling the app's Tick method between each one if it implements Ticker.
+// If fn is not nil it is called after each frame is drawn.
+func (w *Window) Run(app backend.App, frames int, fn func(frame int) error) error {
+	ticker, _ := app.(backend.Ticker)
+	for frame := 0; frame < frames && !w.closed; frame++ {
+		if frame > 0 && ticker != nil {
+			ticker.Tick()
+		}
+		w.Draw(app)
+		if fn != nil {
+			if err := fn(frame); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Image reads back the framebuffer contents. Assumes the projection has the y axis inverted as per scene.View.
+func (w *Window) Image() *image.NRGBA {
+	glu.Init(w.gl)
+	return glu.ReadPixels(0, 0, w.width, w.height, true)
+}
+
+// SavePNG writes the framebuffer contents to a PNG file
+func (w *Window) SavePNG(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, w.Image()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Release frees the framebuffer and destroys the context
+func (w *Window) Release() {
+	w.release()
+}
+
+func (w *Window) release() {
+	gles2.BindFramebuffer(gles2.FRAMEBUFFER, 0)
+	gles2.DeleteRenderbuffers(2, &w.rbo[0])
+	gles2.DeleteFramebuffers(1, &w.fbo)
+	w.egl.release()
+}
@@ -0,0 +1,48 @@
+package offscreen
+
+import (
+	"github.com/go-gl/gl/v3.1/gles2"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/scene"
+	"image"
+)
+
+// Background color used by Render, the default is transparent
+var Background = mgl32.Vec4{0, 0, 0, 0}
+
+// shared context used by Render - meshes and textures are tied to the context they were created in so it
+// is not recreated between calls.
+var shared *Window
+
+// Render draws the scene from the given view to an image using a shared offscreen context which is created
+// on the first call. If no GPU is available then Mesa will use the llvmpipe software renderer, set
+// LIBGL_ALWAYS_SOFTWARE=1 in the environment to force this.
+func Render(root scene.Object, view *scene.View, width, height int) (*image.NRGBA, error) {
+	if shared == nil {
+		win, err := New(width, height)
+		if err != nil {
+			return nil, err
+		}
+		shared = win
+	}
+	return shared.Render(root, view, width, height)
+}
+
+// Render draws the scene from the given view to an image of the given size.
+func (w *Window) Render(root scene.Object, view *scene.View, width, height int) (*image.NRGBA, error) {
+	if err := w.Resize(width, height); err != nil {
+		return nil, err
+	}
+	glu.Init(w.gl)
+	view.FlipY = w.FlipY()
+	view.SetProjection(width, height)
+	glu.Clear(Background)
+	worldToCamera := view.ViewMatrix()
+	view.UpdateLights(worldToCamera, root)
+	if err := view.Draw(worldToCamera, root); err != nil {
+		return nil, err
+	}
+	gles2.Finish()
+	return w.Image(), glu.CheckError()
+}
@@ -0,0 +1,52 @@
+// Package qmlgl runs an application using go-qml, where the GL context is provided by a QML Painter.
+// The QML file is responsible for the user interface and calls methods on the registered Go type.
+package qmlgl
+
+import (
+	"github.com/jnb666/go3d/backend"
+	"gopkg.in/qml.v1"
+	"gopkg.in/qml.v1/gl/es2"
+	"os"
+)
+
+// Window type wraps the QML object which is painted by the application.
+type Window struct {
+	qml.Object
+}
+
+// Update schedules a repaint
+func (w Window) Update() {
+	w.Call("update")
+}
+
+// FlipY is true as QML draws the framebuffer upside down
+func (w Window) FlipY() bool {
+	return true
+}
+
+// Close exits the application
+func (w Window) Close() {
+	os.Exit(0)
+}
+
+// Paint should be called from the Paint method of the registered type to draw the app.
+func (w Window) Paint(p *qml.Painter, app backend.App) {
+	app.Paint(GL.API(p), w.Int("width"), w.Int("height"))
+}
+
+// Run registers the types in the GoExtensions module, loads the QML file and waits for the window to be closed.
+func Run(file string, types ...qml.TypeSpec) error {
+	return qml.Run(func() error {
+		qml.RegisterTypes("GoExtensions", 1, 0, types)
+		engine := qml.NewEngine()
+		engine.On("quit", func() { os.Exit(0) })
+		component, err := engine.LoadFile(file)
+		if err != nil {
+			return err
+		}
+		window := component.CreateWindow(nil)
+		window.Show()
+		window.Wait()
+		return nil
+	})
+}
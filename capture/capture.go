@@ -0,0 +1,83 @@
+// Package capture records a sequence of rendered frames as numbered PNG files or as an animated GIF.
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+)
+
+// Recorder type saves each frame which is added to it
+type Recorder struct {
+	File  string // file name pattern
+	Delay int    // delay between frames for animated GIFs in 100ths of a second
+	frame int
+	anim  *gif.GIF
+}
+
+// New creates a new recorder. If the file has a .gif extension then frames are saved as an animation
+// when Close is called. Otherwise they are written as PNG files numbered using a printf style pattern,
+// e.g. "frame%03d.png". If the pattern has no % verb then a 4 digit frame number is added before the extension.
+func New(file string) *Recorder {
+	r := &Recorder{File: file, Delay: 4}
+	if strings.ToLower(path.Ext(file)) == ".gif" {
+		r.anim = &gif.GIF{}
+	} else if !strings.Contains(file, "%") {
+		ext := path.Ext(file)
+		r.File = strings.TrimSuffix(file, ext) + "%04d" + ext
+	}
+	return r
+}
+
+// Frames returns the number of frames added so far
+func (r *Recorder) Frames() int {
+	return r.frame
+}
+
+// Add saves the next frame
+func (r *Recorder) Add(img image.Image) error {
+	r.frame++
+	if r.anim != nil {
+		pimg := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(pimg, img.Bounds(), img, img.Bounds().Min)
+		r.anim.Image = append(r.anim.Image, pimg)
+		r.anim.Delay = append(r.anim.Delay, r.Delay)
+		return nil
+	}
+	return writePNG(fmt.Sprintf(r.File, r.frame), img)
+}
+
+// Close writes the animation file if recording a GIF
+func (r *Recorder) Close() error {
+	if r.anim == nil || len(r.anim.Image) == 0 {
+		return nil
+	}
+	f, err := os.Create(r.File)
+	if err != nil {
+		return err
+	}
+	if err = gif.EncodeAll(f, r.anim); err != nil {
+		f.Close()
+		return err
+	}
+	r.anim.Image, r.anim.Delay = nil, nil
+	return f.Close()
+}
+
+func writePNG(file string, img image.Image) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
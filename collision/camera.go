@@ -0,0 +1,53 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/scene"
+)
+
+// CameraCollider stops a point of view camera from walking through the items in a scene, by treating the
+// camera as a sphere which slides along any surfaces it touches. Use it with scene.SetCollider.
+// It uses the world positions of the items when it was created, so create a new one if they move.
+type CameraCollider struct {
+	Radius    float32
+	colliders []*Collider
+}
+
+// NewCameraCollider creates a collider for the enabled items under root, with the given camera radius.
+func NewCameraCollider(root scene.Object, radius float32) *CameraCollider {
+	return &CameraCollider{Radius: radius, colliders: Colliders(root)}
+}
+
+// Slide returns the position to move the camera to when it tries to move from one point to another. Long
+// moves are split into steps no larger than the radius so that the camera cannot jump through thin walls.
+func (c *CameraCollider) Slide(from, to mgl32.Vec3) mgl32.Vec3 {
+	move := to.Sub(from)
+	steps := int(move.Len()/c.Radius) + 1
+	pos := from
+	for i := 0; i < steps; i++ {
+		pos = c.resolve(pos.Add(move.Mul(1 / float32(steps))))
+	}
+	return pos
+}
+
+// push the sphere out of any surfaces it overlaps, a few iterations are needed for corners
+func (c *CameraCollider) resolve(pos mgl32.Vec3) mgl32.Vec3 {
+	for iter := 0; iter < 4; iter++ {
+		var deepest Contact
+		for _, col := range c.colliders {
+			if col.Bounds.Distance(pos) > c.Radius {
+				continue
+			}
+			for _, contact := range col.CollideSphere(pos, c.Radius) {
+				if contact.Depth > deepest.Depth {
+					deepest = contact
+				}
+			}
+		}
+		if deepest.Depth == 0 {
+			break
+		}
+		pos = pos.Sub(deepest.Normal.Mul(deepest.Depth))
+	}
+	return pos
+}
@@ -0,0 +1,181 @@
+// Package collision finds contacts between the items in a scene. Items are first checked using their world
+// space bounding spheres and boxes. Convex meshes are then tested with the GJK and EPA algorithms, and concave
+// meshes by testing each of their triangles.
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"sort"
+)
+
+// Contact type has the details of where two items overlap. Normal is the direction from A towards B in
+// world space, so moving B by Normal times Depth would separate them. Point is midway between the surfaces.
+type Contact struct {
+	A, B   *scene.Item
+	Point  mgl32.Vec3
+	Normal mgl32.Vec3
+	Depth  float32
+}
+
+// Collider type is the collision shape for an item at its current world position. Concave is set by
+// NewCollider if any vertex is in front of any of the faces of the mesh, it can be cleared to use the
+// convex hull instead which is faster.
+type Collider struct {
+	Item    *scene.Item
+	World   mgl32.Mat4
+	Bounds  mesh.Bounds
+	Concave bool
+	points  Hull
+	tris    [][3]mgl32.Vec3
+	tree    *triTree
+}
+
+// NewCollider creates a collision shape for the item, where world is the transform from model to world space.
+func NewCollider(item *scene.Item, world mgl32.Mat4) *Collider {
+	c := &Collider{Item: item, World: world, Bounds: item.Mesh.Bounds().Transform(world)}
+	for _, p := range item.Mesh.Points() {
+		c.points = append(c.points, mgl32.TransformCoordinate(p, world))
+	}
+	c.tris = item.Mesh.Triangles()
+	for i, tri := range c.tris {
+		for j, p := range tri {
+			c.tris[i][j] = mgl32.TransformCoordinate(p, world)
+		}
+	}
+	c.Concave = !convex(c.points, c.tris)
+	return c
+}
+
+// Colliders returns a collider for each enabled item under root, not including point sprites.
+func Colliders(root scene.Object) []*Collider {
+	list := []*Collider{}
+	root.Do(scene.NewTransform(mgl32.Ident4()), func(item *scene.Item, t scene.Transform) {
+		if item.Mesh.PointSize() == 0 && !item.Mesh.Bounds().Empty() {
+			list = append(list, NewCollider(item, t.Mat4()))
+		}
+	})
+	return list
+}
+
+// Contacts finds all of the contacts between the enabled items under root. Pairs which could overlap are
+// found by sorting the bounds along the x axis.
+func Contacts(root scene.Object) []Contact {
+	list := Colliders(root)
+	sort.Slice(list, func(i, j int) bool { return list[i].Bounds.Min[0] < list[j].Bounds.Min[0] })
+	contacts := []Contact{}
+	for i, a := range list {
+		for _, b := range list[i+1:] {
+			if b.Bounds.Min[0] > a.Bounds.Max[0] {
+				break
+			}
+			contacts = append(contacts, Collide(a, b)...)
+		}
+	}
+	return contacts
+}
+
+// Overlaps is the broad phase test using the bounding spheres and boxes
+func Overlaps(a, b *Collider) bool {
+	ra := a.Bounds.Radius + b.Bounds.Radius
+	return a.Bounds.Center().Sub(b.Bounds.Center()).Len() <= ra && a.Bounds.Intersects(b.Bounds)
+}
+
+// Collide returns the contacts between two colliders. There is at most one contact if both are convex,
+// otherwise there is one for each overlapping triangle.
+func Collide(a, b *Collider) []Contact {
+	contacts := []Contact{}
+	if !Overlaps(a, b) {
+		return contacts
+	}
+	add := func(sa, sb Shape, dir mgl32.Vec3) {
+		if c, ok := collide(sa, sb, dir); ok {
+			c.A, c.B = a.Item, b.Item
+			contacts = append(contacts, c)
+		}
+	}
+	dir := b.Bounds.Center().Sub(a.Bounds.Center())
+	switch {
+	case !a.Concave && !b.Concave:
+		add(a.points, b.points, dir)
+	case a.Concave && !b.Concave:
+		a.query(b.Bounds, func(tri [3]mgl32.Vec3) { add(Hull(tri[:]), b.points, dir) })
+	case !a.Concave && b.Concave:
+		b.query(a.Bounds, func(tri [3]mgl32.Vec3) { add(a.points, Hull(tri[:]), dir) })
+	default:
+		a.query(b.Bounds, func(ta [3]mgl32.Vec3) {
+			b.query(triBounds(ta), func(tb [3]mgl32.Vec3) { add(Hull(ta[:]), Hull(tb[:]), dir) })
+		})
+	}
+	return contacts
+}
+
+// CollideSphere returns the contacts between a sphere and the triangles of the collider, where A is nil
+// and the normal points from the sphere towards the item. The center should be outside of the mesh.
+func (c *Collider) CollideSphere(center mgl32.Vec3, radius float32) []Contact {
+	contacts := []Contact{}
+	r := mgl32.Vec3{radius, radius, radius}
+	c.query(mesh.Bounds{Min: center.Sub(r), Max: center.Add(r), Radius: radius}, func(tri [3]mgl32.Vec3) {
+		p := ClosestPoint(center, tri)
+		d := p.Sub(center)
+		if dist := d.Len(); dist < radius && dist > 0 {
+			contacts = append(contacts, Contact{
+				B:      c.Item,
+				Point:  p,
+				Normal: d.Mul(1 / dist),
+				Depth:  radius - dist,
+			})
+		}
+	})
+	return contacts
+}
+
+// Convex checks if two convex shapes overlap using the GJK and EPA algorithms, and returns the contact
+// with A and B unset if they do.
+func Convex(a, b Shape) (Contact, bool) {
+	return collide(a, b, mgl32.Vec3{1, 0, 0})
+}
+
+// narrow phase test for a pair of convex shapes, dir is the initial search direction
+func collide(a, b Shape, dir mgl32.Vec3) (Contact, bool) {
+	simplex, ok := gjk(a, b, dir)
+	if !ok {
+		return Contact{}, false
+	}
+	normal, depth, point, ok := epa(a, b, simplex)
+	if !ok || depth <= 0 {
+		return Contact{}, false
+	}
+	return Contact{Point: point.Sub(normal.Mul(depth / 2)), Normal: normal, Depth: depth}, true
+}
+
+// call fn for each triangle in world space which could overlap the box
+func (c *Collider) query(box mesh.Bounds, fn func([3]mgl32.Vec3)) {
+	if c.tree == nil {
+		c.tree = newTriTree(c.tris)
+	}
+	c.tree.query(box, fn)
+}
+
+// check that all of the points are on the same side of each face
+func convex(points []mgl32.Vec3, tris [][3]mgl32.Vec3) bool {
+	eps := tolerance * mesh.BoundsOf(points).Size().Len()
+	for _, tri := range tris {
+		n := tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0]))
+		if n.Len() < tolerance*tolerance {
+			continue
+		}
+		n = n.Normalize()
+		front, back := false, false
+		for _, p := range points {
+			d := n.Dot(p.Sub(tri[0]))
+			front = front || d > eps
+			back = back || d < -eps
+			if front && back {
+				return false
+			}
+		}
+	}
+	return true
+}
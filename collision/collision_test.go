@@ -0,0 +1,240 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"testing"
+)
+
+// corners of a box with the given center and half size
+func box(center, half mgl32.Vec3) Hull {
+	h := Hull{}
+	for i := 0; i < 8; i++ {
+		corner := mgl32.Vec3{float32(i&1*2 - 1), float32(i>>1&1*2 - 1), float32(i>>2&1*2 - 1)}
+		h = append(h, center.Add(mgl32.Vec3{corner[0] * half[0], corner[1] * half[1], corner[2] * half[2]}))
+	}
+	return h
+}
+
+// cube mesh from -1 to 1 on each axis, with a second cube offset along x if twin is set
+func cubeMesh(twin bool) *mesh.Mesh {
+	m := mesh.New()
+	m.AddNormal(0, 0, 1)
+	offsets := []float32{0}
+	if twin {
+		offsets = append(offsets, 4)
+	}
+	for n, dx := range offsets {
+		for i := 0; i < 8; i++ {
+			m.AddVertex(float32(i&1*2-1)+dx, float32(i>>1&1*2-1), float32(i>>2&1*2-1))
+		}
+		for _, f := range [][4]int{{1, 3, 4, 2}, {5, 6, 8, 7}, {1, 2, 6, 5}, {3, 7, 8, 4}, {1, 5, 7, 3}, {2, 4, 8, 6}} {
+			var el [4]mesh.El
+			for i, v := range f {
+				el[i] = mesh.El{Vert: v + 8*n, Norm: 1}
+			}
+			m.AddFace(el[0], el[1], el[2], el[3])
+		}
+	}
+	m.Build("")
+	return m
+}
+
+// item in a scene with the given position and scale
+func cubeItem(m *mesh.Mesh, pos, scale mgl32.Vec3) *scene.Item {
+	item := scene.NewItem(m)
+	item.SetPosition(pos).SetScale(scale)
+	return item
+}
+
+func collider(item *scene.Item) *Collider {
+	world := item.WorldTransform()
+	return NewCollider(item, world.Mat4())
+}
+
+func near(a, b, eps float32) bool {
+	return a-b <= eps && b-a <= eps
+}
+
+func TestConvex(t *testing.T) {
+	unit := mgl32.Vec3{1, 1, 1}
+	tests := []struct {
+		name    string
+		a, b    Shape
+		hit     bool
+		normal  mgl32.Vec3
+		depth   float32
+		plane   float32 // distance of the contact point along the normal
+		epsilon float32
+	}{
+		{"separated boxes", box(mgl32.Vec3{}, unit), box(mgl32.Vec3{3, 0, 0}, unit), false, mgl32.Vec3{}, 0, 0, 0},
+		{"diagonal boxes", box(mgl32.Vec3{}, unit), box(mgl32.Vec3{1.8, 1.8, 1.8}, mgl32.Vec3{0.5, 0.5, 0.5}), false, mgl32.Vec3{}, 0, 0, 0},
+		{"overlapping boxes", box(mgl32.Vec3{}, unit), box(mgl32.Vec3{1.5, 0.2, -0.3}, unit), true, mgl32.Vec3{1, 0, 0}, 0.5, 0.75, 1e-4},
+		{"box below", box(mgl32.Vec3{}, mgl32.Vec3{2, 1, 2}), box(mgl32.Vec3{0.5, -1.8, 0}, unit), true, mgl32.Vec3{0, -1, 0}, 0.2, 0.9, 1e-4},
+		{"separated spheres", Sphere{mgl32.Vec3{}, 1}, Sphere{mgl32.Vec3{0, 0, 2.5}, 1}, false, mgl32.Vec3{}, 0, 0, 0},
+		{"overlapping spheres", Sphere{mgl32.Vec3{}, 1}, Sphere{mgl32.Vec3{0, 0, 1.5}, 1}, true, mgl32.Vec3{0, 0, 1}, 0.5, 0.75, 0.02},
+		{"sphere inside", Sphere{mgl32.Vec3{}, 2}, Sphere{mgl32.Vec3{0, 1, 0}, 0.5}, true, mgl32.Vec3{0, 1, 0}, 1.5, 1.25, 0.02},
+		{"box and sphere", box(mgl32.Vec3{}, unit), Sphere{mgl32.Vec3{1.5, 0, 0}, 1}, true, mgl32.Vec3{1, 0, 0}, 0.5, 0.75, 0.02},
+		{"sphere near box corner", box(mgl32.Vec3{}, unit), Sphere{mgl32.Vec3{1.8, 1.8, 0}, 1}, false, mgl32.Vec3{}, 0, 0, 0},
+	}
+	for _, test := range tests {
+		c, ok := Convex(test.a, test.b)
+		if ok != test.hit {
+			t.Errorf("%s: hit is %v, expecting %v", test.name, ok, test.hit)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if c.Normal.Dot(test.normal) < 1-test.epsilon {
+			t.Errorf("%s: normal is %v, expecting %v", test.name, c.Normal, test.normal)
+		}
+		if !near(c.Depth, test.depth, test.epsilon) {
+			t.Errorf("%s: depth is %g, expecting %g", test.name, c.Depth, test.depth)
+		}
+		if d := c.Point.Dot(test.normal); !near(d, test.plane, test.epsilon) {
+			t.Errorf("%s: contact point %v is at %g along the normal, expecting %g", test.name, c.Point, d, test.plane)
+		}
+	}
+}
+
+// boxes which just touch may or may not be reported, but if they are there is no penetration
+func TestConvexTouching(t *testing.T) {
+	unit := mgl32.Vec3{1, 1, 1}
+	shapes := [][2]Shape{
+		{box(mgl32.Vec3{}, unit), box(mgl32.Vec3{2, 0.5, 0}, unit)},
+		{Sphere{mgl32.Vec3{}, 1}, Sphere{mgl32.Vec3{0, 2, 0}, 1}},
+		{box(mgl32.Vec3{}, unit), Sphere{mgl32.Vec3{0, 0, -2}, 1}},
+	}
+	for i, s := range shapes {
+		if c, ok := Convex(s[0], s[1]); ok && c.Depth > 0.01 {
+			t.Errorf("pair %d: touching shapes have depth %g", i, c.Depth)
+		}
+	}
+}
+
+func TestCollide(t *testing.T) {
+	cube, twin := cubeMesh(false), cubeMesh(true)
+	unit, small := mgl32.Vec3{1, 1, 1}, mgl32.Vec3{0.5, 0.5, 0.5}
+	tests := []struct {
+		name     string
+		a, b     *scene.Item
+		concave  bool
+		contacts int // minimum number expected
+		normal   mgl32.Vec3
+		depth    float32
+	}{
+		{"separated", cubeItem(cube, mgl32.Vec3{}, unit), cubeItem(cube, mgl32.Vec3{0, 2.5, 0}, unit), false, 0, mgl32.Vec3{}, 0},
+		{"overlapping", cubeItem(cube, mgl32.Vec3{}, unit), cubeItem(cube, mgl32.Vec3{0, 1.75, 0.5}, unit), false, 1, mgl32.Vec3{0, 1, 0}, 0.25},
+		{"scaled", cubeItem(cube, mgl32.Vec3{}, mgl32.Vec3{3, 1, 1}), cubeItem(cube, mgl32.Vec3{-3.25, 0, 0}, small), false, 1, mgl32.Vec3{-1, 0, 0}, 0.25},
+		{"in the gap", cubeItem(twin, mgl32.Vec3{}, unit), cubeItem(cube, mgl32.Vec3{2, 0, 0}, small), true, 0, mgl32.Vec3{}, 0},
+		{"concave overlap", cubeItem(twin, mgl32.Vec3{}, unit), cubeItem(cube, mgl32.Vec3{2.75, 0, 0}, small), true, 1, mgl32.Vec3{-1, 0, 0}, 0.25},
+	}
+	for _, test := range tests {
+		a, b := collider(test.a), collider(test.b)
+		if a.Concave != test.concave || b.Concave {
+			t.Errorf("%s: concave flags are %v %v", test.name, a.Concave, b.Concave)
+		}
+		contacts := Collide(a, b)
+		if len(contacts) < test.contacts || test.contacts == 0 && len(contacts) > 0 {
+			t.Errorf("%s: got %d contacts, expecting %d", test.name, len(contacts), test.contacts)
+			continue
+		}
+		if len(contacts) == 0 {
+			continue
+		}
+		// the deepest contact gives the separating direction
+		deepest := contacts[0]
+		for _, c := range contacts {
+			if c.A != test.a || c.B != test.b {
+				t.Errorf("%s: contact is between the wrong items", test.name)
+			}
+			if c.Depth > deepest.Depth {
+				deepest = c
+			}
+		}
+		if deepest.Normal.Dot(test.normal) < 0.999 || !near(deepest.Depth, test.depth, 1e-4) {
+			t.Errorf("%s: normal %v depth %g, expecting %v %g", test.name, deepest.Normal, deepest.Depth, test.normal, test.depth)
+		}
+	}
+}
+
+func TestContacts(t *testing.T) {
+	cube := cubeMesh(false)
+	a := cubeItem(cube, mgl32.Vec3{}, mgl32.Vec3{1, 1, 1})
+	b := cubeItem(cube, mgl32.Vec3{1.5, 0, 0}, mgl32.Vec3{1, 1, 1})
+	c := cubeItem(cube, mgl32.Vec3{10, 0, 0}, mgl32.Vec3{1, 1, 1})
+	d := cubeItem(cube, mgl32.Vec3{10, 1.9, 0}, mgl32.Vec3{1, 1, 1})
+	d.Enable(false)
+	root := scene.NewGroup()
+	root.Add(a, b, c, d)
+	contacts := Contacts(root)
+	if len(contacts) != 1 {
+		t.Fatalf("got %d contacts, expecting 1", len(contacts))
+	}
+	if pair := [2]*scene.Item{contacts[0].A, contacts[0].B}; pair != [2]*scene.Item{a, b} && pair != [2]*scene.Item{b, a} {
+		t.Errorf("contact is between the wrong items")
+	}
+}
+
+func TestCollideSphere(t *testing.T) {
+	floor := collider(cubeItem(cubeMesh(false), mgl32.Vec3{0, -1, 0}, mgl32.Vec3{5, 1, 5}))
+	tests := []struct {
+		name   string
+		center mgl32.Vec3
+		radius float32
+		hit    bool
+		depth  float32
+	}{
+		{"above", mgl32.Vec3{0, 1.5, 0}, 1, false, 0},
+		{"touching", mgl32.Vec3{1, 1, 1}, 1, false, 0},
+		{"resting", mgl32.Vec3{2, 0.5, -1}, 1, true, 0.5},
+		{"outside edge", mgl32.Vec3{6.2, 0.5, 0}, 1, false, 0},
+	}
+	for _, test := range tests {
+		contacts := floor.CollideSphere(test.center, test.radius)
+		if len(contacts) > 0 != test.hit {
+			t.Errorf("%s: got %d contacts", test.name, len(contacts))
+			continue
+		}
+		for _, c := range contacts {
+			if c.A != nil || c.B != floor.Item {
+				t.Errorf("%s: contact items are %v %v", test.name, c.A, c.B)
+			}
+			if c.Normal.Dot(mgl32.Vec3{0, -1, 0}) < 0.999 || !near(c.Depth, test.depth, 1e-5) {
+				t.Errorf("%s: normal %v depth %g, expecting down by %g", test.name, c.Normal, c.Depth, test.depth)
+			}
+			if !near(c.Point[1], 0, 1e-5) {
+				t.Errorf("%s: contact point %v is not on the floor", test.name, c.Point)
+			}
+		}
+	}
+}
+
+func TestCameraSlide(t *testing.T) {
+	// wall from x=2 to 3 and a floor below y=-1
+	cube := cubeMesh(false)
+	root := scene.NewGroup()
+	root.Add(cubeItem(cube, mgl32.Vec3{2.5, 0, 0}, mgl32.Vec3{0.5, 5, 5}))
+	root.Add(cubeItem(cube, mgl32.Vec3{0, -2, 0}, mgl32.Vec3{10, 1, 10}))
+	cam := NewCameraCollider(root, 0.5)
+	tests := []struct {
+		name     string
+		from, to mgl32.Vec3
+		want     mgl32.Vec3
+	}{
+		{"free", mgl32.Vec3{0, 0, 0}, mgl32.Vec3{-1, 0.5, 1}, mgl32.Vec3{-1, 0.5, 1}},
+		{"along wall", mgl32.Vec3{1.5, 0, -2}, mgl32.Vec3{1.5, 0, 2}, mgl32.Vec3{1.5, 0, 2}},
+		{"into wall", mgl32.Vec3{0, 0, 0}, mgl32.Vec3{2.5, 0, 0}, mgl32.Vec3{1.5, 0, 0}},
+		{"slide on wall", mgl32.Vec3{0, 0, 0}, mgl32.Vec3{3, 0, 2}, mgl32.Vec3{1.5, 0, 2}},
+		{"fast move", mgl32.Vec3{0, 0, 0}, mgl32.Vec3{20, 0, 0}, mgl32.Vec3{1.5, 0, 0}},
+		{"slide on floor", mgl32.Vec3{-3, 0, 0}, mgl32.Vec3{-5, -3, 0}, mgl32.Vec3{-5, -0.5, 0}},
+	}
+	for _, test := range tests {
+		got := cam.Slide(test.from, test.to)
+		if got.Sub(test.want).Len() > 1e-3 {
+			t.Errorf("%s: moved to %v, expecting %v", test.name, got, test.want)
+		}
+	}
+}
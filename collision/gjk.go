@@ -0,0 +1,269 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+const (
+	maxIterations = 64
+	tolerance     = 1e-4
+)
+
+// Shape interface is a convex set of points in world space defined by its support function, which returns
+// the point which is furthest in the given direction.
+type Shape interface {
+	Support(dir mgl32.Vec3) mgl32.Vec3
+}
+
+// Hull type is the convex hull of a set of points
+type Hull []mgl32.Vec3
+
+func (h Hull) Support(dir mgl32.Vec3) mgl32.Vec3 {
+	best, dist := h[0], h[0].Dot(dir)
+	for _, p := range h[1:] {
+		if d := p.Dot(dir); d > dist {
+			best, dist = p, d
+		}
+	}
+	return best
+}
+
+// Sphere type is a sphere shape
+type Sphere struct {
+	Center mgl32.Vec3
+	Radius float32
+}
+
+func (s Sphere) Support(dir mgl32.Vec3) mgl32.Vec3 {
+	if l := dir.Len(); l > 0 {
+		return s.Center.Add(dir.Mul(s.Radius / l))
+	}
+	return s.Center
+}
+
+// point on the Minkowski difference a - b, along with the point on a which it came from
+type vertex struct {
+	p, a mgl32.Vec3
+}
+
+func support(a, b Shape, dir mgl32.Vec3) vertex {
+	pa := a.Support(dir)
+	return vertex{p: pa.Sub(b.Support(dir.Mul(-1))), a: pa}
+}
+
+// gjk checks if two convex shapes overlap, using the Gilbert-Johnson-Keerthi algorithm. If they do it returns
+// a tetrahedron from the Minkowski difference which encloses the origin.
+func gjk(a, b Shape, dir mgl32.Vec3) ([]vertex, bool) {
+	if dir.Len() < tolerance {
+		dir = mgl32.Vec3{1, 0, 0}
+	}
+	s := []vertex{support(a, b, dir)}
+	dir = s[0].p.Mul(-1)
+	for i := 0; i < maxIterations; i++ {
+		if dir.Len() < tolerance*tolerance {
+			// origin is on the boundary of the simplex
+			dir = perpendicular(s)
+		}
+		v := support(a, b, dir)
+		if v.p.Dot(dir) < 0 {
+			return nil, false
+		}
+		s = append([]vertex{v}, s...)
+		if nextSimplex(&s, &dir) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// any direction away from a degenerate simplex
+func perpendicular(s []vertex) mgl32.Vec3 {
+	var d mgl32.Vec3
+	if len(s) >= 3 {
+		d = s[1].p.Sub(s[0].p).Cross(s[2].p.Sub(s[0].p))
+	} else if len(s) == 2 {
+		d = s[1].p.Sub(s[0].p).Cross(mgl32.Vec3{1, 0, 0})
+		if d.Len() < tolerance {
+			d = s[1].p.Sub(s[0].p).Cross(mgl32.Vec3{0, 1, 0})
+		}
+	}
+	if d.Len() < tolerance*tolerance {
+		return mgl32.Vec3{0, 1, 0}
+	}
+	return d
+}
+
+// update the simplex, where s[0] is the newest point, to the feature closest to the origin and set the
+// next search direction. Returns true if the simplex is a tetrahedron containing the origin.
+func nextSimplex(s *[]vertex, dir *mgl32.Vec3) bool {
+	switch len(*s) {
+	case 2:
+		return line(s, dir)
+	case 3:
+		return triangle(s, dir)
+	default:
+		return tetrahedron(s, dir)
+	}
+}
+
+func line(s *[]vertex, dir *mgl32.Vec3) bool {
+	a, b := (*s)[0], (*s)[1]
+	ab, ao := b.p.Sub(a.p), a.p.Mul(-1)
+	if ab.Dot(ao) > 0 {
+		*dir = ab.Cross(ao).Cross(ab)
+	} else {
+		*s = []vertex{a}
+		*dir = ao
+	}
+	return false
+}
+
+func triangle(s *[]vertex, dir *mgl32.Vec3) bool {
+	a, b, c := (*s)[0], (*s)[1], (*s)[2]
+	ab, ac, ao := b.p.Sub(a.p), c.p.Sub(a.p), a.p.Mul(-1)
+	abc := ab.Cross(ac)
+	if abc.Cross(ac).Dot(ao) > 0 {
+		if ac.Dot(ao) > 0 {
+			*s = []vertex{a, c}
+			*dir = ac.Cross(ao).Cross(ac)
+			return false
+		}
+		*s = []vertex{a, b}
+		return line(s, dir)
+	}
+	if ab.Cross(abc).Dot(ao) > 0 {
+		*s = []vertex{a, b}
+		return line(s, dir)
+	}
+	if abc.Dot(ao) > 0 {
+		*dir = abc
+	} else {
+		*s = []vertex{a, c, b}
+		*dir = abc.Mul(-1)
+	}
+	return false
+}
+
+func tetrahedron(s *[]vertex, dir *mgl32.Vec3) bool {
+	a, b, c, d := (*s)[0], (*s)[1], (*s)[2], (*s)[3]
+	ab, ac, ad, ao := b.p.Sub(a.p), c.p.Sub(a.p), d.p.Sub(a.p), a.p.Mul(-1)
+	if ab.Cross(ac).Dot(ao) > 0 {
+		*s = []vertex{a, b, c}
+		return triangle(s, dir)
+	}
+	if ac.Cross(ad).Dot(ao) > 0 {
+		*s = []vertex{a, c, d}
+		return triangle(s, dir)
+	}
+	if ad.Cross(ab).Dot(ao) > 0 {
+		*s = []vertex{a, d, b}
+		return triangle(s, dir)
+	}
+	return true
+}
+
+type face struct {
+	v      [3]int
+	normal mgl32.Vec3
+	dist   float32
+}
+
+// create a face with the normal pointing away from the inside point. The origin can't be used for this as
+// it may be on one of the faces if the shapes are only just touching.
+func newFace(poly []vertex, i, j, k int, inside mgl32.Vec3) face {
+	f := face{v: [3]int{i, j, k}}
+	a, b, c := poly[i].p, poly[j].p, poly[k].p
+	n := b.Sub(a).Cross(c.Sub(a))
+	if n.Len() < tolerance*tolerance {
+		// degenerate faces are never closest so are not expanded
+		f.dist = float32(math.Inf(1))
+		return f
+	}
+	f.normal = n.Normalize()
+	if f.normal.Dot(inside.Sub(a)) > 0 {
+		f.normal = f.normal.Mul(-1)
+	}
+	f.dist = f.normal.Dot(a)
+	if f.dist < 0 {
+		f.dist = 0
+	}
+	return f
+}
+
+// epa expands the tetrahedron from gjk using the Expanding Polytope Algorithm to find the face of the Minkowski
+// difference which is closest to the origin. Returns the normal from a towards b, the penetration depth and
+// the contact point on a.
+func epa(a, b Shape, simplex []vertex) (normal mgl32.Vec3, depth float32, point mgl32.Vec3, ok bool) {
+	poly := append([]vertex{}, simplex...)
+	inside := poly[0].p.Add(poly[1].p).Add(poly[2].p).Add(poly[3].p).Mul(0.25)
+	faces := []face{
+		newFace(poly, 0, 1, 2, inside), newFace(poly, 0, 3, 1, inside), newFace(poly, 0, 2, 3, inside),
+		newFace(poly, 1, 3, 2, inside),
+	}
+	for i := 0; i < maxIterations; i++ {
+		closest := 0
+		for j, f := range faces {
+			if f.dist < faces[closest].dist {
+				closest = j
+			}
+		}
+		f := faces[closest]
+		if math.IsInf(float64(f.dist), 1) {
+			return normal, depth, point, false
+		}
+		v := support(a, b, f.normal)
+		if v.p.Dot(f.normal)-f.dist < tolerance {
+			return f.normal, f.dist, contactPoint(poly, f), true
+		}
+		// remove the faces which can see the new point and fill the hole with faces joined to it
+		var edges [][2]int
+		kept := faces[:0]
+		for _, f := range faces {
+			if f.normal.Dot(v.p.Sub(poly[f.v[0]].p)) > 0 {
+				for k := 0; k < 3; k++ {
+					edges = addEdge(edges, f.v[k], f.v[(k+1)%3])
+				}
+			} else {
+				kept = append(kept, f)
+			}
+		}
+		faces = kept
+		poly = append(poly, v)
+		for _, e := range edges {
+			faces = append(faces, newFace(poly, e[0], e[1], len(poly)-1, inside))
+		}
+	}
+	return normal, depth, point, false
+}
+
+// add an edge on the boundary of the hole, or remove it if it is shared with another removed face
+func addEdge(edges [][2]int, i, j int) [][2]int {
+	for k, e := range edges {
+		if e[0] == j && e[1] == i {
+			return append(edges[:k], edges[k+1:]...)
+		}
+	}
+	return append(edges, [2]int{i, j})
+}
+
+// project the origin onto the face and use the barycentric coordinates to find the point on shape a
+func contactPoint(poly []vertex, f face) mgl32.Vec3 {
+	a, b, c := poly[f.v[0]], poly[f.v[1]], poly[f.v[2]]
+	p := f.normal.Mul(f.dist)
+	u, v, w := barycentric(p, a.p, b.p, c.p)
+	return a.a.Mul(u).Add(b.a.Mul(v)).Add(c.a.Mul(w))
+}
+
+func barycentric(p, a, b, c mgl32.Vec3) (u, v, w float32) {
+	v0, v1, v2 := b.Sub(a), c.Sub(a), p.Sub(a)
+	d00, d01, d11 := v0.Dot(v0), v0.Dot(v1), v1.Dot(v1)
+	d20, d21 := v2.Dot(v0), v2.Dot(v1)
+	denom := d00*d11 - d01*d01
+	if denom == 0 {
+		return 1, 0, 0
+	}
+	v = (d11*d20 - d01*d21) / denom
+	w = (d00*d21 - d01*d20) / denom
+	return 1 - v - w, v, w
+}
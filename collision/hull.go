@@ -0,0 +1,135 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+)
+
+// ConvexHull returns the points on the convex hull of a set of points, and its triangles with the vertices in
+// counter clockwise order when viewed from outside. It uses the incremental algorithm, adding each point in
+// turn and replacing the faces which it can see. Returns nil if the points are all in the same plane.
+func ConvexHull(points []mgl32.Vec3) (Hull, [][3]mgl32.Vec3) {
+	eps := tolerance * mesh.BoundsOf(points).Size().Len()
+	start, ok := initialHull(points, eps)
+	if !ok {
+		return nil, nil
+	}
+	verts := []mgl32.Vec3{}
+	for _, i := range start {
+		verts = append(verts, points[i])
+	}
+	center := verts[0].Add(verts[1]).Add(verts[2]).Add(verts[3]).Mul(0.25)
+	faces := [][3]int{}
+	for _, f := range [][3]int{{0, 1, 2}, {0, 3, 1}, {0, 2, 3}, {1, 3, 2}} {
+		if planeDist(verts, f, center) > 0 {
+			f[1], f[2] = f[2], f[1]
+		}
+		faces = append(faces, f)
+	}
+	for i, p := range points {
+		if i == start[0] || i == start[1] || i == start[2] || i == start[3] {
+			continue
+		}
+		var edges [][2]int
+		kept := faces[:0]
+		for _, f := range faces {
+			if planeDist(verts, f, p) > eps {
+				for k := 0; k < 3; k++ {
+					edges = addEdge(edges, f[k], f[(k+1)%3])
+				}
+			} else {
+				kept = append(kept, f)
+			}
+		}
+		faces = kept
+		if len(edges) == 0 {
+			continue
+		}
+		verts = append(verts, p)
+		for _, e := range edges {
+			faces = append(faces, [3]int{e[0], e[1], len(verts) - 1})
+		}
+	}
+	// drop any points which were added but are now inside
+	used := map[int]bool{}
+	tris := make([][3]mgl32.Vec3, len(faces))
+	for i, f := range faces {
+		for j, v := range f {
+			used[v] = true
+			tris[i][j] = verts[v]
+		}
+	}
+	hull := Hull{}
+	for i, v := range verts {
+		if used[i] {
+			hull = append(hull, v)
+		}
+	}
+	return hull, tris
+}
+
+// distance of point p in front of the plane of the face
+func planeDist(verts []mgl32.Vec3, f [3]int, p mgl32.Vec3) float32 {
+	a, b, c := verts[f[0]], verts[f[1]], verts[f[2]]
+	n := b.Sub(a).Cross(c.Sub(a))
+	if l := n.Len(); l > 0 {
+		return n.Dot(p.Sub(a)) / l
+	}
+	return 0
+}
+
+// find four points which are not in the same plane, spread as far apart as possible
+func initialHull(points []mgl32.Vec3, eps float32) (start [4]int, ok bool) {
+	if len(points) < 4 {
+		return start, false
+	}
+	// extreme points along the x axis
+	for i, p := range points {
+		if p[0] < points[start[0]][0] {
+			start[0] = i
+		}
+		if p[0] > points[start[1]][0] {
+			start[1] = i
+		}
+	}
+	a, b := points[start[0]], points[start[1]]
+	if b.Sub(a).Len() <= eps {
+		// all the same in x so use the point furthest from the first
+		for i, p := range points {
+			if p.Sub(a).Len() > points[start[1]].Sub(a).Len() {
+				start[1] = i
+			}
+		}
+		b = points[start[1]]
+		if b.Sub(a).Len() <= eps {
+			return start, false
+		}
+	}
+	// furthest from the line
+	ab := b.Sub(a).Normalize()
+	best := float32(0)
+	for i, p := range points {
+		if d := p.Sub(a).Cross(ab).Len(); d > best {
+			start[2], best = i, d
+		}
+	}
+	if best <= eps {
+		return start, false
+	}
+	// furthest from the plane
+	n := b.Sub(a).Cross(points[start[2]].Sub(a)).Normalize()
+	best = 0
+	for i, p := range points {
+		if d := abs(n.Dot(p.Sub(a))); d > best {
+			start[3], best = i, d
+		}
+	}
+	return start, best > eps
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,169 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"sort"
+)
+
+const leafSize = 4
+
+// triTree is a bounding volume hierarchy over the triangles of a concave mesh in world space
+type triTree struct {
+	tris   [][3]mgl32.Vec3
+	bounds []mesh.Bounds
+	nodes  []triNode
+}
+
+type triNode struct {
+	bounds      mesh.Bounds
+	left, right int // child nodes, or range of triangles if left < 0
+	start, end  int
+}
+
+func newTriTree(tris [][3]mgl32.Vec3) *triTree {
+	t := &triTree{tris: tris, bounds: make([]mesh.Bounds, len(tris))}
+	for i, tri := range tris {
+		t.bounds[i] = triBounds(tri)
+	}
+	if len(tris) > 0 {
+		t.build(0, len(tris))
+	}
+	return t
+}
+
+// box around the triangle, the sphere is not needed
+func triBounds(tri [3]mgl32.Vec3) mesh.Bounds {
+	b := mesh.Bounds{Min: tri[0], Max: tri[0]}
+	for _, p := range tri[1:] {
+		for i := 0; i < 3; i++ {
+			b.Min[i] = min32(b.Min[i], p[i])
+			b.Max[i] = max32(b.Max[i], p[i])
+		}
+	}
+	b.Radius = b.Size().Len() / 2
+	return b
+}
+
+// build the tree top down, splitting at the median along the longest axis
+func (t *triTree) build(start, end int) int {
+	index := len(t.nodes)
+	t.nodes = append(t.nodes, triNode{left: -1, right: -1, start: start, end: end})
+	b := t.bounds[start]
+	for _, tb := range t.bounds[start+1 : end] {
+		for i := 0; i < 3; i++ {
+			b.Min[i] = min32(b.Min[i], tb.Min[i])
+			b.Max[i] = max32(b.Max[i], tb.Max[i])
+		}
+	}
+	b.Radius = b.Size().Len() / 2
+	t.nodes[index].bounds = b
+	if end-start <= leafSize {
+		return index
+	}
+	size := b.Size()
+	axis := 0
+	if size[1] > size[axis] {
+		axis = 1
+	}
+	if size[2] > size[axis] {
+		axis = 2
+	}
+	sort.Sort(byAxis{t, start, end, axis})
+	mid := (start + end) / 2
+	left := t.build(start, mid)
+	right := t.build(mid, end)
+	t.nodes[index].left, t.nodes[index].right = left, right
+	return index
+}
+
+// sort a range of triangles and their bounds by the center along one axis
+type byAxis struct {
+	t                *triTree
+	start, end, axis int
+}
+
+func (s byAxis) Len() int { return s.end - s.start }
+
+func (s byAxis) Less(i, j int) bool {
+	bi, bj := s.t.bounds[s.start+i], s.t.bounds[s.start+j]
+	return bi.Min[s.axis]+bi.Max[s.axis] < bj.Min[s.axis]+bj.Max[s.axis]
+}
+
+func (s byAxis) Swap(i, j int) {
+	i, j = s.start+i, s.start+j
+	s.t.tris[i], s.t.tris[j] = s.t.tris[j], s.t.tris[i]
+	s.t.bounds[i], s.t.bounds[j] = s.t.bounds[j], s.t.bounds[i]
+}
+
+// call fn for each triangle whose bounds overlap the box
+func (t *triTree) query(box mesh.Bounds, fn func(tri [3]mgl32.Vec3)) {
+	if len(t.nodes) == 0 {
+		return
+	}
+	stack := []int{0}
+	for len(stack) > 0 {
+		n := &t.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !n.bounds.Intersects(box) {
+			continue
+		}
+		if n.left < 0 {
+			for i := n.start; i < n.end; i++ {
+				if t.bounds[i].Intersects(box) {
+					fn(t.tris[i])
+				}
+			}
+		} else {
+			stack = append(stack, n.right, n.left)
+		}
+	}
+}
+
+// ClosestPoint returns the point on the triangle nearest to p, from Ericson's Real-Time Collision Detection.
+func ClosestPoint(p mgl32.Vec3, tri [3]mgl32.Vec3) mgl32.Vec3 {
+	a, b, c := tri[0], tri[1], tri[2]
+	ab, ac, ap := b.Sub(a), c.Sub(a), p.Sub(a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := p.Sub(b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.Add(ab.Mul(d1 / (d1 - d3)))
+	}
+	cp := p.Sub(c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.Add(ac.Mul(d2 / (d2 - d6)))
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return b.Add(c.Sub(b).Mul((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	denom := 1 / (va + vb + vc)
+	return a.Add(ab.Mul(vb * denom)).Add(ac.Mul(vc * denom))
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -164,7 +164,7 @@ func (t *GopherCube) Paint(p *qml.Painter) {
 	t.program.Set("tex", 0)
 
 	// draw the cube
-	gl.Enable(GL.DEPTH_TEST)
+	glu.Enable(GL.DEPTH_TEST)
 	gl.ClearColor(0, 0, 1, 1)
 	gl.Clear(GL.COLOR_BUFFER_BIT | GL.DEPTH_BUFFER_BIT)
 	t.cube.Draw(GL.TRIANGLES, GL.CCW)
@@ -173,7 +173,7 @@ func (t *GopherCube) Paint(p *qml.Painter) {
 	t.floor.Enable()
 	t.program.Use()
 	t.program.Set("color", mgl32.Vec3{0, 0, 0})
-	gl.Enable(GL.STENCIL_TEST)
+	glu.Enable(GL.STENCIL_TEST)
 	gl.StencilFunc(GL.ALWAYS, 1, 0xFF)
 	gl.StencilOp(GL.KEEP, GL.KEEP, GL.REPLACE)
 	gl.StencilMask(0xFF)
@@ -191,7 +191,7 @@ func (t *GopherCube) Paint(p *qml.Painter) {
 	m2 := m.Mul4(mgl32.Translate3D(0, 0, -1)).Mul4(mgl32.Scale3D(1, 1, -1))
 	t.program.Set("model", m2)
 	t.cube.Draw(GL.TRIANGLES, GL.CCW)
-	gl.Disable(GL.STENCIL_TEST)
+	glu.Disable(GL.STENCIL_TEST)
 }
 
 func run() error {
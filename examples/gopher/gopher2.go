@@ -31,43 +31,41 @@ type ReflectSurface struct {
 	mesh.Material
 }
 
-func (m ReflectSurface) Enable() *glu.Program {
+func (m ReflectSurface) Enable(v mesh.Variant) *glu.Program {
 	gl := glu.GLRef()
-	gl.Enable(GL.STENCIL_TEST)
+	glu.Enable(GL.STENCIL_TEST)
 	gl.StencilFunc(GL.ALWAYS, 1, 0xFF)
 	gl.StencilOp(GL.KEEP, GL.KEEP, GL.REPLACE)
 	gl.StencilMask(0xFF)
 	gl.DepthMask(false)
 	gl.Clear(GL.STENCIL_BUFFER_BIT)
-	return m.Material.Enable()
+	return m.Material.Enable(v)
 }
 
 func (m ReflectSurface) Disable() {
 	gl := glu.GLRef()
 	gl.DepthMask(true)
-	gl.Disable(GL.STENCIL_TEST)
+	glu.Disable(GL.STENCIL_TEST)
 }
 
 type ReflectImage struct {
 	mesh.Material
 }
 
-func (m ReflectImage) Enable() *glu.Program {
+func (m ReflectImage) Enable(v mesh.Variant) *glu.Program {
 	gl := glu.GLRef()
-	gl.Enable(GL.STENCIL_TEST)
+	glu.Enable(GL.STENCIL_TEST)
 	gl.StencilFunc(GL.EQUAL, 1, 0xFF)
 	gl.StencilMask(0x00)
-	return m.Material.Enable()
+	return m.Material.Enable(v)
 }
 
 func (m ReflectImage) Disable() {
-	gl := glu.GLRef()
-	gl.Disable(GL.STENCIL_TEST)
+	glu.Disable(GL.STENCIL_TEST)
 }
 
 func (t *GopherCube) initGL(gl *GL.GL) scene.Object {
 	fmt.Println("initialise GL")
-	glu.Init(gl)
 	glu.Debug = true
 	t.view = scene.NewView(camera).AddLight(light)
 
@@ -105,6 +103,7 @@ func (t *GopherCube) Rotate() {
 
 func (t *GopherCube) Paint(p *qml.Painter) {
 	gl := GL.API(p)
+	glu.Init(gl)
 	if t.world == nil {
 		t.world = t.initGL(gl)
 	}
@@ -3,26 +3,24 @@ package main
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/backend"
 	"github.com/jnb666/go3d/glu"
 	"github.com/jnb666/go3d/img"
 	"github.com/jnb666/go3d/mesh"
 	"github.com/jnb666/go3d/scene"
-	"gopkg.in/qml.v1"
-	"gopkg.in/qml.v1/gl/es2"
 	"os"
 	"strings"
 )
 
-const (
-	sceneFile = "lighting.qml"
-	moveScale = 0.005
-)
+const moveScale = 0.005
 
 var (
 	lightPos  = glu.Polar{R: 1, Theta: 50, Phi: -110}
 	cameraPos = glu.Polar{R: 2, Theta: 65, Phi: 60}
 	camera    = scene.ArcBallCamera(cameraPos, mgl32.Vec3{}, 0.5, 5.0, 10, 170)
 	light     = scene.DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, lightPos)
+	modes     = []string{"diffuse", "specular", "normal"}
+	texNames  = []string{"brick", "shield", "stone"}
 )
 
 type mouseInfo struct {
@@ -37,7 +35,8 @@ type texInfo struct {
 }
 
 type Model struct {
-	qml.Object
+	win      backend.Window
+	spinning bool
 	mode     string
 	texName  string
 	scene    scene.Object
@@ -47,9 +46,46 @@ type Model struct {
 	mouse    mouseInfo
 }
 
-func (t *Model) Zoom(amount float32) {
-	t.view.Camera.Move(amount)
-	t.Call("update")
+func (t *Model) update() {
+	if t.win != nil {
+		t.win.Update()
+	}
+}
+
+func (t *Model) Zoom(delta int) {
+	t.view.Camera.Move(float32(delta))
+	t.update()
+}
+
+// Key selects the next lighting mode or texture, for use with the GLFW backend.
+func (t *Model) Key(key string) {
+	switch key {
+	case "l":
+		t.SetLighting(next(modes, t.mode))
+	case "t":
+		t.SetTexture(next(texNames, t.texName))
+	case "r":
+		t.Reset()
+	case "s":
+		t.spinning = !t.spinning
+	case "escape":
+		t.win.Close()
+	}
+}
+
+func (t *Model) Tick() {
+	if t.spinning {
+		t.Spin()
+	}
+}
+
+func next(list []string, name string) string {
+	for i, s := range list {
+		if s == name {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
 }
 
 func (t *Model) Mouse(event string, x, y, button int) {
@@ -65,7 +101,7 @@ func (t *Model) Mouse(event string, x, y, button int) {
 				t.view.Lights[0].Rotate(dx, dy)
 			}
 			t.mouse.x, t.mouse.y = x, y
-			t.Call("update")
+			t.update()
 		}
 	case "end":
 		t.mouse.button = 0
@@ -75,26 +111,26 @@ func (t *Model) Mouse(event string, x, y, button int) {
 func (t *Model) Reset() {
 	fmt.Println("reset view")
 	t.view = scene.NewView(camera.Clone()).AddLight(light.Clone())
-	t.object.Mat4 = mgl32.Ident4()
-	t.Call("update")
+	t.object.SetRotation(mgl32.QuatIdent())
+	t.update()
 }
 
 func (t *Model) Spin() {
 	t.object.RotateY(0.25)
-	t.Call("update")
+	t.update()
 }
 
 func (t *Model) SetTexture(name string) {
 	if name != t.texName {
 		t.texName = name
-		t.Call("update")
+		t.update()
 	}
 }
 
 func (t *Model) SetLighting(mode string) {
 	if mode != t.mode {
 		t.mode = mode
-		t.Call("update")
+		t.update()
 	}
 }
 
@@ -128,8 +164,12 @@ func getTexture(name string, conv img.ImageConvert) texInfo {
 func (t *Model) initialise() {
 	fmt.Println("initialise")
 	glu.Debug = true
-	t.mode = "diffuse"
-	t.texName = "brick"
+	if t.mode == "" {
+		t.mode = "diffuse"
+	}
+	if t.texName == "" {
+		t.texName = "brick"
+	}
 	t.textures = map[string]texInfo{
 		"brick":  getTexture("brick.png", img.NoConvert),
 		"shield": getTexture("shield.png", img.NoConvert),
@@ -141,8 +181,7 @@ func (t *Model) initialise() {
 	t.scene = scene.NewGroup().Add(t.object).Scale(2, 2, 2).Translate(0, -1, 0)
 }
 
-func (t *Model) Paint(p *qml.Painter) {
-	gl := GL.API(p)
+func (t *Model) Paint(gl glu.Context, width, height int) {
 	glu.Init(gl)
 	if t.scene == nil {
 		t.initialise()
@@ -162,31 +201,12 @@ func (t *Model) Paint(p *qml.Painter) {
 	}
 	t.object.SetMaterial(mtl)
 	// draw scene
-	t.view.SetProjection(t.Int("width"), t.Int("height"))
+	if t.win != nil {
+		t.view.FlipY = t.win.FlipY()
+	}
+	t.view.SetProjection(width, height)
 	glu.Clear(glu.Black)
 	view := t.view.ViewMatrix()
 	t.view.UpdateLights(view, t.scene)
 	t.view.Draw(view, t.scene)
 }
-
-func run() error {
-	qml.RegisterTypes("GoExtensions", 1, 0, []qml.TypeSpec{{
-		Init: func(t *Model, obj qml.Object) { t.Object = obj },
-	}})
-	engine := qml.NewEngine()
-	engine.On("quit", func() { os.Exit(0) })
-	component, err := engine.LoadFile(sceneFile)
-	if err != nil {
-		return err
-	}
-	window := component.CreateWindow(nil)
-	window.Show()
-	window.Wait()
-	return nil
-}
-
-func main() {
-	if err := qml.Run(run); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-	}
-}
@@ -41,7 +41,7 @@ ApplicationWindow {
             acceptedButtons: Qt.LeftButton | Qt.RightButton
             onPressed: model.mouse("start", mouse.x, mouse.y, mouse.button)
             onPositionChanged: model.mouse("move", mouse.x, mouse.y, mouse.button)
-            onReleased: model.mouse("end", 0, 0, mouse.button)
+            onReleased: model.mouse("end", mouse.x, mouse.y, mouse.button)
         }
     }
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+//go:build glfw
+// +build glfw
+
+package main
+
+import (
+	"fmt"
+	"github.com/jnb666/go3d/backend/glfwgl"
+	"os"
+)
+
+func main() {
+	win, err := glfwgl.New("lighting", 1000, 800)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	win.Run(&Model{win: win})
+}
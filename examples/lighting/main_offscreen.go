@@ -0,0 +1,40 @@
+//go:build offscreen
+// +build offscreen
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"os"
+)
+
+func main() {
+	var width, height, frames int
+	var mode, texture, output string
+	flag.IntVar(&width, "width", 800, "image width")
+	flag.IntVar(&height, "height", 600, "image height")
+	flag.IntVar(&frames, "frames", 1, "number of frames to render, cube spins between each frame")
+	flag.StringVar(&mode, "mode", "normal", "lighting mode: diffuse, specular or normal")
+	flag.StringVar(&texture, "texture", "brick", "texture name")
+	flag.StringVar(&output, "o", "lighting.png", "output PNG file for the last frame")
+	flag.Parse()
+	win, err := offscreen.New(width, height)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer win.Release()
+	model := &Model{win: win, mode: mode, texName: texture, spinning: true}
+	err = win.Run(model, frames, func(frame int) error {
+		if frame == frames-1 {
+			return win.SavePNG(output)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -3,15 +3,20 @@ package main
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/animation"
+	"github.com/jnb666/go3d/backend"
+	"github.com/jnb666/go3d/capture"
+	"github.com/jnb666/go3d/collision"
 	"github.com/jnb666/go3d/glu"
 	"github.com/jnb666/go3d/mesh"
 	"github.com/jnb666/go3d/scene"
-	"gopkg.in/qml.v1"
-	"gopkg.in/qml.v1/gl/es2"
-	"os"
+	"time"
 )
 
-const sceneFile = "shapes.qml"
+const (
+	roomSize     = 16
+	cameraRadius = 0.25
+)
 
 var (
 	cameraPos = glu.Polar{R: 2.0, Theta: 70, Phi: 45}
@@ -28,15 +33,26 @@ var (
 		"sponza":  "sponza/sponza.obj",
 		"sibenik": "sibenik/sibenik.obj",
 	}
+	modelNames = []string{"cube", "teapot", "shuttle", "bunny", "dragon", "sponza", "sibenik"}
+	tickTime   = float32(backend.TickInterval.Seconds())
+	spinTime   = 360 * tickTime // seconds for one turn
 )
 
 type mouseInfo struct {
 	x, y, button int
+	dragged      bool
 }
 
 type Model struct {
-	qml.Object
+	win        backend.Window
+	spinning   bool
+	spin       *animation.Player
+	lastSpin   time.Time
+	fixedStep  bool
+	moving     bool
 	cameraMode int
+	collide    bool
+	walls      *collision.CameraCollider
 	bumpMap    bool
 	setModel   string
 	modelName  string
@@ -45,16 +61,21 @@ type Model struct {
 	scene      scene.Object
 	view       *scene.View
 	mouse      mouseInfo
+	recorder   *capture.Recorder
+	recFrames  int
 }
 
-func (t *Model) initialise(gl *GL.GL) {
+func (t *Model) initialise() {
 	fmt.Println("initialise")
 	glu.Debug = true
 	t.view = scene.NewView(rotCamera.Clone()).AddLight(light)
 	t.background = scene.NewItem(mesh.Cube().Invert().SetMaterial(mesh.Skybox()))
 	t.background.Scale(40, 40, 40)
 	t.models = map[string]*mesh.Mesh{}
-	t.setModel = "cube"
+	t.spin = animation.NewPlayer(spinClip(), nil, animation.Loop)
+	if t.setModel == "" {
+		t.setModel = "cube"
+	}
 	t.loadMesh(t.setModel)
 }
 
@@ -69,23 +90,25 @@ func (t *Model) loadMesh(name string) {
 	}
 	model := t.models[name]
 	model.BumpMap(t.bumpMap)
+	// models are scaled to fit in a unit box, or a box of size roomSize for the interior scenes
 	switch name {
-	case "cube":
-		t.scene = scene.NewItem(model)
-	case "teapot":
-		t.scene = scene.NewItem(model).Scale(0.012, 0.012, 0.012).Translate(0, -0.4, 0)
-	case "shuttle":
-		t.scene = scene.NewGroup().Add(scene.NewItem(model).Scale(0.13, 0.13, 0.13).RotateX(-90))
-	case "bunny":
-		t.scene = scene.NewItem(model).Scale(0.7, 0.7, 0.7)
-	case "dragon":
-		t.scene = scene.NewGroup().Add(scene.NewItem(model).RotateX(-90))
+	case "shuttle", "dragon":
+		t.scene = scene.Normalize(scene.NewModel(name, model).RotateX(-90))
 	case "sponza":
-		t.scene = scene.NewItem(model).Scale(0.5, 0.5, 0.5).Translate(0.5, -1, 0)
+		t.scene = scene.Normalize(scene.NewModel(name, model)).Scale(roomSize, roomSize, roomSize)
 	case "sibenik":
-		t.scene = scene.NewItem(model).Scale(0.5, 0.5, 0.5).RotateY(180).Translate(-0.5, 6, 0)
+		t.scene = scene.Normalize(scene.NewModel(name, model).RotateY(180)).Scale(roomSize, roomSize, roomSize)
+	default:
+		t.scene = scene.Normalize(scene.NewModel(name, model))
 	}
 	t.modelName = name
+	t.walls = nil
+}
+
+func (t *Model) update() {
+	if t.win != nil {
+		t.win.Update()
+	}
 }
 
 func (t *Model) SetModel(name string) {
@@ -94,24 +117,66 @@ func (t *Model) SetModel(name string) {
 	}
 	fmt.Println("set model", name)
 	t.setModel = name
-	t.Call("update")
+	t.update()
+}
+
+// Record starts capturing a turntable animation of the given number of frames to file, which may be a .gif
+// or a pattern for numbered PNG files. The model is rotated by one degree per frame.
+func (t *Model) Record(file string, frames int) {
+	fmt.Println("record", frames, "frames to", file)
+	t.recorder = capture.New(file)
+	t.recFrames = frames
+	t.spinning = true
+	t.update()
+}
+
+func (t *Model) saveFrame() {
+	if err := t.recorder.Add(t.view.Screenshot()); err != nil {
+		fmt.Println("error saving frame:", err)
+	}
+	if t.recorder.Frames() >= t.recFrames {
+		if err := t.recorder.Close(); err != nil {
+			fmt.Println("error saving animation:", err)
+		}
+		fmt.Println("recording complete")
+		t.recorder = nil
+		t.spinning = false
+	}
+}
+
+// clip which turns the model once around the vertical axis
+func spinClip() *animation.Clip {
+	track := animation.NewTrack(animation.Rotation, animation.Slerp)
+	for i := 0; i <= 4; i++ {
+		track.AddAngle(float32(i)*spinTime/4, float32(i)*90, mgl32.Vec3{0, 1, 0})
+	}
+	return animation.NewClip("spin", track)
 }
 
+// Spin advances the spin animation by the time since the last call. When recording, or with fixedStep set,
+// it moves on by one tick each call so every frame turns the model by one degree.
 func (t *Model) Spin() {
-	t.scene.RotateY(1)
-	t.Call("update")
+	dt := float32(time.Since(t.lastSpin).Seconds())
+	if t.fixedStep || t.recorder != nil || dt > 5*tickTime {
+		dt = tickTime
+	}
+	t.lastSpin = time.Now()
+	t.spin.Target = t.scene
+	t.spin.Update(dt)
+	t.walls = nil
+	t.update()
 }
 
 func (t *Model) SetScenery(on bool) {
 	t.background.Enable(on)
-	t.Call("update")
+	t.update()
 }
 
 func (t *Model) EnableBump(on bool) {
 	t.bumpMap = on
 	t.setModel = t.modelName
 	t.modelName = ""
-	t.Call("update")
+	t.update()
 }
 
 func (t *Model) SetCamera(mode int) {
@@ -130,18 +195,108 @@ func (t *Model) Reset() {
 	} else {
 		t.view.Camera = povCamera.Clone()
 	}
-	t.Call("update")
+	t.update()
+}
+
+// EnableCollisions stops the point of view camera from moving through the model
+func (t *Model) EnableCollisions(on bool) {
+	fmt.Println("collisions", on)
+	t.collide = on
+}
+
+// the collider is created when the camera first moves after the model is changed
+func (t *Model) setCollider() {
+	if !t.collide || t.cameraMode == 0 || t.scene == nil {
+		scene.SetCollider(t.view.Camera, nil)
+		return
+	}
+	if t.walls == nil {
+		t.walls = collision.NewCameraCollider(t.scene, cameraRadius)
+	}
+	scene.SetCollider(t.view.Camera, t.walls)
 }
 
 func (t *Model) Move(amount float32) {
+	t.setCollider()
 	t.view.Camera.Move(amount)
-	t.Call("update")
+	t.update()
+}
+
+func (t *Model) Zoom(delta int) {
+	if delta > 0 {
+		t.Move(1)
+	} else if delta < 0 {
+		t.Move(-1)
+	}
 }
 
-func (t *Model) Rotate(event string, x, y, button int) {
+// Key handles the cursor keys to rotate and space to move. The other keys duplicate the controls
+// from the QML user interface for use with the GLFW backend, v records a turntable animation, i prints
+// the drawing statistics for the last frame and x toggles camera collisions.
+func (t *Model) Key(key string) {
+	switch key {
+	case "left":
+		t.view.Camera.Rotate(-2, 0)
+	case "right":
+		t.view.Camera.Rotate(2, 0)
+	case "up":
+		t.view.Camera.Rotate(0, -2)
+	case "down":
+		t.view.Camera.Rotate(0, 2)
+	case "space":
+		t.Move(2)
+		return
+	case "s":
+		t.spinning = !t.spinning
+	case "m":
+		t.moving = !t.moving
+	case "r":
+		t.moving = false
+		t.Reset()
+	case "b":
+		t.EnableBump(!t.bumpMap)
+	case "k":
+		t.SetScenery(!t.background.Enabled())
+	case "c":
+		t.SetCamera(1 - t.cameraMode)
+	case "v":
+		if t.recorder == nil {
+			t.Record("turntable.gif", 360)
+		}
+	case "x":
+		t.EnableCollisions(!t.collide)
+		return
+	case "i":
+		fmt.Printf("%d items drawn, %d culled, %d GL state calls\n", t.view.Stats.Drawn, t.view.Stats.Culled, glu.FrameStats().Calls)
+		return
+	case "n":
+		for i, name := range modelNames {
+			if name == t.modelName {
+				t.SetModel(modelNames[(i+1)%len(modelNames)])
+				break
+			}
+		}
+	case "escape":
+		t.win.Close()
+	default:
+		return
+	}
+	t.update()
+}
+
+func (t *Model) Tick() {
+	if t.spinning {
+		t.Spin()
+	}
+	if t.moving {
+		t.Move(0.25)
+	}
+}
+
+func (t *Model) Mouse(event string, x, y, button int) {
 	switch event {
 	case "start":
-		t.mouse = mouseInfo{x, y, button}
+		t.mouse = mouseInfo{x: x, y: y, button: button}
 	case "move":
 		if t.mouse.button != 0 {
 			dx, dy := float32(x-t.mouse.x), float32(y-t.mouse.y)
@@ -151,26 +306,39 @@ func (t *Model) Rotate(event string, x, y, button int) {
 				t.view.Lights[0].Rotate(dx, dy)
 			}
 			t.mouse.x, t.mouse.y = x, y
-			t.Call("update")
+			t.mouse.dragged = true
+			t.update()
 		}
 	case "end":
+		if t.mouse.button != 0 && !t.mouse.dragged {
+			t.pick(x, y)
+		}
 		t.mouse.button = 0
-	case "keys":
-		t.view.Camera.Rotate(float32(x), float32(y))
-		t.Call("update")
 	}
 }
 
-func (t *Model) Paint(p *qml.Painter) {
-	gl := GL.API(p)
+// print the details of the item under the cursor after a click
+func (t *Model) pick(x, y int) {
+	if hit, ok := scene.Pick(t.scene, t.view.Ray(x, y)); ok {
+		fmt.Printf("picked %s triangle %d at %.3f normal %.3f distance %.3f\n", hit.Item.Path(), hit.Triangle, hit.Point,
+			hit.Normal, hit.Distance)
+	} else {
+		fmt.Println("nothing picked")
+	}
+}
+
+func (t *Model) Paint(gl glu.Context, width, height int) {
 	glu.Init(gl)
 	if t.models == nil {
-		t.initialise(gl)
+		t.initialise()
 	}
 	if t.setModel != t.modelName {
 		t.loadMesh(t.setModel)
 	}
-	t.view.SetProjection(t.Int("width"), t.Int("height"))
+	if t.win != nil {
+		t.view.FlipY = t.win.FlipY()
+	}
+	t.view.SetProjection(width, height)
 	glu.Clear(mgl32.Vec4{0.5, 0.5, 1, 1})
 	view := t.view.ViewMatrix()
 	t.view.UpdateLights(view, nil)
@@ -179,26 +347,7 @@ func (t *Model) Paint(p *qml.Painter) {
 		t.view.Draw(t.view.CenteredView(), t.background)
 	}
 	t.view.Draw(view, t.scene)
-}
-
-func run() error {
-	qml.RegisterTypes("GoExtensions", 1, 0, []qml.TypeSpec{{
-		Init: func(t *Model, obj qml.Object) { t.Object = obj },
-	}})
-	engine := qml.NewEngine()
-	engine.On("quit", func() { os.Exit(0) })
-	component, err := engine.LoadFile(sceneFile)
-	if err != nil {
-		return err
-	}
-	window := component.CreateWindow(nil)
-	window.Show()
-	window.Wait()
-	return nil
-}
-
-func main() {
-	if err := qml.Run(run); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	if t.recorder != nil {
+		t.saveFrame()
 	}
 }
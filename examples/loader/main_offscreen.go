@@ -0,0 +1,43 @@
+//go:build offscreen
+// +build offscreen
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"os"
+)
+
+func main() {
+	var width, height, frames int
+	var name, output, record string
+	flag.IntVar(&width, "width", 800, "image width")
+	flag.IntVar(&height, "height", 600, "image height")
+	flag.IntVar(&frames, "frames", 1, "number of frames to render, model spins between each frame")
+	flag.StringVar(&name, "model", "cube", "model name")
+	flag.StringVar(&output, "o", "loader.png", "output PNG file for the last frame")
+	flag.StringVar(&record, "record", "", "record each frame to a .gif file or numbered PNG files")
+	flag.Parse()
+	win, err := offscreen.New(width, height)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer win.Release()
+	model := &Model{win: win, setModel: name, spinning: true, fixedStep: true}
+	if record != "" {
+		model.Record(record, frames)
+	}
+	err = win.Run(model, frames, func(frame int) error {
+		if frame == frames-1 {
+			return win.SavePNG(output)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !glfw && !offscreen
+// +build !glfw,!offscreen
+
+package main
+
+import (
+	"fmt"
+	"github.com/jnb666/go3d/backend/qmlgl"
+	"gopkg.in/qml.v1"
+	"os"
+)
+
+const sceneFile = "shapes.qml"
+
+// QML type which paints the model
+type qmlModel struct {
+	Model
+	qmlgl.Window
+}
+
+func (t *qmlModel) Paint(p *qml.Painter) {
+	t.Window.Paint(p, &t.Model)
+}
+
+func main() {
+	err := qmlgl.Run(sceneFile, qml.TypeSpec{
+		Name: "Model",
+		Init: func(t *qmlModel, obj qml.Object) {
+			t.Window = qmlgl.Window{Object: obj}
+			t.win = t.Window
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+}
@@ -56,28 +56,28 @@ ApplicationWindow {
         focus: true
         Keys.onPressed: {
             if (event.key == Qt.Key_Left) {
-                model.rotate("keys", -2, 0, 0)
+                model.key("left")
             }
             if (event.key == Qt.Key_Right) {
-                model.rotate("keys", 2, 0, 0)
+                model.key("right")
             }
             if (event.key == Qt.Key_Up) {
-                model.rotate("keys", 0, -2, 0)
+                model.key("up")
             }
             if (event.key == Qt.Key_Down) {
-                model.rotate("keys", 0, 2, 0)
+                model.key("down")
             }
             if (event.key == Qt.Key_Space) {
-                model.move(2)
+                model.key("space")
             }
         }
         MouseArea {
             anchors.fill: parent
             onWheel: model.move(wheel.angleDelta.y > 0 ? 1 : -1)
             acceptedButtons: Qt.LeftButton | Qt.RightButton
-            onPressed: model.rotate("start", mouse.x, mouse.y, mouse.button)
-            onPositionChanged: model.rotate("move", mouse.x, mouse.y, mouse.button)
-            onReleased: model.rotate("end", 0, 0, mouse.button)
+            onPressed: model.mouse("start", mouse.x, mouse.y, mouse.button)
+            onPositionChanged: model.mouse("move", mouse.x, mouse.y, mouse.button)
+            onReleased: model.mouse("end", mouse.x, mouse.y, mouse.button)
         }
     }
 }
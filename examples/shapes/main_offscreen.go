@@ -0,0 +1,40 @@
+//go:build offscreen
+// +build offscreen
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"os"
+)
+
+func main() {
+	var width, height, frames int
+	var shape, material, output string
+	flag.IntVar(&width, "width", 800, "image width")
+	flag.IntVar(&height, "height", 600, "image height")
+	flag.IntVar(&frames, "frames", 1, "number of frames to render, shape spins between each frame")
+	flag.StringVar(&shape, "shape", "cube", "shape name")
+	flag.StringVar(&material, "material", "plastic", "material name")
+	flag.StringVar(&output, "o", "shapes.png", "output PNG file for the last frame")
+	flag.Parse()
+	win, err := offscreen.New(width, height)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer win.Release()
+	shapes := &Shapes{win: win, shapeName: shape, matName: material, spinning: true}
+	err = win.Run(shapes, frames, func(frame int) error {
+		if frame == frames-1 {
+			return win.SavePNG(output)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
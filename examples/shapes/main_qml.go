@@ -0,0 +1,36 @@
+//go:build !glfw && !offscreen
+// +build !glfw,!offscreen
+
+package main
+
+import (
+	"fmt"
+	"github.com/jnb666/go3d/backend/qmlgl"
+	"gopkg.in/qml.v1"
+	"os"
+)
+
+const sceneFile = "shapes.qml"
+
+// QML type which paints the shapes
+type qmlShapes struct {
+	Shapes
+	qmlgl.Window
+}
+
+func (t *qmlShapes) Paint(p *qml.Painter) {
+	t.Window.Paint(p, &t.Shapes)
+}
+
+func main() {
+	err := qmlgl.Run(sceneFile, qml.TypeSpec{
+		Name: "Shapes",
+		Init: func(t *qmlShapes, obj qml.Object) {
+			t.Window = qmlgl.Window{Object: obj}
+			t.win = t.Window
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+}
@@ -3,23 +3,22 @@ package main
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/backend"
 	"github.com/jnb666/go3d/glu"
 	"github.com/jnb666/go3d/img"
 	"github.com/jnb666/go3d/mesh"
 	"github.com/jnb666/go3d/scene"
-	"gopkg.in/qml.v1"
-	"gopkg.in/qml.v1/gl/es2"
 	"image/color"
-	"os"
 )
 
-const sceneFile = "shapes.qml"
-
 var (
 	cameraPos = glu.Polar{R: 2.0, Theta: 70, Phi: 45}
 	lightPos  = glu.Polar{R: 1, Theta: 20, Phi: 90}
 	camera    = scene.ArcBallCamera(cameraPos, mgl32.Vec3{}, 0.5, 5.0, 10, 170)
 	light     = scene.DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, lightPos)
+	shapeList = []string{"cube", "prism", "pyramid", "point", "plane", "circle", "cylinder", "cone", "icosohedron", "sphere"}
+	mtlList   = []string{"plastic", "wood", "rough", "marble", "metallic", "glass", "earth", "emissive", "diffuse",
+		"unshaded", "texture2d", "texturecube"}
 )
 
 type mouseInfo struct {
@@ -27,7 +26,8 @@ type mouseInfo struct {
 }
 
 type Shapes struct {
-	qml.Object
+	win        backend.Window
+	spinning   bool
 	shapeName  string
 	matName    string
 	material   map[string]mesh.Material
@@ -37,6 +37,12 @@ type Shapes struct {
 	mouse      mouseInfo
 }
 
+func (t *Shapes) update() {
+	if t.win != nil {
+		t.win.Update()
+	}
+}
+
 func (t *Shapes) initialise() {
 	fmt.Println("initialise")
 	glu.Debug = true
@@ -54,7 +60,9 @@ func (t *Shapes) initialise() {
 	t.background = scene.NewItem(mesh.Cube().Invert()).SetMaterial(mesh.Skybox())
 	t.background.Enable(false).Scale(10, 10, 10)
 	spec := mgl32.Vec4{0.5, 0.5, 0.5, 1}
-	t.matName = "plastic"
+	if t.matName == "" {
+		t.matName = "plastic"
+	}
 	t.material = map[string]mesh.Material{
 		"plastic":     mesh.Plastic().SetColor(glu.Red),
 		"wood":        mesh.Wood(),
@@ -70,7 +78,9 @@ func (t *Shapes) initialise() {
 		"texturecube": mesh.Reflective(spec, 32, texCube),
 		"point":       mesh.PointMaterial().SetColor(glu.Red),
 	}
-	t.shapeName = "cube"
+	if t.shapeName == "" {
+		t.shapeName = "cube"
+	}
 	t.shapes = map[string]scene.Object{
 		"cube":        scene.NewItem(mesh.Cube()),
 		"prism":       scene.NewItem(mesh.Prism()).Scale(1.1, 1.1, 1.1),
@@ -89,7 +99,7 @@ func (t *Shapes) SetShape(name string) {
 	if _, ok := t.shapes[name]; ok {
 		fmt.Println("set shape to", name)
 		t.shapeName = name
-		t.Call("update")
+		t.update()
 	}
 }
 
@@ -97,7 +107,7 @@ func (t *Shapes) SetMaterial(name string) {
 	if _, ok := t.material[name]; ok {
 		fmt.Println("set material to", name)
 		t.matName = name
-		t.Call("update")
+		t.update()
 	}
 }
 
@@ -119,22 +129,53 @@ func (t *Shapes) SetColor(c color.RGBA) {
 	} else {
 		t.material[t.matName].SetColor(col)
 	}
-	t.Call("update")
+	t.update()
 }
 
 func (t *Shapes) SetScenery(on bool) {
 	t.background.Enable(on)
-	t.Call("update")
+	t.update()
 }
 
 func (t *Shapes) Spin() {
 	t.shapes[t.shapeName].RotateY(1)
-	t.Call("update")
+	t.update()
+}
+
+func (t *Shapes) Zoom(delta int) {
+	t.view.Camera.Move(float32(delta))
+	t.update()
+}
+
+// Key selects the next shape or material, for use with the GLFW backend.
+func (t *Shapes) Key(key string) {
+	switch key {
+	case "n":
+		t.SetShape(next(shapeList, t.shapeName))
+	case "m":
+		t.SetMaterial(next(mtlList, t.matName))
+	case "k":
+		t.SetScenery(!t.background.Enabled())
+	case "s":
+		t.spinning = !t.spinning
+	case "escape":
+		t.win.Close()
+	}
+}
+
+func (t *Shapes) Tick() {
+	if t.spinning {
+		t.Spin()
+	}
 }
 
-func (t *Shapes) Zoom(amount float32) {
-	t.view.Camera.Move(amount)
-	t.Call("update")
+func next(list []string, name string) string {
+	for i, s := range list {
+		if s == name {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
 }
 
 func (t *Shapes) Mouse(event string, x, y, button int) {
@@ -150,20 +191,22 @@ func (t *Shapes) Mouse(event string, x, y, button int) {
 				t.view.Lights[0].Rotate(dx, dy)
 			}
 			t.mouse.x, t.mouse.y = x, y
-			t.Call("update")
+			t.update()
 		}
 	case "end":
 		t.mouse.button = 0
 	}
 }
 
-func (t *Shapes) Paint(p *qml.Painter) {
-	gl := GL.API(p)
+func (t *Shapes) Paint(gl glu.Context, width, height int) {
 	glu.Init(gl)
 	if t.shapes == nil {
 		t.initialise()
 	}
-	t.view.SetProjection(t.Int("width"), t.Int("height"))
+	if t.win != nil {
+		t.view.FlipY = t.win.FlipY()
+	}
+	t.view.SetProjection(width, height)
 	glu.Clear(mgl32.Vec4{0.5, 0.5, 1, 1})
 	// set current material
 	t.shapes[t.shapeName].Do(scene.NewTransform(mgl32.Ident4()),
@@ -183,25 +226,3 @@ func (t *Shapes) Paint(p *qml.Painter) {
 	t.view.UpdateLights(view, nil)
 	t.view.Draw(view, t.shapes[t.shapeName])
 }
-
-func run() error {
-	qml.RegisterTypes("GoExtensions", 1, 0, []qml.TypeSpec{{
-		Init: func(t *Shapes, obj qml.Object) { t.Object = obj },
-	}})
-	engine := qml.NewEngine()
-	engine.On("quit", func() { os.Exit(0) })
-	component, err := engine.LoadFile(sceneFile)
-	if err != nil {
-		return err
-	}
-	window := component.CreateWindow(nil)
-	window.Show()
-	window.Wait()
-	return nil
-}
-
-func main() {
-	if err := qml.Run(run); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-	}
-}
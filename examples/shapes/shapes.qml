@@ -59,7 +59,7 @@ ApplicationWindow {
                 acceptedButtons: Qt.LeftButton | Qt.RightButton
                 onPressed: shapes.mouse("start", mouse.x, mouse.y, mouse.button)
                 onPositionChanged: shapes.mouse("move", mouse.x, mouse.y, mouse.button)
-                onReleased: shapes.mouse("end", 0, 0, mouse.button)
+                onReleased: shapes.mouse("end", mouse.x, mouse.y, mouse.button)
             }
         }
     }
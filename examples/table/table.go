@@ -118,16 +118,17 @@ func (t *Scene) initialise(gl *GL.GL) scene.Object {
 func (t *Scene) Animate(step float64) {
 	t.time += step
 	angle := float32(0.33 * math.Pi * math.Cos(10*t.time))
+	swing, rest := mgl32.QuatRotate(angle, mgl32.Vec3{0, 0, 1}), mgl32.QuatIdent()
 	if angle > 0 {
-		t.balls[3].Mat4 = mgl32.HomogRotate3DZ(angle)
-		t.balls[4].Mat4 = mgl32.HomogRotate3DZ(angle)
-		t.balls[0].Mat4 = mgl32.Ident4()
-		t.balls[1].Mat4 = mgl32.Ident4()
+		t.balls[3].SetRotation(swing)
+		t.balls[4].SetRotation(swing)
+		t.balls[0].SetRotation(rest)
+		t.balls[1].SetRotation(rest)
 	} else {
-		t.balls[0].Mat4 = mgl32.HomogRotate3DZ(angle)
-		t.balls[1].Mat4 = mgl32.HomogRotate3DZ(angle)
-		t.balls[3].Mat4 = mgl32.Ident4()
-		t.balls[4].Mat4 = mgl32.Ident4()
+		t.balls[0].SetRotation(swing)
+		t.balls[1].SetRotation(swing)
+		t.balls[3].SetRotation(rest)
+		t.balls[4].SetRotation(rest)
 	}
 	t.Call("update")
 }
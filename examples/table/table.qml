@@ -43,7 +43,7 @@ ApplicationWindow {
             onWheel: scene.zoom(wheel.angleDelta.y)         
             onPressed: scene.mouse("start", mouse.x, mouse.y)
             onPositionChanged: scene.mouse("move", mouse.x, mouse.y)
-            onReleased: scene.mouse("end", 0, 0)
+            onReleased: scene.mouse("end", mouse.x, mouse.y)
         }
     }
 }
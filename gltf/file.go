@@ -0,0 +1,331 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// subset of the glTF 2.0 JSON schema which is used by the loader
+type document struct {
+	Scene       *int            `json:"scene"`
+	Scenes      []sceneData     `json:"scenes"`
+	Nodes       []nodeData      `json:"nodes"`
+	Meshes      []meshData      `json:"meshes"`
+	Skins       []skinData      `json:"skins"`
+	Animations  []animationData `json:"animations"`
+	Materials   []materialData  `json:"materials"`
+	Textures    []textureData   `json:"textures"`
+	Images      []imageData     `json:"images"`
+	Accessors   []accessor      `json:"accessors"`
+	BufferViews []bufferView    `json:"bufferViews"`
+	Buffers     []bufferData    `json:"buffers"`
+}
+
+type sceneData struct {
+	Nodes []int `json:"nodes"`
+}
+
+type nodeData struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Skin        *int      `json:"skin"`
+	Weights     []float32 `json:"weights"`
+	Matrix      []float32 `json:"matrix"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+}
+
+type meshData struct {
+	Name       string      `json:"name"`
+	Primitives []primitive `json:"primitives"`
+	Weights    []float32   `json:"weights"`
+}
+
+type primitive struct {
+	Attributes map[string]int   `json:"attributes"`
+	Targets    []map[string]int `json:"targets"`
+	Indices    *int             `json:"indices"`
+	Material   *int             `json:"material"`
+	Mode       *int             `json:"mode"`
+}
+
+type skinData struct {
+	Joints              []int `json:"joints"`
+	InverseBindMatrices *int  `json:"inverseBindMatrices"`
+}
+
+type animationData struct {
+	Name     string        `json:"name"`
+	Channels []channelData `json:"channels"`
+	Samplers []samplerData `json:"samplers"`
+}
+
+type channelData struct {
+	Sampler int `json:"sampler"`
+	Target  struct {
+		Node *int   `json:"node"`
+		Path string `json:"path"`
+	} `json:"target"`
+}
+
+type samplerData struct {
+	Input         int    `json:"input"`
+	Output        int    `json:"output"`
+	Interpolation string `json:"interpolation"`
+}
+
+type materialData struct {
+	Name string `json:"name"`
+	PBR  *struct {
+		BaseColorFactor  []float32   `json:"baseColorFactor"`
+		BaseColorTexture *textureRef `json:"baseColorTexture"`
+	} `json:"pbrMetallicRoughness"`
+}
+
+type textureRef struct {
+	Index int `json:"index"`
+}
+
+type textureData struct {
+	Source *int `json:"source"`
+}
+
+type imageData struct {
+	URI        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type accessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type bufferData struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+const (
+	glbMagic     = 0x46546c67 // "glTF"
+	glbChunkJSON = 0x4e4f534a
+	glbChunkBin  = 0x004e4942
+)
+
+var componentSize = map[int]int{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}
+
+var typeSize = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16}
+
+// file is a decoded glTF document with its buffers loaded
+type file struct {
+	document
+	dir     string
+	buffers [][]byte
+}
+
+// read a .gltf or .glb file, external buffers are relative to dir
+func readFile(r io.Reader, dir string) (*file, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f := &file{dir: dir}
+	var bin []byte
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if data, bin, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = json.Unmarshal(data, &f.document); err != nil {
+		return nil, err
+	}
+	for i, buf := range f.Buffers {
+		var b []byte
+		switch {
+		case buf.URI == "":
+			if i != 0 || bin == nil {
+				return nil, fmt.Errorf("buffer %d has no data", i)
+			}
+			b = bin
+		default:
+			if b, err = f.readURI(buf.URI); err != nil {
+				return nil, err
+			}
+		}
+		if len(b) < buf.ByteLength {
+			return nil, fmt.Errorf("buffer %d is too short: %d bytes, expecting %d", i, len(b), buf.ByteLength)
+		}
+		f.buffers = append(f.buffers, b)
+	}
+	return f, nil
+}
+
+// split a binary glTF file into the JSON and binary chunks
+func splitGLB(data []byte) (js, bin []byte, err error) {
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("unsupported glb version %d", version)
+	}
+	if length := int(binary.LittleEndian.Uint32(data[8:])); length < len(data) {
+		data = data[:length]
+	}
+	for pos := 12; pos+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[pos:]))
+		typ := binary.LittleEndian.Uint32(data[pos+4:])
+		pos += 8
+		if pos+size > len(data) {
+			return nil, nil, fmt.Errorf("glb chunk is truncated")
+		}
+		switch typ {
+		case glbChunkJSON:
+			js = data[pos : pos+size]
+		case glbChunkBin:
+			bin = data[pos : pos+size]
+		}
+		pos += size
+	}
+	if js == nil {
+		return nil, nil, fmt.Errorf("glb file has no JSON chunk")
+	}
+	return js, bin, nil
+}
+
+// data URIs are decoded, other URIs are paths relative to the glTF file
+func (f *file) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.Index(uri, ",")
+		if i < 0 || !strings.HasSuffix(uri[:i], ";base64") {
+			return nil, fmt.Errorf("unsupported data URI %.40s", uri)
+		}
+		return base64.StdEncoding.DecodeString(uri[i+1:])
+	}
+	name, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(f.dir, filepath.FromSlash(name)))
+}
+
+// image data for a texture, either from a URI or a buffer view
+func (f *file) image(index int) (io.Reader, error) {
+	if index < 0 || index >= len(f.Textures) || f.Textures[index].Source == nil {
+		return nil, fmt.Errorf("texture %d has no image", index)
+	}
+	src := *f.Textures[index].Source
+	if src < 0 || src >= len(f.Images) {
+		return nil, fmt.Errorf("image %d not found", src)
+	}
+	img := f.Images[src]
+	if img.BufferView != nil {
+		data, _, err := f.view(*img.BufferView)
+		return bytes.NewReader(data), err
+	}
+	data, err := f.readURI(img.URI)
+	return bytes.NewReader(data), err
+}
+
+// bytes for a buffer view and the stride if it is interleaved
+func (f *file) view(index int) ([]byte, int, error) {
+	if index < 0 || index >= len(f.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d not found", index)
+	}
+	v := f.BufferViews[index]
+	if v.Buffer < 0 || v.Buffer >= len(f.buffers) {
+		return nil, 0, fmt.Errorf("buffer %d not found", v.Buffer)
+	}
+	buf := f.buffers[v.Buffer]
+	if v.ByteOffset+v.ByteLength > len(buf) {
+		return nil, 0, fmt.Errorf("buffer view %d is outside buffer %d", index, v.Buffer)
+	}
+	return buf[v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+// read an accessor as a list of float values, with n values per element. Integer values are converted to
+// floats, and scaled to the 0 to 1 or -1 to 1 range if the accessor is normalized.
+func (f *file) read(index int) (values []float32, n int, err error) {
+	if index < 0 || index >= len(f.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d not found", index)
+	}
+	a := f.Accessors[index]
+	size, n := componentSize[a.ComponentType], typeSize[a.Type]
+	if size == 0 || n == 0 {
+		return nil, 0, fmt.Errorf("accessor %d has unsupported type %s %d", index, a.Type, a.ComponentType)
+	}
+	if len(a.Sparse) > 0 {
+		return nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	values = make([]float32, a.Count*n)
+	if a.BufferView == nil {
+		// all zeros
+		return values, n, nil
+	}
+	data, stride, err := f.view(*a.BufferView)
+	if err != nil {
+		return nil, 0, err
+	}
+	if stride == 0 {
+		stride = size * n
+	}
+	if a.Count > 0 && a.ByteOffset+(a.Count-1)*stride+size*n > len(data) {
+		return nil, 0, fmt.Errorf("accessor %d is outside buffer view %d", index, *a.BufferView)
+	}
+	for i := 0; i < a.Count; i++ {
+		for j := 0; j < n; j++ {
+			values[i*n+j] = component(data[a.ByteOffset+i*stride+j*size:], a.ComponentType, a.Normalized)
+		}
+	}
+	return values, n, nil
+}
+
+func component(b []byte, typ int, normalized bool) float32 {
+	var v, scale float32
+	switch typ {
+	case 5120:
+		v, scale = float32(int8(b[0])), 127
+	case 5121:
+		v, scale = float32(b[0]), 255
+	case 5122:
+		v, scale = float32(int16(binary.LittleEndian.Uint16(b))), 32767
+	case 5123:
+		v, scale = float32(binary.LittleEndian.Uint16(b)), 65535
+	case 5125:
+		return float32(binary.LittleEndian.Uint32(b))
+	case 5126:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	if normalized {
+		return float32(math.Max(float64(v/scale), -1))
+	}
+	return v
+}
+
+func open(name string) (*file, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readFile(r, filepath.Dir(name))
+}
@@ -0,0 +1,511 @@
+// Package gltf loads models from glTF 2.0 files, either .gltf JSON files with embedded or external buffers
+// or binary .glb files. Each node becomes a named scene group and each triangle primitive becomes an item
+// with a diffuse material using the base color and texture. Skins become skeletons whose joints are the
+// node groups, morph targets are added to the meshes, and animations become clips with tracks on the node
+// paths, so a skinned model can be played with animation.NewPlayer(clips[0], root, animation.Loop). The GL context must be initialised first as
+// textures are loaded with the model.
+package gltf
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/animation"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/img"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const modeTriangles = 4
+
+type loader struct {
+	*file
+	nodes     []*scene.Group
+	items     [][]*scene.Item // items for the mesh on each node
+	materials map[int]mesh.Material
+	names     map[string]bool
+}
+
+// LoadFile loads a .gltf or .glb file. It returns an unnamed group containing the nodes of the default
+// scene, and a clip for each animation.
+func LoadFile(name string) (*scene.Group, []*animation.Clip, error) {
+	f, err := open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gltf: %s: %s", name, err)
+	}
+	root, clips, err := load(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gltf: %s: %s", name, err)
+	}
+	return root, clips, nil
+}
+
+// Load reads a glTF model from r, external files are relative to dir
+func Load(r io.Reader, dir string) (*scene.Group, []*animation.Clip, error) {
+	f, err := readFile(r, dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gltf: %s", err)
+	}
+	root, clips, err := load(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gltf: %s", err)
+	}
+	return root, clips, nil
+}
+
+func load(f *file) (*scene.Group, []*animation.Clip, error) {
+	l := &loader{file: f, materials: map[int]mesh.Material{}, names: map[string]bool{}}
+	l.nodes = make([]*scene.Group, len(f.Nodes))
+	l.items = make([][]*scene.Item, len(f.Nodes))
+	for i, node := range f.Nodes {
+		grp, err := l.node(i, node)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.nodes[i] = grp
+	}
+	for i, node := range f.Nodes {
+		for _, child := range node.Children {
+			if child < 0 || child >= len(l.nodes) || l.nodes[child].Parent() != nil || l.isAncestor(child, i) {
+				return nil, nil, fmt.Errorf("node %d has invalid child %d", i, child)
+			}
+			l.nodes[i].Add(l.nodes[child])
+		}
+	}
+	root := scene.NewGroup()
+	for _, i := range l.roots() {
+		if i < 0 || i >= len(l.nodes) {
+			return nil, nil, fmt.Errorf("scene has invalid node %d", i)
+		}
+		root.Add(l.nodes[i])
+	}
+	for i, node := range f.Nodes {
+		if node.Skin == nil {
+			continue
+		}
+		skel, err := l.skeleton(*node.Skin)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range l.items[i] {
+			if item.Mesh.Skinned() {
+				item.Skeleton = skel
+			}
+		}
+	}
+	clips := []*animation.Clip{}
+	for i, anim := range f.Animations {
+		clip, err := l.animation(i, anim)
+		if err != nil {
+			return nil, nil, err
+		}
+		clips = append(clips, clip)
+	}
+	return root, clips, nil
+}
+
+// check if node a is node b or one of its parents, so adding it as a child of b would make a cycle
+func (l *loader) isAncestor(a, b int) bool {
+	for g := l.nodes[b]; g != nil; g = g.Parent() {
+		if g == l.nodes[a] {
+			return true
+		}
+	}
+	return false
+}
+
+// nodes in the default scene, or all of the nodes without a parent if there are no scenes
+func (l *loader) roots() []int {
+	if len(l.Scenes) > 0 {
+		scn := 0
+		if l.Scene != nil && *l.Scene >= 0 && *l.Scene < len(l.Scenes) {
+			scn = *l.Scene
+		}
+		return l.Scenes[scn].Nodes
+	}
+	roots := []int{}
+	for i, grp := range l.nodes {
+		if grp.Parent() == nil {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// create a group for the node with its transform and mesh
+func (l *loader) node(index int, node nodeData) (*scene.Group, error) {
+	grp := scene.NewGroup()
+	grp.SetName(l.uniqueName(node.Name, "node"+strconv.Itoa(index)))
+	switch {
+	case len(node.Matrix) == 16:
+		var m mgl32.Mat4
+		copy(m[:], node.Matrix)
+		grp.Transform = scene.NewTransform(m)
+	default:
+		if len(node.Translation) == 3 {
+			grp.SetPosition(mgl32.Vec3{node.Translation[0], node.Translation[1], node.Translation[2]})
+		}
+		if len(node.Rotation) == 4 {
+			r := node.Rotation
+			grp.SetRotation(mgl32.Quat{W: r[3], V: mgl32.Vec3{r[0], r[1], r[2]}}.Normalize())
+		}
+		if len(node.Scale) == 3 {
+			grp.SetScale(mgl32.Vec3{node.Scale[0], node.Scale[1], node.Scale[2]})
+		}
+	}
+	if node.Mesh == nil {
+		return grp, nil
+	}
+	if *node.Mesh < 0 || *node.Mesh >= len(l.Meshes) {
+		return nil, fmt.Errorf("node %d has invalid mesh %d", index, *node.Mesh)
+	}
+	md := l.Meshes[*node.Mesh]
+	weights := md.Weights
+	if node.Weights != nil {
+		weights = node.Weights
+	}
+	for i, prim := range md.Primitives {
+		if prim.Mode != nil && *prim.Mode != modeTriangles {
+			fmt.Printf("gltf: skip mesh %d primitive %d with mode %d\n", *node.Mesh, i, *prim.Mode)
+			continue
+		}
+		msh, err := l.mesh(prim, node.Skin != nil)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %d primitive %d: %s", *node.Mesh, i, err)
+		}
+		name := md.Name
+		if name == "" {
+			name = "mesh" + strconv.Itoa(*node.Mesh)
+		}
+		if len(md.Primitives) > 1 {
+			name += "_" + strconv.Itoa(i)
+		}
+		for target, w := range weights {
+			msh.SetMorphWeight(target, w)
+		}
+		item := scene.NewItem(msh)
+		item.SetName(l.uniqueName(name, name))
+		grp.Add(item)
+		l.items[index] = append(l.items[index], item)
+	}
+	return grp, nil
+}
+
+// names are made unique so tracks can find the nodes by path
+func (l *loader) uniqueName(name, def string) string {
+	name = strings.Replace(name, "/", "_", -1)
+	if name == "" {
+		name = def
+	}
+	unique := name
+	for i := 2; l.names[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	l.names[unique] = true
+	return unique
+}
+
+// build a mesh from the vertex attributes of a primitive
+func (l *loader) mesh(prim primitive, skinned bool) (*mesh.Mesh, error) {
+	posIndex, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return nil, fmt.Errorf("no POSITION attribute")
+	}
+	pos, _, err := l.read(posIndex)
+	if err != nil {
+		return nil, err
+	}
+	count := len(pos) / 3
+	attr := func(name string, size int) ([]float32, error) {
+		index, ok := prim.Attributes[name]
+		if !ok {
+			return nil, nil
+		}
+		values, n, err := l.read(index)
+		if err == nil && (n != size || len(values) != count*size) {
+			err = fmt.Errorf("%s attribute should have %d values per vertex", name, size)
+		}
+		return values, err
+	}
+	var normals, texcoords, texcoords2, colors, joints, weights []float32
+	if normals, err = attr("NORMAL", 3); err != nil {
+		return nil, err
+	}
+	if texcoords, err = attr("TEXCOORD_0", 2); err != nil {
+		return nil, err
+	}
+	if texcoords2, err = attr("TEXCOORD_1", 2); err != nil {
+		return nil, err
+	}
+	// vertex colors are RGB or RGBA
+	csize := 4
+	if colors, err = attr("COLOR_0", csize); err != nil {
+		csize = 3
+		if colors, err = attr("COLOR_0", csize); err != nil {
+			return nil, err
+		}
+	}
+	if skinned {
+		if joints, err = attr("JOINTS_0", 4); err != nil {
+			return nil, err
+		}
+		if weights, err = attr("WEIGHTS_0", 4); err != nil {
+			return nil, err
+		}
+	}
+	m := mesh.New()
+	for i := 0; i < count; i++ {
+		m.AddVertex(pos[3*i], pos[3*i+1], pos[3*i+2])
+		if normals != nil {
+			m.AddNormal(normals[3*i], normals[3*i+1], normals[3*i+2])
+		}
+		if texcoords != nil {
+			// glTF texture coordinates start at the top left of the image, as for the meshes
+			m.AddTexCoord(texcoords[2*i], texcoords[2*i+1])
+		}
+		if texcoords2 != nil {
+			m.AddTexCoord2(texcoords2[2*i], texcoords2[2*i+1])
+		}
+		if colors != nil {
+			c := [4]float32{1, 1, 1, 1}
+			copy(c[:], colors[csize*i:csize*(i+1)])
+			m.AddColor(c[0], c[1], c[2], c[3])
+		}
+		if joints != nil && weights != nil {
+			var j [4]int
+			var w [4]float32
+			for k := range j {
+				j[k], w[k] = int(joints[4*i+k]), weights[4*i+k]
+			}
+			if w[0]+w[1]+w[2]+w[3] <= 0 {
+				return nil, fmt.Errorf("vertex %d has no joint weights", i)
+			}
+			m.AddWeights(j, w)
+		}
+	}
+	for i, target := range prim.Targets {
+		var offsets [2][]mgl32.Vec3
+		for j, name := range []string{"POSITION", "NORMAL"} {
+			index, ok := target[name]
+			if !ok {
+				continue
+			}
+			values, n, err := l.read(index)
+			if err == nil && (n != 3 || len(values) != count*3) {
+				err = fmt.Errorf("%s attribute should have 3 values per vertex", name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("morph target %d: %s", i, err)
+			}
+			offsets[j] = make([]mgl32.Vec3, count)
+			for k := range offsets[j] {
+				offsets[j][k] = mgl32.Vec3{values[3*k], values[3*k+1], values[3*k+2]}
+			}
+		}
+		m.AddTarget(offsets[0], offsets[1])
+	}
+	var indices []float32
+	if prim.Indices != nil {
+		if indices, _, err = l.read(*prim.Indices); err != nil {
+			return nil, err
+		}
+	} else {
+		indices = make([]float32, count)
+		for i := range indices {
+			indices[i] = float32(i)
+		}
+	}
+	el := func(i float32) mesh.El {
+		e := mesh.El{Vert: int(i) + 1}
+		if texcoords != nil {
+			e.Tex = e.Vert
+		}
+		if normals != nil {
+			e.Norm = e.Vert
+		}
+		return e
+	}
+	for i := 0; i+2 < len(indices); i += 3 {
+		for _, index := range indices[i : i+3] {
+			if int(index) >= count {
+				return nil, fmt.Errorf("vertex index %d out of range", int(index))
+			}
+		}
+		m.AddFace(el(indices[i]), el(indices[i+1]), el(indices[i+2]))
+	}
+	m.Build("")
+	mtl, err := l.material(prim.Material)
+	if err != nil {
+		return nil, err
+	}
+	if m.Format().Has(mesh.Colors) {
+		// COLOR_0 multiplies the base color
+		mtl = mesh.VertexColors(mtl)
+	}
+	m.SetMaterial(mtl)
+	return m, nil
+}
+
+// diffuse material with the base color and texture, each item gets its own copy
+func (l *loader) material(index *int) (mesh.Material, error) {
+	if index == nil {
+		return mesh.Diffuse(), nil
+	}
+	if mtl, ok := l.materials[*index]; ok {
+		return mtl.Clone(), nil
+	}
+	if *index < 0 || *index >= len(l.Materials) {
+		return nil, fmt.Errorf("material %d not found", *index)
+	}
+	md := l.Materials[*index]
+	mtl := mesh.Diffuse()
+	if pbr := md.PBR; pbr != nil {
+		if pbr.BaseColorTexture != nil {
+			r, err := l.image(pbr.BaseColorTexture.Index)
+			if err != nil {
+				return nil, err
+			}
+			tex, err := glu.NewTexture2D(false).SetImage(r, img.SRGBToLinear)
+			if err != nil {
+				return nil, fmt.Errorf("material %d: error loading texture: %s", *index, err)
+			}
+			mtl = mesh.Diffuse(tex)
+		}
+		if c := pbr.BaseColorFactor; len(c) == 4 {
+			mtl.SetColor(mgl32.Vec4{c[0], c[1], c[2], c[3]})
+		}
+	}
+	l.materials[*index] = mtl
+	return mtl.Clone(), nil
+}
+
+// skeleton with the node groups as joints
+func (l *loader) skeleton(index int) (*scene.Skeleton, error) {
+	if index < 0 || index >= len(l.Skins) {
+		return nil, fmt.Errorf("skin %d not found", index)
+	}
+	skin := l.Skins[index]
+	joints := make([]scene.Object, len(skin.Joints))
+	inverseBind := make([]mgl32.Mat4, len(skin.Joints))
+	for i, node := range skin.Joints {
+		if node < 0 || node >= len(l.nodes) {
+			return nil, fmt.Errorf("skin %d has invalid joint %d", index, node)
+		}
+		joints[i] = l.nodes[node]
+		inverseBind[i] = mgl32.Ident4()
+	}
+	if skin.InverseBindMatrices != nil {
+		mats, n, err := l.read(*skin.InverseBindMatrices)
+		if err != nil {
+			return nil, err
+		}
+		if n != 16 || len(mats) < 16*len(joints) {
+			return nil, fmt.Errorf("skin %d: expecting a MAT4 for each joint", index)
+		}
+		for i := range inverseBind {
+			copy(inverseBind[i][:], mats[16*i:])
+		}
+	}
+	return scene.NewSkeleton(joints, inverseBind), nil
+}
+
+// convert the channels to tracks, morph target weights have a track for each target
+func (l *loader) animation(index int, anim animationData) (*animation.Clip, error) {
+	name := anim.Name
+	if name == "" {
+		name = "animation" + strconv.Itoa(index)
+	}
+	clip := animation.NewClip(name)
+	for _, ch := range anim.Channels {
+		var prop animation.Property
+		switch ch.Target.Path {
+		case "translation":
+			prop = animation.Position
+		case "rotation":
+			prop = animation.Rotation
+		case "scale":
+			prop = animation.Scale
+		case "weights":
+			prop = animation.Weight
+		default:
+			continue
+		}
+		node := ch.Target.Node
+		if node == nil || *node < 0 || *node >= len(l.nodes) {
+			continue
+		}
+		if ch.Sampler < 0 || ch.Sampler >= len(anim.Samplers) {
+			return nil, fmt.Errorf("animation %d has invalid sampler %d", index, ch.Sampler)
+		}
+		targets := 1
+		if prop == animation.Weight {
+			if targets = l.targets(*node); targets == 0 {
+				continue
+			}
+		}
+		for target := 0; target < targets; target++ {
+			track, err := l.track(anim.Samplers[ch.Sampler], prop, target, targets)
+			if err != nil {
+				return nil, fmt.Errorf("animation %d: %s", index, err)
+			}
+			clip.Add(track.On(l.nodes[*node].Path()).Morph(target))
+		}
+	}
+	return clip, nil
+}
+
+// number of morph targets for the mesh on a node
+func (l *loader) targets(node int) int {
+	if m := l.Nodes[node].Mesh; m != nil && *m >= 0 && *m < len(l.Meshes) {
+		if prims := l.Meshes[*m].Primitives; len(prims) > 0 {
+			return len(prims[0].Targets)
+		}
+	}
+	return 0
+}
+
+// cubic spline keys have in and out tangents either side of each value, only the values are used and the
+// curve is approximated by the track's own cubic interpolation. Weight samplers have a value for each of
+// the morph targets at each key, and the track is for one of them.
+func (l *loader) track(s samplerData, prop animation.Property, target, targets int) (*animation.Track, error) {
+	times, _, err := l.read(s.Input)
+	if err != nil {
+		return nil, err
+	}
+	values, n, err := l.read(s.Output)
+	if err != nil {
+		return nil, err
+	}
+	first, size := 0, n
+	if prop == animation.Weight {
+		first, n = target, targets
+	}
+	interp, stride, offset := animation.Linear, n, 0
+	switch s.Interpolation {
+	case "STEP":
+		interp = animation.Step
+	case "CUBICSPLINE":
+		interp, stride, offset = animation.Cubic, 3*n, n
+	default:
+		if prop == animation.Rotation {
+			interp = animation.Slerp
+		}
+	}
+	if len(values) < len(times)*stride {
+		return nil, fmt.Errorf("sampler has %d values for %d keys", len(values)/n, len(times))
+	}
+	track := animation.NewTrack(prop, interp)
+	for i, t := range times {
+		if i > 0 && t < times[i-1] {
+			return nil, fmt.Errorf("sampler keys are not in time order")
+		}
+		var v mgl32.Vec4
+		start := i*stride + offset + first
+		copy(v[:], values[start:start+size])
+		track.Add(t, v)
+	}
+	return track, nil
+}
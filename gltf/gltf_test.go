@@ -0,0 +1,54 @@
+package gltf
+
+import (
+	"github.com/jnb666/go3d/scene"
+	"strings"
+	"testing"
+)
+
+func TestNodeGraph(t *testing.T) {
+	r := strings.NewReader(`{"nodes":[{"name":"body","children":[1,2]},{"name":"arm","children":[3]},{"name":"leg"},{"name":"hand"}]}`)
+	root, _, err := Load(r, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(root.Objects()); n != 1 {
+		t.Fatalf("expecting 1 root node, got %d", n)
+	}
+	for _, path := range []string{"body", "body/arm", "body/leg", "body/arm/hand"} {
+		if root.Find(path) == nil {
+			t.Errorf("node %s not found", path)
+		}
+	}
+}
+
+func TestBadNodeGraph(t *testing.T) {
+	tests := []struct {
+		name, json, err string
+	}{
+		{"self", `{"nodes":[{"children":[0]}]}`, "node 0 has invalid child 0"},
+		{"cycle", `{"nodes":[{"children":[1]},{"children":[0]}]}`, "node 1 has invalid child 0"},
+		{"long cycle", `{"nodes":[{"children":[1]},{"children":[2]},{"children":[0]}]}`, "node 2 has invalid child 0"},
+		{"two parents", `{"nodes":[{"children":[2]},{"children":[2]},{}]}`, "node 1 has invalid child 2"},
+		{"out of range", `{"nodes":[{"children":[1]}]}`, "node 0 has invalid child 1"},
+		{"negative", `{"nodes":[{"children":[-1]}]}`, "node 0 has invalid child -1"},
+		{"scene node", `{"scenes":[{"nodes":[3]}],"nodes":[{}]}`, "scene has invalid node 3"},
+	}
+	for _, test := range tests {
+		var root *scene.Group
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: panic %v", test.name, r)
+				}
+			}()
+			root, _, err = Load(strings.NewReader(test.json), "")
+		}()
+		if err == nil || root != nil {
+			t.Errorf("%s: expecting an error", test.name)
+		} else if err.Error() != "gltf: "+test.err {
+			t.Errorf("%s: error is %q, expecting %q", test.name, err, test.err)
+		}
+	}
+}
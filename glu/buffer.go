@@ -2,15 +2,16 @@ package glu
 
 import (
 	"fmt"
-	"gopkg.in/qml.v1/gl/es2"
 	"gopkg.in/qml.v1/gl/glbase"
 	"runtime"
+	"sync"
 )
 
 const chunkSize = 4096
 
 // an array of buffer data
 type VertexArray struct {
+	objectLabel
 	buffer glbase.Buffer
 	btype  glbase.Enum
 	size   int
@@ -18,58 +19,108 @@ type VertexArray struct {
 
 // ArrayBuffer creates a new empty Vertex array with associated data. Size is the numer of size of each vertex in words.
 func ArrayBuffer(data []float32, vertexSize int) *VertexArray {
+	return newArrayBuffer(data, vertexSize, STATIC_DRAW)
+}
+
+// DynamicArrayBuffer creates a vertex array for data which is changed often with Update.
+func DynamicArrayBuffer(data []float32, vertexSize int) *VertexArray {
+	return newArrayBuffer(data, vertexSize, DYNAMIC_DRAW)
+}
+
+func newArrayBuffer(data []float32, vertexSize int, usage glbase.Enum) *VertexArray {
 	buf := gl.GenBuffers(1)
-	a := &VertexArray{buffer: buf[0], btype: GL.ARRAY_BUFFER, size: len(data) / vertexSize}
-	gl.BindBuffer(a.btype, buf[0])
-	gl.BufferData(a.btype, len(data)*4, nil, GL.STATIC_DRAW)
+	a := &VertexArray{buffer: buf[0], btype: ARRAY_BUFFER, size: len(data) / vertexSize}
+	bindBuffer(a.btype, buf[0])
+	gl.BufferData(a.btype, len(data)*4, nil, usage)
+	a.write(data)
+	checkError("ArrayBuffer", "buffer", uint32(a.buffer), nil)
+	runtime.SetFinalizer(a, deleteArray)
+	return a
+}
+
+// Update replaces the data in an array buffer, which should be the same size as before. The buffer is
+// left bound.
+func (a *VertexArray) Update(data []float32) {
+	bindBuffer(a.btype, a.buffer)
+	a.write(data)
+	if Debug {
+		checkError("Update", "buffer", uint32(a.buffer), &a.objectLabel)
+	}
+}
+
+func (a *VertexArray) write(data []float32) {
 	for start := 0; start < len(data); start += chunkSize {
 		end := min(start+chunkSize, len(data))
 		gl.BufferSubData(a.btype, start*4, (end-start)*4, data[start:end])
 	}
-	CheckError()
-	runtime.SetFinalizer(a, deleteArray)
-	return a
 }
 
 // ElementArrayBuffer creates a new empty Vertex array with associated data.
 func ElementArrayBuffer(data []uint32) *VertexArray {
 	buf := gl.GenBuffers(1)
-	a := &VertexArray{buffer: buf[0], btype: GL.ELEMENT_ARRAY_BUFFER, size: len(data)}
-	gl.BindBuffer(a.btype, buf[0])
-	gl.BufferData(a.btype, len(data)*4, nil, GL.STATIC_DRAW)
+	a := &VertexArray{buffer: buf[0], btype: ELEMENT_ARRAY_BUFFER, size: len(data)}
+	bindBuffer(a.btype, buf[0])
+	gl.BufferData(a.btype, len(data)*4, nil, STATIC_DRAW)
 	for start := 0; start < len(data); start += chunkSize {
 		end := min(start+chunkSize, len(data))
 		gl.BufferSubData(a.btype, start*4, (end-start)*4, data[start:end])
 	}
-	CheckError()
+	checkError("ElementArrayBuffer", "buffer", uint32(a.buffer), nil)
 	runtime.SetFinalizer(a, deleteArray)
 	return a
 }
 
+// SetLabel sets a debug label which is included in error messages
+func (a *VertexArray) SetLabel(label string) {
+	a.setLabel(khrBuffer, uint32(a.buffer), label)
+}
+
 // Make buffer current
 func (a *VertexArray) Enable() {
-	gl.BindBuffer(a.btype, a.buffer)
+	bindBuffer(a.btype, a.buffer)
 }
 
 // Draw all of the elements in the array buffer or element array
 func (a *VertexArray) Draw(mode glbase.Enum, winding glbase.Enum) {
-	gl.FrontFace(winding)
-	if a.btype == GL.ELEMENT_ARRAY_BUFFER {
-		gl.DrawElements(mode, a.size, GL.UNSIGNED_INT, nil)
+	FrontFace(winding)
+	if a.btype == ELEMENT_ARRAY_BUFFER {
+		gl.DrawElements(mode, a.size, UNSIGNED_INT, nil)
 	} else {
 		gl.DrawArrays(mode, 0, a.size)
 	}
 	if Debug {
-		CheckError()
+		checkError("Draw", "buffer", uint32(a.buffer), &a.objectLabel)
 	}
 }
 
+// finalizers run in their own goroutine, so buffers are queued to be deleted by the next call to Clear
+// on the thread which owns the GL context.
+var deleted struct {
+	sync.Mutex
+	buffers []glbase.Buffer
+}
+
 func deleteArray(a *VertexArray) {
 	fmt.Println("finalizer called for VertexArray")
-	gl.DeleteBuffers([]glbase.Buffer{a.buffer})
+	deleted.Lock()
+	deleted.buffers = append(deleted.buffers, a.buffer)
+	deleted.Unlock()
 	a.buffer = 0
 }
 
+func deletePending() {
+	deleted.Lock()
+	buffers := deleted.buffers
+	deleted.buffers = nil
+	deleted.Unlock()
+	if len(buffers) > 0 {
+		gl.DeleteBuffers(buffers)
+		for _, buf := range buffers {
+			state.forget(sBuffer, uint64(buf))
+		}
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
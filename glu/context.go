@@ -0,0 +1,62 @@
+package glu
+
+import (
+	"gopkg.in/qml.v1/gl/glbase"
+)
+
+// Context interface has the set of OpenGL ES2 functions used by this package. It is implemented by the
+// *GL.GL type from gopkg.in/qml.v1/gl/es2, or by the recording fake in the gltest package for testing.
+type Context interface {
+	ActiveTexture(texture glbase.Enum)
+	AttachShader(program glbase.Program, shader glbase.Shader)
+	BindBuffer(target glbase.Enum, buffer glbase.Buffer)
+	BindTexture(target glbase.Enum, texture glbase.Texture)
+	BlendFunc(sfactor, dfactor glbase.Enum)
+	BufferData(target glbase.Enum, size int, data interface{}, usage glbase.Enum)
+	BufferSubData(target glbase.Enum, offset, size int, data interface{})
+	Clear(mask glbase.Bitfield)
+	ClearColor(red, green, blue, alpha glbase.Clampf)
+	CompileShader(shader glbase.Shader)
+	CreateProgram() glbase.Program
+	CreateShader(gltype glbase.Enum) glbase.Shader
+	DeleteBuffers(buffers []glbase.Buffer)
+	DeleteShader(shader glbase.Shader)
+	DepthFunc(glfunc glbase.Enum)
+	DepthMask(flag bool)
+	Disable(cap glbase.Enum)
+	DisableVertexAttribArray(index glbase.Attrib)
+	DrawArrays(mode glbase.Enum, first, count int)
+	DrawElements(mode glbase.Enum, count int, gltype glbase.Enum, indices interface{})
+	Enable(cap glbase.Enum)
+	EnableVertexAttribArray(index glbase.Attrib)
+	FrontFace(mode glbase.Enum)
+	GenBuffers(n int) []glbase.Buffer
+	GenTextures(n int) []glbase.Texture
+	GenerateMipmap(target glbase.Enum)
+	GetAttribLocation(program glbase.Program, name string) glbase.Attrib
+	GetError() glbase.Enum
+	GetProgramInfoLog(program glbase.Program) []byte
+	GetProgramiv(program glbase.Program, pname glbase.Enum, params []int32)
+	GetShaderInfoLog(shader glbase.Shader) []byte
+	GetShaderiv(shader glbase.Shader, pname glbase.Enum, params []int32)
+	GetUniformLocation(program glbase.Program, name string) glbase.Uniform
+	LinkProgram(program glbase.Program)
+	ReadPixels(x, y, width, height int, format, gltype glbase.Enum, pixels interface{})
+	ShaderSource(shader glbase.Shader, source ...string)
+	StencilFunc(glfunc glbase.Enum, ref int32, mask uint32)
+	StencilMask(mask uint32)
+	StencilOp(fail, zfail, zpass glbase.Enum)
+	TexImage2D(target glbase.Enum, level int, internalFormat int32, width, height, border int, format, gltype glbase.Enum, pixels interface{})
+	TexParameteri(target, pname glbase.Enum, param int32)
+	Uniform1f(location glbase.Uniform, v0 float32)
+	Uniform1i(location glbase.Uniform, v0 int32)
+	Uniform2f(location glbase.Uniform, v0, v1 float32)
+	Uniform2i(location glbase.Uniform, v0, v1 int32)
+	Uniform3fv(location glbase.Uniform, value []float32)
+	Uniform4fv(location glbase.Uniform, value []float32)
+	UniformMatrix3fv(location glbase.Uniform, transpose bool, value []float32)
+	UniformMatrix4fv(location glbase.Uniform, transpose bool, value []float32)
+	UseProgram(program glbase.Program)
+	VertexAttribPointer(index glbase.Attrib, size int, gltype glbase.Enum, normalized bool, stride int, offset uintptr)
+	Viewport(x, y, width, height int)
+}
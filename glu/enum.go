@@ -0,0 +1,72 @@
+package glu
+
+// OpenGL ES2 constants used by the go3d packages. These have the same names as in the es2 package
+// so that code does not need to import it just for the constants.
+const (
+	DEPTH_BUFFER_BIT   = 0x00000100
+	STENCIL_BUFFER_BIT = 0x00000400
+	COLOR_BUFFER_BIT   = 0x00004000
+
+	POINTS    = 0x0000
+	LINES     = 0x0001
+	TRIANGLES = 0x0004
+
+	NO_ERROR = 0
+
+	CW  = 0x0900
+	CCW = 0x0901
+
+	CULL_FACE    = 0x0B44
+	DEPTH_TEST   = 0x0B71
+	STENCIL_TEST = 0x0B90
+	BLEND        = 0x0BE2
+
+	NEVER    = 0x0200
+	LESS     = 0x0201
+	EQUAL    = 0x0202
+	LEQUAL   = 0x0203
+	GREATER  = 0x0204
+	NOTEQUAL = 0x0205
+	GEQUAL   = 0x0206
+	ALWAYS   = 0x0207
+
+	ZERO                = 0
+	ONE                 = 1
+	SRC_ALPHA           = 0x0302
+	ONE_MINUS_SRC_ALPHA = 0x0303
+
+	KEEP    = 0x1E00
+	REPLACE = 0x1E01
+
+	UNSIGNED_BYTE  = 0x1401
+	UNSIGNED_SHORT = 0x1403
+	UNSIGNED_INT   = 0x1405
+	FLOAT          = 0x1406
+
+	RGB  = 0x1907
+	RGBA = 0x1908
+
+	ARRAY_BUFFER         = 0x8892
+	ELEMENT_ARRAY_BUFFER = 0x8893
+	STATIC_DRAW          = 0x88E4
+	DYNAMIC_DRAW         = 0x88E8
+
+	FRAGMENT_SHADER = 0x8B30
+	VERTEX_SHADER   = 0x8B31
+	COMPILE_STATUS  = 0x8B81
+	LINK_STATUS     = 0x8B82
+
+	TEXTURE_2D                  = 0x0DE1
+	TEXTURE_CUBE_MAP            = 0x8513
+	TEXTURE_CUBE_MAP_POSITIVE_X = 0x8515
+	TEXTURE0                    = 0x84C0
+	TEXTURE_MAG_FILTER          = 0x2800
+	TEXTURE_MIN_FILTER          = 0x2801
+	TEXTURE_WRAP_S              = 0x2802
+	TEXTURE_WRAP_T              = 0x2803
+	NEAREST                     = 0x2600
+	LINEAR                      = 0x2601
+	LINEAR_MIPMAP_LINEAR        = 0x2703
+	REPEAT                      = 0x2901
+	CLAMP_TO_EDGE               = 0x812F
+)
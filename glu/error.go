@@ -0,0 +1,129 @@
+package glu
+
+import (
+	"fmt"
+	"gopkg.in/qml.v1/gl/glbase"
+)
+
+// If PanicOnError is set then GL errors cause a panic, else the first error is saved and can be retrieved by calling Err.
+var PanicOnError = true
+
+// GL error codes
+var glErrorText = map[glbase.Enum]string{
+	0x500: "invalid enum",
+	0x501: "invalid value",
+	0x502: "invalid operation",
+	0x503: "stack overflow",
+	0x504: "stack underflow",
+	0x505: "out of memory",
+	0x506: "invalid framebuffer operation",
+}
+
+// Object identifiers used by the KHR_debug extension
+const (
+	khrBuffer  glbase.Enum = 0x82E0
+	khrProgram glbase.Enum = 0x82E2
+	khrTexture glbase.Enum = 0x1702
+)
+
+// Error type is used to report a GL error along with the operation and the object which was being used.
+type Error struct {
+	Op    string      // glu function which was called
+	Kind  string      // type of object: program, buffer or texture, blank if not known
+	ID    uint32      // GL object name
+	Label string      // debug label set using SetLabel
+	Code  glbase.Enum // GL error code
+}
+
+func (e *Error) Error() string {
+	text, ok := glErrorText[e.Code]
+	if !ok {
+		text = fmt.Sprintf("unknown error code %x", uint32(e.Code))
+	}
+	s := "GL error: " + text
+	if e.Op != "" {
+		s += " in " + e.Op
+	}
+	if e.Kind != "" {
+		s += fmt.Sprintf(" for %s %d", e.Kind, e.ID)
+	}
+	if e.Label != "" {
+		s += " (" + e.Label + ")"
+	}
+	return s
+}
+
+// DebugMessage is a message reported via the KHR_debug extension
+type DebugMessage struct {
+	Source   glbase.Enum
+	Type     glbase.Enum
+	ID       uint32
+	Severity glbase.Enum
+	Message  string
+}
+
+// DebugContext is implemented by GL contexts which support the KHR_debug extension.
+type DebugContext interface {
+	DebugMessageCallback(fn func(DebugMessage))
+	ObjectLabel(identifier glbase.Enum, name uint32, label string)
+}
+
+// SetDebugCallback registers a function to be called for each KHR_debug message.
+// Returns false if the current context does not support the extension.
+func SetDebugCallback(fn func(DebugMessage)) bool {
+	if ctx, ok := gl.(DebugContext); ok {
+		ctx.DebugMessageCallback(fn)
+		return true
+	}
+	return false
+}
+
+// debug label which is attached to each GL object
+type objectLabel struct {
+	label string
+}
+
+// Label returns the debug label for the object
+func (o *objectLabel) Label() string {
+	return o.label
+}
+
+func (o *objectLabel) setLabel(identifier glbase.Enum, name uint32, label string) {
+	o.label = label
+	if ctx, ok := gl.(DebugContext); ok {
+		ctx.ObjectLabel(identifier, name, label)
+	}
+}
+
+var firstErr error
+
+// Err returns the first GL error which was detected since the last call and clears it.
+// Errors are only saved if PanicOnError is not set.
+func Err() error {
+	err := firstErr
+	firstErr = nil
+	return err
+}
+
+// CheckError checks for GL errors. It will panic if PanicOnError is set, else returns an *Error.
+func CheckError() error {
+	return checkError("", "", 0, nil)
+}
+
+func checkError(op, kind string, id uint32, obj *objectLabel) error {
+	code := gl.GetError()
+	if code == NO_ERROR {
+		return nil
+	}
+	err := &Error{Op: op, Kind: kind, ID: id, Code: code}
+	if obj != nil {
+		err.Label = obj.label
+	}
+	if PanicOnError {
+		panic(err)
+	}
+	if firstErr == nil {
+		firstErr = err
+	}
+	return err
+}
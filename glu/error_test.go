@@ -0,0 +1,201 @@
+package glu_test
+
+import (
+	"bytes"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/glu/gltest"
+	"github.com/jnb666/go3d/img"
+	"gopkg.in/qml.v1/gl/glbase"
+	"image"
+	"image/png"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// GL error codes returned by the recorder
+const (
+	invalidEnum      glbase.Enum = 0x500
+	invalidValue     glbase.Enum = 0x501
+	invalidOperation glbase.Enum = 0x502
+	outOfMemory      glbase.Enum = 0x505
+)
+
+// recorder which also supports the KHR_debug extension
+type debugRecorder struct {
+	*gltest.Recorder
+	labels map[uint32]string
+	kinds  map[uint32]glbase.Enum
+}
+
+func (r *debugRecorder) ObjectLabel(identifier glbase.Enum, name uint32, label string) {
+	r.labels[name] = label
+	r.kinds[name] = identifier
+}
+
+func (r *debugRecorder) DebugMessageCallback(fn func(glu.DebugMessage)) {}
+
+func setup() *debugRecorder {
+	rec := &debugRecorder{Recorder: gltest.New(), labels: map[uint32]string{}, kinds: map[uint32]glbase.Enum{}}
+	glu.Init(rec)
+	return rec
+}
+
+// id of the object bound by the last call with the given name, skipping calls which unbind it
+func lastBound(rec *gltest.Recorder, name string) (id uint32) {
+	for _, call := range rec.Calls {
+		if call.Name == name {
+			switch v := call.Args[1].(type) {
+			case glbase.Buffer:
+				if v != 0 {
+					id = uint32(v)
+				}
+			case glbase.Texture:
+				if v != 0 {
+					id = uint32(v)
+				}
+			}
+		}
+	}
+	return id
+}
+
+// run fn and return the value it panics with
+func catch(fn func()) (val interface{}) {
+	defer func() {
+		val = recover()
+	}()
+	fn()
+	return nil
+}
+
+func pngImage() *bytes.Reader {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  glu.Error
+		want string
+	}{
+		{glu.Error{Code: 0x500}, "GL error: invalid enum"},
+		{glu.Error{Op: "Draw", Kind: "buffer", ID: 3, Code: 0x502}, "GL error: invalid operation in Draw for buffer 3"},
+		{glu.Error{Op: "Use", Kind: "program", ID: 7, Label: "phong", Code: 0x501}, "GL error: invalid value in Use for program 7 (phong)"},
+		{glu.Error{Op: "SetImage", Code: 0x505}, "GL error: out of memory in SetImage"},
+		{glu.Error{Code: 0x1234}, "GL error: unknown error code 1234"},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("%+v: got %q, expecting %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestPanicOnError(t *testing.T) {
+	rec := setup()
+	glu.PanicOnError = true
+	if err := glu.CheckError(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rec.ErrorCode = invalidValue
+	val := catch(func() { glu.ArrayBuffer([]float32{1, 2, 3}, 3) })
+	err, ok := val.(*glu.Error)
+	if !ok {
+		t.Fatalf("expecting a panic with a *glu.Error, got %v", val)
+	}
+	want := glu.Error{Op: "ArrayBuffer", Kind: "buffer", ID: lastBound(rec.Recorder, "BindBuffer"), Code: invalidValue}
+	if *err != want {
+		t.Errorf("error is %+v, expecting %+v", *err, want)
+	}
+	// errors are not saved when they cause a panic
+	if err := glu.Err(); err != nil {
+		t.Errorf("Err returned %v", err)
+	}
+}
+
+func TestSavedError(t *testing.T) {
+	rec := setup()
+	glu.PanicOnError = false
+	glu.Debug = true
+	defer func() {
+		glu.PanicOnError = true
+		glu.Debug = false
+	}()
+	buf := glu.DynamicArrayBuffer([]float32{1, 2, 3}, 3)
+	bufID := lastBound(rec.Recorder, "BindBuffer")
+	buf.SetLabel("positions")
+	tex := glu.NewTexture2D(true)
+	texID := lastBound(rec.Recorder, "BindTexture")
+	tex.SetLabel("bricks")
+	if err := glu.Err(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the first error is saved until Err is called
+	rec.ErrorCode = invalidOperation
+	if val := catch(func() { buf.Update([]float32{4, 5, 6}) }); val != nil {
+		t.Fatalf("panic with PanicOnError unset: %v", val)
+	}
+	rec.ErrorCode = invalidEnum
+	tex.Activate(0)
+	want := &glu.Error{Op: "Update", Kind: "buffer", ID: bufID, Label: "positions", Code: invalidOperation}
+	if err := glu.Err(); !reflect.DeepEqual(err, want) {
+		t.Errorf("saved error is %v, expecting %v", err, want)
+	} else if msg := err.Error(); msg != "GL error: invalid operation in Update for buffer "+strconv.Itoa(int(bufID))+" (positions)" {
+		t.Errorf("message is %q", msg)
+	}
+	if err := glu.Err(); err != nil {
+		t.Errorf("error was not cleared: %v", err)
+	}
+
+	// errors are also returned by functions which return an error
+	rec.ErrorCode = outOfMemory
+	_, err := tex.SetImage(pngImage(), img.NoConvert)
+	want = &glu.Error{Op: "SetImage", Kind: "texture", ID: texID, Label: "bricks", Code: outOfMemory}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("SetImage returned %v, expecting %v", err, want)
+	}
+	if saved := glu.Err(); !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved error is %v, expecting %v", saved, want)
+	}
+
+	// operations which are only checked in debug mode
+	glu.Debug = false
+	rec.ErrorCode = invalidValue
+	buf.Update([]float32{4, 5, 6})
+	if err := glu.Err(); err != nil {
+		t.Errorf("error checked with Debug unset: %v", err)
+	}
+}
+
+func TestSetLabel(t *testing.T) {
+	rec := setup()
+	buf := glu.ArrayBuffer([]float32{1, 2, 3}, 3)
+	bufID := lastBound(rec.Recorder, "BindBuffer")
+	tex := glu.NewTexture2D(false)
+	texID := lastBound(rec.Recorder, "BindTexture")
+	buf.SetLabel("normals")
+	tex.SetLabel("wood")
+	if buf.Label() != "normals" || tex.Label() != "wood" {
+		t.Errorf("labels are %q and %q", buf.Label(), tex.Label())
+	}
+	// labels are passed to the KHR_debug extension with the object type
+	if rec.labels[bufID] != "normals" || rec.kinds[bufID] != 0x82E0 {
+		t.Errorf("buffer %d label %q type %x", bufID, rec.labels[bufID], rec.kinds[bufID])
+	}
+	if rec.labels[texID] != "wood" || rec.kinds[texID] != 0x1702 {
+		t.Errorf("texture %d label %q type %x", texID, rec.labels[texID], rec.kinds[texID])
+	}
+	if !glu.SetDebugCallback(func(glu.DebugMessage) {}) {
+		t.Errorf("SetDebugCallback not supported")
+	}
+	// contexts without the extension still keep the label for error messages
+	glu.Init(gltest.New())
+	buf = glu.ArrayBuffer([]float32{1, 2, 3}, 3)
+	buf.SetLabel("colors")
+	if buf.Label() != "colors" || glu.SetDebugCallback(func(glu.DebugMessage) {}) {
+		t.Errorf("label %q without the debug extension", buf.Label())
+	}
+}
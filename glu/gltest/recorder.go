@@ -0,0 +1,476 @@
+// Package gltest provides a fake GL context which records the calls made to it, so that rendering code
+// can be tested without a GPU or display.
+package gltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"strings"
+)
+
+// Call records a single GL function call and its arguments
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return c.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Program records the shader source and the uniform values which were last set for a program.
+type Program struct {
+	VertexShader   string
+	FragmentShader string
+	Attribs        map[string]glbase.Attrib
+	Uniforms       map[string][]float32
+}
+
+// Texture records the image data which was loaded into a texture.
+type Texture struct {
+	Target        glbase.Enum
+	Width, Height int
+	Pixels        []uint8
+	Params        map[glbase.Enum]int32
+}
+
+// Draw records the state at the time of a DrawArrays or DrawElements call.
+type Draw struct {
+	Mode      glbase.Enum
+	Count     int
+	FrontFace glbase.Enum
+	Program   glbase.Program
+	Array     glbase.Buffer
+	Elements  glbase.Buffer
+	Uniforms  map[string][]float32
+	Textures  map[int]glbase.Texture
+}
+
+// Recorder is a fake GL context which implements the glu.Context interface. Object names are allocated
+// from a single counter so they are unique across all object types.
+type Recorder struct {
+	Calls    []Call
+	Draws    []Draw
+	Buffers  map[glbase.Buffer][]byte
+	Programs map[glbase.Program]*Program
+	Textures map[glbase.Texture]*Texture
+	Enabled  map[glbase.Enum]bool
+	// ErrorCode is returned by the next call to GetError
+	ErrorCode glbase.Enum
+	nextID    uint32
+	shaders   map[glbase.Shader]shader
+	uniforms  map[glbase.Uniform]uniformRef
+	bound     map[glbase.Enum]glbase.Buffer
+	texUnit   int
+	texBound  map[int]glbase.Texture
+	program   glbase.Program
+	frontFace glbase.Enum
+}
+
+type shader struct {
+	typ    glbase.Enum
+	source string
+}
+
+type uniformRef struct {
+	prog glbase.Program
+	name string
+}
+
+// New creates a new empty recorder
+func New() *Recorder {
+	return &Recorder{
+		Buffers:   map[glbase.Buffer][]byte{},
+		Programs:  map[glbase.Program]*Program{},
+		Textures:  map[glbase.Texture]*Texture{},
+		Enabled:   map[glbase.Enum]bool{},
+		shaders:   map[glbase.Shader]shader{},
+		uniforms:  map[glbase.Uniform]uniformRef{},
+		bound:     map[glbase.Enum]glbase.Buffer{},
+		texBound:  map[int]glbase.Texture{},
+		frontFace: glu.CCW,
+	}
+}
+
+// Reset clears the recorded call stream and draw calls, but keeps the objects which have been created
+func (r *Recorder) Reset() {
+	r.Calls = nil
+	r.Draws = nil
+}
+
+// Count returns the number of recorded calls to the named function
+func (r *Recorder) Count(name string) (n int) {
+	for _, c := range r.Calls {
+		if c.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// Uniform returns the last value set for a uniform in the current program
+func (r *Recorder) Uniform(name string) []float32 {
+	if p, ok := r.Programs[r.program]; ok {
+		return p.Uniforms[name]
+	}
+	return nil
+}
+
+// Floats returns the contents of a buffer as a float32 array
+func (r *Recorder) Floats(buf glbase.Buffer) []float32 {
+	data := make([]float32, len(r.Buffers[buf])/4)
+	binary.Read(bytes.NewReader(r.Buffers[buf]), binary.LittleEndian, data)
+	return data
+}
+
+// Uints returns the contents of a buffer as a uint32 array
+func (r *Recorder) Uints(buf glbase.Buffer) []uint32 {
+	data := make([]uint32, len(r.Buffers[buf])/4)
+	binary.Read(bytes.NewReader(r.Buffers[buf]), binary.LittleEndian, data)
+	return data
+}
+
+func (r *Recorder) record(name string, args ...interface{}) {
+	r.Calls = append(r.Calls, Call{Name: name, Args: args})
+}
+
+func (r *Recorder) id() uint32 {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *Recorder) setUniform(loc glbase.Uniform, v ...float32) {
+	if ref, ok := r.uniforms[loc]; ok {
+		r.Programs[ref.prog].Uniforms[ref.name] = append([]float32{}, v...)
+	}
+}
+
+func toBytes(data interface{}) []byte {
+	var buf bytes.Buffer
+	if data != nil {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (r *Recorder) ActiveTexture(texture glbase.Enum) {
+	r.record("ActiveTexture", texture)
+	r.texUnit = int(texture - glu.TEXTURE0)
+}
+
+func (r *Recorder) AttachShader(program glbase.Program, sh glbase.Shader) {
+	r.record("AttachShader", program, sh)
+	if p, ok := r.Programs[program]; ok {
+		if r.shaders[sh].typ == glu.VERTEX_SHADER {
+			p.VertexShader = r.shaders[sh].source
+		} else {
+			p.FragmentShader = r.shaders[sh].source
+		}
+	}
+}
+
+func (r *Recorder) BindBuffer(target glbase.Enum, buffer glbase.Buffer) {
+	r.record("BindBuffer", target, buffer)
+	r.bound[target] = buffer
+}
+
+func (r *Recorder) BindTexture(target glbase.Enum, texture glbase.Texture) {
+	r.record("BindTexture", target, texture)
+	r.texBound[r.texUnit] = texture
+	if t, ok := r.Textures[texture]; ok {
+		t.Target = target
+	}
+}
+
+func (r *Recorder) BlendFunc(sfactor, dfactor glbase.Enum) {
+	r.record("BlendFunc", sfactor, dfactor)
+}
+
+func (r *Recorder) BufferData(target glbase.Enum, size int, data interface{}, usage glbase.Enum) {
+	r.record("BufferData", target, size, usage)
+	buf := make([]byte, size)
+	copy(buf, toBytes(data))
+	r.Buffers[r.bound[target]] = buf
+}
+
+func (r *Recorder) BufferSubData(target glbase.Enum, offset, size int, data interface{}) {
+	r.record("BufferSubData", target, offset, size)
+	copy(r.Buffers[r.bound[target]][offset:offset+size], toBytes(data))
+}
+
+func (r *Recorder) Clear(mask glbase.Bitfield) {
+	r.record("Clear", mask)
+}
+
+func (r *Recorder) ClearColor(red, green, blue, alpha glbase.Clampf) {
+	r.record("ClearColor", red, green, blue, alpha)
+}
+
+func (r *Recorder) CompileShader(sh glbase.Shader) {
+	r.record("CompileShader", sh)
+}
+
+func (r *Recorder) CreateProgram() glbase.Program {
+	p := glbase.Program(r.id())
+	r.record("CreateProgram")
+	r.Programs[p] = &Program{Attribs: map[string]glbase.Attrib{}, Uniforms: map[string][]float32{}}
+	return p
+}
+
+func (r *Recorder) CreateShader(gltype glbase.Enum) glbase.Shader {
+	sh := glbase.Shader(r.id())
+	r.record("CreateShader", gltype)
+	r.shaders[sh] = shader{typ: gltype}
+	return sh
+}
+
+func (r *Recorder) DeleteBuffers(buffers []glbase.Buffer) {
+	r.record("DeleteBuffers", buffers)
+	for _, buf := range buffers {
+		delete(r.Buffers, buf)
+	}
+}
+
+func (r *Recorder) DeleteShader(sh glbase.Shader) {
+	r.record("DeleteShader", sh)
+}
+
+func (r *Recorder) DepthFunc(glfunc glbase.Enum) {
+	r.record("DepthFunc", glfunc)
+}
+
+func (r *Recorder) DepthMask(flag bool) {
+	r.record("DepthMask", flag)
+}
+
+func (r *Recorder) Disable(cap glbase.Enum) {
+	r.record("Disable", cap)
+	r.Enabled[cap] = false
+}
+
+func (r *Recorder) DrawArrays(mode glbase.Enum, first, count int) {
+	r.record("DrawArrays", mode, first, count)
+	r.draw(mode, count, 0)
+}
+
+func (r *Recorder) DrawElements(mode glbase.Enum, count int, gltype glbase.Enum, indices interface{}) {
+	r.record("DrawElements", mode, count, gltype)
+	r.draw(mode, count, r.bound[glu.ELEMENT_ARRAY_BUFFER])
+}
+
+func (r *Recorder) draw(mode glbase.Enum, count int, elements glbase.Buffer) {
+	d := Draw{
+		Mode:      mode,
+		Count:     count,
+		FrontFace: r.frontFace,
+		Program:   r.program,
+		Array:     r.bound[glu.ARRAY_BUFFER],
+		Elements:  elements,
+		Uniforms:  map[string][]float32{},
+		Textures:  map[int]glbase.Texture{},
+	}
+	if p, ok := r.Programs[r.program]; ok {
+		for name, val := range p.Uniforms {
+			d.Uniforms[name] = val
+		}
+	}
+	for unit, tex := range r.texBound {
+		d.Textures[unit] = tex
+	}
+	r.Draws = append(r.Draws, d)
+}
+
+func (r *Recorder) Enable(cap glbase.Enum) {
+	r.record("Enable", cap)
+	r.Enabled[cap] = true
+}
+
+func (r *Recorder) DisableVertexAttribArray(index glbase.Attrib) {
+	r.record("DisableVertexAttribArray", index)
+}
+
+func (r *Recorder) EnableVertexAttribArray(index glbase.Attrib) {
+	r.record("EnableVertexAttribArray", index)
+}
+
+func (r *Recorder) FrontFace(mode glbase.Enum) {
+	r.record("FrontFace", mode)
+	r.frontFace = mode
+}
+
+func (r *Recorder) GenBuffers(n int) []glbase.Buffer {
+	r.record("GenBuffers", n)
+	bufs := make([]glbase.Buffer, n)
+	for i := range bufs {
+		bufs[i] = glbase.Buffer(r.id())
+	}
+	return bufs
+}
+
+func (r *Recorder) GenTextures(n int) []glbase.Texture {
+	r.record("GenTextures", n)
+	tex := make([]glbase.Texture, n)
+	for i := range tex {
+		tex[i] = glbase.Texture(r.id())
+		r.Textures[tex[i]] = &Texture{Params: map[glbase.Enum]int32{}}
+	}
+	return tex
+}
+
+func (r *Recorder) GenerateMipmap(target glbase.Enum) {
+	r.record("GenerateMipmap", target)
+}
+
+func (r *Recorder) GetAttribLocation(program glbase.Program, name string) glbase.Attrib {
+	r.record("GetAttribLocation", program, name)
+	p := r.Programs[program]
+	if loc, ok := p.Attribs[name]; ok {
+		return loc
+	}
+	loc := glbase.Attrib(len(p.Attribs))
+	p.Attribs[name] = loc
+	return loc
+}
+
+func (r *Recorder) GetError() glbase.Enum {
+	code := r.ErrorCode
+	r.ErrorCode = glu.NO_ERROR
+	return code
+}
+
+func (r *Recorder) GetProgramInfoLog(program glbase.Program) []byte {
+	return nil
+}
+
+func (r *Recorder) GetProgramiv(program glbase.Program, pname glbase.Enum, params []int32) {
+	params[0] = 1
+}
+
+func (r *Recorder) GetShaderInfoLog(sh glbase.Shader) []byte {
+	return nil
+}
+
+func (r *Recorder) GetShaderiv(sh glbase.Shader, pname glbase.Enum, params []int32) {
+	params[0] = 1
+}
+
+func (r *Recorder) GetUniformLocation(program glbase.Program, name string) glbase.Uniform {
+	r.record("GetUniformLocation", program, name)
+	for loc, ref := range r.uniforms {
+		if ref.prog == program && ref.name == name {
+			return loc
+		}
+	}
+	loc := glbase.Uniform(r.id())
+	r.uniforms[loc] = uniformRef{prog: program, name: name}
+	return loc
+}
+
+func (r *Recorder) LinkProgram(program glbase.Program) {
+	r.record("LinkProgram", program)
+}
+
+// ReadPixels leaves the pixel data unchanged
+func (r *Recorder) ReadPixels(x, y, width, height int, format, gltype glbase.Enum, pixels interface{}) {
+	r.record("ReadPixels", x, y, width, height, format, gltype)
+}
+
+func (r *Recorder) ShaderSource(sh glbase.Shader, source ...string) {
+	r.record("ShaderSource", sh)
+	s := r.shaders[sh]
+	for _, src := range source {
+		s.source += src
+	}
+	r.shaders[sh] = s
+}
+
+func (r *Recorder) StencilFunc(glfunc glbase.Enum, ref int32, mask uint32) {
+	r.record("StencilFunc", glfunc, ref, mask)
+}
+
+func (r *Recorder) StencilMask(mask uint32) {
+	r.record("StencilMask", mask)
+}
+
+func (r *Recorder) StencilOp(fail, zfail, zpass glbase.Enum) {
+	r.record("StencilOp", fail, zfail, zpass)
+}
+
+func (r *Recorder) TexImage2D(target glbase.Enum, level int, internalFormat int32, width, height, border int, format, gltype glbase.Enum, pixels interface{}) {
+	r.record("TexImage2D", target, level, width, height)
+	if t, ok := r.Textures[r.texBound[r.texUnit]]; ok && level == 0 {
+		t.Width, t.Height = width, height
+		t.Pixels = toBytes(pixels)
+	}
+}
+
+func (r *Recorder) TexParameteri(target, pname glbase.Enum, param int32) {
+	r.record("TexParameteri", target, pname, param)
+	if t, ok := r.Textures[r.texBound[r.texUnit]]; ok {
+		t.Params[pname] = param
+	}
+}
+
+func (r *Recorder) Uniform1f(location glbase.Uniform, v0 float32) {
+	r.record("Uniform1f", location, v0)
+	r.setUniform(location, v0)
+}
+
+func (r *Recorder) Uniform1i(location glbase.Uniform, v0 int32) {
+	r.record("Uniform1i", location, v0)
+	r.setUniform(location, float32(v0))
+}
+
+func (r *Recorder) Uniform2f(location glbase.Uniform, v0, v1 float32) {
+	r.record("Uniform2f", location, v0, v1)
+	r.setUniform(location, v0, v1)
+}
+
+func (r *Recorder) Uniform2i(location glbase.Uniform, v0, v1 int32) {
+	r.record("Uniform2i", location, v0, v1)
+	r.setUniform(location, float32(v0), float32(v1))
+}
+
+func (r *Recorder) Uniform3fv(location glbase.Uniform, value []float32) {
+	r.record("Uniform3fv", location, value)
+	r.setUniform(location, value...)
+}
+
+func (r *Recorder) Uniform4fv(location glbase.Uniform, value []float32) {
+	r.record("Uniform4fv", location, value)
+	r.setUniform(location, value...)
+}
+
+func (r *Recorder) UniformMatrix3fv(location glbase.Uniform, transpose bool, value []float32) {
+	r.record("UniformMatrix3fv", location, transpose, value)
+	r.setUniform(location, value...)
+}
+
+func (r *Recorder) UniformMatrix4fv(location glbase.Uniform, transpose bool, value []float32) {
+	r.record("UniformMatrix4fv", location, transpose, value)
+	r.setUniform(location, value...)
+}
+
+func (r *Recorder) UseProgram(program glbase.Program) {
+	r.record("UseProgram", program)
+	r.program = program
+}
+
+func (r *Recorder) VertexAttribPointer(index glbase.Attrib, size int, gltype glbase.Enum, normalized bool, stride int, offset uintptr) {
+	r.record("VertexAttribPointer", index, size, gltype, normalized, stride, offset)
+}
+
+func (r *Recorder) Viewport(x, y, width, height int) {
+	r.record("Viewport", x, y, width, height)
+}
+
+var _ glu.Context = (*Recorder)(nil)
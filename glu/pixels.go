@@ -0,0 +1,30 @@
+package glu
+
+import (
+	"image"
+)
+
+// ReadPixels returns the contents of a region of the current framebuffer. GL returns rows from the bottom up,
+// so they are flipped to give a normal top down image unless inverted is set. Set inverted if the y axis of the
+// projection is upside down, as it is when scene.View.FlipY is set for QML or offscreen rendering, since in
+// this case the rows are already in top down order.
+func ReadPixels(x, y, width, height int, inverted bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	gl.ReadPixels(x, y, width, height, RGBA, UNSIGNED_BYTE, img.Pix)
+	if !inverted {
+		flipRows(img)
+	}
+	return img
+}
+
+func flipRows(img *image.NRGBA) {
+	h := img.Rect.Dy()
+	row := make([]uint8, img.Stride)
+	for y := 0; y < h/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(h-1-y)*img.Stride : (h-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+}
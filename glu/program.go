@@ -3,18 +3,23 @@ package glu
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
-	"gopkg.in/qml.v1/gl/es2"
 	"gopkg.in/qml.v1/gl/glbase"
 )
 
 // Type to encapsulate opengl shader program.
 type Program struct {
+	objectLabel
 	prog    glbase.Program
 	uniform map[string]uniform
 	attr    []Attrib
+	attrLoc []glbase.Attrib
 	stride  int
+	extra   map[string]glbase.Attrib
+	used    map[glbase.Attrib]bool // locations of the enabled attributes in the layout and the extras
 }
 
+// Attrib type is the layout of a vertex attribute in the array buffer, size and offset are in words. An
+// attribute with zero size is not in the buffer, so it is disabled and the shader reads it as zero.
 type Attrib struct {
 	Name   string
 	Size   int
@@ -26,12 +31,12 @@ func NewProgram(vertexShader, fragmentShader string, attr []Attrib, stride int)
 	p = new(Program)
 	p.prog = gl.CreateProgram()
 	p.uniform = make(map[string]uniform)
-	vs, err := compileShader(GL.VERTEX_SHADER, vertexShader)
+	vs, err := compileShader(VERTEX_SHADER, vertexShader)
 	if err != nil {
 		return p, err
 	}
 	gl.AttachShader(p.prog, vs)
-	fs, err := compileShader(GL.FRAGMENT_SHADER, fragmentShader)
+	fs, err := compileShader(FRAGMENT_SHADER, fragmentShader)
 	if err != nil {
 		return p, err
 	}
@@ -40,23 +45,44 @@ func NewProgram(vertexShader, fragmentShader string, attr []Attrib, stride int)
 	gl.DeleteShader(vs)
 	gl.DeleteShader(fs)
 	var status [1]int32
-	gl.GetProgramiv(p.prog, GL.LINK_STATUS, status[:])
+	gl.GetProgramiv(p.prog, LINK_STATUS, status[:])
 	if status[0] == 0 {
 		log := gl.GetProgramInfoLog(p.prog)
 		return p, fmt.Errorf("error linking program: %s", log)
 	}
-	CheckError()
+	checkError("NewProgram", "program", uint32(p.prog), nil)
 	p.attr = attr
 	p.stride = stride
+	p.used = map[glbase.Attrib]bool{}
+	for _, att := range attr {
+		loc := gl.GetAttribLocation(p.prog, att.Name)
+		p.attrLoc = append(p.attrLoc, loc)
+		if loc >= 0 && att.Size > 0 {
+			p.used[loc] = true
+		}
+	}
 	return p, nil
 }
 
+// OpenGL ES requires a default precision for floats in fragment shaders
+const fragPrecision = `#ifdef GL_ES
+#ifdef GL_FRAGMENT_PRECISION_HIGH
+precision highp float;
+#else
+precision mediump float;
+#endif
+#endif
+`
+
 func compileShader(typ glbase.Enum, src string) (glbase.Shader, error) {
 	shader := gl.CreateShader(typ)
+	if typ == FRAGMENT_SHADER {
+		src = fragPrecision + src
+	}
 	gl.ShaderSource(shader, src)
 	gl.CompileShader(shader)
 	var status [1]int32
-	gl.GetShaderiv(shader, GL.COMPILE_STATUS, status[:])
+	gl.GetShaderiv(shader, COMPILE_STATUS, status[:])
 	if status[0] == 0 {
 		log := gl.GetShaderInfoLog(shader)
 		return 0, fmt.Errorf("error compiling %s %s\n", src, log)
@@ -64,16 +90,59 @@ func compileShader(typ glbase.Enum, src string) (glbase.Shader, error) {
 	return shader, nil
 }
 
-// Use sets this as the current program.
+// SetLabel sets a debug label which is included in error messages
+func (p *Program) SetLabel(label string) {
+	p.setLabel(khrProgram, uint32(p.prog), label)
+}
+
+// Use sets this as the current program and sets up the vertex attributes for the current array buffer.
+// Arrays which were enabled for another program are disabled if this one does not use them, so they are
+// not left pointing at a buffer which may have been deleted.
 func (p *Program) Use() {
-	gl.UseProgram(p.prog)
-	for _, att := range p.attr {
-		loc := gl.GetAttribLocation(p.prog, att.Name)
-		gl.VertexAttribPointer(loc, att.Size, GL.FLOAT, false, p.stride*4, uintptr(att.Offset*4))
-		gl.EnableVertexAttribArray(loc)
+	useProgram(p.prog)
+	for loc := range state.enabled {
+		if !p.used[loc] {
+			disableAttrib(loc)
+		}
+	}
+	for i, att := range p.attr {
+		// attribute may have been optimised away by the shader compiler
+		if loc := p.attrLoc[i]; loc >= 0 && att.Size > 0 {
+			attribPointer(loc, att.Size, p.stride*4, att.Offset*4)
+			enableAttrib(loc)
+		} else if loc >= 0 {
+			disableAttrib(loc)
+		}
 	}
 	if Debug {
-		CheckError()
+		checkError("Use", "program", uint32(p.prog), &p.objectLabel)
+	}
+}
+
+// SetAttrib points an attribute which is not in the program's layout at data in another array buffer, e.g.
+// to choose which set of values is used for each draw call. Size, stride and offset are in words. This
+// should be called after Use, the current array buffer is not changed.
+func (p *Program) SetAttrib(name string, a *VertexArray, size, stride, offset int) {
+	if p.extra == nil {
+		p.extra = map[string]glbase.Attrib{}
+	}
+	loc, ok := p.extra[name]
+	if !ok {
+		loc = gl.GetAttribLocation(p.prog, name)
+		p.extra[name] = loc
+		if loc >= 0 {
+			p.used[loc] = true
+		}
+	}
+	if loc < 0 {
+		return
+	}
+	prev, bound := state.get(stateKey{typ: sBuffer, target: ARRAY_BUFFER})
+	bindBuffer(ARRAY_BUFFER, a.buffer)
+	attribPointer(loc, size, stride*4, offset*4)
+	enableAttrib(loc)
+	if bound {
+		bindBuffer(ARRAY_BUFFER, glbase.Buffer(prev))
 	}
 }
 
@@ -81,7 +150,7 @@ func (p *Program) Use() {
 func (p *Program) Uniform(typ string, names ...string) {
 	for _, name := range names {
 		u := gl.GetUniformLocation(p.prog, name)
-		CheckError()
+		checkError("Uniform "+name, "program", uint32(p.prog), &p.objectLabel)
 		switch typ {
 		case "1i":
 			p.uniform[name] = uniform1i(u)
@@ -0,0 +1,188 @@
+package glu
+
+import (
+	"gopkg.in/qml.v1/gl/glbase"
+)
+
+// StateStats has counters for the number of GL state changes made and the number of redundant
+// calls which were skipped since the start of the current frame.
+type StateStats struct {
+	Calls int
+	Saved int
+}
+
+// types of state tracked by the cache
+const (
+	sProgram = iota
+	sBuffer
+	sActiveTexture
+	sTexture
+	sCapability
+	sBlendFunc
+	sDepthFunc
+	sFrontFace
+	sAttribArray
+	sAttribPointer
+)
+
+type stateKey struct {
+	typ    int
+	target glbase.Enum
+	index  int
+}
+
+// glState records the current GL state so that calls which would not change it can be skipped.
+// A missing entry means that the value is unknown, so the next call will always be passed through.
+type glState struct {
+	value   map[stateKey]uint64
+	stats   StateStats
+	flipY   bool
+	enabled map[glbase.Attrib]bool // vertex attribute arrays which are enabled
+}
+
+var state = newState()
+
+func newState() *glState {
+	return &glState{value: map[stateKey]uint64{}, enabled: map[glbase.Attrib]bool{}}
+}
+
+// set records a new value, returns false if the GL call can be skipped
+func (s *glState) set(key stateKey, val uint64) bool {
+	if old, ok := s.value[key]; ok && old == val {
+		s.stats.Saved++
+		return false
+	}
+	s.value[key] = val
+	s.stats.Calls++
+	return true
+}
+
+// get returns the current value if known
+func (s *glState) get(key stateKey) (uint64, bool) {
+	val, ok := s.value[key]
+	return val, ok
+}
+
+// forget removes any entries of the given type which refer to an object which has been deleted
+func (s *glState) forget(typ int, id uint64) {
+	for key, val := range s.value {
+		if key.typ == typ && val == id {
+			delete(s.value, key)
+		}
+	}
+}
+
+// ResetState clears the state cache. Call this if the GL context has been modified by code which bypasses
+// the glu package so that the next state change will always be passed through to GL.
+func ResetState() {
+	state.value = map[stateKey]uint64{}
+	state.enabled = map[glbase.Attrib]bool{}
+}
+
+// FrameStats returns the state change counters since the last call to Clear.
+func FrameStats() StateStats {
+	return state.stats
+}
+
+// Enable a GL capability, skipped if it is already enabled
+func Enable(cap glbase.Enum) {
+	if state.set(stateKey{typ: sCapability, target: cap}, 1) {
+		gl.Enable(cap)
+	}
+}
+
+// Disable a GL capability, skipped if it is already disabled
+func Disable(cap glbase.Enum) {
+	if state.set(stateKey{typ: sCapability, target: cap}, 0) {
+		gl.Disable(cap)
+	}
+}
+
+// Set the blending function
+func BlendFunc(sfactor, dfactor glbase.Enum) {
+	if state.set(stateKey{typ: sBlendFunc}, uint64(sfactor)<<32|uint64(dfactor)) {
+		gl.BlendFunc(sfactor, dfactor)
+	}
+}
+
+// Set the depth comparison function
+func DepthFunc(fn glbase.Enum) {
+	if state.set(stateKey{typ: sDepthFunc}, uint64(fn)) {
+		gl.DepthFunc(fn)
+	}
+}
+
+// SetFlipY should be set if the projection turns the y axis upside down. This reverses the winding order of
+// the triangles in window coordinates, so the mode passed to FrontFace is swapped to match.
+func SetFlipY(on bool) {
+	state.flipY = on
+}
+
+// Set the winding order for front facing polygons
+func FrontFace(mode glbase.Enum) {
+	if state.flipY {
+		if mode == CCW {
+			mode = CW
+		} else {
+			mode = CCW
+		}
+	}
+	if state.set(stateKey{typ: sFrontFace}, uint64(mode)) {
+		gl.FrontFace(mode)
+	}
+}
+
+func useProgram(prog glbase.Program) {
+	if state.set(stateKey{typ: sProgram}, uint64(prog)) {
+		gl.UseProgram(prog)
+	}
+}
+
+func bindBuffer(target glbase.Enum, buffer glbase.Buffer) {
+	if state.set(stateKey{typ: sBuffer, target: target}, uint64(buffer)) {
+		gl.BindBuffer(target, buffer)
+	}
+}
+
+func bindTexture(target glbase.Enum, tex glbase.Texture) {
+	unit, ok := state.get(stateKey{typ: sActiveTexture})
+	if !ok {
+		activeTexture(0)
+	}
+	if state.set(stateKey{typ: sTexture, target: target, index: int(unit)}, uint64(tex)) {
+		gl.BindTexture(target, tex)
+	}
+}
+
+func activeTexture(unit int) {
+	if state.set(stateKey{typ: sActiveTexture}, uint64(unit)) {
+		gl.ActiveTexture(TEXTURE0 + glbase.Enum(unit))
+	}
+}
+
+func enableAttrib(loc glbase.Attrib) {
+	state.enabled[loc] = true
+	if state.set(stateKey{typ: sAttribArray, index: int(loc)}, 1) {
+		gl.EnableVertexAttribArray(loc)
+	}
+}
+
+func disableAttrib(loc glbase.Attrib) {
+	delete(state.enabled, loc)
+	if state.set(stateKey{typ: sAttribArray, index: int(loc)}, 0) {
+		gl.DisableVertexAttribArray(loc)
+	}
+}
+
+// the attribute pointer refers to the array buffer which was bound when it was set
+func attribPointer(loc glbase.Attrib, size, stride, offset int) {
+	key := stateKey{typ: sAttribPointer, index: int(loc)}
+	buf, ok := state.get(stateKey{typ: sBuffer, target: ARRAY_BUFFER})
+	if !ok {
+		delete(state.value, key)
+		state.stats.Calls++
+	} else if !state.set(key, buf<<32|uint64(size)<<24|uint64(stride)<<12|uint64(offset)) {
+		return
+	}
+	gl.VertexAttribPointer(loc, size, FLOAT, false, stride, uintptr(offset))
+}
@@ -2,10 +2,10 @@ package glu
 
 import (
 	"github.com/jnb666/go3d/img"
-	"gopkg.in/qml.v1/gl/es2"
 	"gopkg.in/qml.v1/gl/glbase"
 	"io"
 	"os"
+	"path"
 )
 
 // Texture interface type
@@ -20,21 +20,21 @@ type Texture2D struct{ *textureBase }
 // If clamp is set then clamp to edge, else will wrap texture.
 func NewTexture2D(clamp bool) Texture2D {
 	t := &textureBase{
-		typ: GL.TEXTURE_2D,
+		typ: TEXTURE_2D,
 		tex: gl.GenTextures(1),
 	}
-	gl.BindTexture(GL.TEXTURE_2D, t.tex[0])
+	bindTexture(TEXTURE_2D, t.tex[0])
 	if clamp {
-		gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_S, GL.CLAMP_TO_EDGE)
-		gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_T, GL.CLAMP_TO_EDGE)
+		gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, CLAMP_TO_EDGE)
+		gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, CLAMP_TO_EDGE)
 	} else {
-		gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_S, GL.REPEAT)
-		gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_T, GL.REPEAT)
+		gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, REPEAT)
+		gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, REPEAT)
 	}
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_MIN_FILTER, GL.LINEAR_MIPMAP_LINEAR)
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_MAG_FILTER, GL.LINEAR)
-	gl.BindTexture(t.typ, 0)
-	CheckError()
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, LINEAR_MIPMAP_LINEAR)
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, LINEAR)
+	bindTexture(t.typ, 0)
+	t.checkError("NewTexture2D")
 	return Texture2D{t}
 }
 
@@ -45,6 +45,9 @@ func (t Texture2D) SetImageFile(file string, conv img.ImageConvert) (Texture2D,
 		return t, err
 	}
 	defer r.Close()
+	if t.label == "" {
+		t.SetLabel(path.Base(file))
+	}
 	return t.SetImage(r, conv)
 }
 
@@ -55,11 +58,13 @@ func (t Texture2D) SetImage(r io.Reader, conv img.ImageConvert) (Texture2D, erro
 		return t, err
 	}
 	t.dims = []int{bounds.Dx(), bounds.Dy()}
-	gl.BindTexture(GL.TEXTURE_2D, t.tex[0])
-	gl.TexImage2D(GL.TEXTURE_2D, 0, GL.RGBA, t.dims[0], t.dims[1], 0, GL.RGBA, GL.UNSIGNED_BYTE, pix)
-	gl.GenerateMipmap(GL.TEXTURE_2D)
-	gl.BindTexture(GL.TEXTURE_2D, 0)
-	CheckError()
+	bindTexture(TEXTURE_2D, t.tex[0])
+	gl.TexImage2D(TEXTURE_2D, 0, RGBA, t.dims[0], t.dims[1], 0, RGBA, UNSIGNED_BYTE, pix)
+	gl.GenerateMipmap(TEXTURE_2D)
+	bindTexture(TEXTURE_2D, 0)
+	if err := t.checkError("SetImage"); err != nil {
+		return t, err
+	}
 	return t, nil
 }
 
@@ -68,16 +73,16 @@ type TextureCube struct{ *textureBase }
 // NewTextureCube creates a new cubemap texture. If srgba is set then it is converted to linear RGB space.
 func NewTextureCube() TextureCube {
 	t := &textureBase{
-		typ: GL.TEXTURE_CUBE_MAP,
+		typ: TEXTURE_CUBE_MAP,
 		tex: gl.GenTextures(1),
 	}
-	gl.BindTexture(t.typ, t.tex[0])
-	gl.TexParameteri(t.typ, GL.TEXTURE_WRAP_S, GL.CLAMP_TO_EDGE)
-	gl.TexParameteri(t.typ, GL.TEXTURE_WRAP_T, GL.CLAMP_TO_EDGE)
-	gl.TexParameteri(t.typ, GL.TEXTURE_MIN_FILTER, GL.LINEAR)
-	gl.TexParameteri(t.typ, GL.TEXTURE_MAG_FILTER, GL.LINEAR)
-	gl.BindTexture(t.typ, 0)
-	CheckError()
+	bindTexture(t.typ, t.tex[0])
+	gl.TexParameteri(t.typ, TEXTURE_WRAP_S, CLAMP_TO_EDGE)
+	gl.TexParameteri(t.typ, TEXTURE_WRAP_T, CLAMP_TO_EDGE)
+	gl.TexParameteri(t.typ, TEXTURE_MIN_FILTER, LINEAR)
+	gl.TexParameteri(t.typ, TEXTURE_MAG_FILTER, LINEAR)
+	bindTexture(t.typ, 0)
+	t.checkError("NewTextureCube")
 	return TextureCube{t}
 }
 
@@ -88,6 +93,9 @@ func (t TextureCube) SetImageFile(file string, conv img.ImageConvert, index int)
 		return t, err
 	}
 	defer r.Close()
+	if t.label == "" {
+		t.SetLabel(path.Base(file))
+	}
 	return t.SetImage(r, conv, index)
 }
 
@@ -99,11 +107,13 @@ func (t TextureCube) SetImage(r io.Reader, conv img.ImageConvert, index int) (Te
 		return t, err
 	}
 	t.dims = []int{bounds.Dx(), bounds.Dy()}
-	gl.BindTexture(GL.TEXTURE_CUBE_MAP, t.tex[0])
-	target := GL.TEXTURE_CUBE_MAP_POSITIVE_X + glbase.Enum(index)
-	gl.TexImage2D(target, 0, GL.RGBA, t.dims[0], t.dims[1], 0, GL.RGBA, GL.UNSIGNED_BYTE, pix)
-	gl.BindTexture(GL.TEXTURE_CUBE_MAP, 0)
-	CheckError()
+	bindTexture(TEXTURE_CUBE_MAP, t.tex[0])
+	target := TEXTURE_CUBE_MAP_POSITIVE_X + glbase.Enum(index)
+	gl.TexImage2D(target, 0, RGBA, t.dims[0], t.dims[1], 0, RGBA, UNSIGNED_BYTE, pix)
+	bindTexture(TEXTURE_CUBE_MAP, 0)
+	if err := t.checkError("SetImage"); err != nil {
+		return t, err
+	}
 	return t, nil
 }
 
@@ -112,16 +122,16 @@ type Texture3D struct{ *textureBase }
 // NewTexture3D creates a 3D texture mapping.
 func NewTexture3D() Texture3D {
 	t := &textureBase{
-		typ: GL.TEXTURE_2D,
+		typ: TEXTURE_2D,
 		tex: gl.GenTextures(1),
 	}
-	gl.BindTexture(GL.TEXTURE_2D, t.tex[0])
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_S, GL.CLAMP_TO_EDGE)
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_WRAP_T, GL.CLAMP_TO_EDGE)
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_MIN_FILTER, GL.NEAREST)
-	gl.TexParameteri(GL.TEXTURE_2D, GL.TEXTURE_MAG_FILTER, GL.NEAREST)
-	gl.BindTexture(t.typ, 0)
-	CheckError()
+	bindTexture(TEXTURE_2D, t.tex[0])
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, CLAMP_TO_EDGE)
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, CLAMP_TO_EDGE)
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, NEAREST)
+	gl.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, NEAREST)
+	bindTexture(t.typ, 0)
+	t.checkError("NewTexture3D")
 	return Texture3D{t}
 }
 
@@ -132,6 +142,9 @@ func (t Texture3D) SetImageFile(file string, conv img.ImageConvert, dims []int)
 		return t, err
 	}
 	defer r.Close()
+	if t.label == "" {
+		t.SetLabel(path.Base(file))
+	}
 	return t.SetImage(r, conv, dims)
 }
 
@@ -142,28 +155,40 @@ func (t Texture3D) SetImage(r io.Reader, conv img.ImageConvert, dims []int) (Tex
 		return t, err
 	}
 	t.dims = dims
-	gl.BindTexture(GL.TEXTURE_2D, t.tex[0])
-	gl.TexImage2D(GL.TEXTURE_2D, 0, GL.RGBA, dims[0], dims[1]*dims[2], 0, GL.RGBA, GL.UNSIGNED_BYTE, pix)
-	gl.BindTexture(GL.TEXTURE_2D, 0)
-	CheckError()
+	bindTexture(TEXTURE_2D, t.tex[0])
+	gl.TexImage2D(TEXTURE_2D, 0, RGBA, dims[0], dims[1]*dims[2], 0, RGBA, UNSIGNED_BYTE, pix)
+	bindTexture(TEXTURE_2D, 0)
+	if err := t.checkError("SetImage"); err != nil {
+		return t, err
+	}
 	return t, nil
 }
 
 // base type for all textures
 type textureBase struct {
+	objectLabel
 	typ  glbase.Enum
 	tex  []glbase.Texture
 	dims []int
 }
 
 func (t *textureBase) Activate(id int) {
-	gl.ActiveTexture(GL.TEXTURE0 + glbase.Enum(id))
-	gl.BindTexture(t.typ, t.tex[0])
+	activeTexture(id)
+	bindTexture(t.typ, t.tex[0])
 	if Debug {
-		CheckError()
+		t.checkError("Activate")
 	}
 }
 
 func (t *textureBase) Dims() []int {
 	return t.dims
 }
+
+// SetLabel sets a debug label which is included in error messages
+func (t *textureBase) SetLabel(label string) {
+	t.setLabel(khrTexture, uint32(t.tex[0]), label)
+}
+
+func (t *textureBase) checkError(op string) error {
+	return checkError(op, "texture", uint32(t.tex[0]), &t.objectLabel)
+}
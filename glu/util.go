@@ -2,9 +2,7 @@
 package glu
 
 import (
-	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
-	"gopkg.in/qml.v1/gl/es2"
 	"gopkg.in/qml.v1/gl/glbase"
 	"math"
 )
@@ -12,17 +10,6 @@ import (
 // If debug mode is set then check for GL errors after each call
 var Debug = false
 
-// GL error codes
-var glErrorText = map[glbase.Enum]string{
-	0x500: "invalid enum",
-	0x501: "invalid value",
-	0x502: "invalid operation",
-	0x503: "stack overflow",
-	0x504: "stack underflow",
-	0x505: "out of memory",
-	0x506: "invalid framebuffer operation",
-}
-
 // Predefined colors
 var (
 	Black   = mgl32.Vec4{0, 0, 0, 1}
@@ -37,41 +24,33 @@ var (
 )
 
 // Globals
-var gl *GL.GL
+var gl Context
 
-// Init method should be called to set the pointer to GL functions
-func Init(glptr *GL.GL) {
-	gl = glptr
+// Init method should be called to set the GL context. It also resets the state cache, so if the context is
+// shared with other code, such as the QML scene graph, it should be called at the start of each frame.
+func Init(ctx Context) {
+	gl = ctx
+	ResetState()
 }
 
-// Clear the screen ready for draing
+// Clear the screen ready for draing, this also resets the frame statistics
 func Clear(bg mgl32.Vec4) {
-	gl.Enable(GL.DEPTH_TEST)
-	gl.DepthFunc(GL.LEQUAL)
-	gl.Enable(GL.CULL_FACE)
-	gl.Enable(GL.BLEND)
-	gl.BlendFunc(GL.SRC_ALPHA, GL.ONE_MINUS_SRC_ALPHA)
+	state.stats = StateStats{}
+	deletePending()
+	Enable(DEPTH_TEST)
+	DepthFunc(LEQUAL)
+	Enable(CULL_FACE)
+	Enable(BLEND)
+	BlendFunc(SRC_ALPHA, ONE_MINUS_SRC_ALPHA)
 	gl.ClearColor(glbase.Clampf(bg[0]), glbase.Clampf(bg[1]), glbase.Clampf(bg[2]), glbase.Clampf(bg[3]))
-	gl.Clear(GL.COLOR_BUFFER_BIT | GL.DEPTH_BUFFER_BIT)
+	gl.Clear(COLOR_BUFFER_BIT | DEPTH_BUFFER_BIT)
 }
 
-// Reference to GL function pointers
-func GLRef() *GL.GL {
+// Reference to the current GL context
+func GLRef() Context {
 	return gl
 }
 
-// Check for GL errors
-func CheckError() {
-	err := gl.GetError()
-	if err != GL.NO_ERROR {
-		text, ok := glErrorText[err]
-		if !ok {
-			text = fmt.Sprintf("unknown error code %x", err)
-		}
-		panic("GL error: " + text)
-	}
-}
-
 // Force value into range from min to max
 func Clamp(x, min, max float32) float32 {
 	if x < min {
@@ -0,0 +1,178 @@
+// Package golden compares rendered images against saved reference images. Pixels are compared using the
+// CIE76 color difference in Lab space, so small changes which would not be visible are ignored.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Tolerance for comparing images
+type Tolerance struct {
+	DeltaE  float64 // maximum color difference for pixels to be considered equal, 2.3 is just noticeable
+	MaxDiff float64 // maximum fraction of pixels which may differ
+}
+
+var DefaultTolerance = Tolerance{DeltaE: 5, MaxDiff: 0.002}
+
+// Result of comparing an image with the reference version
+type Result struct {
+	Name      string
+	Pixels    int          // total number of pixels
+	Diff      int          // number of pixels which differ
+	MaxDeltaE float64      // largest color difference
+	DiffImage *image.NRGBA // greyscale copy of the reference image with differing pixels in red
+}
+
+// Passed checks if the result is within tolerance
+func (r *Result) Passed(tol Tolerance) bool {
+	return r.Pixels > 0 && float64(r.Diff) <= tol.MaxDiff*float64(r.Pixels)
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s: %d of %d pixels differ, max deltaE %.1f", r.Name, r.Diff, r.Pixels, r.MaxDeltaE)
+}
+
+// Compare two images which should be the same size.
+func Compare(want, got image.Image, tol Tolerance) *Result {
+	r := &Result{}
+	b := want.Bounds()
+	if got.Bounds().Dx() != b.Dx() || got.Bounds().Dy() != b.Dy() {
+		r.Diff = b.Dx() * b.Dy()
+		return r
+	}
+	r.DiffImage = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	off := got.Bounds().Min.Sub(b.Min)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c1, c2 := want.At(x, y), got.At(x+off.X, y+off.Y)
+			de := deltaE(toLab(c1), toLab(c2))
+			r.Pixels++
+			if de > r.MaxDeltaE {
+				r.MaxDeltaE = de
+			}
+			if de > tol.DeltaE {
+				r.Diff++
+				r.DiffImage.SetNRGBA(x-b.Min.X, y-b.Min.Y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				grey := color.GrayModel.Convert(c1).(color.Gray).Y
+				r.DiffImage.SetNRGBA(x-b.Min.X, y-b.Min.Y, color.NRGBA{grey, grey, grey, 64})
+			}
+		}
+	}
+	return r
+}
+
+// Suite manages a directory of reference images
+type Suite struct {
+	Dir     string // directory containing the reference images
+	Update  bool   // if set then the reference images are overwritten
+	Tol     Tolerance
+	Results []*Result
+	Failed  int
+}
+
+// NewSuite returns a new test suite using the default tolerance
+func NewSuite(dir string, update bool) *Suite {
+	return &Suite{Dir: dir, Update: update, Tol: DefaultTolerance}
+}
+
+// Check compares the image with the reference image of the given name. If Update is set the reference
+// is replaced instead. If the check fails then the image and the diff image are saved in the failed
+// subdirectory. Returns an error if the reference cannot be read or the check failed.
+func (s *Suite) Check(name string, img image.Image) (*Result, error) {
+	file := filepath.Join(s.Dir, name+".png")
+	if s.Update {
+		return nil, WritePNG(file, img)
+	}
+	want, err := ReadPNG(file)
+	if err != nil {
+		s.Failed++
+		return nil, err
+	}
+	r := Compare(want, img, s.Tol)
+	r.Name = name
+	s.Results = append(s.Results, r)
+	if r.Passed(s.Tol) {
+		return r, nil
+	}
+	s.Failed++
+	dir := filepath.Join(s.Dir, "failed")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return r, err
+	}
+	if err = WritePNG(filepath.Join(dir, name+".png"), img); err != nil {
+		return r, err
+	}
+	if r.DiffImage != nil {
+		if err = WritePNG(filepath.Join(dir, name+"_diff.png"), r.DiffImage); err != nil {
+			return r, err
+		}
+	}
+	return r, fmt.Errorf("golden image mismatch for %s", r)
+}
+
+// ReadPNG loads an image from a PNG file
+func ReadPNG(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// WritePNG saves an image to a PNG file
+func WritePNG(file string, img image.Image) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type lab struct{ l, a, b float64 }
+
+// convert sRGB color to CIE L*a*b* with D65 white point, transparent pixels are blended with black
+func toLab(c color.Color) lab {
+	r, g, b, _ := c.RGBA()
+	x, y, z := xyz(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff)
+	fx, fy, fz := labf(x/0.95047), labf(y), labf(z/1.08883)
+	return lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func xyz(r, g, b float64) (x, y, z float64) {
+	r, g, b = linear(r), linear(g), linear(b)
+	x = 0.4124*r + 0.3576*g + 0.1805*b
+	y = 0.2126*r + 0.7152*g + 0.0722*b
+	z = 0.0193*r + 0.1192*g + 0.9505*b
+	return
+}
+
+func labf(t float64) float64 {
+	if t > 216.0/24389 {
+		return math.Cbrt(t)
+	}
+	return t*24389/(27*116) + 16.0/116
+}
+
+func deltaE(c1, c2 lab) float64 {
+	dl, da, db := c1.l-c2.l, c1.a-c2.a, c1.b-c2.b
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
@@ -0,0 +1,172 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Bounds type is an axis aligned bounding box, together with a bounding sphere around the center of the box
+// which is usually a tighter fit for rotated objects.
+type Bounds struct {
+	Min, Max mgl32.Vec3
+	Radius   float32
+}
+
+// EmptyBounds returns a bounding box which does not contain any points
+func EmptyBounds() Bounds {
+	inf := float32(math.Inf(1))
+	return Bounds{Min: mgl32.Vec3{inf, inf, inf}, Max: mgl32.Vec3{-inf, -inf, -inf}, Radius: -1}
+}
+
+// BoundsOf returns the bounds of a set of points
+func BoundsOf(points []mgl32.Vec3) Bounds {
+	b := EmptyBounds()
+	for _, p := range points {
+		for i := 0; i < 3; i++ {
+			b.Min[i] = min32(b.Min[i], p[i])
+			b.Max[i] = max32(b.Max[i], p[i])
+		}
+	}
+	center := b.Center()
+	for _, p := range points {
+		b.Radius = max32(b.Radius, p.Sub(center).Len())
+	}
+	return b
+}
+
+// Empty checks if the bounds do not contain anything
+func (b Bounds) Empty() bool {
+	return b.Min[0] > b.Max[0]
+}
+
+// Center of the box and the bounding sphere
+func (b Bounds) Center() mgl32.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Size of the box along each axis
+func (b Bounds) Size() mgl32.Vec3 {
+	if b.Empty() {
+		return mgl32.Vec3{}
+	}
+	return b.Max.Sub(b.Min)
+}
+
+// Contains checks if the point is inside the box
+func (b Bounds) Contains(p mgl32.Vec3) bool {
+	return p[0] >= b.Min[0] && p[0] <= b.Max[0] && p[1] >= b.Min[1] && p[1] <= b.Max[1] &&
+		p[2] >= b.Min[2] && p[2] <= b.Max[2]
+}
+
+// Intersects checks if two boxes overlap
+func (b Bounds) Intersects(b2 Bounds) bool {
+	if b.Empty() || b2.Empty() {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if b.Max[i] < b2.Min[i] || b.Min[i] > b2.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Distance returns the distance from a point to the closest point in the box, or zero if it is inside
+func (b Bounds) Distance(p mgl32.Vec3) float32 {
+	var d mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		if p[i] < b.Min[i] {
+			d[i] = b.Min[i] - p[i]
+		} else if p[i] > b.Max[i] {
+			d[i] = p[i] - b.Max[i]
+		}
+	}
+	return d.Len()
+}
+
+// IntersectRay returns the distance along the ray to where it enters the box, in units of the direction
+// vector, or false if it misses. The distance is zero if the origin is inside the box.
+func (b Bounds) IntersectRay(origin, dir mgl32.Vec3) (float32, bool) {
+	if b.Empty() {
+		return 0, false
+	}
+	tmin, tmax := float32(0), float32(math.Inf(1))
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if origin[i] < b.Min[i] || origin[i] > b.Max[i] {
+				return 0, false
+			}
+			continue
+		}
+		t1, t2 := (b.Min[i]-origin[i])/dir[i], (b.Max[i]-origin[i])/dir[i]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin, tmax = max32(tmin, t1), min32(tmax, t2)
+		if tmin > tmax {
+			return 0, false
+		}
+	}
+	return tmin, true
+}
+
+// Union returns the bounds enclosing both b and b2
+func (b Bounds) Union(b2 Bounds) Bounds {
+	if b.Empty() {
+		return b2
+	}
+	if b2.Empty() {
+		return b
+	}
+	u := Bounds{}
+	for i := 0; i < 3; i++ {
+		u.Min[i] = min32(b.Min[i], b2.Min[i])
+		u.Max[i] = max32(b.Max[i], b2.Max[i])
+	}
+	// sphere around the new center which encloses both spheres, limited to the sphere around the box
+	center := u.Center()
+	u.Radius = max32(center.Sub(b.Center()).Len()+b.Radius, center.Sub(b2.Center()).Len()+b2.Radius)
+	u.Radius = min32(u.Radius, u.Size().Len()/2)
+	return u
+}
+
+// Transform returns the bounds after applying the transformation matrix. The new box encloses the transformed
+// corners of the original box, and the sphere radius is scaled by the largest scale factor.
+func (b Bounds) Transform(m mgl32.Mat4) Bounds {
+	if b.Empty() {
+		return b
+	}
+	t := EmptyBounds()
+	for i := 0; i < 8; i++ {
+		corner := b.Min
+		for j := 0; j < 3; j++ {
+			if i&(1<<uint(j)) != 0 {
+				corner[j] = b.Max[j]
+			}
+		}
+		p := mgl32.TransformCoordinate(corner, m)
+		for j := 0; j < 3; j++ {
+			t.Min[j] = min32(t.Min[j], p[j])
+			t.Max[j] = max32(t.Max[j], p[j])
+		}
+	}
+	scale := max32(m.Col(0).Vec3().Len(), max32(m.Col(1).Vec3().Len(), m.Col(2).Vec3().Len()))
+	// the sphere stays centered on the box if there is no rotation, otherwise allow for the offset
+	offset := mgl32.TransformCoordinate(b.Center(), m).Sub(t.Center()).Len()
+	t.Radius = min32(b.Radius*scale+offset, t.Size().Len()/2)
+	return t
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,247 @@
+package mesh
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"strings"
+)
+
+// Attribute type is a set of flags for the standard vertex attributes, the position is always present.
+type Attribute uint
+
+const (
+	Normals    Attribute = 1 << iota // vertex normal
+	TexCoords                        // texture coordinates
+	Tangents                         // tangent with handedness for normal mapping
+	Colors                           // RGBA vertex color
+	TexCoords2                       // second set of texture coordinates, e.g. for light maps
+	Joints                           // joint indices and weights for skinning
+)
+
+// CustomAttrib type declares an extra vertex attribute for a custom shader, size is the number of floats.
+type CustomAttrib struct {
+	Name string
+	Size int
+}
+
+// Format type declares the attributes which are stored for each vertex of a mesh. The vertex buffer has
+// the position followed by the standard attributes in the order listed above, and then the custom ones.
+type Format struct {
+	Attributes Attribute
+	Custom     []CustomAttrib
+}
+
+// DefaultFormat is used for new meshes
+var DefaultFormat = Format{Attributes: Normals | TexCoords | Tangents}
+
+// name and size of each standard attribute in the shaders, in the order they are stored
+var attribInfo = []struct {
+	attr Attribute
+	name string
+	size int
+}{
+	{0, "position", 3},
+	{Normals, "normal", 3},
+	{TexCoords, "texcoord", 2},
+	{Tangents, "tangent", 4},
+	{Colors, "color", 4},
+	{TexCoords2, "texcoord2", 2},
+	{Joints, "joint", 4},
+	{Joints, "weight", 4},
+}
+
+// Has returns true if the format has all of the given attributes
+func (f Format) Has(attr Attribute) bool {
+	return f.Attributes&attr == attr
+}
+
+// Size returns the number of floats for each vertex
+func (f Format) Size() (size int) {
+	for _, info := range attribInfo {
+		if f.Has(info.attr) {
+			size += info.size
+		}
+	}
+	for _, c := range f.Custom {
+		size += c.Size
+	}
+	return size
+}
+
+// Layout returns the position of each attribute in the vertex buffer, for use with glu.NewProgram.
+func (f Format) Layout() (layout []glu.Attrib) {
+	offset := 0
+	for _, info := range attribInfo {
+		if f.Has(info.attr) {
+			layout = append(layout, glu.Attrib{Name: info.name, Size: info.size, Offset: offset})
+			offset += info.size
+		}
+	}
+	for _, c := range f.Custom {
+		layout = append(layout, glu.Attrib{Name: c.Name, Size: c.Size, Offset: offset})
+		offset += c.Size
+	}
+	return layout
+}
+
+// layout for a shader which reads the given standard attributes, any which are not in the buffer have zero
+// size so they are disabled when the program is used and read as zero
+func (f Format) layout(attr Attribute) (layout []glu.Attrib) {
+	for _, info := range attribInfo {
+		if attr&info.attr != info.attr {
+			continue
+		}
+		if offset := f.offset(info.name); offset >= 0 {
+			layout = append(layout, glu.Attrib{Name: info.name, Size: info.size, Offset: offset})
+		} else {
+			layout = append(layout, glu.Attrib{Name: info.name})
+		}
+	}
+	return layout
+}
+
+// offset of the named attribute in the vertex buffer, or -1 if it is not present
+func (f Format) offset(name string) int {
+	offset := 0
+	for _, info := range attribInfo {
+		if f.Has(info.attr) {
+			if info.name == name {
+				return offset
+			}
+			offset += info.size
+		}
+	}
+	for _, c := range f.Custom {
+		if c.Name == name {
+			return offset
+		}
+		offset += c.Size
+	}
+	return -1
+}
+
+func (f Format) custom(name string) (CustomAttrib, bool) {
+	for _, c := range f.Custom {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return CustomAttrib{}, false
+}
+
+// String lists the attribute names, with the size of the custom ones
+func (f Format) String() string {
+	names := []string{}
+	for _, a := range f.Layout() {
+		if _, ok := f.custom(a.Name); ok {
+			names = append(names, fmt.Sprintf("%s:%d", a.Name, a.Size))
+		} else {
+			names = append(names, a.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Format returns the vertex format of the mesh
+func (m *Mesh) Format() Format {
+	return m.format
+}
+
+// SetFormat sets the attributes which are stored for each vertex, it must be called before the mesh is
+// built. Values which have been added for attributes which are not in the format are ignored.
+func (m *Mesh) SetFormat(f Format) *Mesh {
+	if len(m.vdata) > 0 {
+		panic("SetFormat: mesh has already been built")
+	}
+	for _, c := range f.Custom {
+		if c.Size < 1 || c.Size > 4 {
+			panic(fmt.Sprintf("SetFormat: custom attribute %s should have from 1 to 4 values", c.Name))
+		}
+		for _, info := range attribInfo {
+			if c.Name == info.name {
+				panic("SetFormat: custom attribute has the same name as " + info.name)
+			}
+		}
+	}
+	m.format = f
+	return m
+}
+
+// add a standard attribute to the format if it is not built yet
+func (m *Mesh) require(attr Attribute, caller string) {
+	if m.format.Has(attr) {
+		return
+	}
+	if len(m.vdata) > 0 {
+		panic(caller + ": mesh has already been built without this attribute")
+	}
+	m.format.Attributes |= attr
+}
+
+// AddColor adds a vertex color for the vertex with the same index, the format is updated to include colors.
+func (m *Mesh) AddColor(r, g, b, a float32) int {
+	m.require(Colors, "AddColor")
+	m.colors = append(m.colors, mgl32.Vec4{r, g, b, a})
+	return len(m.colors)
+}
+
+// AddTexCoord2 adds a second set of texture coordinates for the vertex with the same index, the format is
+// updated to include them.
+func (m *Mesh) AddTexCoord2(tx, ty float32) int {
+	m.require(TexCoords2, "AddTexCoord2")
+	m.texcoords2 = append(m.texcoords2, mgl32.Vec2{tx, ty})
+	return len(m.texcoords2)
+}
+
+// AddAttrib adds the value of a custom attribute for the vertex with the same index. The attribute must
+// have been declared with SetFormat.
+func (m *Mesh) AddAttrib(name string, value ...float32) int {
+	c, ok := m.format.custom(name)
+	if !ok {
+		panic("AddAttrib: no custom attribute called " + name + " in the mesh format")
+	}
+	if len(value) != c.Size {
+		panic(fmt.Sprintf("AddAttrib: %s should have %d values", name, c.Size))
+	}
+	if m.attribs == nil {
+		m.attribs = map[string][]float32{}
+	}
+	m.attribs[name] = append(m.attribs[name], value...)
+	return len(m.attribs[name]) / c.Size
+}
+
+// values of the extra attributes are indexed by vertex number, missing colors are white
+func (m *Mesh) color(n int) mgl32.Vec4 {
+	if i := vertexIndex(n, len(m.colors)); i >= 0 {
+		return m.colors[i]
+	}
+	return mgl32.Vec4{1, 1, 1, 1}
+}
+
+func (m *Mesh) texcoord2(n int) mgl32.Vec2 {
+	if i := vertexIndex(n, len(m.texcoords2)); i >= 0 {
+		return m.texcoords2[i]
+	}
+	return mgl32.Vec2{}
+}
+
+func (m *Mesh) attrib(name string, size, n int) []float32 {
+	if i := vertexIndex(n, len(m.attribs[name])/size); i >= 0 {
+		return m.attribs[name][i*size : (i+1)*size]
+	}
+	return make([]float32, size)
+}
+
+// convert a 1 based or negative relative index to a slice index, or -1 if it is out of range
+func vertexIndex(n, count int) int {
+	if n < 0 {
+		n += count
+	} else {
+		n--
+	}
+	if n < 0 || n >= count {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,128 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Hit type has the details of where a ray intersects a mesh
+type Hit struct {
+	Distance float32    // distance along the ray in units of the direction vector
+	Point    mgl32.Vec3 // intersection point in model space
+	Normal   mgl32.Vec3 // interpolated vertex normal in model space
+	Triangle int        // index of the triangle across all of the groups in the mesh
+}
+
+// Intersect finds the closest triangle hit by the ray in model space, using the Moller-Trumbore algorithm.
+// Both front and back faces are tested. Returns false if the ray misses the mesh. Skinned meshes are tested
+// in the pose from the last call to SetPose.
+func (m *Mesh) Intersect(origin, dir mgl32.Vec3) (hit Hit, ok bool) {
+	posed := m.Skinned() && m.pose != nil
+	if _, inside := m.bounds.IntersectRay(origin, dir); !inside && !posed {
+		return hit, false
+	}
+	base := 0
+	for _, grp := range m.groups {
+		var skin [3]mgl32.Mat4
+		for i := 0; i+2 < len(grp.edata); i += 3 {
+			idx := [3]int{int(grp.edata[i]), int(grp.edata[i+1]), int(grp.edata[i+2])}
+			v0, v1, v2 := m.position(idx[0]), m.position(idx[1]), m.position(idx[2])
+			if posed {
+				for j := range skin {
+					skin[j] = m.skinMatrix(grp.palette, idx[j])
+				}
+				v0 = mgl32.TransformCoordinate(v0, skin[0])
+				v1 = mgl32.TransformCoordinate(v1, skin[1])
+				v2 = mgl32.TransformCoordinate(v2, skin[2])
+			}
+			e1, e2 := v1.Sub(v0), v2.Sub(v0)
+			p := dir.Cross(e2)
+			det := e1.Dot(p)
+			if abs(det) < epsilon*epsilon {
+				continue
+			}
+			inv := 1 / det
+			s := origin.Sub(v0)
+			u := s.Dot(p) * inv
+			if u < 0 || u > 1 {
+				continue
+			}
+			q := s.Cross(e1)
+			v := dir.Dot(q) * inv
+			if v < 0 || u+v > 1 {
+				continue
+			}
+			t := e2.Dot(q) * inv
+			if t <= epsilon || (ok && t >= hit.Distance) {
+				continue
+			}
+			ok = true
+			hit.Distance = t
+			hit.Point = origin.Add(dir.Mul(t))
+			hit.Triangle = base + i/3
+			n0, n1, n2 := m.vnormal(idx[0]), m.vnormal(idx[1]), m.vnormal(idx[2])
+			if posed {
+				n0, n1, n2 = skin[0].Mat3().Mul3x1(n0), skin[1].Mat3().Mul3x1(n1), skin[2].Mat3().Mul3x1(n2)
+			}
+			n := n0.Mul(1 - u - v).Add(n1.Mul(u)).Add(n2.Mul(v))
+			if n.Len() < epsilon {
+				n = e1.Cross(e2)
+			}
+			hit.Normal = n.Normalize()
+		}
+		base += len(grp.edata) / 3
+	}
+	return hit, ok
+}
+
+// Triangles returns the vertex positions of each triangle in model space, in the same order as the triangle
+// index returned by Intersect.
+func (m *Mesh) Triangles() [][3]mgl32.Vec3 {
+	tris := [][3]mgl32.Vec3{}
+	for _, grp := range m.groups {
+		for i := 0; i+2 < len(grp.edata); i += 3 {
+			tris = append(tris, [3]mgl32.Vec3{
+				m.position(int(grp.edata[i])), m.position(int(grp.edata[i+1])), m.position(int(grp.edata[i+2])),
+			})
+		}
+	}
+	return tris
+}
+
+// Points returns each distinct vertex position in model space
+func (m *Mesh) Points() []mgl32.Vec3 {
+	seen := map[mgl32.Vec3]bool{}
+	points := []mgl32.Vec3{}
+	for i := 0; i < len(m.vdata)/m.size(); i++ {
+		if p := m.position(i); !seen[p] {
+			seen[p] = true
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// position and normal of a vertex from the built vertex data
+func (m *Mesh) position(i int) mgl32.Vec3 {
+	return mgl32.Vec3{m.vdata[i*m.size()], m.vdata[i*m.size()+1], m.vdata[i*m.size()+2]}
+}
+
+func (m *Mesh) vnormal(i int) mgl32.Vec3 {
+	n := m.format.offset("normal")
+	if n < 0 {
+		return mgl32.Vec3{}
+	}
+	return mgl32.Vec3{m.vdata[i*m.size()+n], m.vdata[i*m.size()+n+1], m.vdata[i*m.size()+n+2]}
+}
+
+// blend of the joint matrices which move a vertex of a skinned mesh, joints are stored as the slot in the
+// group's palette
+func (m *Mesh) skinMatrix(palette []int, i int) mgl32.Mat4 {
+	joint, weight := i*m.size()+m.format.offset("joint"), i*m.size()+m.format.offset("weight")
+	var mat mgl32.Mat4
+	for k := 0; k < 4; k++ {
+		if w := m.vdata[weight+k]; w > 0 {
+			mat = mat.Add(m.jointMatrix(palette[int(m.vdata[joint+k])]).Mul(w))
+		}
+	}
+	return mat
+}
@@ -36,7 +36,10 @@ func LoadObjFile(name string) (m *Mesh, err error) {
 	current, _ := os.Getwd()
 	os.Chdir(path.Dir(name))
 	defer os.Chdir(current)
-	return LoadObj(r)
+	if m, err = LoadObj(r); err == nil {
+		m.source = Source{File: name}
+	}
+	return m, err
 }
 
 // Create a new mesh from data
@@ -57,7 +60,7 @@ func LoadObj(r io.Reader) (m *Mesh, err error) {
 		flds := spaces.Split(line, -1)
 		switch flds[0] {
 		case "v", "vt", "vn":
-			obj.parseVertexData(flds[0], parse3fv(flds[1:]))
+			obj.parseVertexData(flds[0], parsefv(flds[1:]))
 		case "f":
 			obj.parseFaces(flds[1:])
 		case "g":
@@ -90,16 +93,30 @@ func (o *objData) build(name string) {
 		for _, face := range faces {
 			o.AddFace(face...)
 		}
+		n := len(o.Mesh.groups)
 		o.Build(mat)
+		for _, grp := range o.Mesh.groups[n:] {
+			grp.name = o.grpName
+		}
 	}
 	o.grpName = name
 	o.groups = map[string]elements{}
 }
 
-func (o *objData) parseVertexData(typ string, data mgl32.Vec3) {
+// vertices may have an RGB color after the position, others in the same file are white
+func (o *objData) parseVertexData(typ string, data []float32) {
+	if len(data) < 3 {
+		data = append(data, make([]float32, 3-len(data))...)
+	}
 	switch typ {
 	case "v":
-		o.AddVertex(data[0], data[1], data[2])
+		n := o.AddVertex(data[0], data[1], data[2])
+		if len(data) >= 6 {
+			for len(o.colors) < n-1 {
+				o.AddColor(1, 1, 1, 1)
+			}
+			o.AddColor(data[3], data[4], data[5], 1)
+		}
 	case "vt":
 		o.AddTexCoord(data[0], -data[1])
 	case "vn":
@@ -256,7 +273,7 @@ func (m mtlData) toMaterial(bumpMap bool) (mtl Material, err error) {
 		mtl = Reflective(m.specular.Vec4(m.alpha), m.shininess, textures...)
 	}
 	mtl.SetColor(color).SetAmbient(ambScale)
-	return mtl, nil
+	return named(mtl, m.name), nil
 }
 
 func addTexture(pos int, textures []glu.Texture, path string, conv img.ImageConvert) ([]glu.Texture, error) {
@@ -277,6 +294,13 @@ func parse3fv(flds []string) (v mgl32.Vec3) {
 	return
 }
 
+func parsefv(flds []string) (v []float32) {
+	for _, fld := range flds {
+		v = append(v, parsef32(fld))
+	}
+	return
+}
+
 func parsef32(fld string) float32 {
 	val, err := strconv.ParseFloat(fld, 32)
 	if err != nil {
@@ -12,9 +12,12 @@ import (
 	"strings"
 )
 
-// Interface type for a material which can be used to render a mesh
+// Interface type for a material which can be used to render a mesh. Enable selects the version of the shader
+// for the mesh being drawn. Requires returns the vertex attributes which the shader reads, any which are not
+// in the format of the mesh are read as zero.
 type Material interface {
-	Enable() *glu.Program
+	Enable(v Variant) *glu.Program
+	Requires() Attribute
 	Disable()
 	Color() mgl32.Vec4
 	SetColor(c mgl32.Vec4) Material
@@ -23,6 +26,13 @@ type Material interface {
 	Clone() Material
 }
 
+// Variant has the details of the mesh which is being drawn which affect the shader. The zero value is for a
+// mesh with the default vertex format.
+type Variant struct {
+	Format Format
+	Morphs int // number of morph targets blended by the shader
+}
+
 const (
 	mFirstShader = iota
 	mPointShader
@@ -41,6 +51,9 @@ const (
 	mRoughShader
 	mEmissiveShader
 	mMarbleShader
+	mUnshadedColor
+	mDiffuseColor
+	mBlinnPhongColor
 	mLastShader
 )
 
@@ -57,7 +70,7 @@ const (
 )
 
 var (
-	progCache    = map[int]*glu.Program{}
+	progCache    = map[progKey]*glu.Program{}
 	texCache     = map[int]glu.Texture{}
 	mtlCache     = map[string]Material{}
 	mtlDataCache = map[string]mtlData{}
@@ -70,7 +83,7 @@ func LoadMaterial(name string, bumpMap bool) (mtl Material, err error) {
 	if mtl, ok = mtlCache[cname]; ok {
 		return mtl, nil
 	}
-	if data, ok := mtlDataCache[name]; ok {
+	if data, ok := mtlDataCache[strings.ToLower(name)]; ok {
 		mtl, err = data.toMaterial(bumpMap)
 		if err != nil {
 			return nil, err
@@ -91,6 +104,8 @@ func LoadMaterial(name string, bumpMap bool) (mtl Material, err error) {
 		mtl = Glass()
 	case "marble":
 		mtl = Marble()
+	case "metallic":
+		mtl = Metallic()
 	case "plastic":
 		mtl = Plastic()
 	case "rough":
@@ -99,6 +114,8 @@ func LoadMaterial(name string, bumpMap bool) (mtl Material, err error) {
 		mtl = Skybox()
 	case "unshaded":
 		mtl = Unshaded()
+	case "vertexcolor":
+		mtl = named(VertexColors(Diffuse()), "vertexcolor")
 	case "wood":
 		mtl = Wood()
 	default:
@@ -107,6 +124,27 @@ func LoadMaterial(name string, bumpMap bool) (mtl Material, err error) {
 	return mtl, nil
 }
 
+// MaterialName returns the name which LoadMaterial uses for the material, or an empty string if it was not
+// created by name, e.g. a material with a custom texture.
+func MaterialName(mtl Material) string {
+	if m, ok := mtl.(interface {
+		Name() string
+	}); ok {
+		return m.Name()
+	}
+	return ""
+}
+
+func named(mtl Material, name string) Material {
+	switch m := mtl.(type) {
+	case *baseMaterial:
+		m.name = name
+	case *reflective:
+		m.name = name
+	}
+	return mtl
+}
+
 // Save material data to cache - called from mtl loader
 func saveMaterialData(m *mtlData) {
 	mtlDataCache[strings.ToLower(m.name)] = *m
@@ -125,13 +163,14 @@ func ntex(tex []glu.Texture) (n int) {
 func Unshaded(tex ...glu.Texture) Material {
 	m := newMaterial(glu.White)
 	if ntex(tex) == 0 {
-		m.prog = getProgram(mUnshaded)
+		m.name = "unshaded"
+		m.shader = mUnshaded
 	} else {
 		switch tex[0].(type) {
 		case glu.Texture2D:
-			m.prog = getProgram(mUnshadedTex)
+			m.shader = mUnshadedTex
 		case glu.TextureCube:
-			m.prog = getProgram(mUnshadedTexCube)
+			m.shader = mUnshadedTexCube
 		default:
 			panic("unsupported texture type")
 		}
@@ -143,33 +182,36 @@ func Unshaded(tex ...glu.Texture) Material {
 // Material used for drawing points
 func PointMaterial() Material {
 	m := newMaterial(glu.White)
-	m.prog = getProgram(mPointShader)
+	m.name = "point"
+	m.shader = mPointShader
 	return m
 }
 
 // Emissive material which looks like it glows
 func Emissive() Material {
 	m := newMaterial(mgl32.Vec4{0.9, 0.9, 0.9, 1})
-	m.prog = getProgram(mEmissiveShader)
+	m.name = "emissive"
+	m.shader = mEmissiveShader
 	return m
 }
 
 // Skybox using a cubemap texture
 func Skybox() Material {
-	return Unshaded(getTexture(tSkybox))
+	return named(Unshaded(getTexture(tSkybox)), "skybox")
 }
 
 // Diffuse colored material with optional texture
 func Diffuse(tex ...glu.Texture) Material {
 	m := newMaterial(glu.White)
 	if ntex(tex) == 0 {
-		m.prog = getProgram(mDiffuse)
+		m.name = "diffuse"
+		m.shader = mDiffuse
 	} else {
 		switch tex[0].(type) {
 		case glu.Texture2D:
-			m.prog = getProgram(mDiffuseTex)
+			m.shader = mDiffuseTex
 		case glu.TextureCube:
-			m.prog = getProgram(mDiffuseTexCube)
+			m.shader = mDiffuseTexCube
 		default:
 			panic("unsupported texture type")
 		}
@@ -178,6 +220,36 @@ func Diffuse(tex ...glu.Texture) Material {
 	return m
 }
 
+// shaders which multiply the material color by the vertex color
+var colorShader = map[int]int{
+	mUnshaded:   mUnshadedColor,
+	mDiffuse:    mDiffuseColor,
+	mBlinnPhong: mBlinnPhongColor,
+}
+
+// VertexColors returns a copy of an unshaded, diffuse or reflective material without textures which
+// multiplies its color by the interpolated vertex colors of the mesh. Other materials are returned unchanged.
+// If the mesh has no vertex colors then it is drawn as the original material.
+func VertexColors(mtl Material) Material {
+	var m *baseMaterial
+	newMat := mtl.Clone()
+	switch t := newMat.(type) {
+	case *baseMaterial:
+		m = t
+	case *reflective:
+		m = t.baseMaterial
+	default:
+		return mtl
+	}
+	id, ok := colorShader[m.shader]
+	if !ok {
+		return mtl
+	}
+	m.shader = id
+	m.name = ""
+	return newMat
+}
+
 type reflective struct {
 	*baseMaterial
 	specular  mgl32.Vec3
@@ -189,20 +261,20 @@ type reflective struct {
 func Reflective(specular mgl32.Vec4, shininess float32, tex ...glu.Texture) Material {
 	m := newMaterial(glu.White)
 	if ntex(tex) == 0 {
-		m.prog = getProgram(mBlinnPhong)
+		m.shader = mBlinnPhong
 	} else {
 		switch tex[0].(type) {
 		case glu.Texture2D:
 			if len(tex) > 2 {
-				m.prog = getProgram(mBlinnPhongTexNorm)
+				m.shader = mBlinnPhongTexNorm
 			} else {
-				m.prog = getProgram(mBlinnPhongTex)
+				m.shader = mBlinnPhongTex
 			}
 		case glu.TextureCube:
 			if len(tex) > 2 {
-				m.prog = getProgram(mBlinnPhongCubeNorm)
+				m.shader = mBlinnPhongCubeNorm
 			} else {
-				m.prog = getProgram(mBlinnPhongTexCube)
+				m.shader = mBlinnPhongTexCube
 			}
 		default:
 			panic("unsupported texture type")
@@ -232,8 +304,8 @@ func (m *reflective) SetAmbient(scale float32) Material {
 	return m
 }
 
-func (m *reflective) Enable() *glu.Program {
-	prog := m.baseMaterial.Enable()
+func (m *reflective) Enable(v Variant) *glu.Program {
+	prog := m.baseMaterial.Enable(v)
 	prog.Set("specularColor", m.specular)
 	prog.Set("shininess", m.shininess)
 	return prog
@@ -241,19 +313,19 @@ func (m *reflective) Enable() *glu.Program {
 
 // Shiny plastic like material
 func Plastic() Material {
-	return Reflective(mgl32.Vec4{0.8, 0.8, 0.8, 1}, 128)
+	return named(Reflective(mgl32.Vec4{0.8, 0.8, 0.8, 1}, 128), "plastic")
 }
 
 // Glass is reflective and has transparency
 func Glass() Material {
 	mat := Reflective(mgl32.Vec4{0.7, 0.7, 0.7, 1}, 64)
 	mat.SetColor(mgl32.Vec4{1, 1, 1, 0.4})
-	return mat
+	return named(mat, "glass")
 }
 
 // Earth cubemap
 func Earth() Material {
-	return Reflective(mgl32.Vec4{0.5, 0.5, 0.5, 1}, 32, getTexture(tEarth), getTexture(tEarthSpec))
+	return named(Reflective(mgl32.Vec4{0.5, 0.5, 0.5, 1}, 32, getTexture(tEarth), getTexture(tEarthSpec)), "earth")
 }
 
 type metallic struct {
@@ -267,6 +339,10 @@ func Metallic() Material {
 	}
 }
 
+func (m *metallic) Name() string {
+	return "metallic"
+}
+
 func (m *metallic) Clone() Material {
 	return &metallic{m.Material.Clone()}
 }
@@ -285,7 +361,8 @@ func (m *metallic) SetAmbient(amb float32) Material {
 // 3d Textured wood material
 func Wood() Material {
 	m := newMaterial(glu.White)
-	m.prog = getProgram(mWoodShader)
+	m.name = "wood"
+	m.shader = mWoodShader
 	m.tex = append(m.tex, getTexture(tWood), getTexture(tTurbulence))
 	return &reflective{
 		baseMaterial: m,
@@ -297,8 +374,9 @@ func Wood() Material {
 // Rough randomly textured material
 func Rough() Material {
 	m := newMaterial(glu.White)
+	m.name = "rough"
 	m.ambient = 0.3
-	m.prog = getProgram(mRoughShader)
+	m.shader = mRoughShader
 	m.tex = append(m.tex, getTexture(tTurbulence))
 	return &reflective{
 		baseMaterial: m,
@@ -310,7 +388,8 @@ func Rough() Material {
 // Marble textured material
 func Marble() Material {
 	m := newMaterial(glu.White)
-	m.prog = getProgram(mMarbleShader)
+	m.name = "marble"
+	m.shader = mMarbleShader
 	m.tex = append(m.tex, getTexture(tTurbulence))
 	return &reflective{
 		baseMaterial: m,
@@ -321,7 +400,8 @@ func Marble() Material {
 
 // base type for all materials
 type baseMaterial struct {
-	prog    *glu.Program
+	name    string
+	shader  int
 	tex     []glu.Texture
 	color   mgl32.Vec4
 	ambient float32
@@ -331,29 +411,33 @@ func newMaterial(color mgl32.Vec4) *baseMaterial {
 	return &baseMaterial{tex: []glu.Texture{}, color: color, ambient: 1}
 }
 
-func (m *baseMaterial) Enable() *glu.Program {
-	m.prog.Use()
-	m.prog.Set("objectColor", m.color)
-	m.prog.Set("ambientScale", m.ambient)
-	m.prog.Set("numTex", ntex(m.tex))
+func (m *baseMaterial) Enable(v Variant) *glu.Program {
+	prog := getProgram(m.shader, v)
+	prog.Use()
+	prog.Set("objectColor", m.color)
+	prog.Set("ambientScale", m.ambient)
+	prog.Set("numTex", ntex(m.tex))
 	for i, tex := range m.tex {
 		if tex != nil {
 			tex.Activate(i)
-			m.prog.Set("tex"+strconv.Itoa(i), i)
+			prog.Set("tex"+strconv.Itoa(i), i)
 		}
 	}
-	return m.prog
+	return prog
 }
 
 func (m *baseMaterial) Clone() Material {
 	return &baseMaterial{
-		prog:    m.prog,
+		name:    m.name,
+		shader:  m.shader,
 		tex:     append([]glu.Texture{}, m.tex...),
 		color:   m.color,
 		ambient: m.ambient,
 	}
 }
 
+func (m *baseMaterial) Name() string { return m.name }
+
 func (m *baseMaterial) Color() mgl32.Vec4 { return m.color }
 
 func (m *baseMaterial) SetColor(c mgl32.Vec4) Material {
@@ -370,24 +454,51 @@ func (m *baseMaterial) SetAmbient(amb float32) Material {
 
 func (m *baseMaterial) Disable() {}
 
+func (m *baseMaterial) Requires() Attribute {
+	_, attr := vertexShaderFor(m.shader)
+	return attr
+}
+
+// programs are compiled for each shader with the vertex format of the mesh and the number of morph targets
+type progKey struct {
+	id     int
+	format string
+	morphs int
+}
+
 // compile program and setup default uniforms
-func getProgram(id int) *glu.Program {
-	if prog, ok := progCache[id]; ok {
+func getProgram(id int, v Variant) *glu.Program {
+	if id == mPointShader {
+		// point sprites are not morphed
+		v.Morphs = 0
+	}
+	if !v.Format.Has(Colors) {
+		for plain, color := range colorShader {
+			if id == color {
+				id = plain
+			}
+		}
+	}
+	key := progKey{id, v.Format.String(), v.Morphs}
+	if prog, ok := progCache[key]; ok {
 		return prog
 	}
-	var prog *glu.Program
-	var err error
-	switch id {
-	case mBlinnPhongTexNorm, mBlinnPhongCubeNorm:
-		prog, err = glu.NewProgram(vertexShaderTBN, fragmentShader[id], vertexLayoutTBN, vertexSize)
-	case mPointShader:
-		prog, err = glu.NewProgram(vertexShaderPoints, fragmentShader[id], vertexLayoutPoints, vertexSize)
-	default:
-		prog, err = glu.NewProgram(vertexShader, fragmentShader[id], vertexLayout, vertexSize)
+	vertex, attr := vertexShaderFor(id)
+	skinned := v.Format.Has(Joints)
+	label := shaderName[id]
+	if skinned {
+		attr |= Joints
+		label += "Skinned"
+	}
+	if v.Morphs > 0 {
+		label += fmt.Sprintf("Morph%d", v.Morphs)
 	}
+	layout := v.Format.layout(attr)
+	prog, err := glu.NewProgram(withVariant(vertex, v, id != mPointShader), fragmentShader[id], layout, v.Format.Size())
 	if err != nil {
 		panic(err)
 	}
+	prog.SetLabel(label)
 	prog.Uniform("m4f", "modelToCamera", "cameraToClip")
 	prog.Uniform("v4f", "objectColor")
 	prog.Uniform("1f", "ambientScale")
@@ -403,11 +514,17 @@ func getProgram(id int) *glu.Program {
 		prog.Uniform("1i", "numLights")
 		prog.UniformArray(MaxLights, "v4f", "lightPos", "lightCol")
 	}
+	if skinned {
+		prog.UniformArray(MaxJoints, "m4f", "joints")
+	}
+	if v.Morphs > 0 {
+		prog.UniformArray(v.Morphs, "1f", "morphWeight")
+	}
 	prog.Uniform("1i", "numTex")
 	for i := 0; i < numSamplers[id]; i++ {
 		prog.Uniform("1i", fmt.Sprintf("tex%d", i))
 	}
-	progCache[id] = prog
+	progCache[key] = prog
 	return prog
 }
 
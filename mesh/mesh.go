@@ -5,33 +5,12 @@ import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/jnb666/go3d/glu"
-	"gopkg.in/qml.v1/gl/es2"
 	"gopkg.in/qml.v1/gl/glbase"
 )
 
-const (
-	vertexSize = 11
-	epsilon    = 1e-6
-)
-
-var vertexLayoutTBN = []glu.Attrib{
-	{Name: "position", Size: 3, Offset: 0},
-	{Name: "normal", Size: 3, Offset: 3},
-	{Name: "texcoord", Size: 2, Offset: 6},
-	{Name: "tangent", Size: 3, Offset: 8},
-}
-
-var vertexLayout = []glu.Attrib{
-	{Name: "position", Size: 3, Offset: 0},
-	{Name: "normal", Size: 3, Offset: 3},
-	{Name: "texcoord", Size: 2, Offset: 6},
-}
-
-var vertexLayoutPoints = []glu.Attrib{
-	{Name: "position", Size: 3, Offset: 0},
-}
+const epsilon = 1e-6
 
-var winding = [2]glbase.Enum{GL.CW, GL.CCW}
+var winding = [2]glbase.Enum{glu.CCW, glu.CW}
 
 type El struct {
 	Vert, Tex, Norm int
@@ -44,25 +23,45 @@ type el2 struct {
 
 // Mesh type stores a mesh of vertices
 type Mesh struct {
-	inverted  int
-	vdata     []float32
-	groups    []*meshGroup
-	varray    [2]*glu.VertexArray
-	vertices  []mgl32.Vec3
-	normals   []mgl32.Vec3
-	texcoords []mgl32.Vec2
-	tangents  []mgl32.Vec3
-	elements  []el2
-	ncache    normalCache
-	pointSize int
-	bumpMap   bool
+	inverted   int
+	format     Format
+	vdata      []float32
+	groups     []*meshGroup
+	varray     [2]*glu.VertexArray
+	shared     *Mesh // mesh which owns the vertex buffer for a part
+	source     Source
+	custom     bool // material set with SetMaterial
+	vertices   []mgl32.Vec3
+	normals    []mgl32.Vec3
+	texcoords  []mgl32.Vec2
+	tangents   []mgl32.Vec4
+	colors     []mgl32.Vec4
+	texcoords2 []mgl32.Vec2
+	attribs    map[string][]float32 // custom attributes
+	weights    []jointWeights
+	elements   []el2
+	ncache     normalCache
+	pointSize  int
+	bumpMap    bool
+	pose       []mgl32.Mat4
+	bounds     Bounds
+	// morph targets
+	targets      []morphTarget
+	ntargets     int       // number of targets in mdata
+	mdata        []float32 // target offsets for each vertex in vdata
+	marray       [2]*glu.VertexArray
+	morphWeights []float32
+	blended      *glu.VertexArray // vertices blended on the CPU
+	blendWeights []float32        // weights used for the blended vertices
 }
 
 type meshGroup struct {
+	name    string // group name from the obj file
 	mtlName string
 	edata   []uint32
 	mtl     Material
 	earray  *glu.VertexArray
+	palette []int // joint for each slot in the joints uniform array
 }
 
 type normalCache struct {
@@ -73,7 +72,8 @@ type normalCache struct {
 
 // NewMesh creates a new empty mesh structure
 func New() *Mesh {
-	return &Mesh{ncache: newNormalCache(false), groups: []*meshGroup{}, bumpMap: true}
+	return &Mesh{ncache: newNormalCache(false), groups: []*meshGroup{}, bumpMap: true, bounds: EmptyBounds(),
+		format: DefaultFormat}
 }
 
 func newNormalCache(smooth bool) normalCache {
@@ -87,6 +87,14 @@ func (m *Mesh) Clear() *Mesh {
 	m.normals = nil
 	m.texcoords = nil
 	m.tangents = nil
+	m.colors = nil
+	m.texcoords2 = nil
+	m.attribs = nil
+	m.weights = nil
+	for i := range m.targets {
+		// keep the target numbers for the next set of vertices
+		m.targets[i] = morphTarget{}
+	}
 	m.elements = nil
 	m.ncache = newNormalCache(true)
 	return m
@@ -98,13 +106,75 @@ func (m *Mesh) Clone() *Mesh {
 	newMesh.vdata = m.vdata
 	newMesh.inverted = m.inverted
 	newMesh.varray = m.varray
+	newMesh.shared = m.shared
+	newMesh.source = m.source
+	newMesh.custom = m.custom
 	newMesh.pointSize = m.pointSize
+	newMesh.format = m.format
+	newMesh.bounds = m.bounds
+	newMesh.shareTargets(m)
 	for _, grp := range m.groups {
-		newMesh.groups = append(newMesh.groups, &meshGroup{mtl: grp.mtl.Clone(), edata: grp.edata, earray: grp.earray})
+		g := *grp
+		if g.mtl != nil {
+			// materials which are not loaded yet are loaded by name when the copy is drawn
+			g.mtl = g.mtl.Clone()
+		}
+		newMesh.groups = append(newMesh.groups, &g)
 	}
 	return newMesh
 }
 
+// Names returns the distinct group names from the obj file in the order they were loaded. Meshes which were
+// not loaded from a file have a single unnamed group.
+func (m *Mesh) Names() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, grp := range m.groups {
+		if !seen[grp.name] {
+			names = append(names, grp.name)
+			seen[grp.name] = true
+		}
+	}
+	return names
+}
+
+// Part returns a new mesh with only the groups with the given name. The vertex data and buffers are shared
+// with the original mesh, but the materials can be changed separately.
+func (m *Mesh) Part(name string) *Mesh {
+	newMesh := New()
+	newMesh.vdata = m.vdata
+	newMesh.inverted = m.inverted
+	newMesh.shared = m
+	if m.shared != nil {
+		newMesh.shared = m.shared
+	}
+	newMesh.source = m.source
+	newMesh.source.Part = name
+	newMesh.custom = m.custom
+	newMesh.pointSize = m.pointSize
+	newMesh.bumpMap = m.bumpMap
+	newMesh.format = m.format
+	newMesh.shareTargets(m)
+	points := []mgl32.Vec3{}
+	for _, grp := range m.groups {
+		if grp.name == name {
+			g := *grp
+			newMesh.groups = append(newMesh.groups, &g)
+			for _, i := range grp.edata {
+				points = append(points, m.position(int(i)))
+				points = append(points, m.morphPoints(int(i))...)
+			}
+		}
+	}
+	newMesh.bounds = BoundsOf(points)
+	return newMesh
+}
+
+// Bounds returns the extent of the vertices in model space, this is updated each time Build is called.
+func (m *Mesh) Bounds() Bounds {
+	return m.bounds
+}
+
 // Point method returns point size, or zero for non-point
 func (m *Mesh) PointSize() int {
 	return m.pointSize
@@ -131,38 +201,24 @@ func (m *Mesh) AddTexCoord(tx, ty float32) int {
 // Add a triangular or a quad face
 func (m *Mesh) AddFace(el ...El) int {
 	calcNormal := false
-	calcTangent := true
 	vtx := make([]mgl32.Vec3, len(el))
-	tex := make([]mgl32.Vec2, len(el))
 	for i, e := range el {
 		if e.Norm == 0 {
 			calcNormal = true
 		}
 		vtx[i] = m.vertex(e.Vert)
-		if e.Tex == 0 {
-			calcTangent = false
-		} else {
-			tex[i] = m.texcoord(e.Tex)
-		}
 	}
 	base := len(m.elements)
-	ntangent := 0
-	if calcTangent {
-		if tangent, ok := getTangent(vtx, tex); ok {
-			m.tangents = append(m.tangents, tangent)
-			ntangent = len(m.tangents)
-		}
-	}
 	switch len(el) {
 	case 3:
-		m.addElements(ntangent, el)
+		m.addElements(el)
 		if calcNormal {
 			normal := vtx[1].Sub(vtx[0]).Cross(vtx[2].Sub(vtx[0]))
 			m.ncache.add(m, normal.Normalize(), base, el)
 		}
 	case 4:
 		elquad := []El{el[0], el[1], el[2], el[0], el[2], el[3]}
-		m.addElements(ntangent, elquad)
+		m.addElements(elquad)
 		if calcNormal {
 			normal := mgl32.Vec3{}
 			for i, v := range vtx {
@@ -181,25 +237,10 @@ func (m *Mesh) AddFace(el ...El) int {
 	return len(m.elements)
 }
 
-func (m *Mesh) addElements(ntangent int, elems []El) {
+func (m *Mesh) addElements(elems []El) {
 	for _, elem := range elems {
-		m.elements = append(m.elements, el2{El: elem, tang: ntangent})
-	}
-}
-
-// calc tangent vector for triangle
-func getTangent(vtx []mgl32.Vec3, tex []mgl32.Vec2) (tangent mgl32.Vec3, ok bool) {
-	dy1 := tex[1][1] - tex[0][1]
-	dy2 := tex[2][1] - tex[0][1]
-	f := (tex[1][0]-tex[0][0])*dy2 - (tex[2][0]-tex[0][0])*dy1
-	// ensure that triangle is not of zero height
-	if abs(dy1) < epsilon || abs(dy2) < epsilon || abs(f) < epsilon {
-		return
+		m.elements = append(m.elements, el2{El: elem})
 	}
-	// calculate tangent vector in texture coordinate space
-	edge1, edge2 := vtx[1].Sub(vtx[0]), vtx[2].Sub(vtx[0])
-	tangent = edge1.Mul(dy2 / f).Sub(edge2.Mul(dy1 / f)).Normalize()
-	return tangent, true
 }
 
 // If flag is false then turn off smoothing of vertex normals, else start a new smoothing group
@@ -237,31 +278,52 @@ func (n normalCache) build(m *Mesh) {
 }
 
 // Build method processes the data which has been added so far and appends it to the vertex and element buffers.
-// It can be called multiple times to add multiple groups of data.
+// It can be called multiple times to add multiple groups of data. Skinned meshes are split into more than one
+// group if they use more than MaxJoints joints.
 func (m *Mesh) Build(materialName string) {
-	grp := &meshGroup{mtlName: materialName}
-	if grp.mtlName == "" {
+	if materialName == "" {
 		if m.pointSize != 0 {
-			grp.mtlName = "point"
+			materialName = "point"
 		} else {
-			grp.mtlName = "diffuse"
+			materialName = "diffuse"
 		}
 	}
 	m.ncache.build(m)
 	m.ncache = newNormalCache(true)
+	m.buildTangents()
+	if m.format.Has(Joints) {
+		batches, palettes := m.batches()
+		for i, batch := range batches {
+			m.buildGroup(materialName, batch, palettes[i])
+		}
+	} else {
+		m.buildGroup(materialName, m.elements, nil)
+	}
+	m.elements = nil
+}
+
+func (m *Mesh) buildGroup(materialName string, elements []el2, palette []int) {
+	grp := &meshGroup{mtlName: materialName, palette: palette}
+	layout := m.format.Layout()
 	cache := map[el2]uint32{}
-	for _, el := range m.elements {
+	points := []mgl32.Vec3{}
+	for _, el := range elements {
 		index, ok := cache[el]
 		if !ok {
-			index = uint32(len(m.vdata) / vertexSize)
-			m.vdata = append(m.vdata, m.getData(el)...)
+			index = uint32(len(m.vdata) / m.size())
+			if len(m.targets) > 0 {
+				m.addMorphData(el.Vert)
+			}
+			m.vdata = append(m.vdata, m.getData(el, layout, palette)...)
 			cache[el] = index
+			points = append(points, m.vertex(el.Vert))
+			points = append(points, m.morphPoints(int(index))...)
 		}
 		grp.edata = append(grp.edata, index)
 	}
-	//fmt.Printf("mesh group %d: %d vertices, %d elements\n", len(m.groups), len(m.vdata)/vertexSize, len(grp.edata))
+	m.bounds = m.bounds.Union(BoundsOf(points))
+	//fmt.Printf("mesh group %d: %d vertices, %d elements\n", len(m.groups), len(m.vdata)/m.size(), len(grp.edata))
 	m.groups = append(m.groups, grp)
-	m.elements = nil
 }
 
 func (m *Mesh) loadMaterials(force bool) (err error) {
@@ -270,6 +332,10 @@ func (m *Mesh) loadMaterials(force bool) (err error) {
 			if grp.mtl, err = LoadMaterial(grp.mtlName, m.bumpMap); err != nil {
 				return err
 			}
+			if m.format.Has(Colors) {
+				// plain materials are multiplied by the vertex colors
+				grp.mtl = VertexColors(grp.mtl)
+			}
 		}
 	}
 	return nil
@@ -282,15 +348,34 @@ func (m *Mesh) BumpMap(on bool) {
 }
 
 // Draw method draws the mesh by calling GL DrawElements, setUniforms callback can be used to set uniforms after
-// binding the vertex arrays and enabling the shaders, but prior to drawing.
+// binding the vertex arrays and enabling the shaders, but prior to drawing. If glu.PanicOnError is not set then
+// any GL error will be returned.
 func (m *Mesh) Draw(setUniforms func(*glu.Program)) error {
 	if err := m.loadMaterials(false); err != nil {
 		return err
 	}
-	if m.varray[m.inverted] == nil {
-		m.varray[m.inverted] = glu.ArrayBuffer(m.vdata, vertexSize)
+	buf := m
+	if m.shared != nil {
+		buf = m.shared
+	}
+	// materials pick the shader with skinning when the mesh is skinned, and with morphing if a few
+	// morph targets are active. If there are more they are blended on the CPU.
+	v := Variant{Format: m.format}
+	active, slots := m.activeTargets(buf.ntargets), m.morphSlots()
+	if len(active) > slots {
+		m.blend(buf, active)
 	} else {
-		m.varray[m.inverted].Enable()
+		if len(active) > 0 {
+			v.Morphs = slots
+			if buf.marray[buf.inverted] == nil {
+				buf.marray[buf.inverted] = glu.ArrayBuffer(buf.mdata, buf.ntargets*morphSize)
+			}
+		}
+		if buf.varray[buf.inverted] == nil {
+			buf.varray[buf.inverted] = glu.ArrayBuffer(buf.vdata, buf.size())
+		} else {
+			buf.varray[buf.inverted].Enable()
+		}
 	}
 	var lastProg *glu.Program
 	for _, grp := range m.groups {
@@ -299,28 +384,52 @@ func (m *Mesh) Draw(setUniforms func(*glu.Program)) error {
 		} else {
 			grp.earray.Enable()
 		}
-		prog := grp.mtl.Enable()
+		prog := grp.mtl.Enable(v)
 		if prog != lastProg {
 			setUniforms(prog)
 			lastProg = prog
 		}
-		grp.earray.Draw(GL.TRIANGLES, winding[m.inverted])
+		for i, joint := range grp.palette {
+			prog.SetArray("joints", i, m.jointMatrix(joint))
+		}
+		if v.Morphs > 0 {
+			m.setMorphs(prog, buf, active, slots)
+		}
+		grp.earray.Draw(glu.TRIANGLES, winding[m.inverted])
 		grp.mtl.Disable()
 	}
-	return nil
+	return glu.Err()
 }
 
 // Invert method reverses the normals and winding order to flip the shape inside out
 func (m *Mesh) Invert() *Mesh {
 	newMesh := *m
 	newMesh.inverted = 1 - m.inverted
+	newMesh.shared = nil
+	newMesh.source.Inverted = !m.source.Inverted
 	// reverse normal directions
 	newMesh.vdata = append([]float32{}, m.vdata...)
-	for i := 0; i < len(newMesh.vdata); i += vertexSize {
-		newMesh.vdata[i+3] *= -1
-		newMesh.vdata[i+4] *= -1
-		newMesh.vdata[i+5] *= -1
+	if n := m.format.offset("normal"); n >= 0 {
+		for i := n; i < len(newMesh.vdata); i += m.size() {
+			newMesh.vdata[i] *= -1
+			newMesh.vdata[i+1] *= -1
+			newMesh.vdata[i+2] *= -1
+		}
 	}
+	// flip the handedness so the bitangent is unchanged
+	if t := m.format.offset("tangent"); t >= 0 {
+		for i := t + 3; i < len(newMesh.vdata); i += m.size() {
+			newMesh.vdata[i] *= -1
+		}
+	}
+	newMesh.mdata = append([]float32{}, m.mdata...)
+	for i := 0; i < len(newMesh.mdata); i += morphSize {
+		newMesh.mdata[i+3] *= -1
+		newMesh.mdata[i+4] *= -1
+		newMesh.mdata[i+5] *= -1
+	}
+	newMesh.morphWeights = append([]float32{}, m.morphWeights...)
+	newMesh.blended, newMesh.blendWeights = nil, nil
 	return &newMesh
 }
 
@@ -340,9 +449,16 @@ func (m *Mesh) SetMaterial(mtl Material) *Mesh {
 	for _, grp := range m.groups {
 		grp.mtl = mtl
 	}
+	m.custom = true
 	return m
 }
 
+// CustomMaterial checks if SetMaterial has been called, rather than using the materials named when the mesh
+// was built.
+func (m *Mesh) CustomMaterial() bool {
+	return m.custom
+}
+
 // String method for dumping out contents of the mesh
 func (m *Mesh) String() (s string) {
 	s += fmt.Sprintf("vertices: %f\n", m.vertices)
@@ -351,16 +467,52 @@ func (m *Mesh) String() (s string) {
 	return s
 }
 
-func (m *Mesh) getData(el el2) []float32 {
-	data := make([]float32, vertexSize)
-	v := m.vertex(el.Vert)
-	copy(data, v[:])
-	vn := m.normal(el.Norm)
-	copy(data[3:], vn[:])
-	vt := m.texcoord(el.Tex)
-	copy(data[6:], vt[:])
-	if el.tang > 0 {
-		copy(data[8:], m.tangents[el.tang-1][:])
+// pack the values for the attributes in the format, colors and the extra attributes are per vertex
+func (m *Mesh) getData(el el2, layout []glu.Attrib, palette []int) []float32 {
+	data := make([]float32, 0, m.size())
+	for _, a := range layout {
+		switch a.Name {
+		case "position":
+			v := m.vertex(el.Vert)
+			data = append(data, v[:]...)
+		case "normal":
+			vn := m.normal(el.Norm)
+			data = append(data, vn[:]...)
+		case "texcoord":
+			vt := m.texcoord(el.Tex)
+			data = append(data, vt[:]...)
+		case "tangent":
+			var t mgl32.Vec4
+			if el.tang > 0 {
+				t = m.tangents[el.tang-1]
+			}
+			data = append(data, t[:]...)
+		case "color":
+			c := m.color(el.Vert)
+			data = append(data, c[:]...)
+		case "texcoord2":
+			vt := m.texcoord2(el.Vert)
+			data = append(data, vt[:]...)
+		case "joint":
+			// joint numbers are stored as the slot in the group's palette
+			w := m.weight(el.Vert)
+			for i, joint := range w.joints {
+				slot := 0
+				if w.weights[i] > 0 {
+					for s, j := range palette {
+						if j == joint {
+							slot = s
+						}
+					}
+				}
+				data = append(data, float32(slot))
+			}
+		case "weight":
+			w := m.weight(el.Vert)
+			data = append(data, w.weights[:]...)
+		default:
+			data = append(data, m.attrib(a.Name, a.Size, el.Vert)...)
+		}
 	}
 	return data
 }
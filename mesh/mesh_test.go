@@ -0,0 +1,153 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/glu/gltest"
+	"gopkg.in/qml.v1/gl/glbase"
+	"testing"
+)
+
+// size and offset in words of an attribute which is read from the vertex buffer
+type attribLayout struct {
+	size, offset int
+}
+
+// layout of each attribute from the VertexAttribPointer calls, disabled attributes have zero size
+func recordedLayout(rec *gltest.Recorder, prog glbase.Program) (layout map[string]attribLayout, stride int) {
+	layout = map[string]attribLayout{}
+	names := map[glbase.Attrib]string{}
+	for name, loc := range rec.Programs[prog].Attribs {
+		names[loc] = name
+	}
+	for _, c := range rec.Calls {
+		switch c.Name {
+		case "VertexAttribPointer":
+			loc := c.Args[0].(glbase.Attrib)
+			stride = c.Args[4].(int) / 4
+			layout[names[loc]] = attribLayout{size: c.Args[1].(int), offset: int(c.Args[5].(uintptr)) / 4}
+		case "DisableVertexAttribArray":
+			layout[names[c.Args[0].(glbase.Attrib)]] = attribLayout{}
+		}
+	}
+	return layout, stride
+}
+
+func TestDrawProgram(t *testing.T) {
+	color := mgl32.Vec4{0.2, 0.4, 0.6, 1}
+	tests := []struct {
+		name    string
+		mtl     Material
+		format  Format
+		stride  int
+		attribs map[string]attribLayout
+	}{
+		{"unshaded", Unshaded(), DefaultFormat, 12, map[string]attribLayout{
+			"position": {3, 0}, "normal": {3, 3}, "texcoord": {2, 6},
+		}},
+		{"diffuse", Diffuse(), DefaultFormat, 12, map[string]attribLayout{
+			"position": {3, 0}, "normal": {3, 3}, "texcoord": {2, 6},
+		}},
+		{"diffuse without texcoords", Diffuse(), Format{Attributes: Normals}, 6, map[string]attribLayout{
+			"position": {3, 0}, "normal": {3, 3}, "texcoord": {},
+		}},
+		{"plastic", Plastic(), Format{Attributes: Normals | TexCoords}, 8, map[string]attribLayout{
+			"position": {3, 0}, "normal": {3, 3}, "texcoord": {2, 6},
+		}},
+	}
+	for _, test := range tests {
+		rec := setup()
+		m := New().SetFormat(test.format)
+		m.AddVertex(0, 0, 0)
+		m.AddVertex(1, 0, 0)
+		m.AddVertex(0, 1, 0)
+		m.AddNormal(0, 0, 1)
+		m.AddFace(El{1, 0, 1}, El{2, 0, 1}, El{3, 0, 1})
+		m.Build("")
+		m.SetMaterial(test.mtl.SetColor(color))
+		var current *glu.Program
+		if err := m.Draw(func(p *glu.Program) { current = p }); err != nil {
+			t.Fatalf("%s: draw error %s", test.name, err)
+		}
+		if current == nil {
+			t.Errorf("%s: setUniforms was not called", test.name)
+		}
+		if len(rec.Draws) != 1 {
+			t.Fatalf("%s: expecting 1 draw call, got %d", test.name, len(rec.Draws))
+		}
+		draw := rec.Draws[0]
+		if n := rec.Count("UseProgram"); n != 1 {
+			t.Errorf("%s: UseProgram called %d times", test.name, n)
+		}
+		if draw.Mode != glu.TRIANGLES || draw.Count != 3 || draw.FrontFace != glu.CCW {
+			t.Errorf("%s: draw mode %d count %d front face %d", test.name, draw.Mode, draw.Count, draw.FrontFace)
+		}
+		if got := draw.Uniforms["objectColor"]; vec4(got) != color {
+			t.Errorf("%s: objectColor is %v, expecting %v", test.name, got, color)
+		}
+		if got := draw.Uniforms["ambientScale"]; len(got) != 1 || got[0] != 1 {
+			t.Errorf("%s: ambientScale is %v, expecting 1", test.name, got)
+		}
+		layout, stride := recordedLayout(rec, draw.Program)
+		if stride != test.stride {
+			t.Errorf("%s: stride is %d, expecting %d", test.name, stride, test.stride)
+		}
+		for name, want := range test.attribs {
+			if got, ok := layout[name]; !ok || got != want {
+				t.Errorf("%s: attribute %s is %v, expecting %v", test.name, name, got, want)
+			}
+		}
+	}
+}
+
+func vec4(v []float32) (r mgl32.Vec4) {
+	copy(r[:], v)
+	return r
+}
+
+func TestDrawStateCache(t *testing.T) {
+	rec := setup()
+	mtl := Diffuse()
+	a, b := square(0).SetMaterial(mtl), square(0).SetMaterial(mtl)
+	for _, m := range []*Mesh{a, b} {
+		if err := m.Draw(noUniforms); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name  string
+		mesh  *Mesh
+		reset bool
+		calls map[string]int
+	}{
+		{"same mesh", b, false, map[string]int{
+			"UseProgram": 0, "BindBuffer": 0, "VertexAttribPointer": 0, "EnableVertexAttribArray": 0, "FrontFace": 0,
+		}},
+		{"other mesh", a, false, map[string]int{
+			"UseProgram": 0, "BindBuffer": 2, "VertexAttribPointer": 3, "EnableVertexAttribArray": 0, "FrontFace": 0,
+		}},
+		{"inverted", b.Invert(), false, map[string]int{
+			"UseProgram": 0, "BindBuffer": 2, "VertexAttribPointer": 3, "EnableVertexAttribArray": 0, "FrontFace": 1,
+		}},
+		{"after reset", b, true, map[string]int{
+			"UseProgram": 1, "BindBuffer": 2, "VertexAttribPointer": 3, "EnableVertexAttribArray": 3, "FrontFace": 1,
+		}},
+	}
+	for _, test := range tests {
+		if test.reset {
+			glu.ResetState()
+		}
+		rec.Reset()
+		if err := test.mesh.Draw(noUniforms); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range test.calls {
+			if got := rec.Count(name); got != want {
+				t.Errorf("%s: %s called %d times, expecting %d", test.name, name, got, want)
+			}
+		}
+		if len(rec.Draws) != 1 {
+			t.Errorf("%s: expecting 1 draw call, got %d", test.name, len(rec.Draws))
+		}
+	}
+}
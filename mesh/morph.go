@@ -0,0 +1,195 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"strconv"
+)
+
+// MaxMorphTargets is the number of morph targets which are blended by the vertex shader. Together with the
+// other attributes this keeps within the 8 vertex attributes that OpenGL ES 2 guarantees, so skinned meshes
+// only blend one fewer target on the GPU. If more targets have a non-zero weight then the vertices are
+// blended on the CPU instead.
+const MaxMorphTargets = 2
+
+const maxVertexAttribs = 8
+
+// offsets from the base mesh for one morph target, parallel to the vertex positions
+type morphTarget struct {
+	positions []mgl32.Vec3
+	normals   []mgl32.Vec3
+}
+
+// morph target data for the vertex buffer, per vertex and then per target
+const morphSize = 6
+
+// AddTarget adds a morph target with the offset of each vertex added so far from its base position, and
+// optionally the offset of its normal. The shape is blended from the base mesh and the targets using the
+// weights set with SetMorphWeight. Targets should be added before calling Build, it returns the target
+// number.
+func (m *Mesh) AddTarget(positions, normals []mgl32.Vec3) int {
+	m.targets = append(m.targets, morphTarget{positions: positions, normals: normals})
+	m.morphWeights = append(m.morphWeights, 0)
+	return len(m.targets) - 1
+}
+
+// Targets returns the number of morph targets
+func (m *Mesh) Targets() int {
+	return len(m.morphWeights)
+}
+
+// SetMorphWeight sets how much of the given target is added to the base mesh, normally from 0 to 1.
+func (m *Mesh) SetMorphWeight(target int, w float32) *Mesh {
+	if target >= 0 && target < len(m.morphWeights) {
+		m.morphWeights[target] = w
+	}
+	return m
+}
+
+// MorphWeights returns the current weight for each target
+func (m *Mesh) MorphWeights() []float32 {
+	return m.morphWeights
+}
+
+// number of targets which can be blended by the shader, each one uses two attributes
+func (m *Mesh) morphSlots() int {
+	if m.pointSize != 0 {
+		return 0
+	}
+	// position, normal, texcoord and tangent for normal mapping
+	used := 4
+	if m.format.Has(Colors) {
+		used++
+	}
+	if m.format.Has(Joints) {
+		used += 2
+	}
+	if slots := (maxVertexAttribs - used) / 2; slots < MaxMorphTargets {
+		return slots
+	}
+	return MaxMorphTargets
+}
+
+// targets with a non-zero weight which are in the vertex buffer
+func (m *Mesh) activeTargets(built int) (active []int) {
+	for i, w := range m.morphWeights {
+		if w != 0 && i < built {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// offset of the vertex position and normal for one target, zero if they were not given
+func (m *Mesh) targetOffset(target, n int) (pos, norm mgl32.Vec3) {
+	t := m.targets[target]
+	if i := vertexIndex(n, len(m.vertices)); i >= 0 && i < len(t.positions) {
+		pos = t.positions[i]
+	}
+	if i := vertexIndex(n, len(m.vertices)); i >= 0 && i < len(t.normals) {
+		norm = t.normals[i]
+	}
+	return pos, norm
+}
+
+// append the morph target offsets for a vertex which is added to the vertex buffer
+func (m *Mesh) addMorphData(vert int) {
+	if len(m.targets) > m.ntargets {
+		// targets which were added after earlier groups were built have no effect on them
+		nverts := len(m.vdata) / m.size()
+		if m.ntargets > 0 {
+			nverts = len(m.mdata) / (m.ntargets * morphSize)
+		}
+		mdata := make([]float32, 0, nverts*len(m.targets)*morphSize)
+		for i := 0; i < nverts; i++ {
+			mdata = append(mdata, m.mdata[i*m.ntargets*morphSize:(i+1)*m.ntargets*morphSize]...)
+			mdata = append(mdata, make([]float32, (len(m.targets)-m.ntargets)*morphSize)...)
+		}
+		m.mdata, m.ntargets = mdata, len(m.targets)
+	}
+	for t := range m.targets {
+		pos, norm := m.targetOffset(t, vert)
+		m.mdata = append(m.mdata, pos[0], pos[1], pos[2], norm[0], norm[1], norm[2])
+	}
+}
+
+// positions of a vertex in the buffer with each target applied fully, to extend the bounds
+func (m *Mesh) morphPoints(i int) (points []mgl32.Vec3) {
+	if i*m.ntargets*morphSize >= len(m.mdata) {
+		return nil
+	}
+	v := m.position(i)
+	for t := 0; t < m.ntargets; t++ {
+		md := m.mdata[(i*m.ntargets+t)*morphSize:]
+		if pos := (mgl32.Vec3{md[0], md[1], md[2]}); pos != (mgl32.Vec3{}) {
+			points = append(points, v.Add(pos))
+		}
+	}
+	return points
+}
+
+// share the built morph targets with a copy of the mesh, the weights can be changed separately
+func (m *Mesh) shareTargets(from *Mesh) {
+	m.ntargets = from.ntargets
+	m.mdata = from.mdata
+	m.marray = from.marray
+	m.morphWeights = append([]float32{}, from.morphWeights...)
+}
+
+// set the morph attributes and weights for the shader, unused slots are given a weight of zero
+func (m *Mesh) setMorphs(prog *glu.Program, buf *Mesh, active []int, slots int) {
+	stride := buf.ntargets * morphSize
+	for i := 0; i < slots; i++ {
+		t, w := active[0], float32(0)
+		if i < len(active) {
+			t, w = active[i], m.morphWeights[active[i]]
+		}
+		n := strconv.Itoa(i)
+		prog.SetAttrib("morphPosition"+n, buf.marray[buf.inverted], 3, stride, t*morphSize)
+		prog.SetAttrib("morphNormal"+n, buf.marray[buf.inverted], 3, stride, t*morphSize+3)
+		prog.SetArray("morphWeight", i, w)
+	}
+}
+
+// blend the targets into a copy of the vertex data and upload it if the weights have changed
+func (m *Mesh) blend(buf *Mesh, active []int) *glu.VertexArray {
+	changed := len(m.blendWeights) != len(m.morphWeights)
+	for i, w := range m.morphWeights {
+		if !changed && m.blendWeights[i] != w {
+			changed = true
+		}
+	}
+	if m.blended != nil && !changed {
+		m.blended.Enable()
+		return m.blended
+	}
+	m.blendWeights = append(m.blendWeights[:0], m.morphWeights...)
+	size, stride := buf.size(), buf.ntargets*morphSize
+	n := buf.format.offset("normal")
+	vdata := append([]float32{}, buf.vdata...)
+	for i := 0; i*size < len(vdata); i++ {
+		v, md := vdata[i*size:], buf.mdata[i*stride:]
+		for _, t := range active {
+			w := m.morphWeights[t]
+			for j := 0; j < 3; j++ {
+				v[j] += w * md[t*morphSize+j]
+				if n >= 0 {
+					v[n+j] += w * md[t*morphSize+3+j]
+				}
+			}
+		}
+		if n >= 0 {
+			normal := mgl32.Vec3{v[n], v[n+1], v[n+2]}
+			if normal.Len() > 0 {
+				normal = normal.Normalize()
+				copy(v[n:n+3], normal[:])
+			}
+		}
+	}
+	if m.blended == nil {
+		m.blended = glu.DynamicArrayBuffer(vdata, size)
+	} else {
+		m.blended.Update(vdata)
+	}
+	return m.blended
+}
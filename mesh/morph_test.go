@@ -0,0 +1,183 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/glu/gltest"
+	"gopkg.in/qml.v1/gl/glbase"
+	"strings"
+	"testing"
+)
+
+var rec *gltest.Recorder
+
+// setup returns the fake GL context with an empty call list. The same context is used for all of the tests
+// as the compiled programs are cached by the package.
+func setup() *gltest.Recorder {
+	if rec == nil {
+		rec = gltest.New()
+		glu.Init(rec)
+	}
+	glu.ResetState()
+	rec.Reset()
+	return rec
+}
+
+// square in the xy plane with targets which move it along x by 1, 2, 3...
+func square(targets int) *Mesh {
+	m := New()
+	m.AddVertex(-1, -1, 0)
+	m.AddVertex(1, -1, 0)
+	m.AddVertex(1, 1, 0)
+	m.AddVertex(-1, 1, 0)
+	m.AddNormal(0, 0, 1)
+	m.AddFace(El{1, 0, 1}, El{2, 0, 1}, El{3, 0, 1}, El{4, 0, 1})
+	for i := 0; i < targets; i++ {
+		offset := mgl32.Vec3{float32(i + 1), 0, 0}
+		m.AddTarget([]mgl32.Vec3{offset, offset, offset, offset}, nil)
+	}
+	m.Build("")
+	return m
+}
+
+func noUniforms(*glu.Program) {}
+
+func TestMorphVariant(t *testing.T) {
+	rec := setup()
+	mtl := Diffuse()
+	plain := square(0).SetMaterial(mtl)
+	morphed := square(1).SetMaterial(mtl).SetMorphWeight(0, 0.5)
+	blended := square(MaxMorphTargets + 1).SetMaterial(mtl)
+	for i := 0; i < blended.Targets(); i++ {
+		blended.SetMorphWeight(i, 1)
+	}
+	tests := []struct {
+		name   string
+		mesh   *Mesh
+		morphs bool
+	}{
+		{"plain", plain, false},
+		{"morphed", morphed, true},
+		{"plain after morphed", plain, false},
+		{"blended on cpu", blended, false},
+		{"morphed after blended", morphed, true},
+	}
+	programs := map[bool]glbase.Program{}
+	for _, test := range tests {
+		rec.Reset()
+		if err := test.mesh.Draw(noUniforms); err != nil {
+			t.Fatalf("%s: draw error %s", test.name, err)
+		}
+		if len(rec.Draws) != 1 {
+			t.Fatalf("%s: expecting 1 draw call, got %d", test.name, len(rec.Draws))
+		}
+		draw := rec.Draws[0]
+		src := rec.Programs[draw.Program].VertexShader
+		if got := strings.Contains(src, "morphWeight"); got != test.morphs {
+			t.Errorf("%s: morphing shader is %v, expecting %v", test.name, got, test.morphs)
+		}
+		if prog, ok := programs[test.morphs]; ok && prog != draw.Program {
+			t.Errorf("%s: program %d, expecting %d from the previous draw", test.name, draw.Program, prog)
+		}
+		programs[test.morphs] = draw.Program
+		if test.morphs {
+			if w := draw.Uniforms["morphWeight[0]"]; len(w) != 1 || w[0] != 0.5 {
+				t.Errorf("%s: morphWeight[0] is %v, expecting 0.5", test.name, w)
+			}
+		}
+	}
+	if programs[true] == programs[false] {
+		t.Errorf("morphed and plain meshes use the same program")
+	}
+}
+
+func TestMorphBlend(t *testing.T) {
+	rec := setup()
+	m := square(MaxMorphTargets + 1).SetMaterial(Unshaded())
+	for i := 0; i < m.Targets(); i++ {
+		m.SetMorphWeight(i, 0.5)
+	}
+	if err := m.Draw(noUniforms); err != nil {
+		t.Fatal(err)
+	}
+	// offsets of 1, 2 and 3 at half weight move the square along x by 3
+	data := rec.Floats(rec.Draws[0].Array)
+	size := m.size()
+	if len(data) != 4*size {
+		t.Fatalf("expecting 4 vertices, got %d floats", len(data))
+	}
+	for i := 0; i < len(data); i += size {
+		if x := data[i]; x != 2 && x != 4 {
+			t.Errorf("vertex %d: x=%g, expecting 2 or 4", i/size, x)
+		}
+	}
+}
+
+// square with every vertex moved by joint 0
+func skinnedSquare() *Mesh {
+	m := New().SetFormat(Format{Attributes: Normals | Joints})
+	for _, v := range []mgl32.Vec2{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		m.AddVertex(v[0], v[1], 0)
+		m.AddWeights([4]int{0, 0, 0, 0}, [4]float32{1, 0, 0, 0})
+	}
+	m.AddNormal(0, 0, 1)
+	m.AddFace(El{1, 0, 1}, El{2, 0, 1}, El{3, 0, 1}, El{4, 0, 1})
+	m.Build("")
+	return m
+}
+
+// attribute arrays enabled for morphing or skinning are disabled when the next program does not use them
+func TestStaleAttribArrays(t *testing.T) {
+	rec := setup()
+	mtl := Diffuse()
+	plain := square(0).SetMaterial(mtl)
+	tests := []struct {
+		name    string
+		mesh    *Mesh
+		attribs []string // attributes which are only used by the first mesh
+	}{
+		{"morphed", square(2).SetMaterial(mtl).SetMorphWeight(0, 0.5).SetMorphWeight(1, 0.5),
+			[]string{"morphPosition0", "morphNormal0", "morphPosition1", "morphNormal1"}},
+		{"skinned", skinnedSquare().SetMaterial(mtl), []string{"joint", "weight"}},
+		{"plain", square(0).SetMaterial(mtl), nil},
+	}
+	for _, test := range tests {
+		rec.Reset()
+		if err := test.mesh.Draw(noUniforms); err != nil {
+			t.Fatalf("%s: draw error %s", test.name, err)
+		}
+		enabled := map[glbase.Attrib]bool{}
+		for _, c := range rec.Calls {
+			if c.Name == "EnableVertexAttribArray" {
+				enabled[c.Args[0].(glbase.Attrib)] = true
+			}
+		}
+		prog := rec.Programs[rec.Draws[0].Program]
+		want := map[glbase.Attrib]bool{}
+		for _, name := range test.attribs {
+			loc, ok := prog.Attribs[name]
+			if !ok || !enabled[loc] {
+				t.Fatalf("%s: attribute %s is not enabled", test.name, name)
+			}
+			want[loc] = true
+		}
+		rec.Reset()
+		if err := plain.Draw(noUniforms); err != nil {
+			t.Fatalf("%s: draw error %s", test.name, err)
+		}
+		disabled := map[glbase.Attrib]bool{}
+		for _, c := range rec.Calls {
+			if c.Name == "DisableVertexAttribArray" {
+				disabled[c.Args[0].(glbase.Attrib)] = true
+			}
+		}
+		if len(disabled) != len(want) {
+			t.Errorf("%s: disabled %v after drawing a plain mesh, expecting %v", test.name, disabled, want)
+		}
+		for loc := range want {
+			if !disabled[loc] {
+				t.Errorf("%s: attribute array %d is still enabled", test.name, loc)
+			}
+		}
+	}
+}
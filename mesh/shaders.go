@@ -1,7 +1,34 @@
 package mesh
 
+import (
+	"fmt"
+	"strings"
+)
+
 const MaxLights = 4
 
+var shaderName = map[int]string{
+	mPointShader:        "point",
+	mUnshaded:           "unshaded",
+	mDiffuse:            "diffuse",
+	mBlinnPhong:         "blinnPhong",
+	mUnshadedTex:        "unshadedTex",
+	mDiffuseTex:         "diffuseTex",
+	mBlinnPhongTex:      "blinnPhongTex",
+	mBlinnPhongTexNorm:  "blinnPhongTexNorm",
+	mUnshadedTexCube:    "unshadedTexCube",
+	mDiffuseTexCube:     "diffuseTexCube",
+	mBlinnPhongTexCube:  "blinnPhongTexCube",
+	mBlinnPhongCubeNorm: "blinnPhongCubeNorm",
+	mWoodShader:         "wood",
+	mRoughShader:        "rough",
+	mEmissiveShader:     "emissive",
+	mMarbleShader:       "marble",
+	mUnshadedColor:      "unshadedColor",
+	mDiffuseColor:       "diffuseColor",
+	mBlinnPhongColor:    "blinnPhongColor",
+}
+
 var numSamplers = map[int]int{
 	mUnshadedTex:        1,
 	mUnshadedTexCube:    1,
@@ -32,12 +59,41 @@ uniform mat3 normalModelToCamera;
 uniform vec3 modelScale;
 
 void main() {
-	vec4 pos = modelToCamera * vec4(position, 1.0);
+	vec4 pos = modelToCamera * modelPosition();
+	gl_Position = cameraToClip * pos;
+	Normal = normalize(normalModelToCamera * modelNormal(vertexNormal()));
+	CameraSpacePos = pos.xyz;
+	Texcoord = texcoord;
+	ModelPos = vertexPosition() * modelScale;
+}
+`
+
+// as the default with the interpolated vertex color
+var vertexShaderColor = `
+attribute vec3 position;
+attribute vec3 normal;
+attribute vec2 texcoord;
+attribute vec4 color;
+
+varying vec3 Normal;
+varying vec3 CameraSpacePos;
+varying vec2 Texcoord;
+varying vec3 ModelPos;
+varying vec4 Color;
+
+uniform mat4 cameraToClip;
+uniform mat4 modelToCamera;
+uniform mat3 normalModelToCamera;
+uniform vec3 modelScale;
+
+void main() {
+	vec4 pos = modelToCamera * modelPosition();
 	gl_Position = cameraToClip * pos;
-	Normal = normalize(normalModelToCamera * normal);
+	Normal = normalize(normalModelToCamera * modelNormal(vertexNormal()));
 	CameraSpacePos = pos.xyz;
 	Texcoord = texcoord;
-	ModelPos = position * modelScale;
+	ModelPos = vertexPosition() * modelScale;
+	Color = color;
 }
 `
 
@@ -45,7 +101,7 @@ var vertexShaderTBN = `
 attribute vec3 position;
 attribute vec3 normal;
 attribute vec2 texcoord;
-attribute vec3 tangent;
+attribute vec4 tangent;
 
 varying vec3 Normal;
 varying vec3 CameraSpacePos;
@@ -60,21 +116,22 @@ uniform mat3 normalModelToCamera;
 uniform vec3 modelScale;
 
 void main() {
-	vec4 pos = modelToCamera * vec4(position, 1.0);
+	vec4 pos = modelToCamera * modelPosition();
 	gl_Position = cameraToClip * pos;
-	Normal = normalize(normalModelToCamera * normal);
+	Normal = normalize(normalModelToCamera * modelNormal(vertexNormal()));
 	CameraSpacePos = pos.xyz;
 	Texcoord = texcoord;
-	ModelPos = position * modelScale;
-	if (length(tangent) == 0.0) {
+	ModelPos = vertexPosition() * modelScale;
+	if (length(tangent.xyz) == 0.0) {
 		HasTangent = 0.0;
 	} else {
 		HasTangent = 1.0;
-	   	vec3 N = normalize(vec3(modelToCamera * vec4(normal, 0.0)));
- 		vec3 T = normalize(vec3(modelToCamera * vec4(tangent, 0.0)));
- 		T = normalize(T - dot(T, N) * N);
-   		TBN = mat3(T, cross(T, N), N);
-   	}
+		// bitangent from the normal and tangent with the handedness in w
+		vec3 N = normalize(vec3(modelToCamera * vec4(modelNormal(vertexNormal()), 0.0)));
+		vec3 T = normalize(vec3(modelToCamera * vec4(modelNormal(tangent.xyz), 0.0)));
+		T = normalize(T - dot(T, N) * N);
+		TBN = mat3(T, cross(N, T) * tangent.w, N);
+	}
 }
 `
 
@@ -90,13 +147,110 @@ uniform vec3 pointLocation;
 uniform vec2 viewport;
 
 void main() {
-	gl_Position = cameraToClip * modelToCamera * vec4(position, 1.0);
+	gl_Position = cameraToClip * modelToCamera * modelPosition();
 	vec4 pointClip = cameraToClip * vec4(pointLocation, 1.0);
 	vec3 pointNdc = pointClip.xyz / pointClip.w;
 	PointLocation = viewport * (pointNdc.xy + 1.0) / 2.0;
 }
 `
 
+// vertex shader for each material shader and the attributes it reads, as well as the position
+func vertexShaderFor(id int) (string, Attribute) {
+	switch id {
+	case mBlinnPhongTexNorm, mBlinnPhongCubeNorm:
+		return vertexShaderTBN, Normals | TexCoords | Tangents
+	case mUnshadedColor, mDiffuseColor, mBlinnPhongColor:
+		return vertexShaderColor, Normals | TexCoords | Colors
+	case mPointShader:
+		return vertexShaderPoints, 0
+	}
+	return vertexShader, Normals | TexCoords
+}
+
+// vertex shaders get the position and normal in model space from these functions
+var noSkinning = `
+vec4 modelPosition() {
+	return vec4(vertexPosition(), 1.0);
+}
+
+vec3 modelNormal(in vec3 n) {
+	return n;
+}
+`
+
+// blend the joint matrices by the vertex weights, the normal is not corrected for non-uniform scaling
+var skinning = `
+attribute vec4 joint;
+attribute vec4 weight;
+
+uniform mat4 joints[MAX_JOINTS];
+
+mat4 skinMatrix() {
+	return weight.x * joints[int(joint.x)] + weight.y * joints[int(joint.y)] +
+		weight.z * joints[int(joint.z)] + weight.w * joints[int(joint.w)];
+}
+
+vec4 modelPosition() {
+	return skinMatrix() * vec4(vertexPosition(), 1.0);
+}
+
+vec3 modelNormal(in vec3 n) {
+	mat4 s = skinMatrix();
+	return mat3(s[0].xyz, s[1].xyz, s[2].xyz) * n;
+}
+`
+
+// the position and normal before skinning, with the morph targets added if the shader blends them
+var noMorphing = `
+vec3 vertexPosition() {
+	return position;
+}
+`
+
+var noMorphingNormal = `
+vec3 vertexNormal() {
+	return normal;
+}
+`
+
+// morph target attributes and weights for the given number of targets
+func morphing(targets int) string {
+	var decl, pos, norm string
+	for i := 0; i < targets; i++ {
+		decl += fmt.Sprintf("attribute vec3 morphPosition%d;\nattribute vec3 morphNormal%d;\n", i, i)
+		pos += fmt.Sprintf(" + morphWeight[%d] * morphPosition%d", i, i)
+		norm += fmt.Sprintf(" + morphWeight[%d] * morphNormal%d", i, i)
+	}
+	return fmt.Sprintf(`
+%s
+uniform float morphWeight[%d];
+
+vec3 vertexPosition() {
+	return position%s;
+}
+
+vec3 vertexNormal() {
+	return normalize(normal%s);
+}
+`, decl, targets, pos, norm)
+}
+
+// insert the morphing and skinning functions before the main function of the vertex shader
+func withVariant(src string, v Variant, normals bool) string {
+	funcs := noMorphing
+	if v.Morphs > 0 {
+		funcs = morphing(v.Morphs)
+	} else if normals {
+		funcs += noMorphingNormal
+	}
+	if v.Format.Has(Joints) {
+		funcs += fmt.Sprintf("\n#define MAX_JOINTS %d\n", MaxJoints) + skinning
+	} else {
+		funcs += noSkinning
+	}
+	return strings.Replace(src, "\nvoid main() {", funcs+"\nvoid main() {", 1)
+}
+
 var fragShaderHead = `
 varying vec3 Normal;
 varying vec3 CameraSpacePos;
@@ -339,6 +493,31 @@ void main() {
 	vec3 color = blinnPhongLighting(N2, objectColor.rgb*C.rgb, spec);
 	gammaCorrect(vec4(color, objectColor.a*C.a));
 }
+`,
+	mUnshadedColor: fragShaderHead + `
+varying vec4 Color;
+
+void main() {
+	gammaCorrect(objectColor * Color);
+}
+`,
+	mDiffuseColor: fragShaderHead + diffuseLighting + `
+varying vec4 Color;
+
+void main() {
+	vec4 C = objectColor * Color;
+	vec3 color = diffuseLighting(Normal, C.rgb);
+	gammaCorrect(vec4(color, C.a));
+}
+`,
+	mBlinnPhongColor: fragShaderHead + blinnPhongLighting + `
+varying vec4 Color;
+
+void main() {
+	vec4 C = objectColor * Color;
+	vec3 color = blinnPhongLighting(Normal, C.rgb, specularColor);
+	gammaCorrect(vec4(color, C.a));
+}
 `,
 	mWoodShader: fragShaderHead + blinnPhongLighting + noise3D + `
 uniform sampler2D tex0;
@@ -49,6 +49,7 @@ func Point(pointSize int) *Mesh {
 	m.AddNormal(0, 0, 1)
 	m.AddFace(El{4, 1, 1}, El{3, 2, 1}, El{2, 3, 1}, El{1, 4, 1})
 	m.Build("")
+	m.source = Source{Shape: "point", Detail: pointSize}
 	cache[key{mPoint, 0}] = m
 	return m
 }
@@ -71,6 +72,7 @@ func Plane() *Mesh {
 	m.AddNormal(0, 1, 0)
 	m.AddFace(El{1, 1, 1}, El{2, 2, 1}, El{3, 3, 1}, El{4, 4, 1})
 	m.Build("")
+	m.source = Source{Shape: "plane"}
 	cache[key{mPlane, 0}] = m
 	return m
 }
@@ -107,6 +109,7 @@ func Cube() *Mesh {
 	m.AddFace(El{2, 1, 5}, El{6, 2, 5}, El{7, 3, 5}, El{3, 4, 5})
 	m.AddFace(El{6, 1, 6}, El{5, 2, 6}, El{8, 3, 6}, El{7, 4, 6})
 	m.Build("")
+	m.source = Source{Shape: "cube"}
 	cache[key{mCube, 0}] = m
 	return m
 }
@@ -145,6 +148,7 @@ func Prism() *Mesh {
 	m.AddFace(El{2, 3, 4}, El{5, 5, 4}, El{1, 2, 4})
 	m.AddFace(El{4, 3, 5}, El{6, 5, 5}, El{3, 2, 5})
 	m.Build("")
+	m.source = Source{Shape: "prism"}
 	cache[key{mPrism, 0}] = m
 	return m
 }
@@ -159,6 +163,7 @@ func Circle(segments int) *Mesh {
 	pts := getCircle(segments)
 	doCircle(m, pts, 0, 1)
 	m.Build("")
+	m.source = Source{Shape: "circle", Detail: segments}
 	cache[key{mCircle, segments}] = m
 	return m
 }
@@ -228,6 +233,7 @@ func Cylinder(segments int) *Mesh {
 	m.AddFace(El{base + segments, -3, -1}, El{top + segments, -4, -1},
 		El{top + 1, -2, -segments}, El{base + 1, -1, -segments})
 	m.Build("")
+	m.source = Source{Shape: "cylinder", Detail: segments}
 	cache[key{mCylinder, segments}] = m
 	return m
 }
@@ -275,6 +281,7 @@ func Cone(segments int) *Mesh {
 		m.AddFace(El{base + segments, -2, -1}, El{1, -1, -1}, El{base + 1, 3, -2 * segments})
 	}
 	m.Build("")
+	m.source = Source{Shape: "cone", Detail: segments}
 	cache[key{mCone, segments}] = m
 	return m
 }
@@ -290,6 +297,7 @@ func Icosohedron() *Mesh {
 	faces := doIcosohedron(m)
 	m.addElementTriangles(faces, false)
 	m.Build("")
+	m.source = Source{Shape: "icosohedron"}
 	cache[key{mIcosohedron, 0}] = m
 	return m
 }
@@ -373,6 +381,7 @@ func Sphere(recursionLevel int) *Mesh {
 	}
 	m.addElementTriangles(faces, true)
 	m.Build("")
+	m.source = Source{Shape: "sphere", Detail: recursionLevel}
 	cache[key{mSphere, recursionLevel}] = m
 	return m
 }
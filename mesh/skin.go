@@ -0,0 +1,105 @@
+package mesh
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MaxJoints is the number of joint matrices which can be used by one draw call. This keeps the matrix
+// palette within the 128 vertex shader uniform vectors that OpenGL ES 2 guarantees. Meshes with larger
+// skeletons are split into groups which each use at most this many joints.
+const MaxJoints = 24
+
+// joint indices and weights for one vertex
+type jointWeights struct {
+	joints  [4]int
+	weights [4]float32
+}
+
+// AddWeights sets the joints which move the vertex with the same index and how much each one affects it.
+// The weights are normalized so they add up to one, unused joints should have zero weight. Once weights
+// have been added to a mesh every vertex must have them.
+func (m *Mesh) AddWeights(joints [4]int, weights [4]float32) int {
+	m.require(Joints, "AddWeights")
+	sum := weights[0] + weights[1] + weights[2] + weights[3]
+	if sum <= 0 {
+		panic("AddWeights: weights must add up to more than zero")
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	m.weights = append(m.weights, jointWeights{joints: joints, weights: weights})
+	return len(m.weights)
+}
+
+// Skinned returns true if the vertices are moved by joints
+func (m *Mesh) Skinned() bool {
+	return m.format.Has(Joints)
+}
+
+// SetPose sets the joint matrices used to draw a skinned mesh, indexed by the joint numbers passed to
+// AddWeights. Each one transforms from the bind pose to the current pose in model space. Joints without a
+// matrix are left in the bind pose.
+func (m *Mesh) SetPose(mats []mgl32.Mat4) *Mesh {
+	m.pose = mats
+	return m
+}
+
+func (m *Mesh) jointMatrix(joint int) mgl32.Mat4 {
+	if joint < len(m.pose) {
+		return m.pose[joint]
+	}
+	return mgl32.Ident4()
+}
+
+// size of each vertex in the vertex buffer
+func (m *Mesh) size() int {
+	return m.format.Size()
+}
+
+func (m *Mesh) weight(n int) jointWeights {
+	switch {
+	case n > 0 && n <= len(m.weights):
+		return m.weights[n-1]
+	case n < 0 && len(m.weights)+n >= 0:
+		return m.weights[len(m.weights)+n]
+	}
+	panic(fmt.Sprintf("missing joint weights for vertex %d", n))
+}
+
+// split the triangles into batches which each use no more than MaxJoints joints
+func (m *Mesh) batches() (batches [][]el2, palettes [][]int) {
+	var batch []el2
+	var palette []int
+	for i := 0; i+2 < len(m.elements); i += 3 {
+		tri := m.elements[i : i+3]
+		added := []int{}
+		for _, el := range tri {
+			w := m.weight(el.Vert)
+			for j, joint := range w.joints {
+				if w.weights[j] > 0 && !contains(palette, joint) && !contains(added, joint) {
+					added = append(added, joint)
+				}
+			}
+		}
+		if len(palette)+len(added) > MaxJoints {
+			batches = append(batches, batch)
+			palettes = append(palettes, palette)
+			batch, palette = nil, nil
+			i -= 3
+			continue
+		}
+		palette = append(palette, added...)
+		batch = append(batch, tri...)
+	}
+	return append(batches, batch), append(palettes, palette)
+}
+
+func contains(list []int, val int) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
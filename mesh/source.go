@@ -0,0 +1,68 @@
+package mesh
+
+import (
+	"fmt"
+)
+
+// Source type describes how a mesh was created so that it can be saved and created again with FromSource.
+// Either Shape is the name of one of the built in shapes or File is the path of an obj file.
+type Source struct {
+	Shape    string `json:"shape,omitempty"`
+	Detail   int    `json:"detail,omitempty"` // segments for circle, cylinder or cone, recursion level for sphere or point size
+	File     string `json:"file,omitempty"`
+	Part     string `json:"part,omitempty"` // group name from the obj file
+	Inverted bool   `json:"inverted,omitempty"`
+}
+
+// Source returns the description of the mesh, this is empty if the mesh was built directly or loaded with
+// LoadObj from a reader.
+func (m *Mesh) Source() Source {
+	return m.source
+}
+
+// FromSource creates a new mesh from its description. Each call with a file name loads the file again, so to
+// get several parts of the same model load it once and call Part.
+func FromSource(src Source) (m *Mesh, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("FromSource: %v", r)
+		}
+	}()
+	switch {
+	case src.File != "":
+		if m, err = LoadObjFile(src.File); err != nil {
+			return nil, err
+		}
+		if src.Part != "" {
+			m = m.Part(src.Part)
+		}
+	case src.Shape == "point":
+		m = Point(src.Detail)
+	case src.Shape == "plane":
+		m = Plane()
+	case src.Shape == "cube":
+		m = Cube()
+	case src.Shape == "prism":
+		m = Prism()
+	case src.Shape == "circle":
+		m = Circle(src.Detail)
+	case src.Shape == "cylinder":
+		m = Cylinder(src.Detail)
+	case src.Shape == "cone":
+		m = Cone(src.Detail)
+	case src.Shape == "icosohedron":
+		m = Icosohedron()
+	case src.Shape == "sphere":
+		m = Sphere(src.Detail)
+	default:
+		return nil, fmt.Errorf("FromSource: no shape called %q", src.Shape)
+	}
+	if src.File == "" {
+		// the first call to a shape function returns the cached mesh, so take a copy to keep the cache unchanged
+		m = m.Clone()
+	}
+	if src.Inverted {
+		m = m.Invert()
+	}
+	return m, nil
+}
@@ -0,0 +1,119 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Tangents are calculated in the same way as MikkTSpace so that normal maps which were baked by other tools
+// match. Each vertex has a unit tangent along the u texture axis with a handedness in w, and the shader
+// gets the bitangent from cross(normal, tangent) * w, so faces with mirrored texture coordinates are lit
+// the right way round.
+
+// tangent along u for a triangle and if the texture mapping keeps its orientation, ok is false if the
+// texture coordinates are degenerate. The v axis is flipped as the texture coordinates start at the top left
+// of the image while other tools have v going up.
+func getTangent(vtx [3]mgl32.Vec3, tex [3]mgl32.Vec2) (tangent mgl32.Vec3, orient, ok bool) {
+	t1, t2 := tex[1].Sub(tex[0]), tex[2].Sub(tex[0])
+	t1[1], t2[1] = -t1[1], -t2[1]
+	area := t1[0]*t2[1] - t1[1]*t2[0]
+	orient = area > 0
+	if area == 0 {
+		return tangent, orient, false
+	}
+	e1, e2 := vtx[1].Sub(vtx[0]), vtx[2].Sub(vtx[0])
+	tangent = e1.Mul(t2[1]).Sub(e2.Mul(t1[1]))
+	if !orient {
+		tangent = tangent.Mul(-1)
+	}
+	if tangent.Len() == 0 {
+		return tangent, orient, false
+	}
+	return tangent.Normalize(), orient, true
+}
+
+// component of v at right angles to the unit vector n, normalised, or zero if there is none
+func perpendicular(v, n mgl32.Vec3) mgl32.Vec3 {
+	v = v.Sub(n.Mul(n.Dot(v)))
+	if l := v.Len(); l > epsilon {
+		return v.Mul(1 / l)
+	}
+	return mgl32.Vec3{}
+}
+
+// set the tangent for each element of the faces added since the last Build. The tangents of the triangles at
+// each corner with the same position, normal and texture coordinates and the same handedness are projected
+// onto the plane of the normal and averaged, weighted by the angle at the corner. Triangles with degenerate
+// texture coordinates use the tangent of the others at the vertex. Faces with no texture coordinates have no
+// tangent so they are not normal mapped.
+func (m *Mesh) buildTangents() {
+	type corner struct {
+		pos, norm mgl32.Vec3
+		tex       mgl32.Vec2
+		orient    bool
+	}
+	corners := make([]corner, len(m.elements))
+	mapped := make([]bool, len(m.elements))
+	sum := map[corner]mgl32.Vec3{}
+	for base := 0; base+3 <= len(m.elements); base += 3 {
+		tri := m.elements[base : base+3]
+		if tri[0].Tex == 0 || tri[1].Tex == 0 || tri[2].Tex == 0 {
+			continue
+		}
+		var vtx [3]mgl32.Vec3
+		var tex [3]mgl32.Vec2
+		for i, el := range tri {
+			vtx[i], tex[i] = m.vertex(el.Vert), m.texcoord(el.Tex)
+		}
+		tangent, orient, ok := getTangent(vtx, tex)
+		for i, el := range tri {
+			n := m.normal(el.Norm)
+			if n.Len() > 0 {
+				n = n.Normalize()
+			}
+			c := corner{pos: vtx[i], norm: n, tex: tex[i], orient: orient}
+			corners[base+i], mapped[base+i] = c, true
+			if !ok {
+				continue
+			}
+			e1 := perpendicular(vtx[(i+1)%3].Sub(vtx[i]), n)
+			e2 := perpendicular(vtx[(i+2)%3].Sub(vtx[i]), n)
+			cos := float64(e1.Dot(e2))
+			angle := float32(math.Acos(math.Max(-1, math.Min(1, cos))))
+			sum[c] = sum[c].Add(perpendicular(tangent, n).Mul(angle))
+		}
+	}
+	index := map[mgl32.Vec4]int{}
+	for i := range m.elements {
+		m.elements[i].tang = 0
+		if !mapped[i] {
+			continue
+		}
+		c := corners[i]
+		t := sum[c]
+		if t.Len() <= epsilon {
+			// degenerate triangle at this corner
+			c.orient = !c.orient
+			t = sum[c]
+		}
+		if t.Len() <= epsilon {
+			// no triangles at the vertex have a tangent so choose any direction
+			c.orient = true
+			if t = perpendicular(mgl32.Vec3{1, 0, 0}, c.norm); t.Len() == 0 {
+				t = perpendicular(mgl32.Vec3{0, 1, 0}, c.norm)
+			}
+		}
+		w := float32(1)
+		if !c.orient {
+			w = -1
+		}
+		tangent := t.Normalize().Vec4(w)
+		n, ok := index[tangent]
+		if !ok {
+			m.tangents = append(m.tangents, tangent)
+			n = len(m.tangents)
+			index[tangent] = n
+		}
+		m.elements[i].tang = n
+	}
+}
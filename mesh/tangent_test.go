@@ -0,0 +1,166 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// add a quad with its own vertices and the given texture coordinates and normal, returns the index of its
+// first element
+func addQuad(m *Mesh, pos [4]mgl32.Vec3, tex [4]mgl32.Vec2, n mgl32.Vec3) int {
+	norm := m.AddNormal(n[0], n[1], n[2])
+	var el [4]El
+	for i, p := range pos {
+		el[i] = El{Vert: m.AddVertex(p[0], p[1], p[2]), Tex: m.AddTexCoord(tex[i][0], tex[i][1]), Norm: norm}
+	}
+	base := len(m.elements)
+	m.AddFace(el[:]...)
+	return base
+}
+
+// tangent of each element after building the tangents
+func tangents(m *Mesh) []mgl32.Vec4 {
+	m.buildTangents()
+	list := make([]mgl32.Vec4, len(m.elements))
+	for i, el := range m.elements {
+		if el.tang > 0 {
+			list[i] = m.tangents[el.tang-1]
+		}
+	}
+	return list
+}
+
+// tangents at the corners of a quad added by addQuad, the quad is split into elements 0, 1, 2 and 0, 2, 3
+func quadTangents(t []mgl32.Vec4, base int) [4]mgl32.Vec4 {
+	return [4]mgl32.Vec4{t[base], t[base+1], t[base+2], t[base+5]}
+}
+
+func vec4Near(a, b mgl32.Vec4) bool {
+	return a.Sub(b).Len() < 1e-5
+}
+
+func TestTangentMirrored(t *testing.T) {
+	m := New().SetFormat(Format{Attributes: Normals | TexCoords | Tangents})
+	up := mgl32.Vec3{0, 0, 1}
+	// u goes from 0 at x=0 to 1 at the outside edge of each quad, so the left one is mirrored
+	right := addQuad(m, [4]mgl32.Vec3{{0, -1, 0}, {2, -1, 0}, {2, 1, 0}, {0, 1, 0}},
+		[4]mgl32.Vec2{{0, 1}, {1, 1}, {1, 0}, {0, 0}}, up)
+	left := addQuad(m, [4]mgl32.Vec3{{-2, -1, 0}, {0, -1, 0}, {0, 1, 0}, {-2, 1, 0}},
+		[4]mgl32.Vec2{{1, 1}, {0, 1}, {0, 0}, {1, 0}}, up)
+	tang := tangents(m)
+	for i, got := range quadTangents(tang, right) {
+		if want := (mgl32.Vec4{1, 0, 0, 1}); !vec4Near(got, want) {
+			t.Errorf("right quad corner %d: tangent %v, expecting %v", i, got, want)
+		}
+	}
+	for i, got := range quadTangents(tang, left) {
+		if want := (mgl32.Vec4{-1, 0, 0, -1}); !vec4Near(got, want) {
+			t.Errorf("mirrored quad corner %d: tangent %v, expecting %v", i, got, want)
+		}
+	}
+	// the bitangent points up the texture on both sides of the seam
+	for _, base := range []int{right, left} {
+		tn := tang[base]
+		if b := up.Cross(tn.Vec3()).Mul(tn[3]); !b.ApproxEqual(mgl32.Vec3{0, 1, 0}) {
+			t.Errorf("quad %d: bitangent is %v", base, b)
+		}
+	}
+}
+
+func TestTangentDegenerate(t *testing.T) {
+	m := New().SetFormat(Format{Attributes: Normals | TexCoords | Tangents})
+	up := mgl32.Vec3{0, 0, 1}
+	// u goes along +y so the tangent is not the fallback direction along x
+	quad := addQuad(m, [4]mgl32.Vec3{{-1, -1, 0}, {1, -1, 0}, {1, 1, 0}, {-1, 1, 0}},
+		[4]mgl32.Vec2{{0, 0}, {0, 1}, {1, 1}, {1, 0}}, up)
+	// triangle on the right edge of the quad with all of its texture coordinates on a line
+	norm := m.AddNormal(0, 0, 1)
+	tri := len(m.elements)
+	m.AddFace(El{Vert: 2, Tex: 2, Norm: norm}, El{Vert: m.AddVertex(2, 0, 0), Tex: m.AddTexCoord(0.5, 1), Norm: norm},
+		El{Vert: 3, Tex: 3, Norm: norm})
+	tang := tangents(m)
+	want := mgl32.Vec4{0, 1, 0, 1}
+	for i, got := range quadTangents(tang, quad) {
+		if !vec4Near(got, want) {
+			t.Errorf("quad corner %d: tangent %v, expecting %v", i, got, want)
+		}
+	}
+	// corners shared with the quad use its tangent
+	for _, i := range []int{tri, tri + 2} {
+		if got := tang[i]; !vec4Near(got, want) {
+			t.Errorf("degenerate triangle element %d: tangent %v, expecting %v from the quad", i, got, want)
+		}
+	}
+	// the corner on its own still gets a unit tangent in the plane
+	if got := tang[tri+1]; abs(got.Vec3().Len()-1) > 1e-5 || abs(got.Vec3().Dot(up)) > 1e-5 || got[3] != 1 {
+		t.Errorf("degenerate triangle corner: tangent %v", got)
+	}
+}
+
+func TestTangentHardEdge(t *testing.T) {
+	m := New().SetFormat(Format{Attributes: Normals | TexCoords | Tangents})
+	// front and right faces of a cube with the texture wrapped around the corner, so the vertices on the
+	// edge have the same position and texture coordinates but different normals
+	front := addQuad(m, [4]mgl32.Vec3{{-1, -1, 1}, {1, -1, 1}, {1, 1, 1}, {-1, 1, 1}},
+		[4]mgl32.Vec2{{0, 1}, {1, 1}, {1, 0}, {0, 0}}, mgl32.Vec3{0, 0, 1})
+	right := addQuad(m, [4]mgl32.Vec3{{1, -1, 1}, {1, -1, -1}, {1, 1, -1}, {1, 1, 1}},
+		[4]mgl32.Vec2{{1, 1}, {2, 1}, {2, 0}, {1, 0}}, mgl32.Vec3{1, 0, 0})
+	tang := tangents(m)
+	for i, got := range quadTangents(tang, front) {
+		if want := (mgl32.Vec4{1, 0, 0, 1}); !vec4Near(got, want) {
+			t.Errorf("front corner %d: tangent %v, expecting %v", i, got, want)
+		}
+	}
+	for i, got := range quadTangents(tang, right) {
+		if want := (mgl32.Vec4{0, 0, -1, 1}); !vec4Near(got, want) {
+			t.Errorf("right corner %d: tangent %v, expecting %v", i, got, want)
+		}
+	}
+}
+
+// For flat faces with the texture axes at right angles MikkTSpace gives the direction of increasing u as
+// the tangent, with w = 1 if cross(normal, tangent) is the direction of increasing v with v going up the
+// image, or -1 if the mapping is mirrored. The handedness comes from the winding of the texture coordinates
+// so the faces must be counter clockwise around their normals.
+func TestTangentCube(t *testing.T) {
+	faces := []struct {
+		normal, u, v mgl32.Vec3 // v is up the texture
+	}{
+		{mgl32.Vec3{0, 0, 1}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 1, 0}},
+		{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, 1, 0}},
+		{mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, 0, 0}, mgl32.Vec3{0, 1, 0}},
+		{mgl32.Vec3{-1, 0, 0}, mgl32.Vec3{0, 0, 1}, mgl32.Vec3{0, 1, 0}},
+		{mgl32.Vec3{0, 1, 0}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, -1}},
+		// mirrored
+		{mgl32.Vec3{0, -1, 0}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, -1}},
+	}
+	m := New().SetFormat(Format{Attributes: Normals | TexCoords | Tangents})
+	bases := []int{}
+	for _, f := range faces {
+		var pos [4]mgl32.Vec3
+		var tex [4]mgl32.Vec2
+		for i, st := range [4]mgl32.Vec2{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+			pos[i] = f.normal.Add(f.u.Mul(st[0])).Add(f.v.Mul(st[1]))
+			// texture coordinates start at the top left of the image
+			tex[i] = mgl32.Vec2{(st[0] + 1) / 2, (1 - st[1]) / 2}
+		}
+		if f.u.Cross(f.v).Dot(f.normal) < 0 {
+			pos[1], pos[3] = pos[3], pos[1]
+			tex[1], tex[3] = tex[3], tex[1]
+		}
+		bases = append(bases, addQuad(m, pos, tex, f.normal))
+	}
+	tang := tangents(m)
+	for i, f := range faces {
+		w := float32(1)
+		if f.normal.Cross(f.u).Dot(f.v) < 0 {
+			w = -1
+		}
+		want := f.u.Vec4(w)
+		for j, got := range quadTangents(tang, bases[i]) {
+			if !vec4Near(got, want) {
+				t.Errorf("face %d corner %d: tangent %v, expecting %v", i, j, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package physics
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/collision"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"math"
+)
+
+// Shape of the collision volume for a body, which is derived from the bounds or vertices of the mesh
+type Shape int
+
+const (
+	Sphere Shape = iota
+	Box
+	ConvexHull
+)
+
+func (s Shape) String() string {
+	switch s {
+	case Sphere:
+		return "sphere"
+	case Box:
+		return "box"
+	default:
+		return "convex hull"
+	}
+}
+
+// Body type is a rigid body attached to a scene item. Position is the world space location of the center of
+// the mesh bounds. Bodies with zero mass are static and are not moved by the simulation, though they can be
+// repositioned by setting Position and Orientation directly.
+type Body struct {
+	Item            *scene.Item
+	Shape           Shape
+	Mass            float32
+	Restitution     float32
+	Friction        float32
+	Position        mgl32.Vec3
+	Orientation     mgl32.Quat
+	Velocity        mgl32.Vec3
+	AngularVelocity mgl32.Vec3
+	invMass         float32
+	invInertia      mgl32.Vec3 // inverse of the principal moments of inertia
+	center          mgl32.Vec3 // center of the bounds in model space
+	scale           mgl32.Vec3
+	radius          float32
+	verts           []mgl32.Vec3 // vertices in body space for boxes and hulls
+	tris            [][3]mgl32.Vec3
+	planes          []plane
+	// world space data updated at the start of each step
+	world struct {
+		verts  collision.Hull
+		tris   [][3]mgl32.Vec3
+		planes []plane
+		bounds mesh.Bounds
+		invI   mgl32.Mat3
+	}
+}
+
+// face plane where points p with normal.Dot(p) > dist are outside
+type plane struct {
+	normal mgl32.Vec3
+	dist   float32
+}
+
+// create a new body from the item's current position, rotation and scale
+func newBody(item *scene.Item, shape Shape, mass float32) *Body {
+	b := &Body{Item: item, Shape: shape, Mass: mass, Restitution: 0.2, Friction: 0.5}
+	b.Orientation, b.scale = item.Rotation(), item.Scaling()
+	bounds := item.Mesh.Bounds()
+	b.center = bounds.Center()
+	b.Position = mgl32.TransformCoordinate(b.center, item.Mat4())
+	switch shape {
+	case Sphere:
+		b.radius = bounds.Radius * max32(b.scale[0], max32(b.scale[1], b.scale[2]))
+	case ConvexHull:
+		points := item.Mesh.Points()
+		for i, p := range points {
+			points[i] = vmul(p.Sub(b.center), b.scale)
+		}
+		if b.verts, b.tris = collision.ConvexHull(points); b.verts == nil {
+			// flat meshes do not have a hull so use a box instead
+			b.Shape = Box
+		}
+	}
+	if b.Shape == Box {
+		b.box(vmul(bounds.Size(), b.scale).Mul(0.5))
+	}
+	if b.Shape != Sphere {
+		b.planes = facePlanes(b.tris)
+	}
+	b.SetMass(mass)
+	return b
+}
+
+// SetMass updates the mass and the moment of inertia, a mass of zero makes the body static.
+func (b *Body) SetMass(mass float32) *Body {
+	b.Mass = mass
+	if mass <= 0 {
+		b.invMass, b.invInertia = 0, mgl32.Vec3{}
+		return b
+	}
+	b.invMass = 1 / mass
+	if b.Shape == Sphere {
+		i := 0.4 * mass * b.radius * b.radius
+		b.invInertia = mgl32.Vec3{1 / i, 1 / i, 1 / i}
+		return b
+	}
+	// boxes and hulls use the moments of inertia of the bounding box in body space
+	s := b.extent()
+	for i := 0; i < 3; i++ {
+		j, k := (i+1)%3, (i+2)%3
+		if in := mass * (s[j]*s[j] + s[k]*s[k]) / 12; in > 0 {
+			b.invInertia[i] = 1 / in
+		}
+	}
+	return b
+}
+
+// size of the box around the body space vertices
+func (b *Body) extent() mgl32.Vec3 {
+	lo, hi := b.verts[0], b.verts[0]
+	for _, p := range b.verts[1:] {
+		for i := 0; i < 3; i++ {
+			lo[i] = min32(lo[i], p[i])
+			hi[i] = max32(hi[i], p[i])
+		}
+	}
+	return hi.Sub(lo)
+}
+
+// Static checks if the body has zero mass
+func (b *Body) Static() bool {
+	return b.invMass == 0
+}
+
+// ApplyImpulse changes the velocity of the body by applying an impulse at a point in world space
+func (b *Body) ApplyImpulse(impulse, point mgl32.Vec3) *Body {
+	if b.Static() {
+		return b
+	}
+	b.update()
+	b.applyImpulse(impulse, point.Sub(b.Position))
+	return b
+}
+
+func (b *Body) applyImpulse(impulse, r mgl32.Vec3) {
+	b.Velocity = b.Velocity.Add(impulse.Mul(b.invMass))
+	b.AngularVelocity = b.AngularVelocity.Add(b.world.invI.Mul3x1(r.Cross(impulse)))
+}
+
+// velocity of a point on the body at offset r from the center
+func (b *Body) pointVelocity(r mgl32.Vec3) mgl32.Vec3 {
+	return b.Velocity.Add(b.AngularVelocity.Cross(r))
+}
+
+// box with the given half size centered on the origin
+func (b *Body) box(half mgl32.Vec3) {
+	b.verts = b.verts[:0]
+	for i := 0; i < 8; i++ {
+		var p mgl32.Vec3
+		for j := 0; j < 3; j++ {
+			p[j] = half[j]
+			if i&(1<<uint(j)) == 0 {
+				p[j] = -half[j]
+			}
+		}
+		b.verts = append(b.verts, p)
+	}
+	// two triangles for each face, ordered counter clockwise around the outward normal
+	b.tris = nil
+	for axis := 0; axis < 3; axis++ {
+		u, v := (axis+1)%3, (axis+2)%3
+		for _, sign := range []float32{-1, 1} {
+			var quad [4]mgl32.Vec3
+			for i, uv := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+				quad[i][axis] = sign * half[axis]
+				quad[i][u] = uv[0] * half[u]
+				quad[i][v] = uv[1] * half[v]
+			}
+			if sign < 0 {
+				quad[1], quad[3] = quad[3], quad[1]
+			}
+			b.tris = append(b.tris, [3]mgl32.Vec3{quad[0], quad[1], quad[2]}, [3]mgl32.Vec3{quad[0], quad[2], quad[3]})
+		}
+	}
+}
+
+// distinct planes of the faces, where coplanar triangles are merged
+func facePlanes(tris [][3]mgl32.Vec3) []plane {
+	planes := []plane{}
+next:
+	for _, tri := range tris {
+		n := tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0]))
+		if n.Len() == 0 {
+			continue
+		}
+		p := plane{normal: n.Normalize()}
+		p.dist = p.normal.Dot(tri[0])
+		for _, p2 := range planes {
+			if p2.normal.Dot(p.normal) > 1-1e-4 && abs(p2.dist-p.dist) < 1e-4 {
+				continue next
+			}
+		}
+		planes = append(planes, p)
+	}
+	return planes
+}
+
+// update the world space geometry from the current position and orientation
+func (b *Body) update() {
+	rot := b.Orientation.Mat4().Mat3()
+	inv := mgl32.Diag3(b.invInertia)
+	b.world.invI = rot.Mul3(inv).Mul3(rot.Transpose())
+	if b.Shape == Sphere {
+		r := mgl32.Vec3{b.radius, b.radius, b.radius}
+		b.world.bounds = mesh.Bounds{Min: b.Position.Sub(r), Max: b.Position.Add(r), Radius: b.radius}
+		return
+	}
+	b.world.verts = b.world.verts[:0]
+	for _, p := range b.verts {
+		b.world.verts = append(b.world.verts, b.toWorld(p))
+	}
+	b.world.tris = b.world.tris[:0]
+	for _, tri := range b.tris {
+		b.world.tris = append(b.world.tris, [3]mgl32.Vec3{b.toWorld(tri[0]), b.toWorld(tri[1]), b.toWorld(tri[2])})
+	}
+	b.world.planes = b.world.planes[:0]
+	for _, p := range b.planes {
+		n := b.Orientation.Rotate(p.normal)
+		b.world.planes = append(b.world.planes, plane{normal: n, dist: p.dist + n.Dot(b.Position)})
+	}
+	b.world.bounds = mesh.BoundsOf(b.world.verts)
+}
+
+func (b *Body) toWorld(p mgl32.Vec3) mgl32.Vec3 {
+	return b.Position.Add(b.Orientation.Rotate(p))
+}
+
+// write the position and orientation back to the item transform, allowing for the offset of the center
+func (b *Body) setTransform() {
+	offset := b.Orientation.Rotate(vmul(b.center, b.scale))
+	b.Item.SetPosition(b.Position.Sub(offset))
+	b.Item.SetRotation(b.Orientation)
+}
+
+// advance the position and orientation by one time step
+func (b *Body) integrate(dt float32) {
+	b.Position = b.Position.Add(b.Velocity.Mul(dt))
+	w := b.AngularVelocity
+	if w.Len() > 0 {
+		dq := mgl32.Quat{V: w.Mul(0.5 * dt)}.Mul(b.Orientation)
+		b.Orientation = b.Orientation.Add(dq).Normalize()
+	}
+}
+
+func vmul(a, b mgl32.Vec3) mgl32.Vec3 {
+	return mgl32.Vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]}
+}
+
+func abs(x float32) float32 {
+	return float32(math.Abs(float64(x)))
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
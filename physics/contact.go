@@ -0,0 +1,159 @@
+package physics
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/collision"
+	"math"
+)
+
+const (
+	slop       = 0.005 // penetration which is allowed before the position is corrected
+	matchDist  = 0.02  // distance which a contact can move between steps and still be treated as the same
+	faceNormal = 0.999 // contact normals closer than this to a face normal are snapped to it
+)
+
+// contact point between two bodies with the solver state, the normal points from a towards b
+type contact struct {
+	a, b          *Body
+	point, normal mgl32.Vec3
+	depth         float32
+	ra, rb        mgl32.Vec3
+	tangent       [2]mgl32.Vec3
+	mass          [3]float32 // effective mass along the normal and tangents
+	target        float32    // velocity along the normal from restitution and position correction
+	impulse       [3]float32 // accumulated impulses
+	friction      float32
+	local         mgl32.Vec3 // point in body space of a for matching with the last step
+}
+
+type bodyPair struct {
+	a, b *Body
+}
+
+// find the contact points between two bodies
+func collide(a, b *Body) []*contact {
+	switch {
+	case a.Shape == Sphere && b.Shape == Sphere:
+		return sphereSphere(a, b)
+	case a.Shape == Sphere:
+		return spherePolytope(a, b, false)
+	case b.Shape == Sphere:
+		return spherePolytope(b, a, true)
+	default:
+		return polytopes(a, b)
+	}
+}
+
+func sphereSphere(a, b *Body) []*contact {
+	d := b.Position.Sub(a.Position)
+	dist := d.Len()
+	if dist >= a.radius+b.radius {
+		return nil
+	}
+	normal := mgl32.Vec3{0, 1, 0}
+	if dist > 0 {
+		normal = d.Mul(1 / dist)
+	}
+	depth := a.radius + b.radius - dist
+	point := a.Position.Add(normal.Mul(a.radius - depth/2))
+	return []*contact{{a: a, b: b, point: point, normal: normal, depth: depth}}
+}
+
+// sphere s against box or hull p, if swap is set then p is the first body
+func spherePolytope(s, p *Body, swap bool) []*contact {
+	c := s.Position
+	// the deepest face plane tells us if the center is inside
+	inside, face := float32(math.Inf(-1)), plane{}
+	for _, pl := range p.world.planes {
+		if d := pl.normal.Dot(c) - pl.dist; d > inside {
+			inside, face = d, pl
+		}
+	}
+	if inside >= s.radius {
+		return nil
+	}
+	var ct *contact
+	if inside <= 0 {
+		ct = &contact{normal: face.normal.Mul(-1), depth: s.radius - inside, point: c.Sub(face.normal.Mul(inside))}
+	} else {
+		best, dist := c, float32(math.Inf(1))
+		for _, tri := range p.world.tris {
+			q := collision.ClosestPoint(c, tri)
+			if d := q.Sub(c).Len(); d < dist {
+				best, dist = q, d
+			}
+		}
+		if dist >= s.radius || dist == 0 {
+			return nil
+		}
+		ct = &contact{normal: best.Sub(c).Mul(1 / dist), depth: s.radius - dist, point: best}
+	}
+	ct.a, ct.b = s, p
+	if swap {
+		ct.a, ct.b, ct.normal = p, s, ct.normal.Mul(-1)
+	}
+	return []*contact{ct}
+}
+
+// boxes and hulls use GJK and EPA to find the normal. The contact points are then the vertices of each body
+// which are inside the other one, or the EPA contact point if there are none, e.g. for crossing edges.
+func polytopes(a, b *Body) []*contact {
+	c, ok := collision.Convex(a.world.verts, b.world.verts)
+	if !ok {
+		return nil
+	}
+	n := snapNormal(c.Normal, a.world.planes, b.world.planes)
+	contacts := []*contact{}
+	add := func(p mgl32.Vec3, depth float32) {
+		for _, c := range contacts {
+			if c.point.Sub(p).Len() < matchDist {
+				// aligned faces have the same vertices inside both bodies
+				return
+			}
+		}
+		if depth > 0 {
+			contacts = append(contacts, &contact{a: a, b: b, point: p, normal: n, depth: min32(depth, c.Depth)})
+		}
+	}
+	topA := a.world.verts.Support(n).Dot(n)
+	for _, v := range b.world.verts {
+		if insidePlanes(v, a.world.planes) {
+			add(v, topA-v.Dot(n))
+		}
+	}
+	bottomB := b.world.verts.Support(n.Mul(-1)).Dot(n)
+	for _, v := range a.world.verts {
+		if insidePlanes(v, b.world.planes) {
+			add(v, v.Dot(n)-bottomB)
+		}
+	}
+	if len(contacts) == 0 {
+		add(c.Point, c.Depth)
+	}
+	return contacts
+}
+
+// use the face normal if the contact normal is very close to it, which avoids resting boxes slowly rotating
+func snapNormal(n mgl32.Vec3, pa, pb []plane) mgl32.Vec3 {
+	best, dot := n, float32(faceNormal)
+	for _, pl := range pa {
+		if d := pl.normal.Dot(n); d > dot {
+			best, dot = pl.normal, d
+		}
+	}
+	for _, pl := range pb {
+		if d := -pl.normal.Dot(n); d > dot {
+			best, dot = pl.normal.Mul(-1), d
+		}
+	}
+	return best
+}
+
+func insidePlanes(p mgl32.Vec3, planes []plane) bool {
+	for _, pl := range planes {
+		if pl.normal.Dot(p)-pl.dist > slop {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+// Package physics provides a simple rigid body simulation for scene items. Bodies are boxes, spheres or convex
+// hulls derived from the item's mesh. The world is stepped at a fixed time step and contacts are resolved with
+// sequential impulses, then the new position and orientation of each body is written to its item's Transform.
+//
+// Items should be at the top level of the scene, or in groups without a transform, as the body positions are
+// in world space. The simulation does not depend on the frame rate or a GL context, and for a given seed gives
+// the same results each time it is run, so it can be tested headlessly.
+package physics
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/scene"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Default simulation settings
+var (
+	Gravity    = mgl32.Vec3{0, -9.81, 0}
+	TimeStep   = float32(1) / 60
+	Iterations = 10
+	MaxSteps   = 5
+)
+
+const (
+	baumgarte     = 0.2 // fraction of the penetration which is corrected each step
+	bounceSpeed   = 1.0 // minimum approach speed for restitution
+	linearDamping = 0.999
+	spinDamping   = 0.995
+)
+
+// World type owns a set of rigid bodies
+type World struct {
+	Gravity    mgl32.Vec3
+	TimeStep   float32
+	Iterations int
+	bodies     []*Body
+	contacts   []*contact
+	previous   map[bodyPair][]*contact
+	rand       *rand.Rand
+	elapsed    float32
+}
+
+// NewWorld creates an empty world using the default settings. The seed is used to shuffle the order in which
+// contacts are solved each step, which stops stacks from drifting in one direction.
+func NewWorld(seed int64) *World {
+	return &World{
+		Gravity:    Gravity,
+		TimeStep:   TimeStep,
+		Iterations: Iterations,
+		rand:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Add creates a new body for the item, using its current transform for the initial position and orientation.
+// A mass of zero creates a static body.
+func (w *World) Add(item *scene.Item, shape Shape, mass float32) *Body {
+	b := newBody(item, shape, mass)
+	w.bodies = append(w.bodies, b)
+	return b
+}
+
+// Remove a body from the world, the item is not changed
+func (w *World) Remove(b *Body) {
+	for i, b2 := range w.bodies {
+		if b2 == b {
+			w.bodies = append(w.bodies[:i], w.bodies[i+1:]...)
+			return
+		}
+	}
+}
+
+// Bodies returns the list of bodies in the order they were added
+func (w *World) Bodies() []*Body {
+	return w.bodies
+}
+
+// Contacts returns the number of contact points found in the last step
+func (w *World) Contacts() int {
+	return len(w.contacts)
+}
+
+// Rand returns the world's random number generator, which can be used to set up a scene reproducibly.
+func (w *World) Rand() *rand.Rand {
+	return w.rand
+}
+
+// Update advances the simulation by the elapsed time in seconds, running as many fixed steps as needed. Any
+// remainder is carried over to the next call. At most MaxSteps are run so the simulation slows down rather
+// than falling further behind if the frame rate drops. Returns the number of steps.
+func (w *World) Update(elapsed float32) int {
+	w.elapsed += elapsed
+	steps := 0
+	for w.elapsed >= w.TimeStep && steps < MaxSteps {
+		w.Step()
+		w.elapsed -= w.TimeStep
+		steps++
+	}
+	if steps == MaxSteps {
+		w.elapsed = 0
+	}
+	return steps
+}
+
+// Step advances the simulation by one time step and updates the item transforms
+func (w *World) Step() {
+	dt := w.TimeStep
+	for _, b := range w.bodies {
+		if !b.Static() {
+			b.Velocity = b.Velocity.Add(w.Gravity.Mul(dt)).Mul(linearDamping)
+			b.AngularVelocity = b.AngularVelocity.Mul(spinDamping)
+		}
+		b.update()
+	}
+	w.findContacts()
+	for _, c := range w.contacts {
+		c.prepare(dt)
+	}
+	for _, c := range w.contacts {
+		c.apply(c.normal.Mul(c.impulse[0]).Add(c.tangent[0].Mul(c.impulse[1])).Add(c.tangent[1].Mul(c.impulse[2])))
+	}
+	w.rand.Shuffle(len(w.contacts), func(i, j int) { w.contacts[i], w.contacts[j] = w.contacts[j], w.contacts[i] })
+	for i := 0; i < w.Iterations; i++ {
+		for _, c := range w.contacts {
+			c.solve()
+		}
+	}
+	for _, b := range w.bodies {
+		if !b.Static() {
+			b.integrate(dt)
+		}
+		b.setTransform()
+	}
+}
+
+// broad phase sorts the bounds along the x axis to find pairs which may overlap
+func (w *World) findContacts() {
+	w.previous = map[bodyPair][]*contact{}
+	for _, c := range w.contacts {
+		pair := bodyPair{c.a, c.b}
+		w.previous[pair] = append(w.previous[pair], c)
+	}
+	w.contacts = nil
+	list := append([]*Body{}, w.bodies...)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].world.bounds.Min[0] < list[j].world.bounds.Min[0] })
+	for i, a := range list {
+		for _, b := range list[i+1:] {
+			if b.world.bounds.Min[0] > a.world.bounds.Max[0] {
+				break
+			}
+			if a.Static() && b.Static() || !a.world.bounds.Intersects(b.world.bounds) {
+				continue
+			}
+			for _, c := range collide(a, b) {
+				w.match(c)
+				w.contacts = append(w.contacts, c)
+			}
+		}
+	}
+}
+
+// copy the impulses from the same contact in the last step if there is one, so the solver starts from the
+// previous solution which makes stacks much more stable
+func (w *World) match(c *contact) {
+	c.local = c.a.Orientation.Conjugate().Rotate(c.point.Sub(c.a.Position))
+	pair := bodyPair{c.a, c.b}
+	for i, prev := range w.previous[pair] {
+		if prev.local.Sub(c.local).Len() < matchDist && prev.normal.Dot(c.normal) > faceNormal {
+			c.impulse = prev.impulse
+			w.previous[pair] = append(w.previous[pair][:i], w.previous[pair][i+1:]...)
+			return
+		}
+	}
+}
+
+// calculate the effective mass and target velocity before solving
+func (c *contact) prepare(dt float32) {
+	c.ra, c.rb = c.point.Sub(c.a.Position), c.point.Sub(c.b.Position)
+	c.tangent[0], c.tangent[1] = basis(c.normal)
+	dirs := [3]mgl32.Vec3{c.normal, c.tangent[0], c.tangent[1]}
+	for i, d := range dirs {
+		k := c.a.invMass + c.b.invMass
+		k += c.a.world.invI.Mul3x1(c.ra.Cross(d)).Cross(c.ra).Dot(d)
+		k += c.b.world.invI.Mul3x1(c.rb.Cross(d)).Cross(c.rb).Dot(d)
+		if k > 0 {
+			c.mass[i] = 1 / k
+		}
+	}
+	c.friction = float32(math.Sqrt(float64(c.a.Friction * c.b.Friction)))
+	// separating speed needed to bounce and to push the bodies apart
+	vn := c.relativeVelocity().Dot(c.normal)
+	c.target = baumgarte / dt * max32(c.depth-slop, 0)
+	if vn < -bounceSpeed {
+		c.target = max32(c.target, -max32(c.a.Restitution, c.b.Restitution)*vn)
+	}
+}
+
+// velocity of b relative to a at the contact point
+func (c *contact) relativeVelocity() mgl32.Vec3 {
+	return c.b.pointVelocity(c.rb).Sub(c.a.pointVelocity(c.ra))
+}
+
+// apply impulses to reach the target normal velocity, then friction limited by the normal impulse
+func (c *contact) solve() {
+	v := c.relativeVelocity()
+	lambda := (c.target - v.Dot(c.normal)) * c.mass[0]
+	old := c.impulse[0]
+	c.impulse[0] = max32(old+lambda, 0)
+	c.apply(c.normal.Mul(c.impulse[0] - old))
+	limit := c.friction * c.impulse[0]
+	for i := 1; i < 3; i++ {
+		t := c.tangent[i-1]
+		lambda := -c.relativeVelocity().Dot(t) * c.mass[i]
+		old := c.impulse[i]
+		c.impulse[i] = max32(-limit, min32(old+lambda, limit))
+		c.apply(t.Mul(c.impulse[i] - old))
+	}
+}
+
+// apply an impulse to b and the opposite impulse to a
+func (c *contact) apply(p mgl32.Vec3) {
+	if !c.a.Static() {
+		c.a.applyImpulse(p.Mul(-1), c.ra)
+	}
+	if !c.b.Static() {
+		c.b.applyImpulse(p, c.rb)
+	}
+}
+
+// two unit vectors perpendicular to n and each other
+func basis(n mgl32.Vec3) (mgl32.Vec3, mgl32.Vec3) {
+	t := mgl32.Vec3{1, 0, 0}
+	if abs(n[0]) > 0.57 {
+		t = mgl32.Vec3{0, 1, 0}
+	}
+	t1 := n.Cross(t).Normalize()
+	return t1, n.Cross(t1)
+}
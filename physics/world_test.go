@@ -0,0 +1,123 @@
+package physics
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/scene"
+	"testing"
+)
+
+// static ground with its top at y=0
+func ground(w *World) *Body {
+	item := scene.NewItem(mesh.Cube())
+	item.Translate(0, -0.1, 0).Scale(20, 0.2, 20)
+	return w.Add(item, Box, 0)
+}
+
+// the same scene as util/drop: a stack of boxes and a random mix of shapes dropped on top
+func dropScene(seed int64) *World {
+	w := NewWorld(seed)
+	rng := w.Rand()
+	ground(w)
+	for i := 0; i < 3; i++ {
+		item := scene.NewItem(mesh.Cube())
+		item.Translate(0, 0.5+float32(i), 0)
+		w.Add(item, Box, 1)
+	}
+	for i := 0; i < 8; i++ {
+		var item *scene.Item
+		shape := Shape(rng.Intn(3))
+		switch shape {
+		case Sphere:
+			item = scene.NewItem(mesh.Sphere(2))
+		case Box:
+			item = scene.NewItem(mesh.Cube())
+		default:
+			item = scene.NewItem(mesh.Icosohedron())
+		}
+		item.Translate(rng.Float32()*8-4, 2+float32(i), rng.Float32()*8-4)
+		item.Rotate(rng.Float32()*360, mgl32.Vec3{rng.Float32(), rng.Float32(), rng.Float32()}.Normalize())
+		w.Add(item, shape, 0.5+rng.Float32())
+	}
+	return w
+}
+
+func run(w *World, seconds float32) {
+	steps := int(seconds / w.TimeStep)
+	for i := 0; i < steps; i++ {
+		w.Step()
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	w1, w2 := dropScene(1), dropScene(1)
+	run(w1, 4)
+	run(w2, 4)
+	moved := false
+	for i, b1 := range w1.Bodies() {
+		b2 := w2.Bodies()[i]
+		if b1.Shape != b2.Shape || b1.Position != b2.Position || b1.Orientation != b2.Orientation ||
+			b1.Velocity != b2.Velocity || b1.AngularVelocity != b2.AngularVelocity {
+			t.Errorf("body %d: %s at %v %v, expecting %s at %v %v", i, b1.Shape, b1.Position, b1.Orientation,
+				b2.Shape, b2.Position, b2.Orientation)
+		}
+		if m1, m2 := b1.Item.Mat4(), b2.Item.Mat4(); m1 != m2 {
+			t.Errorf("body %d: item transform is %v, expecting %v", i, m1, m2)
+		}
+		if !b1.Static() && b1.Position[1] < 1.5 {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Errorf("expecting the dropped shapes to have fallen")
+	}
+	w3 := dropScene(2)
+	run(w3, 4)
+	if w3.Bodies()[len(w3.Bodies())-1].Position == w1.Bodies()[len(w1.Bodies())-1].Position {
+		t.Errorf("expecting a different seed to give a different scene")
+	}
+}
+
+func TestResting(t *testing.T) {
+	w := NewWorld(1)
+	ground(w)
+	ball := scene.NewItem(mesh.Sphere(2))
+	ball.Translate(-3, 3, 0)
+	sphere := w.Add(ball, Sphere, 1)
+	var stack []*Body
+	for i := 0; i < 3; i++ {
+		item := scene.NewItem(mesh.Cube())
+		item.Translate(3, 0.6+1.1*float32(i), 0)
+		stack = append(stack, w.Add(item, Box, 1))
+	}
+	run(w, 5)
+	const eps = 0.02
+	check := func(name string, b *Body, height float32) {
+		want := mgl32.Vec3{b.Position[0], height, b.Position[2]}
+		if b.Position.Sub(want).Len() > eps {
+			t.Errorf("%s: resting at %v, expecting height %g", name, b.Position, height)
+		}
+		if b.Velocity.Len() > eps || b.AngularVelocity.Len() > eps {
+			t.Errorf("%s: still moving with velocity %v spin %v", name, b.Velocity, b.AngularVelocity)
+		}
+		if pos := b.Item.Position(); pos.Sub(b.Position).Len() > eps {
+			t.Errorf("%s: item is at %v, expecting %v", name, pos, b.Position)
+		}
+	}
+	check("sphere", sphere, sphere.radius)
+	if d := sphere.Position.Sub(mgl32.Vec3{-3, 0, 0}); d[0]*d[0]+d[2]*d[2] > eps*eps {
+		t.Errorf("sphere: rolled to %v", sphere.Position)
+	}
+	for i, b := range stack {
+		check("box", b, 0.5+float32(i))
+		if up := b.Orientation.Rotate(mgl32.Vec3{0, 1, 0}); up[1] < 1-eps {
+			t.Errorf("box %d: tipped over with up vector %v", i, up)
+		}
+		if d := b.Position.Sub(mgl32.Vec3{3, 0, 0}); d[0]*d[0]+d[2]*d[2] > eps*eps {
+			t.Errorf("box %d: slid to %v", i, b.Position)
+		}
+	}
+	if n := w.Contacts(); n < 4*len(stack) {
+		t.Errorf("expecting at least 4 contacts under each box, got %d", n)
+	}
+}
@@ -0,0 +1,467 @@
+// Package raster is a software renderer written in pure Go which implements the glu.Context interface.
+// It does not run GLSL, instead each shader program is matched to the closest of the built in unshaded,
+// diffuse or Blinn-Phong lighting models, with optional 2D or cube map textures and vertex colors. Output is
+// depth tested, with perspective correct texture mapping, and is deterministic so it can be used for golden
+// image tests and for server side previews without a GPU.
+package raster
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"image"
+	"strings"
+)
+
+type attribPtr struct {
+	buffer  glbase.Buffer
+	size    int
+	stride  int
+	offset  int
+	enabled bool
+}
+
+type program struct {
+	vertex, fragment string
+	attribs          map[string]glbase.Attrib
+	uniforms         map[string][]float32
+	shade            shader
+	skinned          bool // vertices are moved by the joints uniform array
+	morphs           int  // number of morph target attributes blended by the vertex shader
+}
+
+type uniformRef struct {
+	prog glbase.Program
+	name string
+}
+
+// Context is a software GL context which renders to an in memory framebuffer.
+type Context struct {
+	width, height int
+	color         []uint8 // RGBA rows from the bottom up as per GL
+	depth         []float32
+	viewport      [4]int
+	clearColor    [4]float32
+	depthFunc     glbase.Enum
+	depthMask     bool
+	blend         [2]glbase.Enum
+	frontFace     glbase.Enum
+	enabled       map[glbase.Enum]bool
+	nextID        uint32
+	buffers       map[glbase.Buffer][]byte
+	bound         map[glbase.Enum]glbase.Buffer
+	attribs       map[glbase.Attrib]*attribPtr
+	shaders       map[glbase.Shader]string
+	shaderType    map[glbase.Shader]glbase.Enum
+	programs      map[glbase.Program]*program
+	uniforms      map[glbase.Uniform]uniformRef
+	program       glbase.Program
+	textures      map[glbase.Texture]*texture
+	texUnit       int
+	texBound      map[int]glbase.Texture
+	errorCode     glbase.Enum
+}
+
+// New creates a new context with a framebuffer of the given size.
+func New(width, height int) *Context {
+	c := &Context{
+		depthFunc:  glu.LESS,
+		depthMask:  true,
+		blend:      [2]glbase.Enum{glu.ONE, glu.ZERO},
+		frontFace:  glu.CCW,
+		enabled:    map[glbase.Enum]bool{},
+		buffers:    map[glbase.Buffer][]byte{},
+		bound:      map[glbase.Enum]glbase.Buffer{},
+		attribs:    map[glbase.Attrib]*attribPtr{},
+		shaders:    map[glbase.Shader]string{},
+		shaderType: map[glbase.Shader]glbase.Enum{},
+		programs:   map[glbase.Program]*program{},
+		uniforms:   map[glbase.Uniform]uniformRef{},
+		textures:   map[glbase.Texture]*texture{},
+		texBound:   map[int]glbase.Texture{},
+	}
+	c.Resize(width, height)
+	return c
+}
+
+// Resize reallocates the framebuffer and sets the viewport to cover it
+func (c *Context) Resize(width, height int) {
+	if width != c.width || height != c.height {
+		c.width, c.height = width, height
+		c.color = make([]uint8, 4*width*height)
+		c.depth = make([]float32, width*height)
+		for i := range c.depth {
+			c.depth[i] = 1
+		}
+	}
+	c.viewport = [4]int{0, 0, width, height}
+}
+
+// Image returns a copy of the framebuffer with the rows in GL order, i.e. from the bottom up. This is the
+// right way up if the scene was drawn with the inverted projection from scene.View.
+func (c *Context) Image() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, c.width, c.height))
+	copy(img.Pix, c.color)
+	return img
+}
+
+func (c *Context) id() uint32 {
+	c.nextID++
+	return c.nextID
+}
+
+func (c *Context) setError(code glbase.Enum) {
+	if c.errorCode == glu.NO_ERROR {
+		c.errorCode = code
+	}
+}
+
+func (c *Context) setUniform(loc glbase.Uniform, v ...float32) {
+	if ref, ok := c.uniforms[loc]; ok {
+		c.programs[ref.prog].uniforms[ref.name] = append([]float32{}, v...)
+	}
+}
+
+// convert buffer or texture data to bytes in little endian order
+func toBytes(data interface{}) []byte {
+	var buf bytes.Buffer
+	if data != nil {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (c *Context) ActiveTexture(texture glbase.Enum) {
+	c.texUnit = int(texture - glu.TEXTURE0)
+}
+
+func (c *Context) AttachShader(prog glbase.Program, sh glbase.Shader) {
+	if p, ok := c.programs[prog]; ok {
+		if c.shaderType[sh] == glu.VERTEX_SHADER {
+			p.vertex = c.shaders[sh]
+		} else {
+			p.fragment = c.shaders[sh]
+		}
+	}
+}
+
+func (c *Context) BindBuffer(target glbase.Enum, buffer glbase.Buffer) {
+	c.bound[target] = buffer
+}
+
+func (c *Context) BindTexture(target glbase.Enum, tex glbase.Texture) {
+	c.texBound[c.texUnit] = tex
+	if t, ok := c.textures[tex]; ok {
+		t.target = target
+	}
+}
+
+func (c *Context) BlendFunc(sfactor, dfactor glbase.Enum) {
+	c.blend = [2]glbase.Enum{sfactor, dfactor}
+}
+
+func (c *Context) BufferData(target glbase.Enum, size int, data interface{}, usage glbase.Enum) {
+	buf := make([]byte, size)
+	copy(buf, toBytes(data))
+	c.buffers[c.bound[target]] = buf
+}
+
+func (c *Context) BufferSubData(target glbase.Enum, offset, size int, data interface{}) {
+	buf := c.buffers[c.bound[target]]
+	if offset+size > len(buf) {
+		c.setError(0x501)
+		return
+	}
+	copy(buf[offset:offset+size], toBytes(data))
+}
+
+func (c *Context) Clear(mask glbase.Bitfield) {
+	if mask&glu.COLOR_BUFFER_BIT != 0 {
+		var col [4]uint8
+		for i, v := range c.clearColor {
+			col[i] = toByte(v)
+		}
+		for i := 0; i < len(c.color); i += 4 {
+			copy(c.color[i:i+4], col[:])
+		}
+	}
+	if mask&glu.DEPTH_BUFFER_BIT != 0 {
+		for i := range c.depth {
+			c.depth[i] = 1
+		}
+	}
+}
+
+func (c *Context) ClearColor(red, green, blue, alpha glbase.Clampf) {
+	c.clearColor = [4]float32{float32(red), float32(green), float32(blue), float32(alpha)}
+}
+
+func (c *Context) CompileShader(sh glbase.Shader) {}
+
+func (c *Context) CreateProgram() glbase.Program {
+	p := glbase.Program(c.id())
+	c.programs[p] = &program{attribs: map[string]glbase.Attrib{}, uniforms: map[string][]float32{}}
+	return p
+}
+
+func (c *Context) CreateShader(gltype glbase.Enum) glbase.Shader {
+	sh := glbase.Shader(c.id())
+	c.shaderType[sh] = gltype
+	return sh
+}
+
+func (c *Context) DeleteBuffers(buffers []glbase.Buffer) {
+	for _, buf := range buffers {
+		delete(c.buffers, buf)
+	}
+}
+
+func (c *Context) DeleteShader(sh glbase.Shader) {
+	delete(c.shaders, sh)
+	delete(c.shaderType, sh)
+}
+
+func (c *Context) DepthFunc(glfunc glbase.Enum) {
+	c.depthFunc = glfunc
+}
+
+func (c *Context) DepthMask(flag bool) {
+	c.depthMask = flag
+}
+
+func (c *Context) Disable(cap glbase.Enum) {
+	c.enabled[cap] = false
+}
+
+func (c *Context) DisableVertexAttribArray(index glbase.Attrib) {
+	if a, ok := c.attribs[index]; ok {
+		a.enabled = false
+	}
+}
+
+func (c *Context) DrawArrays(mode glbase.Enum, first, count int) {
+	index := make([]uint32, count)
+	for i := range index {
+		index[i] = uint32(first + i)
+	}
+	c.draw(mode, index)
+}
+
+func (c *Context) DrawElements(mode glbase.Enum, count int, gltype glbase.Enum, indices interface{}) {
+	buf, ok := c.buffers[c.bound[glu.ELEMENT_ARRAY_BUFFER]]
+	if !ok || gltype != glu.UNSIGNED_INT || 4*count > len(buf) {
+		c.setError(0x502)
+		return
+	}
+	index := make([]uint32, count)
+	for i := range index {
+		index[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+	c.draw(mode, index)
+}
+
+func (c *Context) Enable(cap glbase.Enum) {
+	c.enabled[cap] = true
+}
+
+func (c *Context) EnableVertexAttribArray(index glbase.Attrib) {
+	if a, ok := c.attribs[index]; ok {
+		a.enabled = true
+	} else {
+		c.attribs[index] = &attribPtr{enabled: true}
+	}
+}
+
+func (c *Context) FrontFace(mode glbase.Enum) {
+	c.frontFace = mode
+}
+
+func (c *Context) GenBuffers(n int) []glbase.Buffer {
+	bufs := make([]glbase.Buffer, n)
+	for i := range bufs {
+		bufs[i] = glbase.Buffer(c.id())
+	}
+	return bufs
+}
+
+func (c *Context) GenTextures(n int) []glbase.Texture {
+	tex := make([]glbase.Texture, n)
+	for i := range tex {
+		tex[i] = glbase.Texture(c.id())
+		c.textures[tex[i]] = newTexture()
+	}
+	return tex
+}
+
+func (c *Context) GenerateMipmap(target glbase.Enum) {
+	if t, ok := c.textures[c.texBound[c.texUnit]]; ok {
+		t.generateMipmap()
+	}
+}
+
+// GetAttribLocation returns -1 if the attribute is not declared in the vertex shader
+func (c *Context) GetAttribLocation(prog glbase.Program, name string) glbase.Attrib {
+	p, ok := c.programs[prog]
+	if !ok || !strings.Contains(p.vertex, " "+name+";") {
+		return -1
+	}
+	if loc, ok := p.attribs[name]; ok {
+		return loc
+	}
+	loc := glbase.Attrib(len(p.attribs))
+	p.attribs[name] = loc
+	return loc
+}
+
+func (c *Context) GetError() glbase.Enum {
+	code := c.errorCode
+	c.errorCode = glu.NO_ERROR
+	return code
+}
+
+func (c *Context) GetProgramInfoLog(prog glbase.Program) []byte {
+	return nil
+}
+
+func (c *Context) GetProgramiv(prog glbase.Program, pname glbase.Enum, params []int32) {
+	params[0] = 1
+}
+
+func (c *Context) GetShaderInfoLog(sh glbase.Shader) []byte {
+	return nil
+}
+
+func (c *Context) GetShaderiv(sh glbase.Shader, pname glbase.Enum, params []int32) {
+	params[0] = 1
+}
+
+func (c *Context) GetUniformLocation(prog glbase.Program, name string) glbase.Uniform {
+	for loc, ref := range c.uniforms {
+		if ref.prog == prog && ref.name == name {
+			return loc
+		}
+	}
+	loc := glbase.Uniform(c.id())
+	c.uniforms[loc] = uniformRef{prog: prog, name: name}
+	return loc
+}
+
+// LinkProgram selects the lighting model to use from the fragment shader source, and checks if the vertex
+// shader does skinning or blends morph targets
+func (c *Context) LinkProgram(prog glbase.Program) {
+	if p, ok := c.programs[prog]; ok {
+		p.shade = newShader(p.fragment)
+		p.skinned = strings.Contains(p.vertex, "joints[")
+		p.morphs = strings.Count(p.vertex, "attribute vec3 morphPosition")
+	}
+}
+
+// ReadPixels supports RGBA format with unsigned bytes only
+func (c *Context) ReadPixels(x, y, width, height int, format, gltype glbase.Enum, pixels interface{}) {
+	pix, ok := pixels.([]uint8)
+	if !ok || format != glu.RGBA || gltype != glu.UNSIGNED_BYTE || len(pix) < 4*width*height {
+		c.setError(0x500)
+		return
+	}
+	for row := 0; row < height; row++ {
+		if y+row < 0 || y+row >= c.height {
+			continue
+		}
+		for col := 0; col < width; col++ {
+			if x+col >= 0 && x+col < c.width {
+				src := 4 * ((y+row)*c.width + x + col)
+				copy(pix[4*(row*width+col):], c.color[src:src+4])
+			}
+		}
+	}
+}
+
+func (c *Context) ShaderSource(sh glbase.Shader, source ...string) {
+	c.shaders[sh] = strings.Join(source, "")
+}
+
+// Stencil operations are not supported
+func (c *Context) StencilFunc(glfunc glbase.Enum, ref int32, mask uint32) {}
+
+func (c *Context) StencilMask(mask uint32) {}
+
+func (c *Context) StencilOp(fail, zfail, zpass glbase.Enum) {}
+
+func (c *Context) TexImage2D(target glbase.Enum, level int, internalFormat int32, width, height, border int, format, gltype glbase.Enum, pixels interface{}) {
+	t, ok := c.textures[c.texBound[c.texUnit]]
+	if !ok || gltype != glu.UNSIGNED_BYTE || (format != glu.RGBA && format != glu.RGB) {
+		c.setError(0x500)
+		return
+	}
+	if level == 0 {
+		t.setImage(target, width, height, format, toBytes(pixels))
+	}
+}
+
+func (c *Context) TexParameteri(target, pname glbase.Enum, param int32) {
+	if t, ok := c.textures[c.texBound[c.texUnit]]; ok {
+		t.params[pname] = glbase.Enum(param)
+	}
+}
+
+func (c *Context) Uniform1f(location glbase.Uniform, v0 float32) {
+	c.setUniform(location, v0)
+}
+
+func (c *Context) Uniform1i(location glbase.Uniform, v0 int32) {
+	c.setUniform(location, float32(v0))
+}
+
+func (c *Context) Uniform2f(location glbase.Uniform, v0, v1 float32) {
+	c.setUniform(location, v0, v1)
+}
+
+func (c *Context) Uniform2i(location glbase.Uniform, v0, v1 int32) {
+	c.setUniform(location, float32(v0), float32(v1))
+}
+
+func (c *Context) Uniform3fv(location glbase.Uniform, value []float32) {
+	c.setUniform(location, value...)
+}
+
+func (c *Context) Uniform4fv(location glbase.Uniform, value []float32) {
+	c.setUniform(location, value...)
+}
+
+func (c *Context) UniformMatrix3fv(location glbase.Uniform, transpose bool, value []float32) {
+	c.setUniform(location, value...)
+}
+
+func (c *Context) UniformMatrix4fv(location glbase.Uniform, transpose bool, value []float32) {
+	c.setUniform(location, value...)
+}
+
+func (c *Context) UseProgram(prog glbase.Program) {
+	c.program = prog
+}
+
+func (c *Context) VertexAttribPointer(index glbase.Attrib, size int, gltype glbase.Enum, normalized bool, stride int, offset uintptr) {
+	if gltype != glu.FLOAT {
+		c.setError(0x500)
+		return
+	}
+	a, ok := c.attribs[index]
+	if !ok {
+		a = &attribPtr{}
+		c.attribs[index] = a
+	}
+	a.buffer = c.bound[glu.ARRAY_BUFFER]
+	a.size, a.stride, a.offset = size, stride, int(offset)
+	if a.stride == 0 {
+		a.stride = 4 * size
+	}
+}
+
+func (c *Context) Viewport(x, y, width, height int) {
+	c.viewport = [4]int{x, y, width, height}
+}
+
+var _ glu.Context = (*Context)(nil)
@@ -0,0 +1,380 @@
+package raster
+
+import (
+	"encoding/binary"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"math"
+	"strconv"
+)
+
+// varyings are packed as camera space position, normal, texcoord, model position, tangent with handedness
+// and color
+const (
+	vPos      = 0
+	vNormal   = 3
+	vTexcoord = 6
+	vModelPos = 8
+	vTangent  = 11
+	vColor    = 15
+	nvary     = 19
+)
+
+type vertex struct {
+	clip mgl32.Vec4
+	vary [nvary]float32
+}
+
+// vertex in window coordinates
+type winVertex struct {
+	x, y, z, invw float64
+	vary          *[nvary]float32
+}
+
+// per draw call state
+type drawState struct {
+	shade     shader
+	uniforms  uniforms
+	useDeriv  bool
+	cull      bool
+	ccw       bool
+	depthTest bool
+	blend     bool
+}
+
+// read an attribute for the vertex with the given index, missing components are filled in as per GL
+func (c *Context) attrib(p *program, name string, index uint32) mgl32.Vec4 {
+	v := mgl32.Vec4{0, 0, 0, 1}
+	loc, ok := p.attribs[name]
+	if !ok {
+		return v
+	}
+	a, ok := c.attribs[loc]
+	if !ok || !a.enabled {
+		return v
+	}
+	buf := c.buffers[a.buffer]
+	start := a.offset + int(index)*a.stride
+	for i := 0; i < a.size && i < 4; i++ {
+		if pos := start + 4*i; pos+4 <= len(buf) {
+			v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[pos:]))
+		}
+	}
+	return v
+}
+
+func (u uniforms) mat4(name string) (m mgl32.Mat4) {
+	copy(m[:], u[name])
+	return m
+}
+
+func (u uniforms) mat3(name string) (m mgl32.Mat3) {
+	copy(m[:], u[name])
+	return m
+}
+
+// blend the joint matrices by the vertex weights
+func (c *Context) skinMatrix(p *program, u uniforms, index uint32) (m mgl32.Mat4) {
+	joint, weight := c.attrib(p, "joint", index), c.attrib(p, "weight", index)
+	for i := range joint {
+		if weight[i] != 0 {
+			m = m.Add(u.mat4("joints[" + strconv.Itoa(int(joint[i])) + "]").Mul(weight[i]))
+		}
+	}
+	return m
+}
+
+// add the weighted morph target offsets to the position and normal
+func (c *Context) morph(p *program, u uniforms, index uint32) (pos, normal mgl32.Vec3) {
+	pos, normal = c.attrib(p, "position", index).Vec3(), c.attrib(p, "normal", index).Vec3()
+	if p.morphs == 0 {
+		return pos, normal
+	}
+	for i := 0; i < p.morphs; i++ {
+		n := strconv.Itoa(i)
+		w := u.float("morphWeight[" + n + "]")
+		pos = pos.Add(c.attrib(p, "morphPosition"+n, index).Vec3().Mul(w))
+		normal = normal.Add(c.attrib(p, "morphNormal"+n, index).Vec3().Mul(w))
+	}
+	if normal.Len() > 0 {
+		normal = normal.Normalize()
+	}
+	return pos, normal
+}
+
+// run the equivalent of the vertex shader
+func (c *Context) transform(p *program, index uint32) *vertex {
+	u := uniforms(p.uniforms)
+	modelToCamera := u.mat4("modelToCamera")
+	skin := mgl32.Ident4()
+	if p.skinned {
+		skin = c.skinMatrix(p, u, index)
+	}
+	position, normal := c.morph(p, u, index)
+	pos := modelToCamera.Mul4(skin).Mul4x1(position.Vec4(1))
+	v := &vertex{clip: u.mat4("cameraToClip").Mul4x1(pos)}
+	copy(v.vary[vPos:], pos[:3])
+	normal = u.mat3("normalModelToCamera").Mul3(skin.Mat3()).Mul3x1(normal)
+	if normal.Len() > 0 {
+		normal = normal.Normalize()
+	}
+	copy(v.vary[vNormal:], normal[:])
+	texcoord := c.attrib(p, "texcoord", index)
+	copy(v.vary[vTexcoord:], texcoord[:2])
+	modelPos := mul3(position, u.vec3("modelScale"))
+	copy(v.vary[vModelPos:], modelPos[:])
+	if p.shade.normMap {
+		t := c.attrib(p, "tangent", index)
+		tangent := modelToCamera.Mul4(skin).Mul4x1(t.Vec3().Vec4(0))
+		copy(v.vary[vTangent:], tangent[:3])
+		v.vary[vTangent+3] = t[3]
+	}
+	if p.shade.vertexColor {
+		color := c.attrib(p, "color", index)
+		copy(v.vary[vColor:], color[:])
+	}
+	return v
+}
+
+// draw triangles with the current program, other primitive types are not supported
+func (c *Context) draw(mode glbase.Enum, index []uint32) {
+	p, ok := c.programs[c.program]
+	if !ok {
+		c.setError(0x502)
+		return
+	}
+	if mode != glu.TRIANGLES {
+		c.setError(0x500)
+		return
+	}
+	d := &drawState{
+		shade:     p.shade,
+		uniforms:  uniforms(p.uniforms),
+		useDeriv:  p.shade.texture == texture2D || p.shade.normMap,
+		cull:      c.enabled[glu.CULL_FACE],
+		ccw:       c.frontFace == glu.CCW,
+		depthTest: c.enabled[glu.DEPTH_TEST],
+		blend:     c.enabled[glu.BLEND],
+	}
+	verts := map[uint32]*vertex{}
+	var tri [3]*vertex
+	for i := 0; i+2 < len(index); i += 3 {
+		for j := range tri {
+			if tri[j], ok = verts[index[i+j]]; !ok {
+				tri[j] = c.transform(p, index[i+j])
+				verts[index[i+j]] = tri[j]
+			}
+		}
+		poly := clipPolygon([]*vertex{tri[0], tri[1], tri[2]})
+		if len(poly) < 3 {
+			continue
+		}
+		win := make([]winVertex, len(poly))
+		for j, v := range poly {
+			win[j] = c.toWindow(v)
+		}
+		for j := 1; j+1 < len(win); j++ {
+			c.triangle(d, &win[0], &win[j], &win[j+1])
+		}
+	}
+}
+
+// clip against the near and far planes, no need to clip to the sides as the triangles are scissored
+func clipPolygon(poly []*vertex) []*vertex {
+	for _, sign := range []float32{1, -1} {
+		dist := func(v *vertex) float32 { return v.clip[3] + sign*v.clip[2] }
+		inside := true
+		for _, v := range poly {
+			if dist(v) < 0 {
+				inside = false
+			}
+		}
+		if inside {
+			continue
+		}
+		var out []*vertex
+		for i, v1 := range poly {
+			v2 := poly[(i+1)%len(poly)]
+			d1, d2 := dist(v1), dist(v2)
+			if d1 >= 0 {
+				out = append(out, v1)
+			}
+			if (d1 >= 0) != (d2 >= 0) {
+				out = append(out, lerp(v1, v2, d1/(d1-d2)))
+			}
+		}
+		if poly = out; len(poly) < 3 {
+			return nil
+		}
+	}
+	return poly
+}
+
+func lerp(v1, v2 *vertex, t float32) *vertex {
+	v := &vertex{clip: v1.clip.Add(v2.clip.Sub(v1.clip).Mul(t))}
+	for i := range v.vary {
+		v.vary[i] = v1.vary[i] + t*(v2.vary[i]-v1.vary[i])
+	}
+	return v
+}
+
+func (c *Context) toWindow(v *vertex) winVertex {
+	invw := 1 / float64(v.clip[3])
+	vp := c.viewport
+	return winVertex{
+		x:    float64(vp[0]) + (float64(v.clip[0])*invw+1)*float64(vp[2])/2,
+		y:    float64(vp[1]) + (float64(v.clip[1])*invw+1)*float64(vp[3])/2,
+		z:    (float64(v.clip[2])*invw + 1) / 2,
+		invw: invw,
+		vary: &v.vary,
+	}
+}
+
+func edge(a, b *winVertex, x, y float64) float64 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// top left fill rule so pixels on an edge shared by two triangles are only drawn once
+func topLeft(a, b *winVertex) bool {
+	dx, dy := b.x-a.x, b.y-a.y
+	return dy > 0 || (dy == 0 && dx < 0)
+}
+
+// rasterize a triangle in window coordinates sampling at the pixel centers
+func (c *Context) triangle(d *drawState, v0, v1, v2 *winVertex) {
+	area := edge(v0, v1, v2.x, v2.y)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+	if d.cull && (area > 0) != d.ccw {
+		return
+	}
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+	vp := c.viewport
+	x0 := max(int(math.Floor(math.Min(v0.x, math.Min(v1.x, v2.x)))), max(vp[0], 0))
+	x1 := min(int(math.Ceil(math.Max(v0.x, math.Max(v1.x, v2.x)))), min(vp[0]+vp[2], c.width))
+	y0 := max(int(math.Floor(math.Min(v0.y, math.Min(v1.y, v2.y)))), max(vp[1], 0))
+	y1 := min(int(math.Ceil(math.Max(v0.y, math.Max(v1.y, v2.y)))), min(vp[1]+vp[3], c.height))
+	tl := [3]bool{topLeft(v1, v2), topLeft(v2, v0), topLeft(v0, v1)}
+	f := &fragment{}
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			w := [3]float64{edge(v1, v2, px, py), edge(v2, v0, px, py), edge(v0, v1, px, py)}
+			if !inside(w, tl) {
+				continue
+			}
+			z := (w[0]*v0.z + w[1]*v1.z + w[2]*v2.z) / area
+			pix := y*c.width + x
+			if d.depthTest && !c.depthPass(float32(z), c.depth[pix]) {
+				continue
+			}
+			f.x, f.y = x, y
+			vary := interpolate(w, v0, v1, v2)
+			f.pos = mgl32.Vec3{vary[vPos], vary[vPos+1], vary[vPos+2]}
+			f.normal = mgl32.Vec3{vary[vNormal], vary[vNormal+1], vary[vNormal+2]}
+			f.texcoord = mgl32.Vec2{vary[vTexcoord], vary[vTexcoord+1]}
+			f.modelPos = mgl32.Vec3{vary[vModelPos], vary[vModelPos+1], vary[vModelPos+2]}
+			f.tangent = mgl32.Vec4{vary[vTangent], vary[vTangent+1], vary[vTangent+2], vary[vTangent+3]}
+			f.color = mgl32.Vec4{vary[vColor], vary[vColor+1], vary[vColor+2], vary[vColor+3]}
+			if d.useDeriv {
+				// texture coordinate derivatives for selecting the mipmap level
+				for i, off := range [2][2]float64{{1, 0}, {0, 1}} {
+					qx, qy := px+off[0], py+off[1]
+					q := interpolate([3]float64{edge(v1, v2, qx, qy), edge(v2, v0, qx, qy), edge(v0, v1, qx, qy)}, v0, v1, v2)
+					f.deriv[i] = mgl32.Vec2{q[vTexcoord], q[vTexcoord+1]}.Sub(f.texcoord)
+				}
+			}
+			color, ok := c.shade(d.shade, d.uniforms, f)
+			if !ok {
+				continue
+			}
+			if d.depthTest && c.depthMask {
+				c.depth[pix] = float32(z)
+			}
+			c.writeColor(d, 4*pix, color)
+		}
+	}
+}
+
+func inside(w [3]float64, tl [3]bool) bool {
+	for i := range w {
+		if w[i] < 0 || (w[i] == 0 && !tl[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// perspective correct interpolation of the varyings from the edge function values
+func interpolate(w [3]float64, v0, v1, v2 *winVertex) (vary [nvary]float32) {
+	a0, a1, a2 := w[0]*v0.invw, w[1]*v1.invw, w[2]*v2.invw
+	sum := a0 + a1 + a2
+	b0, b1, b2 := float32(a0/sum), float32(a1/sum), float32(a2/sum)
+	for i := range vary {
+		vary[i] = b0*v0.vary[i] + b1*v1.vary[i] + b2*v2.vary[i]
+	}
+	return vary
+}
+
+func (c *Context) depthPass(z, current float32) bool {
+	switch c.depthFunc {
+	case glu.NEVER:
+		return false
+	case glu.LESS:
+		return z < current
+	case glu.EQUAL:
+		return z == current
+	case glu.LEQUAL:
+		return z <= current
+	case glu.GREATER:
+		return z > current
+	case glu.NOTEQUAL:
+		return z != current
+	case glu.GEQUAL:
+		return z >= current
+	}
+	return true
+}
+
+func blendFactor(f glbase.Enum, alpha float32) float32 {
+	switch f {
+	case glu.ZERO:
+		return 0
+	case glu.SRC_ALPHA:
+		return alpha
+	case glu.ONE_MINUS_SRC_ALPHA:
+		return 1 - alpha
+	}
+	return 1
+}
+
+func (c *Context) writeColor(d *drawState, pos int, color mgl32.Vec4) {
+	for i := range color {
+		color[i] = glu.Clamp(color[i], 0, 1)
+	}
+	if d.blend {
+		src, dst := blendFactor(c.blend[0], color[3]), blendFactor(c.blend[1], color[3])
+		for i := range color {
+			color[i] = glu.Clamp(color[i]*src+float32(c.color[pos+i])/255*dst, 0, 1)
+		}
+	}
+	for i, v := range color {
+		c.color[pos+i] = toByte(v)
+	}
+}
+
+func toByte(v float32) uint8 {
+	return uint8(glu.Clamp(v, 0, 1)*255 + 0.5)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,259 @@
+package raster
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"math"
+	"testing"
+)
+
+// minimal shaders which are matched to the unshaded model, with or without a texture
+const (
+	testVertex   = "attribute vec3 position;\nattribute vec2 texcoord;\n"
+	testUnshaded = "void main() { gl_FragColor = objectColor; }"
+	testTextured = "void main() { gl_FragColor = objectColor * texture2D(tex0, Texcoord); }"
+)
+
+var (
+	red   = mgl32.Vec4{1, 0, 0, 1}
+	green = mgl32.Vec4{0, 1, 0, 1}
+)
+
+// context with a program which uses camera space coordinates directly, with the given projection
+func newTest(size int, fragment string, proj mgl32.Mat4) (*Context, glbase.Program) {
+	c := New(size, size)
+	prog := c.CreateProgram()
+	for typ, src := range map[glbase.Enum]string{glu.VERTEX_SHADER: testVertex, glu.FRAGMENT_SHADER: fragment} {
+		sh := c.CreateShader(typ)
+		c.ShaderSource(sh, src)
+		c.AttachShader(prog, sh)
+	}
+	c.LinkProgram(prog)
+	c.UseProgram(prog)
+	ident := mgl32.Ident4()
+	c.UniformMatrix4fv(c.GetUniformLocation(prog, "modelToCamera"), false, ident[:])
+	c.UniformMatrix4fv(c.GetUniformLocation(prog, "cameraToClip"), false, proj[:])
+	c.Uniform1i(c.GetUniformLocation(prog, "tex0"), 0)
+	return c, prog
+}
+
+// draw triangles from a list of x, y, z positions and s, t texture coordinates
+func drawTris(c *Context, prog glbase.Program, color mgl32.Vec4, verts ...float32) {
+	buf := c.GenBuffers(1)[0]
+	c.BindBuffer(glu.ARRAY_BUFFER, buf)
+	c.BufferData(glu.ARRAY_BUFFER, 4*len(verts), verts, glu.STATIC_DRAW)
+	for _, a := range []struct {
+		name         string
+		size, offset int
+	}{{"position", 3, 0}, {"texcoord", 2, 12}} {
+		loc := c.GetAttribLocation(prog, a.name)
+		c.VertexAttribPointer(loc, a.size, glu.FLOAT, false, 20, uintptr(a.offset))
+		c.EnableVertexAttribArray(loc)
+	}
+	c.Uniform4fv(c.GetUniformLocation(prog, "objectColor"), color[:])
+	c.DrawArrays(glu.TRIANGLES, 0, len(verts)/5)
+}
+
+// pixel color with y from the bottom of the framebuffer
+func (c *Context) pixel(x, y int) [4]uint8 {
+	var p [4]uint8
+	copy(p[:], c.color[4*(y*c.width+x):])
+	return p
+}
+
+func rgba(v mgl32.Vec4) [4]uint8 {
+	return [4]uint8{toByte(v[0]), toByte(v[1]), toByte(v[2]), toByte(v[3])}
+}
+
+func TestCoverage(t *testing.T) {
+	c, prog := newTest(8, testUnshaded, mgl32.Ident4())
+	// lower left half of the screen at z = 0.5
+	drawTris(c, prog, red, -1, -1, 0.5, 0, 0, 1, -1, 0.5, 0, 0, -1, 1, 0.5, 0, 0)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			want := [4]uint8{}
+			// pixel centers on the diagonal are on the edge which is included by the fill rule
+			if x+y <= 7 {
+				want = rgba(red)
+			}
+			if got := c.pixel(x, y); got != want {
+				t.Errorf("pixel %d,%d is %v, expecting %v", x, y, got, want)
+			}
+		}
+	}
+	// pixel centers on a shared edge are only drawn by one of the triangles
+	c, prog = newTest(8, testUnshaded, mgl32.Ident4())
+	c.Enable(glu.BLEND)
+	c.BlendFunc(glu.ONE, glu.ONE)
+	quarter := mgl32.Vec4{0.25, 0.25, 0.25, 0.25}
+	// two triangles making a square with a shared diagonal through the pixel centers
+	drawTris(c, prog, quarter, -1, -1, 0, 0, 0, 1, -1, 0, 0, 0, 1, 1, 0, 0, 0,
+		-1, -1, 0, 0, 0, 1, 1, 0, 0, 0, -1, 1, 0, 0, 0)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if got := c.pixel(x, y); got != rgba(quarter) {
+				t.Errorf("pixel %d,%d is %v, expecting it to be drawn once", x, y, got)
+			}
+		}
+	}
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		depthTest bool
+		fn        glbase.Enum
+		mask      bool
+		color     mgl32.Vec4
+		depth     float32
+	}{
+		{"depth test", true, glu.LESS, true, red, 0.25},
+		{"no depth test", false, glu.LESS, true, green, 1},
+		{"greater", true, glu.GREATER, true, green, 0.75},
+		{"no depth writes", true, glu.LESS, false, green, 1},
+		{"always", true, glu.ALWAYS, true, green, 0.75},
+	}
+	for _, test := range tests {
+		c, prog := newTest(4, testUnshaded, mgl32.Ident4())
+		if test.depthTest {
+			c.Enable(glu.DEPTH_TEST)
+		}
+		c.DepthFunc(test.fn)
+		c.DepthMask(test.mask)
+		// near red triangle at z = -0.5 then far green one at z = 0.5, both covering the screen
+		if test.fn == glu.GREATER {
+			c.Clear(glu.DEPTH_BUFFER_BIT)
+			for i := range c.depth {
+				c.depth[i] = 0
+			}
+		}
+		drawTris(c, prog, red, -1, -1, -0.5, 0, 0, 3, -1, -0.5, 0, 0, -1, 3, -0.5, 0, 0)
+		drawTris(c, prog, green, -1, -1, 0.5, 0, 0, 3, -1, 0.5, 0, 0, -1, 3, 0.5, 0, 0)
+		for _, p := range [][2]int{{0, 0}, {3, 0}, {1, 2}, {3, 3}} {
+			if got := c.pixel(p[0], p[1]); got != rgba(test.color) {
+				t.Errorf("%s: pixel %v is %v, expecting %v", test.name, p, got, rgba(test.color))
+			}
+			if got := c.depth[p[1]*4+p[0]]; abs(got-test.depth) > 1e-6 {
+				t.Errorf("%s: depth at %v is %g, expecting %g", test.name, p, got, test.depth)
+			}
+		}
+	}
+}
+
+func TestPerspectiveTexture(t *testing.T) {
+	const size, texels = 64, 64
+	// 90 degree field of view so a camera space point x, y, z is at x / -z, y / -z in normalized coordinates
+	proj := mgl32.Perspective(mgl32.DegToRad(90), 1, 0.5, 10)
+	c, prog := newTest(size, testTextured, proj)
+	// texture with the red value going up by 4 for each texel along s
+	pix := make([]uint8, 4*texels)
+	for i := 0; i < texels; i++ {
+		pix[4*i], pix[4*i+3] = uint8(4*i), 255
+	}
+	c.ActiveTexture(glu.TEXTURE0)
+	tex := c.GenTextures(1)[0]
+	c.BindTexture(glu.TEXTURE_2D, tex)
+	c.TexParameteri(glu.TEXTURE_2D, glu.TEXTURE_MIN_FILTER, glu.NEAREST)
+	c.TexParameteri(glu.TEXTURE_2D, glu.TEXTURE_MAG_FILTER, glu.NEAREST)
+	c.TexImage2D(glu.TEXTURE_2D, 0, glu.RGBA, texels, 1, 0, glu.RGBA, glu.UNSIGNED_BYTE, pix)
+	// wall going away from the camera from x = -1, z = -1 to x = 1, z = -3 with s going from 0 to 1
+	drawTris(c, prog, mgl32.Vec4{1, 1, 1, 1},
+		-1, -1, -1, 0, 0, 1, -1, -3, 1, 0, 1, 1, -3, 1, 1,
+		-1, -1, -1, 0, 0, 1, 1, -3, 1, 1, -1, 1, -1, 0, 1)
+	for x := 0; x < size; x++ {
+		// intersect the ray through the pixel center with the plane z = -2 - x
+		ndc := 2*(float64(x)+0.5)/size - 1
+		if ndc >= 1.0/3 {
+			if got := c.pixel(x, size/2); got[3] != 0 {
+				t.Errorf("pixel %d beyond the wall is %v", x, got)
+			}
+			continue
+		}
+		wx := 2 * ndc / (1 - ndc)
+		s := (wx + 1) / 2
+		want := 4 * math.Floor(s*texels)
+		got := c.pixel(x, size/2)
+		// allow for rounding at the texel edges
+		if got[3] != 255 || math.Abs(float64(got[0])-want) > 4 {
+			t.Errorf("pixel %d: color is %v, expecting red %g for s = %.3f", x, got, want, s)
+		}
+	}
+}
+
+func TestNearClip(t *testing.T) {
+	const size = 16
+	proj := mgl32.Perspective(mgl32.DegToRad(90), 1, 0.5, 10)
+	c, prog := newTest(size, testUnshaded, proj)
+	c.Enable(glu.DEPTH_TEST)
+	// floor at y = -1 which goes from in front of the camera to behind it
+	drawTris(c, prog, red,
+		-5, -1, -5, 0, 0, 5, -1, 5, 0, 0, 5, -1, -5, 0, 0,
+		-5, -1, -5, 0, 0, -5, -1, 5, 0, 0, 5, -1, 5, 0, 0)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			got := c.pixel(x, y)
+			// the floor is below the horizon and the far edge is above the bottom of the screen
+			ndcY := 2*(float32(y)+0.5)/size - 1
+			if ndcY >= -0.2 {
+				if got[3] != 0 {
+					t.Errorf("pixel %d,%d above the floor is %v", x, y, got)
+				}
+				continue
+			}
+			if got != rgba(red) {
+				t.Errorf("pixel %d,%d on the floor is %v", x, y, got)
+				continue
+			}
+			// depth of the point on the floor under the pixel
+			dist := -1 / ndcY
+			clip := proj.Mul4x1(mgl32.Vec4{0, -1, -dist, 1})
+			want := (clip[2]/clip[3] + 1) / 2
+			if d := c.depth[y*size+x]; abs(d-want) > 1e-4 {
+				t.Errorf("pixel %d,%d: depth %g, expecting %g", x, y, d, want)
+			}
+		}
+	}
+}
+
+func TestCulling(t *testing.T) {
+	ccw := []float32{-1, -1, 0, 0, 0, 1, -1, 0, 0, 0, -1, 1, 0, 0, 0}
+	cw := []float32{-1, -1, 0, 0, 0, -1, 1, 0, 0, 0, 1, -1, 0, 0, 0}
+	tests := []struct {
+		name      string
+		verts     []float32
+		flipY     bool
+		cull      bool
+		frontFace glbase.Enum
+		drawn     bool
+	}{
+		{"ccw front", ccw, false, true, glu.CCW, true},
+		{"cw back", cw, false, true, glu.CCW, false},
+		{"cw front", cw, false, true, glu.CW, true},
+		{"ccw back", ccw, false, true, glu.CW, false},
+		{"no culling", cw, false, false, glu.CCW, true},
+		// the inverted projection reverses the winding in window coordinates, so it is drawn with the
+		// front face set to clockwise as it is by glu.SetFlipY
+		{"flipped ccw", ccw, true, true, glu.CW, true},
+		{"flipped cw", cw, true, true, glu.CW, false},
+	}
+	for _, test := range tests {
+		proj := mgl32.Ident4()
+		if test.flipY {
+			proj = mgl32.Scale3D(1, -1, 1)
+		}
+		c, prog := newTest(4, testUnshaded, proj)
+		if test.cull {
+			c.Enable(glu.CULL_FACE)
+		}
+		c.FrontFace(test.frontFace)
+		drawTris(c, prog, red, test.verts...)
+		y := 0
+		if test.flipY {
+			y = 3
+		}
+		if got := c.pixel(0, y) == rgba(red); got != test.drawn {
+			t.Errorf("%s: drawn is %v, expecting %v", test.name, got, test.drawn)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package raster
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/scene"
+	"image"
+)
+
+// Background color used by Render, the default is transparent
+var Background = mgl32.Vec4{0, 0, 0, 0}
+
+// shared context used by Render as meshes and textures are tied to the context they were created in
+var shared *Context
+
+// Render draws the scene from the given view to an image using a shared software context which is created
+// on the first call. This is the same as offscreen.Render but does not need a GL driver.
+func Render(root scene.Object, view *scene.View, width, height int) (*image.NRGBA, error) {
+	if shared == nil {
+		shared = New(width, height)
+	}
+	return shared.Render(root, view, width, height)
+}
+
+// Render draws the scene from the given view to an image of the given size.
+func (c *Context) Render(root scene.Object, view *scene.View, width, height int) (*image.NRGBA, error) {
+	c.Resize(width, height)
+	glu.Init(c)
+	// the image rows are in the same order as the offscreen backend
+	view.FlipY = true
+	view.SetProjection(width, height)
+	glu.Clear(Background)
+	worldToCamera := view.ViewMatrix()
+	view.UpdateLights(worldToCamera, root)
+	if err := view.Draw(worldToCamera, root); err != nil {
+		return nil, err
+	}
+	return c.Image(), glu.CheckError()
+}
@@ -0,0 +1,242 @@
+package raster
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"math"
+	"strings"
+)
+
+// lighting models
+const (
+	unshaded = iota
+	diffuse
+	blinnPhong
+	emissive
+	point
+)
+
+// texture mapping
+const (
+	noTexture = iota
+	texture2D
+	textureCube
+	textureWood
+)
+
+// shader is the software equivalent of one of the built in fragment shaders
+type shader struct {
+	lighting    int
+	texture     int
+	specMap     bool
+	normMap     bool
+	vertexColor bool
+	gamma       bool
+}
+
+// newShader picks the lighting model and texture mapping from the main function of the fragment shader.
+// Procedural shaders are approximated: the wood grain is sampled without the noise perturbation and the
+// rough and marble materials are drawn as plain Blinn-Phong.
+func newShader(src string) shader {
+	main := src
+	if i := strings.LastIndex(src, "void main()"); i >= 0 {
+		main = src[i:]
+	}
+	var s shader
+	switch {
+	case strings.Contains(main, "PointLocation"):
+		s.lighting = point
+	case strings.Contains(main, "blinnPhongLighting("):
+		s.lighting = blinnPhong
+	case strings.Contains(main, "diffuseLighting("):
+		s.lighting = diffuse
+	case strings.Contains(main, "CameraSpacePos"):
+		s.lighting = emissive
+	}
+	switch {
+	case strings.Contains(main, "woodPos"):
+		s.texture = textureWood
+	case strings.Contains(main, "texture2D(tex0, Texcoord)"):
+		s.texture = texture2D
+	case strings.Contains(main, "textureCube(tex0,"):
+		s.texture = textureCube
+	}
+	s.specMap = strings.Contains(main, "(tex1, Texcoord)") || strings.Contains(main, "(tex1, ModelPos)")
+	s.normMap = strings.Contains(main, "TBN *")
+	s.vertexColor = strings.Contains(main, "objectColor * Color")
+	s.gamma = strings.Contains(main, "gammaCorrect(")
+	return s
+}
+
+// fragment shader inputs after interpolation
+type fragment struct {
+	x, y     int
+	pos      mgl32.Vec3 // camera space position
+	normal   mgl32.Vec3
+	texcoord mgl32.Vec2
+	modelPos mgl32.Vec3
+	tangent  mgl32.Vec4 // with the handedness in w
+	color    mgl32.Vec4
+	deriv    [2]mgl32.Vec2 // texcoord change for one pixel step in x and y
+}
+
+type uniforms map[string][]float32
+
+func (u uniforms) float(name string) float32 {
+	if v := u[name]; len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+func (u uniforms) vec3(name string) (v mgl32.Vec3) {
+	copy(v[:], u[name])
+	return v
+}
+
+func (u uniforms) vec4(name string) (v mgl32.Vec4) {
+	copy(v[:], u[name])
+	return v
+}
+
+var lightNames [][2]string
+
+func init() {
+	for i := 0; i < mesh.MaxLights; i++ {
+		lightNames = append(lightNames, [2]string{fmt.Sprintf("lightPos[%d]", i), fmt.Sprintf("lightCol[%d]", i)})
+	}
+}
+
+// shade returns the fragment color, or false if it is discarded
+func (c *Context) shade(s shader, u uniforms, f *fragment) (mgl32.Vec4, bool) {
+	color := u.vec4("objectColor")
+	if s.lighting == point {
+		// same as the PointLocation varying, in window coordinates
+		var proj mgl32.Mat4
+		copy(proj[:], u["cameraToClip"])
+		clip := proj.Mul4x1(u.vec3("pointLocation").Vec4(1))
+		var viewport mgl32.Vec2
+		copy(viewport[:], u["viewport"])
+		size := u.float("pointSize")
+		dx := viewport[0]*(clip[0]/clip[3]+1)/2 - float32(f.x) - 0.5
+		dy := viewport[1]*(clip[1]/clip[3]+1)/2 - float32(f.y) - 0.5
+		if size >= 4 && dx*dx+dy*dy > size*size/4 {
+			return color, false
+		}
+		return color, true
+	}
+	if s.vertexColor {
+		color = mul4(color, f.color)
+	}
+	numTex := int(u.float("numTex"))
+	switch s.texture {
+	case texture2D:
+		color = mul4(color, c.sample2D(u, "tex0", f.texcoord, f.deriv))
+	case textureCube:
+		color = mul4(color, c.sampleCube(u, "tex0", f.modelPos))
+	case textureWood:
+		p := f.modelPos
+		st := mgl32.Vec2{0.5 - 0.85*p[2] - 0.1*p[0], 0.5 - 0.85*p[1] - 0.1*p[0]}
+		color = mul4(color, c.sample2D(u, "tex0", st, [2]mgl32.Vec2{}))
+		color[3] = 1
+	}
+	normal := f.normal
+	if tangent := f.tangent.Vec3(); s.normMap && tangent.Len() > 0 && numTex > 2 {
+		n := normal.Normalize()
+		t := tangent.Sub(n.Mul(tangent.Dot(n))).Normalize()
+		b := n.Cross(t)
+		if f.tangent[3] < 0 {
+			b = b.Mul(-1)
+		}
+		m := c.sample2D(u, "tex2", f.texcoord, f.deriv).Vec3().Mul(2).Sub(mgl32.Vec3{1, 1, 1}).Normalize()
+		normal = t.Mul(m[0]).Add(b.Mul(m[1])).Add(n.Mul(m[2]))
+	}
+	switch s.lighting {
+	case diffuse, blinnPhong:
+		spec := u.vec3("specularColor")
+		minTex := 1
+		if s.normMap {
+			minTex = 2
+		}
+		if s.specMap && numTex > minTex {
+			if s.texture == textureCube {
+				spec = mul3(spec, c.sampleCube(u, "tex1", f.modelPos).Vec3())
+			} else {
+				spec = mul3(spec, c.sample2D(u, "tex1", f.texcoord, f.deriv).Vec3())
+			}
+		}
+		rgb := lighting(u, s.lighting == blinnPhong, f.pos, normal, color.Vec3(), spec)
+		color = rgb.Vec4(color[3])
+	case emissive:
+		d := f.pos.Mul(-1).Normalize().Dot(normal.Normalize())
+		scale := float32(1)
+		if d > 0 {
+			scale = float32(math.Max(math.Pow(float64(d)*1.5, 0.4)*1.1, 1))
+		}
+		color = color.Vec3().Mul(scale).Vec4(1)
+	}
+	if s.gamma {
+		for i := 0; i < 3; i++ {
+			color[i] = float32(math.Pow(math.Max(float64(color[i]), 0), 1/2.2))
+		}
+	}
+	return color, true
+}
+
+// lighting is the same calculation as the diffuseLighting and blinnPhongLighting shader functions
+func lighting(u uniforms, specular bool, pos, normal, objColor, specColor mgl32.Vec3) mgl32.Vec3 {
+	var color mgl32.Vec3
+	norm := normal.Normalize()
+	ambientScale := u.float("ambientScale")
+	shininess := float64(u.float("shininess"))
+	numLights := int(u.float("numLights"))
+	for i := 0; i < numLights && i < len(lightNames); i++ {
+		lightPos, lightCol := u.vec4(lightNames[i][0]), u.vec4(lightNames[i][1])
+		var lightDir, intensity mgl32.Vec3
+		if lightPos[3] == 0 {
+			lightDir = lightPos.Vec3()
+			intensity = lightCol.Vec3()
+		} else {
+			diff := lightPos.Vec3().Sub(pos)
+			dist2 := diff.Dot(diff)
+			lightDir = diff.Mul(1 / float32(math.Sqrt(float64(dist2))))
+			intensity = lightCol.Vec3().Mul(1 / (1 + lightPos[3]*dist2))
+		}
+		ambient := lightCol[3] * ambientScale
+		diffuse := norm.Dot(lightDir)
+		if diffuse < 0 {
+			diffuse = 0
+		}
+		color = color.Add(mul3(objColor, intensity).Mul(ambient + diffuse))
+		if specular {
+			viewDir := pos.Mul(-1).Normalize()
+			halfAngle := lightDir.Add(viewDir).Normalize()
+			spec := math.Pow(math.Max(float64(norm.Dot(halfAngle)), 0), shininess)
+			color = color.Add(mul3(specColor, intensity).Mul(float32(spec)))
+		}
+	}
+	return color
+}
+
+func (c *Context) sample2D(u uniforms, sampler string, st mgl32.Vec2, deriv [2]mgl32.Vec2) mgl32.Vec4 {
+	if t, ok := c.textures[c.texBound[int(u.float(sampler))]]; ok {
+		return t.sample2D(st, deriv)
+	}
+	return mgl32.Vec4{0, 0, 0, 1}
+}
+
+func (c *Context) sampleCube(u uniforms, sampler string, dir mgl32.Vec3) mgl32.Vec4 {
+	if t, ok := c.textures[c.texBound[int(u.float(sampler))]]; ok {
+		return t.sampleCube(dir)
+	}
+	return mgl32.Vec4{0, 0, 0, 1}
+}
+
+func mul3(a, b mgl32.Vec3) mgl32.Vec3 {
+	return mgl32.Vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]}
+}
+
+func mul4(a, b mgl32.Vec4) mgl32.Vec4 {
+	return mgl32.Vec4{a[0] * b[0], a[1] * b[1], a[2] * b[2], a[3] * b[3]}
+}
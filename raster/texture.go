@@ -0,0 +1,208 @@
+package raster
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"gopkg.in/qml.v1/gl/glbase"
+	"math"
+)
+
+// one mipmap level with RGBA values scaled to 0-1
+type level struct {
+	width, height int
+	pix           []float32
+}
+
+type texture struct {
+	target glbase.Enum
+	params map[glbase.Enum]glbase.Enum
+	faces  [6][]*level
+}
+
+func newTexture() *texture {
+	return &texture{
+		target: glu.TEXTURE_2D,
+		params: map[glbase.Enum]glbase.Enum{
+			glu.TEXTURE_WRAP_S:     glu.REPEAT,
+			glu.TEXTURE_WRAP_T:     glu.REPEAT,
+			glu.TEXTURE_MIN_FILTER: glu.LINEAR_MIPMAP_LINEAR,
+			glu.TEXTURE_MAG_FILTER: glu.LINEAR,
+		},
+	}
+}
+
+// set the base level for a 2D texture or cube map face
+func (t *texture) setImage(target glbase.Enum, width, height int, format glbase.Enum, data []byte) {
+	face := 0
+	if target >= glu.TEXTURE_CUBE_MAP_POSITIVE_X && target < glu.TEXTURE_CUBE_MAP_POSITIVE_X+6 {
+		face = int(target - glu.TEXTURE_CUBE_MAP_POSITIVE_X)
+	}
+	ncomp := 4
+	if format == glu.RGB {
+		ncomp = 3
+	}
+	l := &level{width: width, height: height, pix: make([]float32, 4*width*height)}
+	for i := 0; i < width*height; i++ {
+		l.pix[4*i+3] = 1
+		for j := 0; j < ncomp && ncomp*i+j < len(data); j++ {
+			l.pix[4*i+j] = float32(data[ncomp*i+j]) / 255
+		}
+	}
+	t.faces[face] = []*level{l}
+}
+
+// generate mipmaps by averaging each 2x2 block of texels
+func (t *texture) generateMipmap() {
+	for face, levels := range t.faces {
+		if len(levels) == 0 {
+			continue
+		}
+		l := levels[0]
+		levels = levels[:1]
+		for l.width > 1 || l.height > 1 {
+			next := &level{width: max(l.width/2, 1), height: max(l.height/2, 1)}
+			next.pix = make([]float32, 4*next.width*next.height)
+			for y := 0; y < next.height; y++ {
+				for x := 0; x < next.width; x++ {
+					for j := 0; j < 4; j++ {
+						sum := l.texel(2*x, 2*y, j) + l.texel(2*x+1, 2*y, j) + l.texel(2*x, 2*y+1, j) + l.texel(2*x+1, 2*y+1, j)
+						next.pix[4*(y*next.width+x)+j] = sum / 4
+					}
+				}
+			}
+			levels = append(levels, next)
+			l = next
+		}
+		t.faces[face] = levels
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// texel component clamped to the edge of the image
+func (l *level) texel(x, y, j int) float32 {
+	x = clampInt(x, 0, l.width-1)
+	y = clampInt(y, 0, l.height-1)
+	return l.pix[4*(y*l.width+x)+j]
+}
+
+func clampInt(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
+
+func wrap(i, n int, mode glbase.Enum) int {
+	if mode == glu.REPEAT {
+		if i %= n; i < 0 {
+			i += n
+		}
+		return i
+	}
+	return clampInt(i, 0, n-1)
+}
+
+// sample a single level with nearest or bilinear filtering
+func (t *texture) sampleLevel(l *level, s, u float32, linear bool) (c mgl32.Vec4) {
+	ws, wt := t.params[glu.TEXTURE_WRAP_S], t.params[glu.TEXTURE_WRAP_T]
+	x, y := float64(s)*float64(l.width), float64(u)*float64(l.height)
+	if !linear {
+		i := wrap(int(math.Floor(x)), l.width, ws)
+		j := wrap(int(math.Floor(y)), l.height, wt)
+		copy(c[:], l.pix[4*(j*l.width+i):])
+		return c
+	}
+	x, y = x-0.5, y-0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := float32(x-x0), float32(y-y0)
+	i0, j0 := wrap(int(x0), l.width, ws), wrap(int(y0), l.height, wt)
+	i1, j1 := wrap(int(x0)+1, l.width, ws), wrap(int(y0)+1, l.height, wt)
+	for k := 0; k < 4; k++ {
+		a := l.pix[4*(j0*l.width+i0)+k]*(1-fx) + l.pix[4*(j0*l.width+i1)+k]*fx
+		b := l.pix[4*(j1*l.width+i0)+k]*(1-fx) + l.pix[4*(j1*l.width+i1)+k]*fx
+		c[k] = a*(1-fy) + b*fy
+	}
+	return c
+}
+
+// sample a 2D texture, the mipmap level is selected from the texture coordinate derivatives
+func (t *texture) sample2D(st mgl32.Vec2, deriv [2]mgl32.Vec2) mgl32.Vec4 {
+	levels := t.faces[0]
+	if len(levels) == 0 {
+		return mgl32.Vec4{0, 0, 0, 1}
+	}
+	w, h := float64(levels[0].width), float64(levels[0].height)
+	rho := math.Max(math.Hypot(float64(deriv[0][0])*w, float64(deriv[0][1])*h),
+		math.Hypot(float64(deriv[1][0])*w, float64(deriv[1][1])*h))
+	lod := float32(math.Log2(rho))
+	min, mag := t.params[glu.TEXTURE_MIN_FILTER], t.params[glu.TEXTURE_MAG_FILTER]
+	if lod <= 0 || min == glu.LINEAR || min == glu.NEAREST {
+		filter := mag
+		if lod > 0 {
+			filter = min
+		}
+		return t.sampleLevel(levels[0], st[0], st[1], filter != glu.NEAREST)
+	}
+	// trilinear filtering between the two nearest mipmap levels
+	n := len(levels) - 1
+	l0 := int(lod)
+	if l0 >= n {
+		return t.sampleLevel(levels[n], st[0], st[1], true)
+	}
+	f := lod - float32(l0)
+	c0 := t.sampleLevel(levels[l0], st[0], st[1], true)
+	c1 := t.sampleLevel(levels[l0+1], st[0], st[1], true)
+	return c0.Mul(1 - f).Add(c1.Mul(f))
+}
+
+// sample a cube map by selecting the face from the major axis of the direction vector
+func (t *texture) sampleCube(dir mgl32.Vec3) mgl32.Vec4 {
+	ax, ay, az := abs(dir[0]), abs(dir[1]), abs(dir[2])
+	var face int
+	var sc, tc, ma float32
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if dir[0] > 0 {
+			face, sc, tc = 0, -dir[2], -dir[1]
+		} else {
+			face, sc, tc = 1, dir[2], -dir[1]
+		}
+	case ay >= az:
+		ma = ay
+		if dir[1] > 0 {
+			face, sc, tc = 2, dir[0], dir[2]
+		} else {
+			face, sc, tc = 3, dir[0], -dir[2]
+		}
+	default:
+		ma = az
+		if dir[2] > 0 {
+			face, sc, tc = 4, dir[0], -dir[1]
+		} else {
+			face, sc, tc = 5, -dir[0], -dir[1]
+		}
+	}
+	levels := t.faces[face]
+	if len(levels) == 0 || ma == 0 {
+		return mgl32.Vec4{0, 0, 0, 1}
+	}
+	linear := t.params[glu.TEXTURE_MAG_FILTER] != glu.NEAREST
+	return t.sampleLevel(levels[0], (sc/ma+1)/2, (tc/ma+1)/2, linear)
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,296 @@
+package scene
+
+import (
+	"container/heap"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+	"sort"
+)
+
+// BVH is a bounding volume hierarchy over the world space bounds of the enabled items in a scene, for fast
+// spatial queries. It only uses the scene graph and mesh bounds, so does not need a GL context.
+type BVH struct {
+	root   Object
+	nodes  []bvhNode
+	leaves []*bvhLeaf
+}
+
+type bvhNode struct {
+	bounds      mesh.Bounds
+	parent      int
+	left, right int
+	leaf        *bvhLeaf
+	dirty       bool
+}
+
+type bvhLeaf struct {
+	item   *Item
+	path   []Object // ancestors of the item from the root, followed by the item
+	world  mgl32.Mat4
+	bounds mesh.Bounds
+	node   int
+}
+
+// NewBVH builds the hierarchy for all of the enabled items under root. Call Refit after moving objects,
+// or build a new tree if objects have been added, removed, enabled or disabled.
+func NewBVH(root Object) *BVH {
+	t := &BVH{root: root}
+	walkPath(root, nil, func(item *Item, path []Object) {
+		leaf := &bvhLeaf{item: item, path: path, world: worldMatrix(path)}
+		leaf.bounds = item.modelBounds().Transform(leaf.world)
+		t.leaves = append(t.leaves, leaf)
+	})
+	if len(t.leaves) > 0 {
+		t.build(append([]*bvhLeaf{}, t.leaves...), -1)
+	}
+	return t
+}
+
+// call fn for each enabled item with the path from the root
+func walkPath(obj Object, path []Object, fn func(*Item, []Object)) {
+	if !obj.Enabled() {
+		return
+	}
+	path = append(path[:len(path):len(path)], obj)
+	switch o := obj.(type) {
+	case *Group:
+		for _, child := range o.objects {
+			walkPath(child, path, fn)
+		}
+	case *Item:
+		fn(o, path)
+	}
+}
+
+func worldMatrix(path []Object) mgl32.Mat4 {
+	m := mgl32.Ident4()
+	for _, obj := range path {
+		switch o := obj.(type) {
+		case *Group:
+			m = m.Mul4(o.Transform.Mat4())
+		case *Item:
+			m = m.Mul4(o.Transform.Mat4())
+		}
+	}
+	return m
+}
+
+// build the tree top down, splitting at the median along the longest axis of the bounds centers
+func (t *BVH) build(leaves []*bvhLeaf, parent int) int {
+	index := len(t.nodes)
+	t.nodes = append(t.nodes, bvhNode{parent: parent, left: -1, right: -1})
+	if len(leaves) == 1 {
+		leaves[0].node = index
+		t.nodes[index].leaf = leaves[0]
+		t.nodes[index].bounds = leaves[0].bounds
+		return index
+	}
+	centers := mesh.EmptyBounds()
+	for _, leaf := range leaves {
+		c := leaf.bounds.Center()
+		centers = centers.Union(mesh.Bounds{Min: c, Max: c})
+	}
+	size := centers.Size()
+	axis := 0
+	if size[1] > size[axis] {
+		axis = 1
+	}
+	if size[2] > size[axis] {
+		axis = 2
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].bounds.Center()[axis] < leaves[j].bounds.Center()[axis]
+	})
+	mid := len(leaves) / 2
+	left := t.build(leaves[:mid], index)
+	right := t.build(leaves[mid:], index)
+	t.nodes[index].left, t.nodes[index].right = left, right
+	t.nodes[index].bounds = t.nodes[left].bounds.Union(t.nodes[right].bounds)
+	return index
+}
+
+// Rebuild creates a new tree from the same root object, after adding or removing objects
+func (t *BVH) Rebuild() {
+	*t = *NewBVH(t.root)
+}
+
+// Len returns the number of items in the tree
+func (t *BVH) Len() int {
+	return len(t.leaves)
+}
+
+// Bounds returns the world space bounds of all of the items
+func (t *BVH) Bounds() mesh.Bounds {
+	if len(t.nodes) == 0 {
+		return mesh.EmptyBounds()
+	}
+	return t.nodes[0].bounds
+}
+
+// ItemBounds returns the world space bounds of an item, and false if it is not in the tree
+func (t *BVH) ItemBounds(item *Item) (mesh.Bounds, bool) {
+	for _, leaf := range t.leaves {
+		if leaf.item == item {
+			return leaf.bounds, true
+		}
+	}
+	return mesh.EmptyBounds(), false
+}
+
+// Refit updates the bounds of items whose world transform has changed since the tree was built or last
+// refit, and then the bounds of the nodes above them. Items with a skeleton are also updated if the joints
+// have moved. The tree structure is unchanged so queries may get slower if objects move a long way.
+// Returns the number of items which moved.
+func (t *BVH) Refit() int {
+	return t.refit(nil)
+}
+
+// RefitObject is the same as Refit but only checks the items at or below obj in the scene graph, e.g. after
+// calling Translate or Rotate on it. Items with a skeleton are always checked as their joints may be
+// elsewhere in the scene.
+func (t *BVH) RefitObject(obj Object) int {
+	return t.refit(obj)
+}
+
+func (t *BVH) refit(obj Object) int {
+	moved := 0
+	for _, leaf := range t.leaves {
+		skinned := leaf.item.Skeleton != nil
+		if obj != nil && !inPath(leaf.path, obj) && !skinned {
+			continue
+		}
+		world := worldMatrix(leaf.path)
+		if world == leaf.world && !skinned {
+			continue
+		}
+		bounds := leaf.item.modelBounds().Transform(world)
+		if world == leaf.world && bounds == leaf.bounds {
+			continue
+		}
+		moved++
+		leaf.world = world
+		leaf.bounds = bounds
+		t.nodes[leaf.node].bounds = leaf.bounds
+		for n := t.nodes[leaf.node].parent; n >= 0 && !t.nodes[n].dirty; n = t.nodes[n].parent {
+			t.nodes[n].dirty = true
+		}
+	}
+	if moved > 0 {
+		// children always come after their parent so update from the end
+		for i := len(t.nodes) - 1; i >= 0; i-- {
+			if n := &t.nodes[i]; n.dirty {
+				n.bounds = t.nodes[n.left].bounds.Union(t.nodes[n.right].bounds)
+				n.dirty = false
+			}
+		}
+	}
+	return moved
+}
+
+func inPath(path []Object, obj Object) bool {
+	for _, o := range path {
+		if o == obj {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the items whose world bounds intersect the box
+func (t *BVH) Query(box mesh.Bounds) []*Item {
+	items := []*Item{}
+	t.search(func(b mesh.Bounds) bool { return b.Intersects(box) }, func(leaf *bvhLeaf) {
+		items = append(items, leaf.item)
+	})
+	return items
+}
+
+// InFrustum returns the items whose world bounds are at least partly inside the frustum, e.g. from
+// View.Frustum with the camera view matrix.
+func (t *BVH) InFrustum(f Frustum) []*Item {
+	items := []*Item{}
+	t.search(f.Intersects, func(leaf *bvhLeaf) {
+		items = append(items, leaf.item)
+	})
+	return items
+}
+
+// Within returns the items whose world bounds are within radius of the point, nearest first
+func (t *BVH) Within(p mgl32.Vec3, radius float32) []*Item {
+	var found []itemDist
+	t.search(func(b mesh.Bounds) bool { return b.Distance(p) <= radius }, func(leaf *bvhLeaf) {
+		found = append(found, itemDist{leaf.item, leaf.bounds.Distance(p)})
+	})
+	sort.SliceStable(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+	items := make([]*Item, len(found))
+	for i, f := range found {
+		items[i] = f.item
+	}
+	return items
+}
+
+// visit each leaf where test is true for all of the nodes above it
+func (t *BVH) search(test func(mesh.Bounds) bool, fn func(*bvhLeaf)) {
+	if len(t.nodes) == 0 {
+		return
+	}
+	stack := []int{0}
+	for len(stack) > 0 {
+		n := &t.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !test(n.bounds) {
+			continue
+		}
+		if n.leaf != nil {
+			fn(n.leaf)
+		} else {
+			stack = append(stack, n.right, n.left)
+		}
+	}
+}
+
+// Nearest returns up to k items ordered by the distance from the point to their world bounds.
+func (t *BVH) Nearest(p mgl32.Vec3, k int) []*Item {
+	items := []*Item{}
+	if len(t.nodes) == 0 || k <= 0 {
+		return items
+	}
+	// best first search where nodes are expanded in order of distance to their bounds
+	queue := &nodeQueue{{node: 0, dist: t.nodes[0].bounds.Distance(p)}}
+	for queue.Len() > 0 && len(items) < k {
+		n := &t.nodes[heap.Pop(queue).(nodeDist).node]
+		if n.leaf != nil {
+			// leaf bounds are the item bounds so nothing left in the queue can be closer
+			items = append(items, n.leaf.item)
+		} else {
+			heap.Push(queue, nodeDist{node: n.left, dist: t.nodes[n.left].bounds.Distance(p)})
+			heap.Push(queue, nodeDist{node: n.right, dist: t.nodes[n.right].bounds.Distance(p)})
+		}
+	}
+	return items
+}
+
+type itemDist struct {
+	item *Item
+	dist float32
+}
+
+type nodeDist struct {
+	node int
+	dist float32
+}
+
+// priority queue ordered by distance
+type nodeQueue []nodeDist
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(nodeDist)) }
+
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,311 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/mesh"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// cube from -1 to 1 on each axis
+func cube() *mesh.Mesh {
+	m := mesh.New()
+	for i := 0; i < 8; i++ {
+		m.AddVertex(float32(i&1*2-1), float32(i>>1&1*2-1), float32(i>>2&1*2-1))
+	}
+	m.AddNormal(0, 0, 1)
+	for _, f := range [][4]int{{1, 3, 4, 2}, {5, 6, 8, 7}, {1, 2, 6, 5}, {3, 7, 8, 4}, {1, 5, 7, 3}, {2, 4, 8, 6}} {
+		m.AddFace(mesh.El{Vert: f[0], Norm: 1}, mesh.El{Vert: f[1], Norm: 1}, mesh.El{Vert: f[2], Norm: 1}, mesh.El{Vert: f[3], Norm: 1})
+	}
+	m.Build("")
+	return m
+}
+
+func randVec(rng *rand.Rand, scale float32) mgl32.Vec3 {
+	return mgl32.Vec3{rng.Float32()*2 - 1, rng.Float32()*2 - 1, rng.Float32()*2 - 1}.Mul(scale)
+}
+
+// random scene of cubes in nested groups, with a few items and a group disabled
+func randomScene(seed int64) (root *Group, groups []*Group) {
+	rng := rand.New(rand.NewSource(seed))
+	msh := cube()
+	root = NewGroup()
+	for i := 0; i < 8; i++ {
+		g := NewGroup()
+		p := randVec(rng, 20)
+		g.Translate(p[0], p[1], p[2]).Rotate(rng.Float32()*360, randVec(rng, 1).Add(mgl32.Vec3{0, 2, 0}))
+		for j := 0; j < 25; j++ {
+			item := NewItem(msh)
+			p, s := randVec(rng, 8), 0.2+rng.Float32()
+			item.Translate(p[0], p[1], p[2]).RotateY(rng.Float32()*360).Scale(s, s*(0.5+rng.Float32()), s)
+			item.Enable(j%10 != 0)
+			g.Add(item)
+		}
+		if i > 0 && i%3 == 0 {
+			// nested inside the previous group
+			groups[i-1].Add(g)
+		} else {
+			root.Add(g)
+		}
+		groups = append(groups, g)
+	}
+	groups[5].Enable(false)
+	return root, groups
+}
+
+// world bounds of every enabled item without the tree
+func bruteBounds(root Object) map[*Item]mesh.Bounds {
+	bounds := map[*Item]mesh.Bounds{}
+	walkPath(root, nil, func(item *Item, path []Object) {
+		bounds[item] = item.Mesh.Bounds().Transform(worldMatrix(path))
+	})
+	return bounds
+}
+
+func bruteFilter(bounds map[*Item]mesh.Bounds, test func(mesh.Bounds) bool) map[*Item]bool {
+	found := map[*Item]bool{}
+	for item, b := range bounds {
+		if test(b) {
+			found[item] = true
+		}
+	}
+	return found
+}
+
+func sameItems(t *testing.T, name string, got []*Item, want map[*Item]bool) {
+	seen := map[*Item]bool{}
+	for _, item := range got {
+		if !want[item] || seen[item] {
+			t.Errorf("%s: unexpected or duplicate item %p", name, item)
+		}
+		seen[item] = true
+	}
+	if len(seen) != len(want) {
+		t.Errorf("%s: got %d items, expecting %d", name, len(seen), len(want))
+	}
+}
+
+func boundsEqual(a, b mesh.Bounds) bool {
+	return a.Min.ApproxEqualThreshold(b.Min, 1e-4) && a.Max.ApproxEqualThreshold(b.Max, 1e-4)
+}
+
+func TestBVHBuild(t *testing.T) {
+	root, _ := randomScene(1)
+	tree := NewBVH(root)
+	bounds := bruteBounds(root)
+	if tree.Len() != len(bounds) {
+		t.Errorf("tree has %d items, expecting %d enabled items", tree.Len(), len(bounds))
+	}
+	all := mesh.EmptyBounds()
+	for item, want := range bounds {
+		all = all.Union(want)
+		if got, ok := tree.ItemBounds(item); !ok || !boundsEqual(got, want) {
+			t.Errorf("item bounds are %v, expecting %v", got, want)
+		}
+	}
+	if !boundsEqual(tree.Bounds(), all) {
+		t.Errorf("tree bounds are %v, expecting %v", tree.Bounds(), all)
+	}
+	if n := NewBVH(NewGroup()).Len(); n != 0 {
+		t.Errorf("empty scene has %d items", n)
+	}
+}
+
+func TestBVHQueries(t *testing.T) {
+	root, _ := randomScene(2)
+	tree := NewBVH(root)
+	bounds := bruteBounds(root)
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 50; i++ {
+		p, half := randVec(rng, 25), mgl32.Vec3{rng.Float32() * 6, rng.Float32() * 6, rng.Float32() * 6}
+		box := mesh.Bounds{Min: p.Sub(half), Max: p.Add(half)}
+		sameItems(t, "Query", tree.Query(box), bruteFilter(bounds, box.Intersects))
+
+		radius := rng.Float32() * 8
+		within := tree.Within(p, radius)
+		sameItems(t, "Within", within, bruteFilter(bounds, func(b mesh.Bounds) bool { return b.Distance(p) <= radius }))
+		for j := 1; j < len(within); j++ {
+			if bounds[within[j]].Distance(p) < bounds[within[j-1]].Distance(p) {
+				t.Errorf("Within: items are not sorted by distance")
+			}
+		}
+
+		k := 1 + rng.Intn(10)
+		var dists []float32
+		for _, b := range bounds {
+			dists = append(dists, b.Distance(p))
+		}
+		sort.Slice(dists, func(i, j int) bool { return dists[i] < dists[j] })
+		nearest := tree.Nearest(p, k)
+		if len(nearest) != k {
+			t.Fatalf("Nearest: got %d items, expecting %d", len(nearest), k)
+		}
+		for j, item := range nearest {
+			if d := bounds[item].Distance(p); d != dists[j] {
+				t.Errorf("Nearest: item %d is at distance %g, expecting %g", j, d, dists[j])
+			}
+		}
+	}
+}
+
+func TestBVHPick(t *testing.T) {
+	root, _ := randomScene(4)
+	tree := NewBVH(root)
+	rng := rand.New(rand.NewSource(5))
+	hits := 0
+	for i := 0; i < 200; i++ {
+		origin, target := randVec(rng, 40), randVec(rng, 20)
+		ray := Ray{Origin: origin, Dir: target.Sub(origin).Normalize()}
+		want, wantOK := Pick(root, ray)
+		got, ok := tree.Pick(ray)
+		if ok != wantOK || ok && (got.Item != want.Item || got.Triangle != want.Triangle || got.Distance != want.Distance) {
+			t.Errorf("ray %v: tree hit %v %v, expecting %v %v", ray, ok, got, wantOK, want)
+		}
+		if ok {
+			hits++
+		}
+	}
+	if hits == 0 {
+		t.Errorf("no rays hit anything")
+	}
+}
+
+func TestBVHFrustum(t *testing.T) {
+	root, _ := randomScene(6)
+	tree := NewBVH(root)
+	bounds := bruteBounds(root)
+	partial := 0
+	for _, phi := range []float32{0, 60, 150, 270} {
+		for _, r := range []float32{5, 20, 40} {
+			view := NewView(ArcBallCamera(glu.Polar{R: r, Theta: 70, Phi: phi}, mgl32.Vec3{}, 1, 50, 10, 170))
+			view.SetProjection(400, 300)
+			f := view.Frustum(view.ViewMatrix())
+			want := bruteFilter(bounds, f.Intersects)
+			if len(want) > 0 && len(want) < len(bounds) {
+				partial++
+			}
+			sameItems(t, "InFrustum", tree.InFrustum(f), want)
+		}
+	}
+	if partial == 0 {
+		t.Errorf("expecting some views with only part of the scene in the frustum")
+	}
+}
+
+func TestBVHRefit(t *testing.T) {
+	root, groups := randomScene(7)
+	tree := NewBVH(root)
+	// groups[3] is nested in groups[2] so moving groups[2] moves both
+	moving := 0
+	walkPath(groups[2], nil, func(*Item, []Object) { moving++ })
+	if moving != 44 {
+		t.Fatalf("expecting 22 enabled items in each of groups 2 and 3, got %d", moving)
+	}
+	groups[2].Translate(15, -5, 3).RotateX(40)
+	if n := tree.RefitObject(groups[0]); n != 0 {
+		t.Errorf("RefitObject on a group which did not move: %d items moved", n)
+	}
+	if n := tree.RefitObject(groups[2]); n != moving {
+		t.Errorf("RefitObject: %d items moved, expecting %d", n, moving)
+	}
+	item := groups[7].Objects()[1].(*Item)
+	item.Translate(0, 30, 0)
+	if n := tree.Refit(); n != 1 {
+		t.Errorf("Refit: %d items moved, expecting 1", n)
+	}
+	if n := tree.Refit(); n != 0 {
+		t.Errorf("Refit again: %d items moved, expecting 0", n)
+	}
+	bounds := bruteBounds(root)
+	all := mesh.EmptyBounds()
+	for item, want := range bounds {
+		all = all.Union(want)
+		if got, _ := tree.ItemBounds(item); !boundsEqual(got, want) {
+			t.Errorf("item bounds after refit are %v, expecting %v", got, want)
+		}
+	}
+	if !boundsEqual(tree.Bounds(), all) {
+		t.Errorf("tree bounds after refit are %v, expecting %v", tree.Bounds(), all)
+	}
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 50; i++ {
+		p := randVec(rng, 30)
+		box := mesh.Bounds{Min: p.Sub(mgl32.Vec3{4, 4, 4}), Max: p.Add(mgl32.Vec3{4, 4, 4})}
+		sameItems(t, "Query after refit", tree.Query(box), bruteFilter(bounds, box.Intersects))
+		origin := randVec(rng, 40)
+		ray := Ray{Origin: origin, Dir: p.Sub(origin).Normalize()}
+		want, wantOK := Pick(root, ray)
+		if got, ok := tree.Pick(ray); ok != wantOK || got.Item != want.Item {
+			t.Errorf("Pick after refit: tree hit %v %p, expecting %v %p", ok, got.Item, wantOK, want.Item)
+		}
+	}
+}
+
+// cube whose vertices are all moved by joint 0
+func skinnedCube() *mesh.Mesh {
+	m := mesh.New().SetFormat(mesh.Format{Attributes: mesh.Normals | mesh.Joints})
+	for i := 0; i < 8; i++ {
+		m.AddVertex(float32(i&1*2-1), float32(i>>1&1*2-1), float32(i>>2&1*2-1))
+		m.AddWeights([4]int{0, 0, 0, 0}, [4]float32{1, 0, 0, 0})
+	}
+	m.AddNormal(0, 0, 1)
+	for _, f := range [][4]int{{1, 3, 4, 2}, {5, 6, 8, 7}, {1, 2, 6, 5}, {3, 7, 8, 4}, {1, 5, 7, 3}, {2, 4, 8, 6}} {
+		m.AddFace(mesh.El{Vert: f[0], Norm: 1}, mesh.El{Vert: f[1], Norm: 1}, mesh.El{Vert: f[2], Norm: 1}, mesh.El{Vert: f[3], Norm: 1})
+	}
+	m.Build("")
+	return m
+}
+
+func TestBVHSkinned(t *testing.T) {
+	item := NewItem(skinnedCube())
+	joint, other := NewGroup(), NewGroup()
+	root := NewGroup()
+	root.Add(item, joint, other)
+	item.Skeleton = NewSkeleton([]Object{joint}, nil).Bind(item)
+	joint.Translate(6, 0, 0)
+	tree := NewBVH(root)
+	at := func(p mgl32.Vec3) mesh.Bounds {
+		return mesh.Bounds{Min: p.Sub(mgl32.Vec3{0.5, 0.5, 0.5}), Max: p.Add(mgl32.Vec3{0.5, 0.5, 0.5})}
+	}
+	tests := []struct {
+		name   string
+		pos    mgl32.Vec3
+		hit    bool
+		normal mgl32.Vec3
+	}{
+		{"bind pose", mgl32.Vec3{}, false, mgl32.Vec3{}},
+		{"posed", mgl32.Vec3{6, 0, 0}, true, mgl32.Vec3{0, 0, 1}},
+	}
+	check := func(stage string) {
+		for _, test := range tests {
+			if n := len(tree.Query(at(test.pos))); (n == 1) != test.hit {
+				t.Errorf("%s %s: query found %d items", stage, test.name, n)
+			}
+			ray := Ray{Origin: test.pos.Add(mgl32.Vec3{0.2, 0.3, 10}), Dir: mgl32.Vec3{0, 0, -1}}
+			for i, pick := range []func(Ray) (Hit, bool){tree.Pick, func(r Ray) (Hit, bool) { return Pick(root, r) }} {
+				h, ok := pick(ray)
+				if ok != test.hit {
+					t.Errorf("%s %s: pick %d hit is %v", stage, test.name, i, ok)
+					continue
+				}
+				want := test.pos.Add(mgl32.Vec3{0.2, 0.3, 1})
+				if ok && (h.Item != item || h.Point.Sub(want).Len() > 1e-4 || h.Normal.Sub(test.normal).Len() > 1e-4) {
+					t.Errorf("%s %s: pick %d hit %v, expecting point %v normal %v", stage, test.name, i, h, want, test.normal)
+				}
+			}
+		}
+	}
+	check("build")
+	// moving the joint moves the bounds, though the item has not moved
+	joint.Translate(0, 0, -6).RotateZ(90)
+	tests[1].pos = mgl32.Vec3{6, 0, -6}
+	if n := tree.RefitObject(other); n != 1 {
+		t.Errorf("refit moved %d items, expecting 1", n)
+	}
+	if n := tree.Refit(); n != 0 {
+		t.Errorf("refit again moved %d items, expecting 0", n)
+	}
+	check("refit")
+}
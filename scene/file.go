@@ -0,0 +1,496 @@
+package scene
+
+// Scene files are JSON documents with the camera, the lights and a tree of nodes, for example
+//
+//	{
+//	  "camera": {"type": "arcball", "center": [0, 1, 0], "distance": 5, "theta": 60, "phi": 30},
+//	  "lights": [{"color": [0.8, 0.8, 0.8], "ambient": 0.2, "direction": [0, 1, 1]}],
+//	  "root": {
+//	    "name": "world",
+//	    "children": [
+//	      {"name": "floor", "mesh": {"shape": "plane"}, "material": {"name": "marble"}, "scale": [10, 1, 10]},
+//	      {"name": "lamp", "mesh": {"shape": "sphere", "detail": 2}, "position": [0, 3, 0],
+//	        "light": {"color": [1, 1, 0.8], "ambient": 0.1, "attenuation": 0.5}},
+//	      {"name": "car", "position": [2, 0, 0], "rotation": [0, 90, 0], "tags": ["vehicle"], "children": [
+//	        {"name": "body", "mesh": {"file": "car.obj", "part": "body"}},
+//	        {"name": "wheel_fl", "mesh": {"file": "car.obj", "part": "wheel_fl"}, "disabled": true}
+//	      ]}
+//	    ]
+//	  }
+//	}
+//
+// Nodes with a mesh are items and the others are groups. All nodes can have these fields:
+//
+//	name, tags  used to find objects with Group.Find, Glob and Select
+//	position    translation in the coordinate space of the parent, default [0, 0, 0]
+//	rotation    angles in degrees about the X, then Y, then Z axes, default [0, 0, 0]
+//	scale       scale factor along each axis, default [1, 1, 1]
+//	disabled    set to true if the node is not drawn
+//
+// and items can also have:
+//
+//	mesh        a built in shape or an obj file as described by mesh.Source. Relative paths in a scene
+//	            file are relative to the directory with the scene file.
+//	material    name which is passed to mesh.LoadMaterial with optional color and ambient overrides. If this
+//	            is omitted then the default for the shape or the materials from the obj file are used.
+//	light       point light attached to the item with color, ambient, attenuation and off fields
+//
+// Lights in the view have a color and ambient with a direction for a directional light or a position and
+// attenuation for a point light. The camera type is either "arcball" with center, distance, theta, phi and
+// optional minDistance, maxDistance, minTheta and maxTheta limits, or "pov" with position and direction.
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/mesh"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+type sceneFile struct {
+	Camera *cameraData `json:"camera,omitempty"`
+	Lights []lightData `json:"lights,omitempty"`
+	Root   *nodeData   `json:"root"`
+}
+
+type nodeData struct {
+	Name     string        `json:"name,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	Position []float32     `json:"position,omitempty"`
+	Rotation []float32     `json:"rotation,omitempty"`
+	Scale    []float32     `json:"scale,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
+	Mesh     *mesh.Source  `json:"mesh,omitempty"`
+	Material *materialData `json:"material,omitempty"`
+	Light    *lightData    `json:"light,omitempty"`
+	Children []*nodeData   `json:"children,omitempty"`
+}
+
+type materialData struct {
+	Name    string    `json:"name"`
+	Color   []float32 `json:"color,omitempty"`
+	Ambient *float32  `json:"ambient,omitempty"`
+}
+
+type lightData struct {
+	Color       []float32 `json:"color"`
+	Ambient     float32   `json:"ambient"`
+	Direction   []float32 `json:"direction,omitempty"`
+	Position    []float32 `json:"position,omitempty"`
+	Attenuation float32   `json:"attenuation,omitempty"`
+	Off         bool      `json:"off,omitempty"`
+}
+
+type cameraData struct {
+	Type        string    `json:"type"`
+	Center      []float32 `json:"center,omitempty"`
+	Distance    float32   `json:"distance,omitempty"`
+	Theta       float32   `json:"theta,omitempty"`
+	Phi         float32   `json:"phi,omitempty"`
+	MinDistance float32   `json:"minDistance,omitempty"`
+	MaxDistance float32   `json:"maxDistance,omitempty"`
+	MinTheta    float32   `json:"minTheta,omitempty"`
+	MaxTheta    float32   `json:"maxTheta,omitempty"`
+	Position    []float32 `json:"position,omitempty"`
+	Direction   []float32 `json:"direction,omitempty"`
+}
+
+// SaveFile writes the scene to a JSON file. Relative paths to model files are converted so they are relative
+// to the directory of the scene file. The view is optional, if it is nil then the camera and lights are not saved.
+func SaveFile(name string, root *Group, view *View) error {
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = save(f, root, view, dir); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Save writes the scene to w in JSON format. Items whose mesh does not have a source, such as meshes built
+// in code, are skipped as they can't be loaded again.
+func Save(w io.Writer, root *Group, view *View) error {
+	return save(w, root, view, "")
+}
+
+func save(w io.Writer, root *Group, view *View, dir string) error {
+	data := sceneFile{Root: saveNode(root, dir)}
+	if view != nil {
+		data.Camera = saveCamera(view.Camera)
+		for _, l := range view.Lights {
+			data.Lights = append(data.Lights, *saveLight(l, true))
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+func saveNode(obj Object, dir string) *nodeData {
+	n := &nodeData{Name: obj.Name(), Tags: obj.Tags(), Disabled: !obj.Enabled()}
+	if pos := obj.Position(); pos != (mgl32.Vec3{}) {
+		n.Position = pos[:]
+	}
+	if angles := eulerAngles(obj.Rotation()); angles != (mgl32.Vec3{}) {
+		n.Rotation = angles[:]
+	}
+	if scale := obj.Scaling(); scale != (mgl32.Vec3{1, 1, 1}) {
+		n.Scale = scale[:]
+	}
+	switch o := obj.(type) {
+	case *Group:
+		for _, child := range o.objects {
+			if c := saveNode(child, dir); c != nil {
+				n.Children = append(n.Children, c)
+			}
+		}
+	case *Item:
+		src := o.Mesh.Source()
+		if src.Shape == "" && src.File == "" {
+			return nil
+		}
+		if src.File != "" && dir != "" && !filepath.IsAbs(src.File) {
+			if abs, err := filepath.Abs(src.File); err == nil {
+				if rel, err := filepath.Rel(dir, abs); err == nil {
+					src.File = filepath.ToSlash(rel)
+				}
+			}
+		}
+		n.Mesh = &src
+		if o.Mesh.CustomMaterial() {
+			mtl := o.Material()
+			if o.Light != nil {
+				// material is swapped when the light is switched on or off
+				mtl = o.lightMat[o.Mesh.PointSize() != 0]
+			}
+			n.Material = saveMaterial(mtl)
+		}
+		if o.Light != nil {
+			n.Light = saveLight(o.Light, false)
+		}
+	}
+	return n
+}
+
+// only write the color and ambient if they are different from the default for the named material
+func saveMaterial(mtl mesh.Material) *materialData {
+	name := mesh.MaterialName(mtl)
+	if name == "" {
+		return nil
+	}
+	m := &materialData{Name: name}
+	def, err := mesh.LoadMaterial(name, true)
+	if err != nil || mtl.Color() != def.Color() {
+		col := mtl.Color()
+		m.Color = col[:]
+	}
+	if err != nil || mtl.Ambient() != def.Ambient() {
+		amb := mtl.Ambient()
+		m.Ambient = &amb
+	}
+	return m
+}
+
+func saveLight(l *Light, view bool) *lightData {
+	col := l.Col.Vec3()
+	d := &lightData{Color: col[:], Ambient: l.Col.W(), Off: !l.On}
+	if l.posw == 0 {
+		dir := l.Pos.Vec3()
+		d.Direction = dir[:]
+	} else {
+		d.Attenuation = l.Pos.W()
+		if view {
+			pos := l.Pos.Vec3()
+			d.Position = pos[:]
+		}
+	}
+	return d
+}
+
+func saveCamera(camera Camera) *cameraData {
+	switch c := camera.(type) {
+	case *arcBallCamera:
+		return &cameraData{
+			Type: "arcball", Center: c.center[:], Distance: c.toEye.R, Theta: c.toEye.Theta, Phi: c.toEye.Phi,
+			MinDistance: c.minz, MaxDistance: c.maxz, MinTheta: c.mint, MaxTheta: c.maxt,
+		}
+	case *povCamera:
+		return &cameraData{Type: "pov", Position: c.pos[:], Direction: c.dir[:]}
+	}
+	return nil
+}
+
+// LoadFile reads a scene from a JSON file. The view is nil if the file does not have a camera. Materials are
+// created as the scene is loaded, so this must be called after glu.Init.
+func LoadFile(name string) (*Group, *View, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return load(f, filepath.Dir(name))
+}
+
+// Load reads a scene in JSON format from r, relative paths to model files are relative to the current directory.
+func Load(r io.Reader) (*Group, *View, error) {
+	return load(r, "")
+}
+
+type loader struct {
+	dir   string
+	files map[string]*mesh.Mesh
+	used  map[*mesh.Mesh]bool
+}
+
+func load(r io.Reader, dir string) (root *Group, view *View, err error) {
+	var data sceneFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&data); err != nil {
+		return nil, nil, fmt.Errorf("scene.Load: %v", err)
+	}
+	if data.Root == nil || data.Root.Mesh != nil {
+		return nil, nil, fmt.Errorf("scene.Load: root must be a group")
+	}
+	l := &loader{dir: dir, files: map[string]*mesh.Mesh{}, used: map[*mesh.Mesh]bool{}}
+	obj, err := l.node(data.Root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scene.Load: %v", err)
+	}
+	root = obj.(*Group)
+	if data.Camera != nil {
+		camera, err := loadCamera(data.Camera)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scene.Load: %v", err)
+		}
+		view = NewView(camera)
+		for _, d := range data.Lights {
+			light, err := loadLight(d)
+			if err != nil {
+				return nil, nil, fmt.Errorf("scene.Load: %v", err)
+			}
+			view.AddLight(light)
+		}
+	} else if len(data.Lights) > 0 {
+		return nil, nil, fmt.Errorf("scene.Load: lights need a camera")
+	}
+	return root, view, nil
+}
+
+func (l *loader) node(n *nodeData) (Object, error) {
+	var obj Object
+	if n.Mesh != nil {
+		if len(n.Children) > 0 {
+			return nil, fmt.Errorf("item %q can't have children", n.Name)
+		}
+		item, err := l.item(n)
+		if err != nil {
+			return nil, err
+		}
+		obj = item
+	} else {
+		g := NewGroup()
+		for _, c := range n.Children {
+			child, err := l.node(c)
+			if err != nil {
+				return nil, err
+			}
+			g.Add(child)
+		}
+		obj = g
+	}
+	pos, err := vec3(n.Position, mgl32.Vec3{}, n.Name, "position")
+	if err != nil {
+		return nil, err
+	}
+	angles, err := vec3(n.Rotation, mgl32.Vec3{}, n.Name, "rotation")
+	if err != nil {
+		return nil, err
+	}
+	scale, err := vec3(n.Scale, mgl32.Vec3{1, 1, 1}, n.Name, "scale")
+	if err != nil {
+		return nil, err
+	}
+	obj.SetPosition(pos).SetRotation(fromEuler(angles)).SetScale(scale)
+	return obj.SetName(n.Name).Tag(n.Tags...).Enable(!n.Disabled), nil
+}
+
+func (l *loader) item(n *nodeData) (*Item, error) {
+	m, err := l.mesh(*n.Mesh)
+	if err != nil {
+		return nil, err
+	}
+	item := NewItem(m)
+	if n.Material != nil {
+		mtl, err := mesh.LoadMaterial(n.Material.Name, true)
+		if err != nil {
+			return nil, err
+		}
+		// materials from mtl files are cached so take a copy before changing them
+		mtl = mtl.Clone()
+		if n.Material.Color != nil {
+			if len(n.Material.Color) != 4 {
+				return nil, fmt.Errorf("%q: material color should have 4 values", n.Name)
+			}
+			mtl.SetColor(mgl32.Vec4{n.Material.Color[0], n.Material.Color[1], n.Material.Color[2], n.Material.Color[3]})
+		}
+		if n.Material.Ambient != nil {
+			mtl.SetAmbient(*n.Material.Ambient)
+		}
+		item.SetMaterial(mtl)
+	}
+	if n.Light != nil {
+		col, err := vec3(n.Light.Color, mgl32.Vec3{1, 1, 1}, n.Name, "light color")
+		if err != nil {
+			return nil, err
+		}
+		item.Illuminate(1, n.Light.Ambient, n.Light.Attenuation)
+		item.Light.Col = col.Vec4(n.Light.Ambient)
+		item.Light.On = !n.Light.Off
+	}
+	return item, nil
+}
+
+// obj files are only loaded once, with each part sharing the same vertex data
+func (l *loader) mesh(src mesh.Source) (*mesh.Mesh, error) {
+	if src.File == "" {
+		return mesh.FromSource(src)
+	}
+	file := src.File
+	if l.dir != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(l.dir, filepath.FromSlash(file))
+	}
+	full, ok := l.files[file]
+	if !ok {
+		var err error
+		if full, err = mesh.LoadObjFile(file); err != nil {
+			return nil, err
+		}
+		l.files[file] = full
+	}
+	var m *mesh.Mesh
+	switch {
+	case src.Part != "":
+		m = full.Part(src.Part)
+	case !l.used[full]:
+		m = full
+		l.used[full] = true
+	default:
+		m = full.Clone()
+	}
+	if src.Inverted {
+		m = m.Invert()
+	}
+	return m, nil
+}
+
+func loadLight(d lightData) (*Light, error) {
+	col, err := vec3(d.Color, mgl32.Vec3{1, 1, 1}, "light", "color")
+	if err != nil {
+		return nil, err
+	}
+	var l *Light
+	if d.Direction != nil {
+		dir, err := vec3(d.Direction, mgl32.Vec3{}, "light", "direction")
+		if err != nil {
+			return nil, err
+		}
+		// normalizing a unit vector again can change the last bit, so leave it as saved
+		if math.Abs(float64(dir.Len())-1) > 1e-6 {
+			dir = dir.Normalize()
+		}
+		l = &Light{Pos: dir.Vec4(0), Col: col.Vec4(d.Ambient)}
+	} else {
+		pos, err := vec3(d.Position, mgl32.Vec3{}, "light", "position")
+		if err != nil {
+			return nil, err
+		}
+		l = PointLight(col, d.Ambient, pos, d.Attenuation)
+	}
+	l.On = !d.Off
+	return l, nil
+}
+
+func loadCamera(d *cameraData) (Camera, error) {
+	switch d.Type {
+	case "arcball":
+		center, err := vec3(d.Center, mgl32.Vec3{}, "camera", "center")
+		if err != nil {
+			return nil, err
+		}
+		maxz, maxt := d.MaxDistance, d.MaxTheta
+		if maxz == 0 {
+			maxz = math.MaxFloat32
+		}
+		if maxt == 0 {
+			maxt = 180
+		}
+		toEye := glu.Polar{R: d.Distance, Theta: d.Theta, Phi: d.Phi}
+		return ArcBallCamera(toEye, center, d.MinDistance, maxz, d.MinTheta, maxt), nil
+	case "pov":
+		pos, err := vec3(d.Position, mgl32.Vec3{}, "camera", "position")
+		if err != nil {
+			return nil, err
+		}
+		dir, err := vec3(d.Direction, mgl32.Vec3{0, 0, -1}, "camera", "direction")
+		if err != nil {
+			return nil, err
+		}
+		return POVCamera(pos, dir), nil
+	}
+	return nil, fmt.Errorf("unknown camera type %q", d.Type)
+}
+
+func vec3(v []float32, def mgl32.Vec3, name, field string) (mgl32.Vec3, error) {
+	switch len(v) {
+	case 0:
+		return def, nil
+	case 3:
+		return mgl32.Vec3{v[0], v[1], v[2]}, nil
+	}
+	return def, fmt.Errorf("%q: %s should have 3 values", name, field)
+}
+
+// rotation about the X axis, then Y, then Z in the local coordinate space, as for RotateX then RotateY and
+// RotateZ
+func fromEuler(angles mgl32.Vec3) mgl32.Quat {
+	q := mgl32.QuatIdent()
+	for i, axis := range []mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		q = q.Mul(mgl32.QuatRotate(mgl32.DegToRad(angles[i]), axis))
+	}
+	return q
+}
+
+// inverse of fromEuler, rounded so values which were typed in are written back out the same
+func eulerAngles(q mgl32.Quat) mgl32.Vec3 {
+	m := q.Mat4()
+	var x, y, z float64
+	if sy := float64(m.At(0, 2)); math.Abs(sy) < 0.99999 {
+		y = math.Asin(sy)
+		x = math.Atan2(float64(-m.At(1, 2)), float64(m.At(2, 2)))
+		z = math.Atan2(float64(-m.At(0, 1)), float64(m.At(0, 0)))
+	} else {
+		// gimbal lock, so put all of the rotation about X
+		y = math.Copysign(math.Pi/2, sy)
+		x = math.Atan2(float64(m.At(2, 1)), float64(m.At(1, 1)))
+	}
+	var angles mgl32.Vec3
+	for i, a := range []float64{x, y, z} {
+		deg := math.Round(a*180/math.Pi*1e4) / 1e4
+		if deg == 0 {
+			deg = 0 // no negative zero
+		}
+		angles[i] = float32(deg)
+	}
+	return angles
+}
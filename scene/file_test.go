@@ -0,0 +1,134 @@
+package scene
+
+import (
+	"bytes"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/mesh"
+	"strings"
+	"testing"
+)
+
+func shape(name string, detail int) *Item {
+	m, err := mesh.FromSource(mesh.Source{Shape: name, Detail: detail})
+	if err != nil {
+		panic(err)
+	}
+	return NewItem(m)
+}
+
+// scene with each of the node fields set somewhere in the tree
+func fileScene() (*Group, *View) {
+	root := NewGroup()
+	root.SetName("world")
+	floor := shape("plane", 0)
+	floor.SetName("floor").SetScale(mgl32.Vec3{10, 1, 10})
+	car := NewGroup()
+	car.SetName("car").Tag("vehicle", "red").SetPosition(mgl32.Vec3{2, 0, -1}).SetRotation(fromEuler(mgl32.Vec3{10, 90, 30}))
+	body := shape("cube", 0)
+	body.SetName("body").SetMaterial(mesh.Plastic().Clone().SetColor(mgl32.Vec4{1, 0, 0, 1}))
+	wheel := shape("cylinder", 12)
+	wheel.SetName("wheel_fl").Tag("wheel").SetPosition(mgl32.Vec3{1, -0.5, 1}).Enable(false)
+	car.Add(body, wheel)
+	lamp := shape("sphere", 2)
+	lamp.SetMaterial(mesh.Diffuse().SetColor(mgl32.Vec4{1, 1, 0.5, 1}))
+	lamp.Illuminate(1, 0.1, 0.5)
+	lamp.Light.On = false
+	lamp.SetName("lamp").SetPosition(mgl32.Vec3{0, 3, 0})
+	// built in code so it is not saved
+	custom := NewItem(square())
+	custom.SetName("custom")
+	root.Add(floor, car, lamp, custom)
+
+	view := NewView(ArcBallCamera(glu.Polar{R: 5, Theta: 60, Phi: 30}, mgl32.Vec3{0, 1, 0}, 1, 20, 10, 170))
+	view.AddLight(DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, glu.Polar{Theta: 45, Phi: 30}))
+	view.AddLight(PointLight(mgl32.Vec3{1, 1, 1}, 0, mgl32.Vec3{1, 2, 3}, 0.25))
+	return root, view
+}
+
+func TestSaveLoad(t *testing.T) {
+	setup()
+	root, view := fileScene()
+	var first bytes.Buffer
+	if err := Save(&first, root, view); err != nil {
+		t.Fatal(err)
+	}
+	root2, view2, err := Load(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := Save(&second, root2, view2); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("saved scene changed after loading:\n%s\nexpecting\n%s", &second, &first)
+	}
+	for _, s := range []string{`"rotation": [`, `"tags": [`, `"disabled": true`, `"name": "plastic"`, `"attenuation": 0.5`} {
+		if !strings.Contains(first.String(), s) {
+			t.Errorf("saved scene does not have %s:\n%s", s, &first)
+		}
+	}
+	if root2.Find("custom") != nil {
+		t.Errorf("item without a source was saved")
+	}
+
+	// check the loaded objects match the originals
+	for _, path := range []string{"floor", "car", "car/body", "car/wheel_fl", "lamp"} {
+		a, b := root.Find(path), root2.Find(path)
+		if b == nil {
+			t.Errorf("%s not found", path)
+			continue
+		}
+		ta, tb := a.WorldTransform(), b.WorldTransform()
+		if a.Enabled() != b.Enabled() || !matEqual(ta.Mat4(), tb.Mat4()) {
+			t.Errorf("%s: enabled %v matrix %v, expecting %v %v", path, b.Enabled(), tb.Mat4(), a.Enabled(), ta.Mat4())
+		}
+	}
+	if car := root2.Find("car"); strings.Join(car.Tags(), ",") != "vehicle,red" {
+		t.Errorf("car tags are %v", car.Tags())
+	}
+	if body := root2.Find("car/body").(*Item); mesh.MaterialName(body.Material()) != "plastic" || body.Material().Color() != (mgl32.Vec4{1, 0, 0, 1}) {
+		t.Errorf("body material is %s %v", mesh.MaterialName(body.Material()), body.Material().Color())
+	}
+	lamp := root2.Find("lamp").(*Item)
+	if lamp.Light == nil || lamp.Light.On || lamp.Light.Col != (mgl32.Vec4{1, 1, 0.5, 0.1}) || lamp.Light.Pos.W() != 0.5 {
+		t.Errorf("lamp light is %+v", lamp.Light)
+	}
+	if len(view2.Lights) != 2 || !view2.Lights[0].Directional() || view2.Lights[1].Pos != (mgl32.Vec4{1, 2, 3, 0.25}) {
+		t.Errorf("view lights are %+v", view2.Lights)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	setup()
+	tests := []struct {
+		json, err string
+	}{
+		{`{"root": {"children": [{"name": "car", "position": [1, 2]}]}}`, `"car": position should have 3 values`},
+		{`{"root": {"children": [{"name": "car", "rotation": [1, 2, 3, 4]}]}}`, `"car": rotation should have 3 values`},
+		{`{"root": {"name": "world", "scale": [2]}}`, `"world": scale should have 3 values`},
+		{`{"root": {"children": [{"name": "lamp", "mesh": {"shape": "sphere"}, "light": {"color": [1, 1], "ambient": 0}}]}}`,
+			`"lamp": light color should have 3 values`},
+		{`{"root": {"children": [{"name": "box", "mesh": {"shape": "cube"}, "material": {"name": "plastic", "color": [1, 0, 0]}}]}}`,
+			`"box": material color should have 4 values`},
+		{`{"camera": {"type": "arcball", "center": [0, 1]}, "root": {}}`, `"camera": center should have 3 values`},
+		{`{"camera": {"type": "pov", "direction": [0, 0, 0, 1]}, "root": {}}`, `"camera": direction should have 3 values`},
+		{`{"camera": {"type": "pov"}, "lights": [{"color": [1, 1, 1], "ambient": 0, "direction": [1]}], "root": {}}`,
+			`"light": direction should have 3 values`},
+		{`{"camera": {"type": "fixed"}, "root": {}}`, `unknown camera type "fixed"`},
+		{`{"lights": [{"color": [1, 1, 1], "ambient": 0}], "root": {}}`, `lights need a camera`},
+		{`{"root": {"mesh": {"shape": "cube"}}}`, `root must be a group`},
+		{`{"root": {"children": [{"name": "box", "mesh": {"shape": "cube"}, "children": [{}]}]}}`, `item "box" can't have children`},
+		{`{"root": {"children": [{"mesh": {"shape": "teapot"}}]}}`, `FromSource: no shape called "teapot"`},
+		{`{"root": {"colour": [1, 0, 0]}}`, `json: unknown field "colour"`},
+	}
+	for _, test := range tests {
+		root, view, err := Load(strings.NewReader(test.json))
+		if err == nil || root != nil || view != nil {
+			t.Errorf("%s: expecting an error", test.json)
+		} else if err.Error() != "scene.Load: "+test.err {
+			t.Errorf("%s: error is %q, expecting %q", test.json, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+)
+
+// FrustumCulling can be cleared to draw every enabled item, e.g. to compare the frame rate.
+var FrustumCulling = true
+
+// CullStats has the number of items drawn and skipped by View.Draw since the last call to SetProjection.
+// Culled counts items which were outside the view, including those in groups which were skipped entirely.
+type CullStats struct {
+	Drawn  int
+	Culled int
+}
+
+// Frustum type has the left, right, bottom, top, near and far clipping planes. Each plane is stored as
+// (a, b, c, d) with the normal pointing inwards, so a point p is inside if dot(p, abc) + d >= 0.
+type Frustum [6]mgl32.Vec4
+
+// NewFrustum extracts the clipping planes from a combined projection and view matrix. The planes are in the
+// space that the matrix transforms from, so for View.Proj times the view matrix they are in world space.
+func NewFrustum(m mgl32.Mat4) Frustum {
+	var f Frustum
+	r := [4]mgl32.Vec4{m.Row(0), m.Row(1), m.Row(2), m.Row(3)}
+	for i := 0; i < 3; i++ {
+		f[2*i] = r[3].Add(r[i])
+		f[2*i+1] = r[3].Sub(r[i])
+	}
+	for i, p := range f {
+		if l := p.Vec3().Len(); l > 0 {
+			f[i] = p.Mul(1 / l)
+		}
+	}
+	return f
+}
+
+// Intersects checks if the bounds are at least partly inside the frustum. The bounding sphere is tested
+// first, then the box.
+func (f Frustum) Intersects(b mesh.Bounds) bool {
+	if b.Empty() {
+		return false
+	}
+	center := b.Center()
+	for _, p := range f {
+		n := p.Vec3()
+		if n.Dot(center)+p[3] < -b.Radius {
+			return false
+		}
+		// corner of the box which is furthest along the plane normal
+		corner := b.Min
+		for i := 0; i < 3; i++ {
+			if n[i] > 0 {
+				corner[i] = b.Max[i]
+			}
+		}
+		if n.Dot(corner)+p[3] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Frustum returns the view frustum in world space for the current projection and given view matrix.
+func (v *View) Frustum(worldToCamera mgl32.Mat4) Frustum {
+	return NewFrustum(v.Proj.Mul4(worldToCamera))
+}
+
+// visible calls fn for each enabled item under root which is inside the view frustum, where trans maps from
+// world to camera space. The bounds of every object are found in camera space in one pass from the leaves
+// up, so each group is tested against the same frustum without computing the bounds again at every level.
+func (v *View) visible(root Object, trans Transform, fn func(*Item, Transform)) {
+	if v.bounds == nil {
+		v.bounds = map[Object]mesh.Bounds{}
+	}
+	for obj := range v.bounds {
+		delete(v.bounds, obj)
+	}
+	v.cameraBounds(root, trans.Mat4())
+	v.visit(root, trans, NewFrustum(v.Proj), fn)
+}
+
+// cameraBounds saves the bounds of obj and the enabled objects under it, where trans maps from the parent of
+// obj to camera space.
+func (v *View) cameraBounds(obj Object, trans mgl32.Mat4) mesh.Bounds {
+	b := mesh.EmptyBounds()
+	if !obj.Enabled() {
+		return b
+	}
+	switch o := obj.(type) {
+	case *Group:
+		m := trans.Mul4(o.Transform.Mat4())
+		for _, child := range o.objects {
+			b = b.Union(v.cameraBounds(child, m))
+		}
+	case *Item:
+		b = o.modelBounds().Transform(trans.Mul4(o.Transform.Mat4()))
+	default:
+		return b
+	}
+	v.bounds[obj] = b
+	return b
+}
+
+// visit calls fn for each enabled item under obj with camera space bounds inside the frustum. trans maps from
+// the parent of obj to camera space.
+func (v *View) visit(obj Object, trans Transform, f Frustum, fn func(*Item, Transform)) {
+	if !obj.Enabled() {
+		return
+	}
+	switch o := obj.(type) {
+	case *Group:
+		if !f.Intersects(v.bounds[o]) {
+			v.Stats.Culled += o.count()
+			return
+		}
+		newTrans := trans.Mul(&o.Transform)
+		for _, child := range o.objects {
+			v.visit(child, newTrans, f, fn)
+		}
+	case *Item:
+		if !f.Intersects(v.bounds[o]) {
+			v.Stats.Culled++
+			return
+		}
+		v.Stats.Drawn++
+		o.Do(trans, fn)
+	default:
+		// other object types are always drawn
+		obj.Do(trans, func(item *Item, t Transform) {
+			v.Stats.Drawn++
+			fn(item, t)
+		})
+	}
+}
+
+// number of enabled items in the group
+func (g *Group) count() (n int) {
+	g.Do(NewTransform(mgl32.Ident4()), func(*Item, Transform) { n++ })
+	return n
+}
@@ -0,0 +1,75 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"testing"
+)
+
+func TestVisible(t *testing.T) {
+	root, _ := randomScene(3)
+	cameras := []Camera{
+		POVCamera(mgl32.Vec3{0, 0, 40}, mgl32.Vec3{0, 0, -1}),
+		POVCamera(mgl32.Vec3{5, 2, 0}, mgl32.Vec3{1, -0.2, 0.5}),
+		ArcBallCamera(glu.Polar{R: 30, Theta: 60, Phi: 40}, mgl32.Vec3{}, 1, 100, 0, 180),
+	}
+	for i, camera := range cameras {
+		view := NewView(camera)
+		view.SetProjection(200, 100)
+		worldToCamera := view.ViewMatrix()
+		// an item is drawn if its bounds in camera space are inside the frustum, whatever the groups above it
+		frustum := NewFrustum(view.Proj)
+		want := map[*Item]bool{}
+		total := 0
+		walkPath(root, nil, func(item *Item, path []Object) {
+			total++
+			if frustum.Intersects(item.Mesh.Bounds().Transform(worldToCamera.Mul4(worldMatrix(path)))) {
+				want[item] = true
+			}
+		})
+		got := []*Item{}
+		view.visible(root, NewTransform(worldToCamera), func(item *Item, trans Transform) {
+			got = append(got, item)
+			if w := item.WorldTransform(); !matEqual(trans.Mat4(), worldToCamera.Mul4(w.Mat4())) {
+				t.Errorf("camera %d: item transform is %v", i, trans.Mat4())
+			}
+		})
+		sameItems(t, "visible", got, want)
+		if len(want) == 0 || len(want) == total {
+			t.Errorf("camera %d: %d of %d items visible, expecting some to be culled", i, len(want), total)
+		}
+		if view.Stats.Drawn != len(want) || view.Stats.Culled != total-len(want) {
+			t.Errorf("camera %d: stats %+v, expecting %d drawn %d culled", i, view.Stats, len(want), total-len(want))
+		}
+	}
+}
+
+func TestVisibleDeep(t *testing.T) {
+	// item at the bottom of a long chain of groups, each moved along x
+	root := NewGroup()
+	g := root
+	for i := 0; i < 100; i++ {
+		child := NewGroup()
+		child.Translate(0.1, 0, 0)
+		g.Add(child)
+		g = child
+	}
+	item := NewItem(cube())
+	g.Add(item)
+	for _, test := range []struct {
+		x     float32
+		drawn bool
+	}{{10, true}, {-10, false}} {
+		view := NewView(POVCamera(mgl32.Vec3{test.x, 0, 10}, mgl32.Vec3{0, 0, -1}))
+		view.SetProjection(100, 100)
+		drawn := false
+		// the bounds are only computed once for each object
+		view.visible(root, NewTransform(view.ViewMatrix()), func(*Item, Transform) { drawn = true })
+		if drawn != test.drawn || len(view.bounds) != 102 {
+			t.Errorf("camera at x=%g: drawn %v with %d bounds, expecting %v", test.x, drawn, len(view.bounds), test.drawn)
+		}
+		if test.drawn && (view.Stats.Drawn != 1 || view.Stats.Culled != 0) || !test.drawn && (view.Stats.Drawn != 0 || view.Stats.Culled != 1) {
+			t.Errorf("camera at x=%g: stats %+v", test.x, view.Stats)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package scene
+
+import (
+	"github.com/jnb666/go3d/mesh"
+	"path"
+	"strings"
+)
+
+// label holds the name and tags which are common to groups and items
+type label struct {
+	name string
+	tags []string
+}
+
+// Name returns the object name, which is used to look it up by path
+func (l *label) Name() string {
+	return l.name
+}
+
+// Tags returns the list of tags added to the object
+func (l *label) Tags() []string {
+	return l.tags
+}
+
+// HasTag checks if the object has the given tag
+func (l *label) HasTag(tag string) bool {
+	for _, t := range l.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *label) addTags(tags []string) {
+	for _, tag := range tags {
+		if !l.HasTag(tag) {
+			l.tags = append(l.tags, tag)
+		}
+	}
+}
+
+func (l *label) removeTags(tags []string) {
+	kept := []string{}
+	for _, t := range l.tags {
+		remove := false
+		for _, tag := range tags {
+			remove = remove || t == tag
+		}
+		if !remove {
+			kept = append(kept, t)
+		}
+	}
+	l.tags = kept
+}
+
+func (l label) clone() label {
+	return label{name: l.name, tags: append([]string{}, l.tags...)}
+}
+
+// SetName method sets the name of the group, which should not contain a '/'
+func (g *Group) SetName(name string) Object {
+	g.name = name
+	return g
+}
+
+// Tag method adds one or more tags to the group
+func (g *Group) Tag(tags ...string) Object {
+	g.addTags(tags)
+	return g
+}
+
+// Untag method removes one or more tags from the group
+func (g *Group) Untag(tags ...string) Object {
+	g.removeTags(tags)
+	return g
+}
+
+// Path returns the names of the group and its parents separated by '/', not including the root group
+func (g *Group) Path() string {
+	return objectPath(g)
+}
+
+// Walk calls fn for this group and then each object under it, including those which are disabled. If fn
+// returns false then the objects under a group are skipped.
+func (g *Group) Walk(fn func(Object) bool) {
+	if fn(g) {
+		for _, obj := range g.objects {
+			obj.Walk(fn)
+		}
+	}
+}
+
+// Find returns the object with the given path relative to this group, or nil if there is none. Groups
+// without a name are not included in paths, so "car/wheel_fl" will find wheel_fl inside an unnamed group
+// in car.
+func (g *Group) Find(name string) Object {
+	var found Object
+	g.walkNames("", func(obj Object, p string) bool {
+		if p == name {
+			found = obj
+		}
+		return found == nil
+	})
+	return found
+}
+
+// Glob returns the objects whose path relative to this group matches the pattern, using the same syntax
+// as path.Match. e.g. "car/wheel_*" or "*/wheel_*".
+func (g *Group) Glob(pattern string) (list []Object, err error) {
+	if _, err = path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	g.walkNames("", func(obj Object, p string) bool {
+		if ok, _ := path.Match(pattern, p); ok {
+			list = append(list, obj)
+		}
+		return true
+	})
+	return list, nil
+}
+
+// Select returns the objects under this group for which fn returns true
+func (g *Group) Select(fn func(Object) bool) []Object {
+	list := []Object{}
+	for _, child := range g.objects {
+		child.Walk(func(obj Object) bool {
+			if fn(obj) {
+				list = append(list, obj)
+			}
+			return true
+		})
+	}
+	return list
+}
+
+// call fn with the relative path of each named object under the group, stops if fn returns false
+func (g *Group) walkNames(prefix string, fn func(Object, string) bool) bool {
+	for _, obj := range g.objects {
+		p := prefix
+		if name := obj.Name(); name != "" {
+			p = path.Join(prefix, name)
+			if !fn(obj, p) {
+				return false
+			}
+		}
+		if grp, ok := obj.(*Group); ok && !grp.walkNames(p, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetName method sets the name of the item, which should not contain a '/'
+func (o *Item) SetName(name string) Object {
+	o.name = name
+	return o
+}
+
+// Tag method adds one or more tags to the item
+func (o *Item) Tag(tags ...string) Object {
+	o.addTags(tags)
+	return o
+}
+
+// Untag method removes one or more tags from the item
+func (o *Item) Untag(tags ...string) Object {
+	o.removeTags(tags)
+	return o
+}
+
+// Path returns the names of the item and its parents separated by '/', not including the root group
+func (o *Item) Path() string {
+	return objectPath(o)
+}
+
+// Walk calls fn with the item, whether or not it is enabled
+func (o *Item) Walk(fn func(Object) bool) {
+	fn(o)
+}
+
+// NewModel creates a group with an item for each of the named groups in the mesh, such as the groups in an
+// obj file, so the parts can be found by name and moved or hidden separately. A mesh with no groups, such as
+// one which has not been built yet, is added as a single unnamed item rather than being dropped.
+func NewModel(name string, msh *mesh.Mesh) *Group {
+	g := NewGroup()
+	g.SetName(name)
+	names := msh.Names()
+	switch len(names) {
+	case 0:
+		return g.Add(NewItem(msh))
+	case 1:
+		return g.Add(NewItem(msh).SetName(names[0]))
+	}
+	for _, part := range names {
+		g.Add(NewItem(msh.Part(part)).SetName(part))
+	}
+	return g
+}
+
+func objectPath(obj Object) string {
+	names := []string{}
+	for parent := obj.Parent(); parent != nil; parent = parent.Parent() {
+		if name := obj.Name(); name != "" {
+			names = append(names, name)
+		}
+		obj = parent
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "/")
+}
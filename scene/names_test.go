@@ -0,0 +1,227 @@
+package scene
+
+import (
+	"github.com/jnb666/go3d/mesh"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// root -> car (body, unnamed group -> wheels) and lamp, with one wheel disabled
+func namedScene() *Group {
+	root := NewGroup()
+	car := NewGroup()
+	car.SetName("car").Tag("vehicle")
+	wheels := NewGroup()
+	for _, name := range []string{"wheel_fl", "wheel_fr", "wheel_rl"} {
+		wheels.Add(NewItem(cube()).SetName(name).Tag("wheel"))
+	}
+	wheels.Find("wheel_fr").Enable(false)
+	car.Add(NewItem(cube()).SetName("body").Tag("paint"), wheels)
+	root.Add(car, NewItem(cube()).SetName("lamp").Tag("light", "paint"))
+	return root
+}
+
+func paths(list []Object) []string {
+	s := []string{}
+	for _, obj := range list {
+		s = append(s, obj.Path())
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestFind(t *testing.T) {
+	root := namedScene()
+	tests := []struct {
+		path, want string
+	}{
+		{"car", "car"},
+		{"car/body", "car/body"},
+		{"car/wheel_fl", "car/wheel_fl"}, // through the unnamed group
+		{"car/wheel_fr", "car/wheel_fr"}, // disabled
+		{"lamp", "lamp"},
+		{"wheel_fl", ""},
+		{"car/lamp", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		obj := root.Find(test.path)
+		if test.want == "" {
+			if obj != nil {
+				t.Errorf("Find %q: found %s, expecting nil", test.path, obj.Path())
+			}
+		} else if obj == nil || obj.Path() != test.want {
+			t.Errorf("Find %q: got %v, expecting %s", test.path, obj, test.want)
+		}
+	}
+	// lookup relative to a group below the root
+	car := root.Find("car").(*Group)
+	if obj := car.Find("wheel_rl"); obj == nil || obj.Path() != "car/wheel_rl" {
+		t.Errorf("Find from car: got %v", obj)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	root := namedScene()
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*", []string{"car", "lamp"}},
+		{"car/*", []string{"car/body", "car/wheel_fl", "car/wheel_fr", "car/wheel_rl"}},
+		{"car/wheel_?l", []string{"car/wheel_fl", "car/wheel_rl"}},
+		{"*/wheel_f*", []string{"car/wheel_fl", "car/wheel_fr"}},
+		{"car/[bw]*_r?", []string{"car/wheel_rl"}},
+		{"truck/*", []string{}},
+	}
+	for _, test := range tests {
+		list, err := root.Glob(test.pattern)
+		if err != nil {
+			t.Errorf("Glob %q: %v", test.pattern, err)
+		} else if got := paths(list); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Glob %q: got %v, expecting %v", test.pattern, got, test.want)
+		}
+	}
+	if _, err := root.Glob("car/[a"); err == nil {
+		t.Errorf("expecting an error for a bad pattern")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	root := namedScene()
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"wheel", []string{"car/wheel_fl", "car/wheel_fr", "car/wheel_rl"}},
+		{"paint", []string{"car/body", "lamp"}},
+		{"vehicle", []string{"car"}},
+		{"engine", []string{}},
+	}
+	for _, test := range tests {
+		list := root.Select(func(obj Object) bool { return obj.HasTag(test.tag) })
+		if got := paths(list); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Select tag %s: got %v, expecting %v", test.tag, got, test.want)
+		}
+	}
+	// the group itself is not included
+	root.Tag("wheel")
+	if list := root.Select(func(obj Object) bool { return obj == root }); len(list) != 0 {
+		t.Errorf("Select included the group")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := namedScene()
+	root.Find("car").Tag("closed")
+	walked := []string{}
+	root.Walk(func(obj Object) bool {
+		if _, ok := obj.(*Item); ok {
+			walked = append(walked, obj.Name())
+		}
+		return true
+	})
+	drawn := []string{}
+	root.Do(Transform{}, func(item *Item, trans Transform) {
+		drawn = append(drawn, item.Name())
+	})
+	sort.Strings(walked)
+	sort.Strings(drawn)
+	if want := []string{"body", "lamp", "wheel_fl", "wheel_fr", "wheel_rl"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk visited %v, expecting %v", walked, want)
+	}
+	if want := []string{"body", "lamp", "wheel_fl", "wheel_rl"}; !reflect.DeepEqual(drawn, want) {
+		t.Errorf("Do visited %v, expecting %v", drawn, want)
+	}
+	// returning false skips the objects under a group
+	visited := []string{}
+	root.Walk(func(obj Object) bool {
+		visited = append(visited, obj.Name())
+		return !obj.HasTag("closed")
+	})
+	if want := []string{"", "car", "lamp"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("Walk with a closed group visited %v, expecting %v", visited, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	root := namedScene()
+	root.SetName("world")
+	tests := []struct {
+		obj  Object
+		want string
+	}{
+		{root, ""},
+		{root.Find("car"), "car"},
+		{root.Find("car/wheel_fr"), "car/wheel_fr"},
+		{NewItem(cube()).SetName("loose"), ""},
+	}
+	for _, test := range tests {
+		if got := test.obj.Path(); got != test.want {
+			t.Errorf("path of %s is %q, expecting %q", test.obj.Name(), got, test.want)
+		}
+	}
+	// path changes when the object is moved
+	wheel := root.Find("car/wheel_fl")
+	root.Reparent(wheel, false)
+	if got := wheel.Path(); got != "wheel_fl" {
+		t.Errorf("path after reparent is %q", got)
+	}
+}
+
+const twoPartObj = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+g base
+f 1 2 3
+f 1 3 4
+g top
+f 1 2 5
+g base
+f 2 3 5
+`
+
+func TestNewModel(t *testing.T) {
+	msh, err := mesh.LoadObj(strings.NewReader(twoPartObj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel("shape", msh)
+	if got := paths(model.Objects()); !reflect.DeepEqual(got, []string{"base", "top"}) {
+		t.Fatalf("model parts are %v", got)
+	}
+	base := model.Find("base").(*Item)
+	top := model.Find("top").(*Item)
+	if base.Mesh == msh || base.Mesh.Source().Part != "base" {
+		t.Errorf("base part mesh is %+v", base.Mesh.Source())
+	}
+	// parts have their own bounds
+	if b := top.Mesh.Bounds(); b.Min[2] != 0 || b.Max[2] != 1 || b.Max[1] != 0 {
+		t.Errorf("top bounds are %v", b)
+	}
+	if b := base.Mesh.Bounds(); b.Max[1] != 1 || b.Max[2] != 1 {
+		t.Errorf("base bounds are %v", b)
+	}
+
+	// a mesh with one group is used as it is
+	cubeMesh := cube()
+	model = NewModel("cube", cubeMesh)
+	if list := model.Objects(); len(list) != 1 || list[0].(*Item).Mesh != cubeMesh {
+		t.Errorf("single part model has %d objects", len(list))
+	}
+
+	// a mesh without any groups is kept as a single item
+	empty := mesh.New()
+	model = NewModel("empty", empty)
+	if list := model.Objects(); len(list) != 1 || list[0].(*Item).Mesh != empty || list[0].Name() != "" {
+		t.Errorf("model of a mesh without groups has %d objects", len(list))
+	}
+	if model.Name() != "empty" {
+		t.Errorf("model name is %q", model.Name())
+	}
+}
@@ -0,0 +1,105 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+)
+
+// Ray type is a half line in world space starting from Origin. Dir is normalized by View.Ray.
+type Ray struct {
+	Origin, Dir mgl32.Vec3
+}
+
+// At returns the point at distance t along the ray
+func (r Ray) At(t float32) mgl32.Vec3 {
+	return r.Origin.Add(r.Dir.Mul(t))
+}
+
+// Hit type is returned by Pick with the item under the ray. Point and Normal are in world space and
+// Distance is from the ray origin. Triangle is the index of the triangle within the item's mesh.
+type Hit struct {
+	Item     *Item
+	Point    mgl32.Vec3
+	Normal   mgl32.Vec3
+	Triangle int
+	Distance float32
+}
+
+// Ray returns the world space ray from the camera through the center of the pixel at x, y in window
+// coordinates, with the origin on the near plane. The y axis points down as for mouse events whether or not
+// FlipY is set. It uses the projection from SetProjection, which should have been called first.
+func (v *View) Ray(x, y int) Ray {
+	ndcX := 2*(float32(x)+0.5)/v.width - 1
+	ndcY := 1 - 2*(float32(y)+0.5)/v.height
+	if v.FlipY {
+		ndcY = -ndcY
+	}
+	inv := v.Proj.Mul4(v.ViewMatrix()).Inv()
+	near := mgl32.TransformCoordinate(mgl32.Vec3{ndcX, ndcY, -1}, inv)
+	far := mgl32.TransformCoordinate(mgl32.Vec3{ndcX, ndcY, 1}, inv)
+	return Ray{Origin: near, Dir: far.Sub(near).Normalize()}
+}
+
+// Pick finds the closest enabled item under root which is hit by the world space ray. Items are first
+// tested against their bounds, then each triangle of the mesh. Point sprites are skipped.
+func Pick(root Object, ray Ray) (hit Hit, ok bool) {
+	walkPath(root, nil, func(item *Item, path []Object) {
+		world := worldMatrix(path)
+		if ok && !closer(item.modelBounds().Transform(world), ray, hit.Distance) {
+			return
+		}
+		if h, found := pickItem(item, world, ray); found && (!ok || h.Distance < hit.Distance) {
+			hit, ok = h, true
+		}
+	})
+	return hit, ok
+}
+
+// Pick is the same as the Pick function but uses the tree to skip items which are not along the ray.
+func (t *BVH) Pick(ray Ray) (hit Hit, ok bool) {
+	t.search(func(b mesh.Bounds) bool {
+		return !ok && intersects(b, ray) || ok && closer(b, ray, hit.Distance)
+	}, func(leaf *bvhLeaf) {
+		if h, found := pickItem(leaf.item, leaf.world, ray); found && (!ok || h.Distance < hit.Distance) {
+			hit, ok = h, true
+		}
+	})
+	return hit, ok
+}
+
+func intersects(b mesh.Bounds, ray Ray) bool {
+	_, ok := b.IntersectRay(ray.Origin, ray.Dir)
+	return ok
+}
+
+// check if the ray enters the box before distance
+func closer(b mesh.Bounds, ray Ray, dist float32) bool {
+	t, ok := b.IntersectRay(ray.Origin, ray.Dir)
+	return ok && t < dist
+}
+
+// intersect the ray with the item's mesh in model space, where world maps from model to world space.
+// Skinned meshes are posed by the item's skeleton first.
+func pickItem(item *Item, world mgl32.Mat4, ray Ray) (Hit, bool) {
+	if item.Mesh.PointSize() != 0 || !intersects(item.modelBounds().Transform(world), ray) {
+		return Hit{}, false
+	}
+	if item.Skeleton != nil {
+		item.Mesh.SetPose(item.Skeleton.Matrices(item))
+	}
+	inv := world.Inv()
+	// the direction is not normalized so the distance along the ray is the same in both spaces
+	origin := mgl32.TransformCoordinate(ray.Origin, inv)
+	dir := inv.Mul4x1(ray.Dir.Vec4(0)).Vec3()
+	h, ok := item.Mesh.Intersect(origin, dir)
+	if !ok {
+		return Hit{}, false
+	}
+	return Hit{
+		Item:     item,
+		Point:    ray.At(h.Distance),
+		Normal:   inv.Mat3().Transpose().Mul3x1(h.Normal).Normalize(),
+		Triangle: h.Triangle,
+		Distance: h.Distance,
+	}, true
+}
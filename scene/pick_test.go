@@ -0,0 +1,109 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"testing"
+)
+
+func vecNear(a, b mgl32.Vec3) bool {
+	return a.Sub(b).Len() < 1e-4
+}
+
+func TestViewRay(t *testing.T) {
+	// odd sizes so the middle pixel is at the center of the screen
+	const width, height = 201, 101
+	for _, flip := range []bool{false, true} {
+		view := NewView(POVCamera(mgl32.Vec3{1, 2, 5}, mgl32.Vec3{0, 0, -1}))
+		view.FlipY = flip
+		view.SetProjection(width, height)
+		// tangent of half the field of view in each direction from the projection
+		tx, ty := 1/view.Proj[0], 1/view.Proj[5]
+		if flip {
+			ty = -ty
+		}
+		tests := []struct {
+			name string
+			x, y int
+			ndc  mgl32.Vec2
+		}{
+			{"center", 100, 50, mgl32.Vec2{0, 0}},
+			{"top left", 0, 0, mgl32.Vec2{-200.0 / 201, 100.0 / 101}},
+			{"top right", 200, 0, mgl32.Vec2{200.0 / 201, 100.0 / 101}},
+			{"bottom left", 0, 100, mgl32.Vec2{-200.0 / 201, -100.0 / 101}},
+			{"bottom right", 200, 100, mgl32.Vec2{200.0 / 201, -100.0 / 101}},
+		}
+		for _, test := range tests {
+			ray := view.Ray(test.x, test.y)
+			want := mgl32.Vec3{test.ndc[0] * tx, test.ndc[1] * ty, -1}
+			if !vecNear(ray.Dir, want.Normalize()) {
+				t.Errorf("flip %v %s: direction %v, expecting %v", flip, test.name, ray.Dir, want.Normalize())
+			}
+			// the origin is on the near plane
+			if origin := (mgl32.Vec3{1, 2, 5}).Add(want.Mul(Near)); !vecNear(ray.Origin, origin) {
+				t.Errorf("flip %v %s: origin %v, expecting %v", flip, test.name, ray.Origin, origin)
+			}
+		}
+	}
+
+	// the center of an arc ball camera view looks at the center point
+	camera := ArcBallCamera(glu.Polar{R: 6, Theta: 50, Phi: 30}, mgl32.Vec3{1, 0, -2}, 1, 10, 0, 180)
+	view := NewView(camera)
+	view.SetProjection(201, 101)
+	ray := view.Ray(100, 50)
+	if want := camera.Center().Sub(camera.Eye()).Normalize(); !vecNear(ray.Dir, want) {
+		t.Errorf("arc ball center ray %v, expecting %v", ray.Dir, want)
+	}
+	// the top of the screen is above the center
+	if up := view.Ray(100, 0); up.Dir.Dot(Up) <= ray.Dir.Dot(Up) {
+		t.Errorf("ray to the top of the screen %v is not above %v", up.Dir, ray.Dir)
+	}
+}
+
+func TestPick(t *testing.T) {
+	root := NewGroup()
+	// cube rotated so the +z face points along +x, scaled to 2 units along x and moved to x = 3
+	item := NewItem(cube())
+	item.SetPosition(mgl32.Vec3{3, 0, 0}).RotateY(90).SetScale(mgl32.Vec3{1, 1, 2})
+	root.Add(item)
+	// the face which is hit has vertices 5, 6, 8, 7 at z = 1 in model space. It is the second quad so it is
+	// split into triangles 2 and 3, with the diagonal from (-1, -1) to (1, 1) and model x along world -z.
+	tests := []struct {
+		name   string
+		ray    Ray
+		point  mgl32.Vec3
+		tri    int
+		normal mgl32.Vec3
+	}{
+		{"below the diagonal", Ray{Origin: mgl32.Vec3{10, 0.25, -0.5}, Dir: mgl32.Vec3{-1, 0, 0}}, mgl32.Vec3{5, 0.25, -0.5}, 2, mgl32.Vec3{1, 0, 0}},
+		{"above the diagonal", Ray{Origin: mgl32.Vec3{10, 0.5, 0.25}, Dir: mgl32.Vec3{-1, 0, 0}}, mgl32.Vec3{5, 0.5, 0.25}, 3, mgl32.Vec3{1, 0, 0}},
+	}
+	for _, test := range tests {
+		hit, ok := Pick(root, test.ray)
+		if !ok || hit.Item != item {
+			t.Errorf("%s: missed the cube", test.name)
+			continue
+		}
+		if !vecNear(hit.Point, test.point) || hit.Triangle != test.tri || !vecNear(hit.Normal, test.normal) {
+			t.Errorf("%s: hit %v triangle %d normal %v, expecting %v %d %v", test.name, hit.Point, hit.Triangle, hit.Normal,
+				test.point, test.tri, test.normal)
+		}
+		if d := test.ray.Origin.Sub(test.point).Len(); hit.Distance-d > 1e-4 || d-hit.Distance > 1e-4 {
+			t.Errorf("%s: distance %g, expecting %g", test.name, hit.Distance, d)
+		}
+	}
+	// rays which miss, or start past the cube
+	for _, ray := range []Ray{
+		{Origin: mgl32.Vec3{10, 1.5, 0}, Dir: mgl32.Vec3{-1, 0, 0}},
+		{Origin: mgl32.Vec3{0, 0, 0}, Dir: mgl32.Vec3{-1, 0, 0}},
+	} {
+		if hit, ok := Pick(root, ray); ok {
+			t.Errorf("ray from %v hit %v", ray.Origin, hit.Point)
+		}
+	}
+	// disabled items are skipped
+	item.Enable(false)
+	if _, ok := Pick(root, tests[0].ray); ok {
+		t.Errorf("disabled item was picked")
+	}
+}
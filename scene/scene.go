@@ -6,16 +6,6 @@ import (
 	"github.com/jnb666/go3d/mesh"
 )
 
-// Combined transformation matrix and scaling vector
-type Transform struct {
-	mgl32.Mat4
-	Scale mgl32.Vec3
-}
-
-func NewTransform(m mgl32.Mat4) Transform {
-	return Transform{Mat4: m, Scale: mgl32.Vec3{1, 1, 1}}
-}
-
 // Object is the base abstract interface type for something which can be added to the scene
 type Object interface {
 	Do(trans Transform, f func(*Item, Transform))
@@ -25,16 +15,40 @@ type Object interface {
 	RotateX(angle float32) Object
 	RotateY(angle float32) Object
 	RotateZ(angle float32) Object
+	SetPosition(pos mgl32.Vec3) Object
+	SetRotation(q mgl32.Quat) Object
+	SetScale(scale mgl32.Vec3) Object
+	LookAt(target, up mgl32.Vec3) Object
+	Position() mgl32.Vec3
+	Rotation() mgl32.Quat
+	Scaling() mgl32.Vec3
+	Parent() *Group
+	WorldTransform() Transform
+	PointToWorld(p mgl32.Vec3) mgl32.Vec3
+	PointToLocal(p mgl32.Vec3) mgl32.Vec3
+	DirToWorld(d mgl32.Vec3) mgl32.Vec3
+	DirToLocal(d mgl32.Vec3) mgl32.Vec3
+	Name() string
+	SetName(name string) Object
+	Tags() []string
+	HasTag(tag string) bool
+	Tag(tags ...string) Object
+	Untag(tags ...string) Object
+	Path() string
+	Walk(fn func(Object) bool)
 	Clone() Object
 	Enabled() bool
 	Enable(on bool) Object
 	SetMaterial(mtl mesh.Material) Object
+	Bounds() mesh.Bounds
 }
 
 // Group type represents a set of objects, it implements the Object interface
 type Group struct {
 	Transform
+	label
 	objects []Object
+	parent  *Group
 	enabled bool
 }
 
@@ -47,20 +61,114 @@ func NewGroup() *Group {
 	return g
 }
 
-// Add method adds one or more objects to the group
+// Add method adds one or more objects to the group. An object can only have one parent, so if it is already
+// in another group then it is removed from there first. It panics if the object is the group itself or one
+// of its parents, as this would make a cycle.
 func (g *Group) Add(obj ...Object) *Group {
-	g.objects = append(g.objects, obj...)
+	for _, o := range obj {
+		if g.isWithin(o) {
+			panic("cannot add a group to itself or one of its descendants")
+		}
+		if p := o.Parent(); p != nil {
+			p.Remove(o)
+		}
+		setParent(o, g)
+		g.objects = append(g.objects, o)
+	}
 	return g
 }
 
+// Remove method takes one or more objects out of the group, objects which are not children are ignored
+func (g *Group) Remove(obj ...Object) *Group {
+	for _, o := range obj {
+		for i, child := range g.objects {
+			if child == o {
+				g.objects = append(g.objects[:i], g.objects[i+1:]...)
+				setParent(o, nil)
+				break
+			}
+		}
+	}
+	return g
+}
+
+// Reparent method moves an object from its current parent into this group. If keepWorld is set then the
+// local transform is updated so the object stays in the same place, otherwise it keeps the same local
+// transform and moves with its new parent. If the new parent is scaled by different amounts along each axis
+// and the object is rotated relative to it then the local matrix which keeps it in place has a shear. This
+// is kept until the position, rotation or scale of the object is next set, when it will be lost and the
+// object will change shape. As with Add it panics if the move would make a cycle.
+func (g *Group) Reparent(obj Object, keepWorld bool) *Group {
+	if g.isWithin(obj) {
+		panic("cannot move a group into itself or one of its descendants")
+	}
+	if keepWorld {
+		world := obj.WorldTransform()
+		parent := g.WorldTransform()
+		local := NewTransform(parent.Mat4().Inv().Mul4(world.Mat4()))
+		setTransform(obj, local)
+	}
+	return g.Add(obj)
+}
+
+// true if the group is obj or is under it in the tree
+func (g *Group) isWithin(obj Object) bool {
+	for p := g; p != nil; p = p.parent {
+		if Object(p) == obj {
+			return true
+		}
+	}
+	return false
+}
+
+// Objects returns the list of child objects
+func (g *Group) Objects() []Object {
+	return g.objects
+}
+
+// Parent returns the group which this object was added to, or nil for the root of the scene
+func (g *Group) Parent() *Group {
+	return g.parent
+}
+
+// WorldTransform returns the combined transform from the object's local coordinate space to the root
+func (g *Group) WorldTransform() Transform {
+	return worldTransform(g.parent, &g.Transform)
+}
+
+// PointToWorld converts a point in the group's local coordinate space to world space
+func (g *Group) PointToWorld(p mgl32.Vec3) mgl32.Vec3 {
+	t := g.WorldTransform()
+	return mgl32.TransformCoordinate(p, t.Mat4())
+}
+
+// PointToLocal converts a point in world space to the group's local coordinate space
+func (g *Group) PointToLocal(p mgl32.Vec3) mgl32.Vec3 {
+	t := g.WorldTransform()
+	return mgl32.TransformCoordinate(p, t.Mat4().Inv())
+}
+
+// DirToWorld converts a direction or offset to world space, the result is not normalized
+func (g *Group) DirToWorld(d mgl32.Vec3) mgl32.Vec3 {
+	t := g.WorldTransform()
+	return mgl32.TransformNormal(d, t.Mat4())
+}
+
+// DirToLocal converts a direction or offset in world space to the group's local coordinate space
+func (g *Group) DirToLocal(d mgl32.Vec3) mgl32.Vec3 {
+	t := g.WorldTransform()
+	return mgl32.TransformNormal(d, t.Mat4().Inv())
+}
+
 // Clone method returns a deep copy of the group
 func (g *Group) Clone() Object {
 	newg := NewGroup()
 	newg.Transform = g.Transform
-	newg.objects = make([]Object, len(g.objects))
-	for i, obj := range g.objects {
-		newg.objects[i] = obj.Clone()
+	newg.label = g.label.clone()
+	for _, obj := range g.objects {
+		newg.Add(obj.Clone())
 	}
+	remapSkeletons(g, newg)
 	return newg
 }
 
@@ -70,10 +178,7 @@ func (g *Group) Do(trans Transform, fn func(*Item, Transform)) {
 	if !g.enabled {
 		return
 	}
-	newTrans := Transform{
-		Mat4:  trans.Mul4(g.Transform.Mat4),
-		Scale: vmul(trans.Scale, g.Transform.Scale),
-	}
+	newTrans := trans.Mul(&g.Transform)
 	for _, obj := range g.objects {
 		obj.Do(newTrans, fn)
 	}
@@ -89,38 +194,72 @@ func (g *Group) SetMaterial(mtl mesh.Material) Object {
 
 // Scale method scales the size of the object
 func (g *Group) Scale(scaleX, scaleY, scaleZ float32) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.Scale3D(scaleX, scaleY, scaleZ))
-	g.Transform.Scale = vmul(g.Transform.Scale, mgl32.Vec3{scaleX, scaleY, scaleZ})
+	g.scaleBy(mgl32.Vec3{scaleX, scaleY, scaleZ})
 	return g
 }
 
-// Translate method moves the object in world space
+// Translate method moves the object along its rotated axes, the distance is not affected by the scale
 func (g *Group) Translate(trX, trY, trZ float32) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.Translate3D(trX/g.Transform.Scale[0], trY/g.Transform.Scale[1], trZ/g.Transform.Scale[2]))
+	g.translate(mgl32.Vec3{trX, trY, trZ})
 	return g
 }
 
 // Rotate method rotates the object around given axis by angle (in degrees)
 func (g *Group) Rotate(degrees float32, axis mgl32.Vec3) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), axis))
+	g.rotate(degrees, axis)
 	return g
 }
 
 func (g *Group) RotateX(degrees float32) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{1, 0, 0}))
+	g.rotate(degrees, mgl32.Vec3{1, 0, 0})
 	return g
 }
 
 func (g *Group) RotateY(degrees float32) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{0, 1, 0}))
+	g.rotate(degrees, mgl32.Vec3{0, 1, 0})
 	return g
 }
 
 func (g *Group) RotateZ(degrees float32) Object {
-	g.Transform.Mat4 = g.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{0, 0, 1}))
+	g.rotate(degrees, mgl32.Vec3{0, 0, 1})
+	return g
+}
+
+// SetPosition method moves the object to the given position in the coordinate space of its parent
+func (g *Group) SetPosition(pos mgl32.Vec3) Object {
+	g.Transform.SetPosition(pos)
+	return g
+}
+
+// SetRotation method replaces the current rotation
+func (g *Group) SetRotation(q mgl32.Quat) Object {
+	g.Transform.SetRotation(q)
+	return g
+}
+
+// SetScale method replaces the current scale
+func (g *Group) SetScale(scale mgl32.Vec3) Object {
+	g.Transform.SetScale(scale)
+	return g
+}
+
+// LookAt method rotates the object so its -Z axis faces the target
+func (g *Group) LookAt(target, up mgl32.Vec3) Object {
+	g.Transform.LookAt(target, up)
 	return g
 }
 
+// Bounds returns the extent of the enabled objects in the group, in the coordinate space of the parent.
+func (g *Group) Bounds() mesh.Bounds {
+	b := mesh.EmptyBounds()
+	if g.enabled {
+		for _, obj := range g.objects {
+			b = b.Union(obj.Bounds())
+		}
+	}
+	return b.Transform(g.Transform.Mat4())
+}
+
 func (g *Group) Enabled() bool {
 	return g.enabled
 }
@@ -130,12 +269,16 @@ func (g *Group) Enable(on bool) Object {
 	return g
 }
 
-// Item type represents a single component which is added to the scene
+// Item type represents a single component which is added to the scene. If the mesh is skinned then
+// Skeleton has the joints which move it.
 type Item struct {
 	Transform
 	*mesh.Mesh
+	label
 	Light    *Light
+	Skeleton *Skeleton
 	lightMat map[bool]mesh.Material
+	parent   *Group
 	enabled  bool
 }
 
@@ -183,12 +326,14 @@ func (o *Item) Do(trans Transform, fn func(*Item, Transform)) {
 	if o.Light != nil {
 		o.Mesh.SetMaterial(o.lightMat[o.Light.On])
 	}
-	fn(o, Transform{Mat4: trans.Mul4(o.Transform.Mat4), Scale: vmul(trans.Scale, o.Transform.Scale)})
+	fn(o, trans.Mul(&o.Transform))
 }
 
 // Get a copy of the item. Note that the mesh is not copied, it is a reference to the same object
 func (o *Item) Clone() Object {
 	item := *o
+	item.parent = nil
+	item.label = o.label.clone()
 	item.Mesh = o.Mesh.Clone()
 	if o.Light != nil {
 		lgt := *o.Light
@@ -203,38 +348,77 @@ func (o *Item) Clone() Object {
 
 // Scale method scales the size of the object
 func (o *Item) Scale(scaleX, scaleY, scaleZ float32) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.Scale3D(scaleX, scaleY, scaleZ))
-	o.Transform.Scale = vmul(o.Transform.Scale, mgl32.Vec3{scaleX, scaleY, scaleZ})
+	o.scaleBy(mgl32.Vec3{scaleX, scaleY, scaleZ})
 	return o
 }
 
-// Translate method moves the object in world space
+// Translate method moves the object along its rotated axes, the distance is not affected by the scale
 func (o *Item) Translate(trX, trY, trZ float32) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.Translate3D(trX/o.Transform.Scale[0], trY/o.Transform.Scale[1], trZ/o.Transform.Scale[2]))
+	o.translate(mgl32.Vec3{trX, trY, trZ})
 	return o
 }
 
 // Rotate method rotates the object around given axis by angle (in degrees)
 func (o *Item) Rotate(degrees float32, axis mgl32.Vec3) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), axis))
+	o.rotate(degrees, axis)
 	return o
 }
 
 func (o *Item) RotateX(degrees float32) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{1, 0, 0}))
+	o.rotate(degrees, mgl32.Vec3{1, 0, 0})
 	return o
 }
 
 func (o *Item) RotateY(degrees float32) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{0, 1, 0}))
+	o.rotate(degrees, mgl32.Vec3{0, 1, 0})
 	return o
 }
 
 func (o *Item) RotateZ(degrees float32) Object {
-	o.Transform.Mat4 = o.Mul4(mgl32.HomogRotate3D(mgl32.DegToRad(degrees), mgl32.Vec3{0, 0, 1}))
+	o.rotate(degrees, mgl32.Vec3{0, 0, 1})
+	return o
+}
+
+// SetPosition method moves the object to the given position in the coordinate space of its parent
+func (o *Item) SetPosition(pos mgl32.Vec3) Object {
+	o.Transform.SetPosition(pos)
+	return o
+}
+
+// SetRotation method replaces the current rotation
+func (o *Item) SetRotation(q mgl32.Quat) Object {
+	o.Transform.SetRotation(q)
+	return o
+}
+
+// SetScale method replaces the current scale
+func (o *Item) SetScale(scale mgl32.Vec3) Object {
+	o.Transform.SetScale(scale)
 	return o
 }
 
+// LookAt method rotates the object so its -Z axis faces the target
+func (o *Item) LookAt(target, up mgl32.Vec3) Object {
+	o.Transform.LookAt(target, up)
+	return o
+}
+
+// Bounds returns the extent of the mesh in the coordinate space of the parent, or empty bounds if disabled.
+func (o *Item) Bounds() mesh.Bounds {
+	if !o.enabled {
+		return mesh.EmptyBounds()
+	}
+	return o.modelBounds().Transform(o.Transform.Mat4())
+}
+
+// extent of the mesh in model space, in the current pose if the item has a skeleton
+func (o *Item) modelBounds() mesh.Bounds {
+	if o.Skeleton != nil {
+		return o.Skeleton.Bounds(o)
+	}
+	return o.Mesh.Bounds()
+}
+
 func (o *Item) Enabled() bool {
 	return o.enabled
 }
@@ -244,6 +428,87 @@ func (o *Item) Enable(on bool) Object {
 	return o
 }
 
+// Parent returns the group which this item was added to, or nil if it is not in a group
+func (o *Item) Parent() *Group {
+	return o.parent
+}
+
+// WorldTransform returns the combined transform from the item's model space to the root
+func (o *Item) WorldTransform() Transform {
+	return worldTransform(o.parent, &o.Transform)
+}
+
+// PointToWorld converts a point in model space to world space
+func (o *Item) PointToWorld(p mgl32.Vec3) mgl32.Vec3 {
+	t := o.WorldTransform()
+	return mgl32.TransformCoordinate(p, t.Mat4())
+}
+
+// PointToLocal converts a point in world space to model space
+func (o *Item) PointToLocal(p mgl32.Vec3) mgl32.Vec3 {
+	t := o.WorldTransform()
+	return mgl32.TransformCoordinate(p, t.Mat4().Inv())
+}
+
+// DirToWorld converts a direction or offset in model space to world space, the result is not normalized
+func (o *Item) DirToWorld(d mgl32.Vec3) mgl32.Vec3 {
+	t := o.WorldTransform()
+	return mgl32.TransformNormal(d, t.Mat4())
+}
+
+// DirToLocal converts a direction or offset in world space to model space
+func (o *Item) DirToLocal(d mgl32.Vec3) mgl32.Vec3 {
+	t := o.WorldTransform()
+	return mgl32.TransformNormal(d, t.Mat4().Inv())
+}
+
+// Normalize returns a new group containing the object scaled to fit in a unit box centered on the origin.
+// The returned group has an identity transform, so rotating it will rotate the object around its center.
+func Normalize(obj Object) *Group {
+	inner := NewGroup().Add(obj)
+	if b := obj.Bounds(); !b.Empty() {
+		maxSize := float32(0)
+		for _, size := range b.Size() {
+			if size > maxSize {
+				maxSize = size
+			}
+		}
+		if maxSize > 0 {
+			scale := 1 / maxSize
+			center := b.Center().Mul(-scale)
+			inner.Scale(scale, scale, scale)
+			inner.Translate(center[0], center[1], center[2])
+		}
+	}
+	return NewGroup().Add(inner)
+}
+
 func vmul(a, b mgl32.Vec3) mgl32.Vec3 {
 	return mgl32.Vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]}
 }
+
+func worldTransform(parent *Group, local *Transform) Transform {
+	if parent == nil {
+		return *local
+	}
+	t := parent.WorldTransform()
+	return t.Mul(local)
+}
+
+func setParent(obj Object, parent *Group) {
+	switch o := obj.(type) {
+	case *Group:
+		o.parent = parent
+	case *Item:
+		o.parent = parent
+	}
+}
+
+func setTransform(obj Object, t Transform) {
+	switch o := obj.(type) {
+	case *Group:
+		o.Transform = t
+	case *Item:
+		o.Transform = t
+	}
+}
@@ -0,0 +1,87 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// root -> a -> b -> c
+func chain() (root, a, b, c *Group) {
+	root, a, b, c = NewGroup(), NewGroup(), NewGroup(), NewGroup()
+	root.Add(a)
+	a.Add(b)
+	b.Add(c)
+	return
+}
+
+func panics(fn func()) (ok bool) {
+	defer func() {
+		ok = recover() != nil
+	}()
+	fn()
+	return false
+}
+
+// elements are within a small absolute distance, as the relative threshold is too strict for values near zero
+func matEqual(a, b mgl32.Mat4) bool {
+	for i := range a {
+		if d := a[i] - b[i]; d > 1e-5 || d < -1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAddCycle(t *testing.T) {
+	root, a, b, c := chain()
+	tests := []struct {
+		name   string
+		group  *Group
+		obj    Object
+		panics bool
+	}{
+		{"self", a, a, true},
+		{"parent", b, a, true},
+		{"root", c, root, true},
+		{"child", root, c, false},
+		{"sibling", a, NewGroup(), false},
+	}
+	for _, test := range tests {
+		if got := panics(func() { test.group.Add(test.obj) }); got != test.panics {
+			t.Errorf("Add %s: panic is %v, expecting %v", test.name, got, test.panics)
+		}
+		if got := panics(func() { test.group.Reparent(test.obj, true) }); got != test.panics {
+			t.Errorf("Reparent %s: panic is %v, expecting %v", test.name, got, test.panics)
+		}
+	}
+	// the tree is unchanged by the rejected moves
+	if a.Parent() != root || b.Parent() != a || root.Parent() != nil {
+		t.Errorf("tree was modified")
+	}
+	if c.Parent() != root {
+		t.Errorf("c should have moved to root")
+	}
+}
+
+func TestReparentKeepWorld(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale mgl32.Vec3
+	}{
+		{"uniform", mgl32.Vec3{2, 2, 2}},
+		{"non uniform", mgl32.Vec3{1, 3, 0.5}},
+	}
+	for _, test := range tests {
+		root, a, b, _ := chain()
+		a.SetPosition(mgl32.Vec3{1, 2, 3}).RotateY(30)
+		b.SetScale(test.scale).RotateX(45)
+		obj := NewGroup().Translate(0, 1, 0).RotateZ(60)
+		root.Add(obj)
+		before := obj.WorldTransform()
+		b.Reparent(obj, true)
+		after := obj.WorldTransform()
+		if !matEqual(after.Mat4(), before.Mat4()) {
+			t.Errorf("%s: world transform changed from %v to %v", test.name, before.Mat4(), after.Mat4())
+		}
+	}
+}
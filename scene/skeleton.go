@@ -0,0 +1,93 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/mesh"
+)
+
+// Skeleton type is the set of joints which move the vertices of a skinned mesh. The joints are ordinary
+// objects in the scene, usually empty groups, so they can be animated by moving them. The joint numbers
+// passed to mesh.AddWeights index the Joints list. InverseBind has the matrix for each joint which takes
+// the mesh from its model space into the joint's space when the mesh is in its bind pose.
+type Skeleton struct {
+	Joints      []Object
+	InverseBind []mgl32.Mat4
+}
+
+// NewSkeleton creates a skeleton with the given joints and inverse bind matrices, call Bind instead to
+// calculate them from the current pose.
+func NewSkeleton(joints []Object, inverseBind []mgl32.Mat4) *Skeleton {
+	return &Skeleton{Joints: joints, InverseBind: inverseBind}
+}
+
+// Bind method makes the current position of the joints relative to the item the bind pose, so the mesh
+// is drawn as it was built until the joints move.
+func (s *Skeleton) Bind(item *Item) *Skeleton {
+	itemWorld := item.WorldTransform()
+	s.InverseBind = make([]mgl32.Mat4, len(s.Joints))
+	for i, joint := range s.Joints {
+		jointWorld := joint.WorldTransform()
+		s.InverseBind[i] = jointWorld.Mat4().Inv().Mul4(itemWorld.Mat4())
+	}
+	return s
+}
+
+// Matrices returns the joint matrices for the current pose of the skeleton in the model space of the item.
+func (s *Skeleton) Matrices(item *Item) []mgl32.Mat4 {
+	itemWorld := item.WorldTransform()
+	toModel := itemWorld.Mat4().Inv()
+	mats := make([]mgl32.Mat4, len(s.Joints))
+	for i, joint := range s.Joints {
+		jointWorld := joint.WorldTransform()
+		mats[i] = toModel.Mul4(jointWorld.Mat4())
+		if i < len(s.InverseBind) {
+			mats[i] = mats[i].Mul4(s.InverseBind[i])
+		}
+	}
+	return mats
+}
+
+// Bounds returns the extent of the item's mesh in model space in the current pose. This is the union of
+// the bounds moved by each joint, so it may be larger than the mesh.
+func (s *Skeleton) Bounds(item *Item) mesh.Bounds {
+	b := item.Mesh.Bounds()
+	if b.Empty() {
+		return b
+	}
+	posed := mesh.EmptyBounds()
+	for _, mat := range s.Matrices(item) {
+		posed = posed.Union(b.Transform(mat))
+	}
+	return posed
+}
+
+// after a group is cloned point the skeletons of the cloned items at the cloned joints
+func remapSkeletons(from, to Object) {
+	var src, dst []Object
+	from.Walk(func(obj Object) bool {
+		src = append(src, obj)
+		return true
+	})
+	to.Walk(func(obj Object) bool {
+		dst = append(dst, obj)
+		return true
+	})
+	clones := map[Object]Object{}
+	for i, obj := range src {
+		clones[obj] = dst[i]
+	}
+	for _, obj := range dst {
+		item, ok := obj.(*Item)
+		if !ok || item.Skeleton == nil {
+			continue
+		}
+		skel := &Skeleton{InverseBind: item.Skeleton.InverseBind}
+		for _, joint := range item.Skeleton.Joints {
+			if clone, ok := clones[joint]; ok {
+				joint = clone
+			}
+			skel.Joints = append(skel.Joints, joint)
+		}
+		item.Skeleton = skel
+	}
+}
@@ -0,0 +1,175 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Transform type holds a position, rotation and scale. The matrix which applies the scale, then the rotation
+// and then the translation is only calculated when it is needed after one of these has been changed.
+//
+// A rotation after a scale which is different along each axis gives a matrix with a shear, which is not just a
+// position, rotation and scale. In this case the matrix is updated in the same way as for the other relative
+// changes, so the result is the same as multiplying the matrices in turn, and the position, rotation and scale
+// are the closest match to it. Any shear is lost if the position, rotation or scale is set.
+type Transform struct {
+	position mgl32.Vec3
+	rotation mgl32.Quat
+	scale    mgl32.Vec3
+	matrix   mgl32.Mat4
+	dirty    bool
+	sheared  bool
+}
+
+// NewTransform creates a transform from a matrix, which is split into position, rotation and scale. Any shear
+// is kept in the matrix until the position, rotation or scale is set.
+func NewTransform(m mgl32.Mat4) Transform {
+	t := Transform{matrix: m, position: m.Col(3).Vec3()}
+	var rot mgl32.Mat3
+	for i := 0; i < 3; i++ {
+		col := m.Col(i).Vec3()
+		t.scale[i] = col.Len()
+		if t.scale[i] > 0 {
+			col = col.Mul(1 / t.scale[i])
+		}
+		rot.SetCol(i, col)
+	}
+	if rot.Det() < 0 {
+		// a mirror image, which is a rotation with a negative scale on each axis
+		t.scale, rot = t.scale.Mul(-1), rot.Mul(-1)
+	}
+	t.rotation = mgl32.Mat4ToQuat(rot.Mat4()).Normalize()
+	t.sheared = !nearlyEqual(t.compose(), m)
+	return t
+}
+
+// Mat4 returns the transformation matrix
+func (t *Transform) Mat4() mgl32.Mat4 {
+	if t.dirty {
+		t.matrix = t.compose()
+		t.dirty = false
+	}
+	return t.matrix
+}
+
+// Mul combines a parent transform with a child. The matrix is exact, the rotation and scale are only exact
+// if the parent has the same scale on each axis.
+func (t *Transform) Mul(child *Transform) Transform {
+	return Transform{
+		matrix:   t.Mat4().Mul4(child.Mat4()),
+		position: mgl32.TransformCoordinate(child.position, t.Mat4()),
+		rotation: t.rotation.Mul(child.rotation),
+		scale:    vmul(t.scale, child.scale),
+		sheared:  t.sheared || child.sheared || !uniform(t.scale),
+	}
+}
+
+// matrix from the position, rotation and scale
+func (t *Transform) compose() mgl32.Mat4 {
+	p, s := t.position, t.scale
+	return mgl32.Translate3D(p[0], p[1], p[2]).Mul4(t.rotation.Mat4()).Mul4(mgl32.Scale3D(s[0], s[1], s[2]))
+}
+
+// Position returns the translation in the coordinate space of the parent
+func (t *Transform) Position() mgl32.Vec3 {
+	return t.position
+}
+
+// Rotation returns the orientation as a quaternion
+func (t *Transform) Rotation() mgl32.Quat {
+	return t.rotation
+}
+
+// Scaling returns the scale factor along each axis
+func (t *Transform) Scaling() mgl32.Vec3 {
+	return t.scale
+}
+
+// SetPosition sets the translation in the coordinate space of the parent
+func (t *Transform) SetPosition(pos mgl32.Vec3) *Transform {
+	t.position, t.dirty, t.sheared = pos, true, false
+	return t
+}
+
+// SetRotation sets the orientation, the quaternion is normalized
+func (t *Transform) SetRotation(q mgl32.Quat) *Transform {
+	t.rotation, t.dirty, t.sheared = q.Normalize(), true, false
+	return t
+}
+
+// SetScale sets the scale factor along each axis
+func (t *Transform) SetScale(scale mgl32.Vec3) *Transform {
+	t.scale, t.dirty, t.sheared = scale, true, false
+	return t
+}
+
+// LookAt rotates so the -Z axis points towards the target and the Y axis is as close as possible to up,
+// in the same way as a camera. The target is in the coordinate space of the parent.
+func (t *Transform) LookAt(target, up mgl32.Vec3) *Transform {
+	dir := target.Sub(t.position)
+	if dir.Len() == 0 {
+		return t
+	}
+	z := dir.Normalize().Mul(-1)
+	x := up.Cross(z)
+	if x.Len() < 1e-6 {
+		// looking along the up vector so any perpendicular will do
+		x = mgl32.Vec3{0, 0, 1}.Cross(z)
+		if x.Len() < 1e-6 {
+			x = mgl32.Vec3{1, 0, 0}
+		}
+	}
+	x = x.Normalize()
+	rot := mgl32.Mat3FromCols(x, z.Cross(x), z)
+	return t.SetRotation(mgl32.Mat4ToQuat(rot.Mat4()))
+}
+
+// move along the rotated axes, as for a translation applied after the rotation
+func (t *Transform) translate(v mgl32.Vec3) {
+	t.position = t.position.Add(t.rotation.Rotate(v))
+	if t.sheared {
+		t.matrix.SetCol(3, t.position.Vec4(1))
+	} else {
+		t.dirty = true
+	}
+}
+
+// rotate about an axis in the local coordinate space
+func (t *Transform) rotate(degrees float32, axis mgl32.Vec3) {
+	q := mgl32.QuatRotate(mgl32.DegToRad(degrees), axis.Normalize())
+	if t.sheared || !uniform(t.scale) {
+		// the rotation does not commute with the scale so apply it to the matrix
+		*t = NewTransform(t.Mat4().Mul4(q.Mat4()))
+		return
+	}
+	t.rotation, t.dirty = t.rotation.Mul(q).Normalize(), true
+}
+
+func (t *Transform) scaleBy(s mgl32.Vec3) {
+	if t.sheared {
+		*t = NewTransform(t.Mat4().Mul4(mgl32.Scale3D(s[0], s[1], s[2])))
+		return
+	}
+	t.scale, t.dirty = vmul(t.scale, s), true
+}
+
+func uniform(s mgl32.Vec3) bool {
+	return s[0] == s[1] && s[1] == s[2]
+}
+
+// matrices are the same to within rounding errors
+func nearlyEqual(a, b mgl32.Mat4) bool {
+	var max float32 = 1
+	for _, x := range a {
+		if x > max {
+			max = x
+		} else if -x > max {
+			max = -x
+		}
+	}
+	for i := range a {
+		if d := a[i] - b[i]; d > 1e-5*max || d < -1e-5*max {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// relative change to an object and the matrix it was multiplied by before transforms were split into
+// position, rotation and scale
+type transformOp struct {
+	apply  func(Object)
+	matrix mgl32.Mat4
+}
+
+func scaleOp(x, y, z float32) transformOp {
+	return transformOp{func(o Object) { o.Scale(x, y, z) }, mgl32.Scale3D(x, y, z)}
+}
+
+func rotateOp(degrees float32, axis mgl32.Vec3) transformOp {
+	return transformOp{func(o Object) { o.Rotate(degrees, axis) }, mgl32.HomogRotate3D(mgl32.DegToRad(degrees), axis.Normalize())}
+}
+
+// translation before any rotation, with the distance divided by the scale as before
+func translateOp(x, y, z float32, scale mgl32.Vec3) transformOp {
+	return transformOp{func(o Object) { o.Translate(x, y, z) }, mgl32.Translate3D(x/scale[0], y/scale[1], z/scale[2])}
+}
+
+func TestTransformOps(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []transformOp
+	}{
+		{"uniform", []transformOp{translateOp(1, 2, 3, mgl32.Vec3{1, 1, 1}), scaleOp(2, 2, 2), rotateOp(30, mgl32.Vec3{1, 1, 0}), scaleOp(0.5, 0.5, 0.5), rotateOp(-60, mgl32.Vec3{0, 0, 1})}},
+		{"scale then rotate", []transformOp{scaleOp(1, 3, 1), rotateOp(30, mgl32.Vec3{1, 0, 0})}},
+		{"rotate then scale", []transformOp{rotateOp(30, mgl32.Vec3{1, 0, 0}), scaleOp(1, 3, 1)}},
+		{"sheared", []transformOp{scaleOp(2, 1, 1), translateOp(4, 0, 0, mgl32.Vec3{2, 1, 1}), rotateOp(45, mgl32.Vec3{0, 0, 1}), scaleOp(1, 2, 1), rotateOp(20, mgl32.Vec3{0, 1, 0})}},
+		{"aligned", []transformOp{scaleOp(2, 2, 1), rotateOp(90, mgl32.Vec3{0, 0, 1}), scaleOp(1, 3, 1), rotateOp(180, mgl32.Vec3{1, 0, 0})}},
+	}
+	for _, test := range tests {
+		obj := NewItem(nil)
+		want := mgl32.Ident4()
+		for _, op := range test.ops {
+			op.apply(obj)
+			want = want.Mul4(op.matrix)
+		}
+		if got := obj.Transform.Mat4(); !matEqual(got, want) {
+			t.Errorf("%s: got matrix %v, expecting %v", test.name, got, want)
+		}
+	}
+}
+
+func TestTransformSetClearsShear(t *testing.T) {
+	obj := NewItem(nil)
+	obj.Scale(1, 2, 1).RotateZ(30)
+	if !obj.sheared {
+		t.Fatalf("expecting shear after rotating a non uniform scale")
+	}
+	pos := mgl32.Vec3{1, 2, 3}
+	obj.SetPosition(pos)
+	s, p := obj.Scaling(), obj.Position()
+	want := mgl32.Translate3D(p[0], p[1], p[2]).Mul4(obj.Rotation().Mat4()).Mul4(mgl32.Scale3D(s[0], s[1], s[2]))
+	if obj.sheared || !matEqual(obj.Transform.Mat4(), want) || p != pos {
+		t.Errorf("expecting matrix from position, rotation and scale after set, got %v", obj.Transform.Mat4())
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/jnb666/go3d/glu"
 	"github.com/jnb666/go3d/mesh"
+	"image"
+	"math"
 )
 
 // Default projection settings
@@ -15,14 +17,19 @@ var (
 	StepSize       float32    = 0.05
 )
 
-// View settings
+// View settings. FlipY is set if the framebuffer is drawn upside down, as it is for QML and the offscreen
+// backends, in which case the projection inverts the y axis. It defaults to true, the backend.Window FlipY
+// method returns the setting for each backend.
 type View struct {
 	Camera Camera
 	Lights []*Light
 	Proj   mgl32.Mat4
+	Stats  CullStats
+	FlipY  bool
 	ldata  []*Light
 	width  float32
 	height float32
+	bounds map[Object]mesh.Bounds // camera space bounds used for culling
 }
 
 // Setup a new view, makes a copy of the camera which was passed in
@@ -30,14 +37,28 @@ func NewView(camera Camera) *View {
 	v := new(View)
 	v.Camera = camera
 	v.Lights = []*Light{}
+	v.FlipY = true
 	return v
 }
 
-// Draw the scene with the given view matrix
-func (v *View) Draw(worldToCamera mgl32.Mat4, scene Object) {
-	scene.Do(NewTransform(worldToCamera), func(o *Item, t Transform) {
-		err := o.Mesh.Draw(func(prog *glu.Program) {
-			mat := t.Mat4
+// Draw the scene with the given view matrix. Panics on error unless glu.PanicOnError is cleared, in which
+// case drawing stops at the first error and it is returned. Items and groups which are outside the view
+// frustum are skipped if FrustumCulling is set.
+func (v *View) Draw(worldToCamera mgl32.Mat4, scene Object) (err error) {
+	glu.SetFlipY(v.FlipY)
+	do := scene.Do
+	if FrustumCulling {
+		do = func(trans Transform, fn func(*Item, Transform)) { v.visible(scene, trans, fn) }
+	}
+	do(NewTransform(worldToCamera), func(o *Item, t Transform) {
+		if err != nil {
+			return
+		}
+		if o.Skeleton != nil {
+			o.Mesh.SetPose(o.Skeleton.Matrices(o))
+		}
+		err = o.Mesh.Draw(func(prog *glu.Program) {
+			mat := t.Mat4()
 			if psize := o.Mesh.PointSize(); psize != 0 {
 				// points are always facing the camera at a constant size
 				pos := mgl32.Vec3{mat[12], mat[13], mat[14]}
@@ -49,7 +70,7 @@ func (v *View) Draw(worldToCamera mgl32.Mat4, scene Object) {
 			} else {
 				//prog.Set("texScale", o.TexScale)
 				prog.Set("normalModelToCamera", mat.Mat3().Inv().Transpose())
-				prog.Set("modelScale", t.Scale)
+				prog.Set("modelScale", t.Scaling())
 				prog.Set("numLights", len(v.ldata))
 				for i, light := range v.ldata {
 					prog.SetArray("lightPos", i, light.Pos)
@@ -59,11 +80,15 @@ func (v *View) Draw(worldToCamera mgl32.Mat4, scene Object) {
 			prog.Set("cameraToClip", v.Proj)
 			prog.Set("modelToCamera", mat)
 		})
-		if err != nil {
-			// seems better to panic as caller might otherwise skip checking the error
-			panic(err)
+		if !FrustumCulling {
+			v.Stats.Drawn++
 		}
 	})
+	if err != nil && glu.PanicOnError {
+		// seems better to panic as caller might otherwise skip checking the error
+		panic(err)
+	}
+	return err
 }
 
 // Add a new light to the scene
@@ -87,7 +112,7 @@ func (v *View) UpdateLights(worldToCamera mgl32.Mat4, scene Object) *View {
 	if scene != nil {
 		trans := NewTransform(worldToCamera)
 		scene.Do(trans, func(o *Item, t Transform) {
-			v.addLight(o.Light, t.Mat4)
+			v.addLight(o.Light, t.Mat4())
 		})
 	}
 	return v
@@ -102,14 +127,22 @@ func (v *View) addLight(l *Light, trans mgl32.Mat4) {
 	}
 }
 
-// Set the projection matrix
+// Set the projection matrix, this also resets the culling statistics for the new frame
 func (v *View) SetProjection(width, height int) {
+	v.Stats = CullStats{}
 	aspect := float32(width) / float32(height)
-	// this is inverted as QML puts the y axis upside down
-	v.Proj = mgl32.Perspective(FOV, aspect, Near, Far).Mul4(mgl32.Scale3D(1, -1, 1))
+	v.Proj = mgl32.Perspective(FOV, aspect, Near, Far)
+	if v.FlipY {
+		v.Proj = v.Proj.Mul4(mgl32.Scale3D(1, -1, 1))
+	}
 	v.width, v.height = float32(width), float32(height)
 }
 
+// Screenshot reads back the current framebuffer, this should be called after drawing the scene.
+func (v *View) Screenshot() *image.NRGBA {
+	return glu.ReadPixels(0, 0, int(v.width), int(v.height), v.FlipY)
+}
+
 // Get the camera view matrix.
 func (v *View) ViewMatrix() mgl32.Mat4 {
 	return mgl32.LookAtV(v.Camera.Eye(), v.Camera.Center(), Up)
@@ -121,6 +154,28 @@ func (v *View) CenteredView() mgl32.Mat4 {
 	return v.ViewMatrix().Mul4(mgl32.Translate3D(pos[0], pos[1], pos[2]))
 }
 
+// Fit moves the camera so the bounding sphere of the object fills the field of view. An arc ball camera is
+// centered on the object, a point of view camera keeps the same direction and steps back from it. Large
+// models may still be clipped by the Far plane, use Normalize to scale them first.
+func (v *View) Fit(obj Object) *View {
+	b := obj.Bounds()
+	if b.Empty() {
+		return v
+	}
+	dist := b.Radius / float32(math.Sin(float64(mgl32.DegToRad(FOV/2))))
+	switch c := v.Camera.(type) {
+	case *arcBallCamera:
+		c.center = b.Center()
+		c.toEye.R = dist
+		if dist < c.minz || dist > c.maxz {
+			c.minz, c.maxz = dist/2, dist*2
+		}
+	case *povCamera:
+		c.pos = b.Center().Sub(c.dir.Mul(dist))
+	}
+	return v
+}
+
 // Camera interface type defines the viewing position
 type Camera interface {
 	Eye() mgl32.Vec3
@@ -130,9 +185,16 @@ type Camera interface {
 	Clone() Camera
 }
 
+// Collider interface is used by a point of view camera to stop it moving through objects. Slide returns the
+// allowed position when moving from one point to another.
+type Collider interface {
+	Slide(from, to mgl32.Vec3) mgl32.Vec3
+}
+
 type povCamera struct {
-	pos mgl32.Vec3
-	dir mgl32.Vec3
+	pos      mgl32.Vec3
+	dir      mgl32.Vec3
+	collider Collider
 }
 
 // Create a new point of view camera with yaw and pitch controls.
@@ -140,6 +202,29 @@ func POVCamera(pos mgl32.Vec3, dir mgl32.Vec3) Camera {
 	return &povCamera{pos: pos, dir: dir.Normalize()}
 }
 
+// SetCollider sets the collision check used when a point of view camera moves, or clears it if col is nil.
+// Other cameras are not changed.
+func SetCollider(camera Camera, col Collider) Camera {
+	if c, ok := camera.(*povCamera); ok {
+		c.collider = col
+	}
+	return camera
+}
+
+// PlaceCamera moves the camera to eye and points it towards center. An arc ball camera then orbits around
+// center, its limits are not changed.
+func PlaceCamera(camera Camera, eye, center mgl32.Vec3) Camera {
+	switch c := camera.(type) {
+	case *arcBallCamera:
+		c.center = center
+		c.toEye.Set(eye.Sub(center))
+	case *povCamera:
+		c.pos = eye
+		c.dir = center.Sub(eye).Normalize()
+	}
+	return camera
+}
+
 func (c *povCamera) Clone() Camera {
 	cam := *c
 	return &cam
@@ -155,7 +240,11 @@ func (c *povCamera) Center() mgl32.Vec3 {
 
 // Step forwards if amount > 0 or backwards if amount <0
 func (c *povCamera) Move(amount float32) {
-	c.pos = c.pos.Add(c.dir.Mul(StepSize * amount))
+	pos := c.pos.Add(c.dir.Mul(StepSize * amount))
+	if c.collider != nil {
+		pos = c.collider.Slide(c.pos, pos)
+	}
+	c.pos = pos
 }
 
 // Change the direction of the camera: dx controls the yaw, dy controls the pitch
@@ -259,6 +348,11 @@ func (l *Light) Rotate(dx, dy float32) *Light {
 	return l
 }
 
+// Directional returns true for a directional light, or false for a point light
+func (l *Light) Directional() bool {
+	return l.posw == 0
+}
+
 // Make a copy of the light struct
 func (l *Light) Clone() *Light {
 	newLight := *l
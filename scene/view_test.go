@@ -0,0 +1,135 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/glu/gltest"
+	"github.com/jnb666/go3d/mesh"
+	"gopkg.in/qml.v1/gl/glbase"
+	"testing"
+)
+
+var rec *gltest.Recorder
+
+// setup returns the fake GL context with an empty call list. The same context is used for all of the tests
+// as the compiled programs are cached by the mesh package.
+func setup() *gltest.Recorder {
+	if rec == nil {
+		rec = gltest.New()
+		glu.Init(rec)
+	}
+	glu.ResetState()
+	rec.Reset()
+	return rec
+}
+
+// unit square in the xy plane facing +z
+func square() *mesh.Mesh {
+	m := mesh.New()
+	m.AddVertex(-1, -1, 0)
+	m.AddVertex(1, -1, 0)
+	m.AddVertex(1, 1, 0)
+	m.AddVertex(-1, 1, 0)
+	m.AddNormal(0, 0, 1)
+	m.AddFace(mesh.El{Vert: 1, Norm: 1}, mesh.El{Vert: 2, Norm: 1}, mesh.El{Vert: 3, Norm: 1}, mesh.El{Vert: 4, Norm: 1})
+	m.Build("")
+	return m
+}
+
+// two squares side by side with the same material in front of the camera
+func testScene() (root *Group, items []*Item, view *View) {
+	mtl := mesh.Diffuse()
+	a := NewItem(square()).SetMaterial(mtl).Translate(-1.5, 0, 0)
+	b := NewItem(square()).SetMaterial(mtl).Translate(1.5, 0, 0).Scale(0.5, 0.5, 0.5)
+	root = NewGroup()
+	root.Add(a, b)
+	cam := ArcBallCamera(glu.Polar{R: 8, Theta: 90, Phi: 90}, mgl32.Vec3{}, 1, 20, 10, 170)
+	view = NewView(cam).AddLight(DirectionalLight(mgl32.Vec3{1, 1, 1}, 0.2, glu.Polar{R: 1, Theta: 45, Phi: 90}))
+	return root, []*Item{a.(*Item), b.(*Item)}, view
+}
+
+func drawFrame(t *testing.T, root Object, view *View) mgl32.Mat4 {
+	view.SetProjection(200, 100)
+	glu.Clear(mgl32.Vec4{})
+	worldToCamera := view.ViewMatrix()
+	view.UpdateLights(worldToCamera, root)
+	if err := view.Draw(worldToCamera, root); err != nil {
+		t.Fatal(err)
+	}
+	return worldToCamera
+}
+
+func TestViewDraw(t *testing.T) {
+	tests := []struct {
+		flipY     bool
+		frontFace glbase.Enum
+	}{
+		{true, glu.CW},
+		{false, glu.CCW},
+	}
+	for _, test := range tests {
+		rec := setup()
+		root, items, view := testScene()
+		view.FlipY = test.flipY
+		worldToCamera := drawFrame(t, root, view)
+		if len(rec.Draws) != len(items) {
+			t.Fatalf("flip %v: expecting %d draws, got %d", test.flipY, len(items), len(rec.Draws))
+		}
+		if n := rec.Count("UseProgram"); n != 1 {
+			t.Errorf("flip %v: UseProgram called %d times for a shared material", test.flipY, n)
+		}
+		if flipped := view.Proj[5] < 0; flipped != test.flipY {
+			t.Errorf("flip %v: projection y scale is %g", test.flipY, view.Proj[5])
+		}
+		for i, draw := range rec.Draws {
+			if draw.FrontFace != test.frontFace {
+				t.Errorf("flip %v: draw %d front face is %#x, expecting %#x", test.flipY, i, draw.FrontFace, test.frontFace)
+			}
+			want := worldToCamera.Mul4(items[i].Transform.Mat4())
+			if got := toMat4(draw.Uniforms["modelToCamera"]); !matEqual(got, want) {
+				t.Errorf("flip %v: draw %d modelToCamera is %v, expecting %v", test.flipY, i, got, want)
+			}
+			if got := toMat4(draw.Uniforms["cameraToClip"]); got != view.Proj {
+				t.Errorf("flip %v: draw %d cameraToClip is %v, expecting %v", test.flipY, i, got, view.Proj)
+			}
+			if got := draw.Uniforms["numLights"]; len(got) != 1 || got[0] != 1 {
+				t.Errorf("flip %v: draw %d numLights is %v, expecting 1", test.flipY, i, got)
+			}
+		}
+		if got, want := toVec3(rec.Draws[1].Uniforms["modelScale"]), (mgl32.Vec3{0.5, 0.5, 0.5}); got != want {
+			t.Errorf("flip %v: modelScale is %v, expecting %v", test.flipY, got, want)
+		}
+	}
+}
+
+func TestViewStateCache(t *testing.T) {
+	rec := setup()
+	root, _, view := testScene()
+	drawFrame(t, root, view)
+	first := glu.FrameStats()
+	rec.Reset()
+	drawFrame(t, root, view)
+	second := glu.FrameStats()
+	// the program and its attribute layout are unchanged from the first frame
+	for _, name := range []string{"UseProgram", "EnableVertexAttribArray", "Enable", "DepthFunc", "BlendFunc", "FrontFace"} {
+		if n := rec.Count(name); n != 0 {
+			t.Errorf("%s called %d times in the second frame", name, n)
+		}
+	}
+	if second.Calls >= first.Calls || second.Saved <= first.Saved {
+		t.Errorf("expecting fewer state changes in the second frame: first %+v second %+v", first, second)
+	}
+	if len(rec.Draws) != 2 {
+		t.Errorf("expecting 2 draws, got %d", len(rec.Draws))
+	}
+}
+
+func toMat4(v []float32) (m mgl32.Mat4) {
+	copy(m[:], v)
+	return m
+}
+
+func toVec3(v []float32) (r mgl32.Vec3) {
+	copy(r[:], v)
+	return r
+}
@@ -0,0 +1,98 @@
+// drop utility runs a physics simulation headlessly, dropping a stack of boxes and a random mix of shapes onto
+// the ground, and prints where they come to rest. The results only depend on the -seed flag, so two runs can
+// be compared with diff. With -o the final scene is rendered with the software rasterizer.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/physics"
+	"github.com/jnb666/go3d/raster"
+	"github.com/jnb666/go3d/scene"
+	"image/png"
+	"os"
+)
+
+func main() {
+	var seed int64
+	var stack, count int
+	var seconds float64
+	var output string
+	flag.Int64Var(&seed, "seed", 1, "random seed")
+	flag.IntVar(&stack, "stack", 5, "height of the stack of boxes")
+	flag.IntVar(&count, "n", 10, "number of random shapes to drop")
+	flag.Float64Var(&seconds, "t", 10, "simulation time in seconds")
+	flag.StringVar(&output, "o", "", "render the final scene to this PNG file")
+	flag.Parse()
+	// materials need a context even if nothing is rendered
+	ctx := raster.New(512, 384)
+	glu.Init(ctx)
+	root, world := setup(seed, stack, count)
+	steps := int(float32(seconds) / world.TimeStep)
+	for i := 0; i < steps; i++ {
+		world.Step()
+	}
+	for i, b := range world.Bodies() {
+		fmt.Printf("%2d %-11s %8.4f %8.4f\n", i, b.Shape, b.Position, b.Orientation.V.Vec4(b.Orientation.W))
+	}
+	if output != "" {
+		if err := render(ctx, root, output); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// static ground with a stack of boxes in the middle and the other shapes at random positions above it
+func setup(seed int64, stack, count int) (*scene.Group, *physics.World) {
+	world := physics.NewWorld(seed)
+	rng := world.Rand()
+	ground := scene.NewItem(mesh.Cube().SetMaterial(mesh.Diffuse().SetColor(glu.Grey)))
+	ground.Translate(0, -0.1, 0).Scale(20, 0.2, 20)
+	world.Add(ground, physics.Box, 0)
+	root := scene.NewGroup().Add(ground)
+	for i := 0; i < stack; i++ {
+		box := scene.NewItem(mesh.Cube().SetMaterial(mesh.Plastic().SetColor(glu.Red)))
+		box.Translate(0, 0.5+float32(i), 0)
+		world.Add(box, physics.Box, 1)
+		root.Add(box)
+	}
+	for i := 0; i < count; i++ {
+		var item *scene.Item
+		shape := physics.Shape(rng.Intn(3))
+		switch shape {
+		case physics.Sphere:
+			item = scene.NewItem(mesh.Sphere(2).SetMaterial(mesh.Plastic().SetColor(glu.Green)))
+		case physics.Box:
+			item = scene.NewItem(mesh.Cube().SetMaterial(mesh.Plastic().SetColor(glu.Blue)))
+		default:
+			item = scene.NewItem(mesh.Icosohedron().SetMaterial(mesh.Plastic().SetColor(glu.Yellow)))
+		}
+		item.Translate(rng.Float32()*8-4, 2+float32(i), rng.Float32()*8-4)
+		item.Rotate(rng.Float32()*360, mgl32.Vec3{rng.Float32(), rng.Float32(), rng.Float32()}.Normalize())
+		world.Add(item, shape, 0.5+rng.Float32())
+		root.Add(item)
+	}
+	return root, world
+}
+
+func render(ctx *raster.Context, root scene.Object, file string) error {
+	camera := scene.ArcBallCamera(glu.Polar{R: 16, Theta: 60, Phi: 30}, mgl32.Vec3{0, 1, 0}, 0, 100, 0, 180)
+	light := scene.DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, glu.Polar{R: 1, Theta: 30, Phi: 60})
+	img, err := ctx.Render(root, scene.NewView(camera).AddLight(light), 512, 384)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/golden"
+	"github.com/jnb666/go3d/raster"
+	"runtime"
+	"testing"
+)
+
+var (
+	update = flag.Bool("update", false, "overwrite the reference images with the current output")
+	useGL  = flag.Bool("gl", false, "check the OpenGL images instead of the software rasterizer")
+)
+
+// check each image against the references in dir, differing images are saved in dir/failed
+func checkImages(t *testing.T, render renderFunc, dir string) {
+	suite := golden.NewSuite(dir, *update)
+	mtls, objs := materials(), shapes()
+	for _, gi := range imageList() {
+		// subtests run on another goroutine, so render here where the context is current
+		im, err := draw(render, objs[gi.shape], mtls[gi.material])
+		t.Run(gi.name, func(t *testing.T) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := suite.Check(gi.name, im); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	if *update {
+		t.Logf("updated %d reference images in %s", len(imageList()), dir)
+	}
+}
+
+// The material programs are cached for the first context, so only one renderer is checked in each run.
+// Use go test -gl to check the OpenGL output.
+func TestRaster(t *testing.T) {
+	if *useGL {
+		t.Skip("checking OpenGL images")
+	}
+	glu.PanicOnError = false
+	raster.Background = background
+	ctx := raster.New(width, height)
+	glu.Init(ctx)
+	checkImages(t, ctx.Render, "testdata/raster")
+}
+
+func TestGL(t *testing.T) {
+	if !*useGL {
+		t.Skip("use -gl to check the OpenGL images")
+	}
+	// the EGL context is current on the thread where it was created
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	glu.PanicOnError = false
+	offscreen.Background = background
+	win, err := offscreen.New(width, height)
+	if err != nil {
+		t.Skipf("no EGL context: %s", err)
+	}
+	defer win.Release()
+	glu.Init(win.GL())
+	checkImages(t, win.Render, "testdata")
+}
@@ -0,0 +1,205 @@
+// golden utility renders each of the built in shapes with each material offscreen and compares them
+// against the reference images. Run with -update to regenerate the references after an intended change.
+// With -raster the software renderer is used, which has its own set of references in testdata/raster.
+// go test checks the same images with the software renderer, or with OpenGL if the -gl flag is given.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/golden"
+	"github.com/jnb666/go3d/img"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/raster"
+	"github.com/jnb666/go3d/scene"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"regexp"
+)
+
+var (
+	width      = 128
+	height     = 96
+	background = mgl32.Vec4{0.5, 0.5, 1, 1}
+	cameraPos  = glu.Polar{R: 2.0, Theta: 70, Phi: 45}
+	lightPos   = glu.Polar{R: 1, Theta: 20, Phi: 90}
+	shapeList  = []string{"cube", "prism", "pyramid", "point", "plane", "circle", "cylinder", "cone", "icosohedron", "sphere"}
+	mtlList    = []string{"plastic", "wood", "rough", "marble", "metallic", "glass", "earth", "emissive", "diffuse",
+		"unshaded", "texture2d", "texturecube"}
+)
+
+// checkerboard test pattern used for the texture materials
+func checkerboard() []byte {
+	im := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x/8+y/8)%2 == 0 {
+				im.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			} else {
+				im.SetNRGBA(x, y, color.NRGBA{40, 80, 200, 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, im)
+	return buf.Bytes()
+}
+
+func materials() map[string]mesh.Material {
+	data := checkerboard()
+	tex2d, err := glu.NewTexture2D(false).SetImage(bytes.NewReader(data), img.NoConvert)
+	if err != nil {
+		panic(err)
+	}
+	texCube := glu.NewTextureCube()
+	for i := 0; i < 6; i++ {
+		if _, err = texCube.SetImage(bytes.NewReader(data), img.NoConvert, i); err != nil {
+			panic(err)
+		}
+	}
+	spec := mgl32.Vec4{0.5, 0.5, 0.5, 1}
+	return map[string]mesh.Material{
+		"plastic":     mesh.Plastic().SetColor(glu.Red),
+		"wood":        mesh.Wood(),
+		"rough":       mesh.Rough().SetColor(glu.Grey),
+		"marble":      mesh.Marble(),
+		"metallic":    mesh.Metallic(),
+		"glass":       mesh.Glass(),
+		"earth":       mesh.Earth(),
+		"emissive":    mesh.Emissive().SetColor(mgl32.Vec4{0.8, 0.8, 0.6, 1}),
+		"diffuse":     mesh.Diffuse().SetColor(glu.Red),
+		"unshaded":    mesh.Unshaded().SetColor(glu.Red),
+		"texture2d":   mesh.Reflective(spec, 32, tex2d),
+		"texturecube": mesh.Reflective(spec, 32, texCube),
+		"point":       mesh.PointMaterial().SetColor(glu.Red),
+	}
+}
+
+func shapes() map[string]scene.Object {
+	return map[string]scene.Object{
+		"cube":        scene.NewItem(mesh.Cube()),
+		"prism":       scene.NewItem(mesh.Prism()).Scale(1.1, 1.1, 1.1),
+		"pyramid":     scene.NewItem(mesh.Cone(4)).Scale(1.4, 1.1, 1.4),
+		"point":       scene.NewGroup().Add(scene.NewItem(mesh.Point(10)).Translate(0.5, 0, 0.5)),
+		"plane":       scene.NewItem(mesh.Plane()).Scale(2, 1, 2),
+		"circle":      scene.NewItem(mesh.Circle(60)).Scale(2, 1, 2),
+		"cylinder":    scene.NewGroup().Add(scene.NewItem(mesh.Cylinder(60)).RotateX(90)),
+		"cone":        scene.NewGroup().Add(scene.NewItem(mesh.Cone(120)).Scale(1.2, 1.2, 1.2).RotateX(90)),
+		"icosohedron": scene.NewItem(mesh.Icosohedron()).Scale(1.6, 1.6, 1.6),
+		"sphere":      scene.NewItem(mesh.Sphere(3)).Scale(1.4, 1.4, 1.4),
+	}
+}
+
+type goldenImage struct {
+	name, shape, material string
+}
+
+// each shape with each material, except for points which only use the point material
+func imageList() []goldenImage {
+	list := []goldenImage{}
+	for _, shape := range shapeList {
+		for _, mtl := range mtlList {
+			if shape == "point" {
+				if mtl != mtlList[0] {
+					continue
+				}
+				mtl = "point"
+			}
+			list = append(list, goldenImage{name: shape + "_" + mtl, shape: shape, material: mtl})
+		}
+	}
+	return list
+}
+
+// render method of the offscreen window or the software rasterizer
+type renderFunc func(scene.Object, *scene.View, int, int) (*image.NRGBA, error)
+
+// render the object with the material using the same camera and light for each image
+func draw(render renderFunc, obj scene.Object, mtl mesh.Material) (*image.NRGBA, error) {
+	obj.SetMaterial(mtl)
+	camera := scene.ArcBallCamera(cameraPos, mgl32.Vec3{}, 0.5, 5.0, 10, 170)
+	light := scene.DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, lightPos)
+	return render(obj, scene.NewView(camera).AddLight(light), width, height)
+}
+
+func main() {
+	var dir, match string
+	var software bool
+	suite := golden.NewSuite("", false)
+	flag.BoolVar(&suite.Update, "update", false, "overwrite the reference images with the current output")
+	flag.StringVar(&dir, "dir", "", "directory with the reference images (default testdata or testdata/raster)")
+	flag.BoolVar(&software, "raster", false, "use the software rasterizer instead of OpenGL")
+	flag.StringVar(&match, "run", "", "only check images with names matching this regular expression")
+	flag.IntVar(&width, "width", 128, "image width")
+	flag.IntVar(&height, "height", 96, "image height")
+	flag.Float64Var(&suite.Tol.DeltaE, "deltae", suite.Tol.DeltaE, "color difference for a pixel to be counted as changed")
+	flag.Float64Var(&suite.Tol.MaxDiff, "maxdiff", suite.Tol.MaxDiff, "fraction of pixels which may change")
+	flag.Parse()
+	if dir == "" {
+		dir = "testdata"
+		if software {
+			dir = "testdata/raster"
+		}
+	}
+	suite.Dir = dir
+	filter, err := regexp.Compile(match)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	glu.PanicOnError = false
+	var render renderFunc
+	if software {
+		raster.Background = background
+		ctx := raster.New(width, height)
+		glu.Init(ctx)
+		render = ctx.Render
+	} else {
+		offscreen.Background = background
+		win, err := offscreen.New(width, height)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+		defer win.Release()
+		glu.Init(win.GL())
+		render = win.Render
+	}
+	mtls, objs := materials(), shapes()
+	count := 0
+	for _, gi := range imageList() {
+		if !filter.MatchString(gi.name) {
+			continue
+		}
+		count++
+		im, err := draw(render, objs[gi.shape], mtls[gi.material])
+		if err == nil {
+			var res *golden.Result
+			if res, err = suite.Check(gi.name, im); res != nil && err == nil {
+				fmt.Println("ok  ", res)
+			}
+		}
+		if err != nil {
+			fmt.Println("FAIL", err)
+		}
+	}
+	switch {
+	case suite.Update:
+		fmt.Printf("updated %d reference images in %s\n", count, dir)
+	case suite.Failed > 0:
+		fmt.Printf("FAIL: %d of %d images differ, see %s/failed\n", suite.Failed, count, dir)
+		os.Exit(1)
+	default:
+		fmt.Printf("PASS: %d images\n", count)
+	}
+}
@@ -0,0 +1,148 @@
+// objrender utility renders a Wavefront OBJ model, a glTF model or a JSON scene file to a PNG file without a
+// display. With -raster it uses the software renderer so does not need a GL driver at all. The camera options
+// are ignored for a scene file which has its own camera. A glTF model is posed at time -t of its first
+// animation if it has one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/jnb666/go3d/animation"
+	"github.com/jnb666/go3d/backend/offscreen"
+	"github.com/jnb666/go3d/gltf"
+	"github.com/jnb666/go3d/glu"
+	"github.com/jnb666/go3d/mesh"
+	"github.com/jnb666/go3d/raster"
+	"github.com/jnb666/go3d/scene"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parse a comma separated list of floats
+func parseVec(s string, n int) ([]float32, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != n {
+		return nil, fmt.Errorf("expecting %d comma separated values: %q", n, s)
+	}
+	v := make([]float32, n)
+	for i, f := range fields {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 32)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = float32(x)
+	}
+	return v, nil
+}
+
+func main() {
+	var width, height int
+	var dist, theta, phi, scale, time float64
+	var output, center, bg string
+	var software, fit bool
+	flag.IntVar(&width, "width", 256, "image width")
+	flag.IntVar(&height, "height", 256, "image height")
+	flag.Float64Var(&dist, "r", 2, "camera distance from center")
+	flag.Float64Var(&theta, "theta", 70, "camera angle from vertical in degrees")
+	flag.Float64Var(&phi, "phi", 45, "camera angle around vertical axis in degrees")
+	flag.Float64Var(&scale, "scale", 1, "scale factor to apply to the model")
+	flag.Float64Var(&time, "t", 0, "time in seconds of the glTF animation to show")
+	flag.StringVar(&center, "center", "0,0,0", "point which the camera looks at")
+	flag.StringVar(&bg, "bg", "0,0,0,0", "background color as r,g,b,a")
+	flag.BoolVar(&fit, "fit", false, "scale the model to fit in a unit box centered on the origin before applying -scale")
+	flag.BoolVar(&software, "raster", false, "use the software rasterizer instead of OpenGL")
+	flag.StringVar(&output, "o", "", "output file, defaults to input file name with .png extension")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: objrender [options] file.obj|model.gltf|model.glb|scene.json")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if err := render(flag.Arg(0), output, center, bg, software, fit, width, height, float32(dist), float32(theta), float32(phi), float32(scale), float32(time)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func render(file, output, center, bg string, software, fit bool, width, height int, dist, theta, phi, scale, time float32) error {
+	c, err := parseVec(center, 3)
+	if err != nil {
+		return err
+	}
+	col, err := parseVec(bg, 4)
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	if output == "" {
+		output = strings.TrimSuffix(file, filepath.Ext(file)) + ".png"
+	}
+	// materials in a scene file are created as it is loaded so the context is needed first
+	background := mgl32.Vec4{col[0], col[1], col[2], col[3]}
+	var draw func(scene.Object, *scene.View, int, int) (*image.NRGBA, error)
+	if software {
+		raster.Background = background
+		ctx := raster.New(width, height)
+		glu.Init(ctx)
+		draw = ctx.Render
+	} else {
+		offscreen.Background = background
+		win, err := offscreen.New(width, height)
+		if err != nil {
+			return err
+		}
+		defer win.Release()
+		glu.Init(win.GL())
+		draw = win.Render
+	}
+	var root scene.Object
+	var view *scene.View
+	switch ext {
+	case ".json":
+		if root, view, err = scene.LoadFile(file); err != nil {
+			return err
+		}
+	case ".gltf", ".glb":
+		model, clips, err := gltf.LoadFile(file)
+		if err != nil {
+			return err
+		}
+		if len(clips) > 0 {
+			animation.NewPlayer(clips[0], model, animation.Loop).Seek(time)
+		}
+		root = model
+	default:
+		model, err := mesh.LoadObjFile(file)
+		if err != nil {
+			return err
+		}
+		root = scene.NewItem(model)
+	}
+	if fit {
+		root = scene.Normalize(root)
+	}
+	root.Scale(scale, scale, scale)
+	if view == nil {
+		camera := scene.ArcBallCamera(glu.Polar{R: dist, Theta: theta, Phi: phi}, mgl32.Vec3{c[0], c[1], c[2]}, 0, 1e6, 0, 180)
+		light := scene.DirectionalLight(mgl32.Vec3{0.8, 0.8, 0.8}, 0.2, glu.Polar{R: 1, Theta: theta - 30, Phi: phi + 30})
+		view = scene.NewView(camera).AddLight(light)
+	}
+	img, err := draw(root, view, width, height)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}